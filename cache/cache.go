@@ -14,4 +14,11 @@ type Cache interface {
 	Get(ctx context.Context, key string) (interface{}, error)
 	GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error)
 	Delete(ctx context.Context, key string) error
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	RPush(ctx context.Context, key string, ttl time.Duration, values ...interface{}) error
+	BLPop(ctx context.Context, timeout time.Duration, key string) (string, error)
+	BLMove(ctx context.Context, timeout time.Duration, source string, destination string) (string, error)
+	LRange(ctx context.Context, key string) ([]string, error)
+	LRem(ctx context.Context, key string, value interface{}) (int64, error)
+	Incr(ctx context.Context, key string) (int64, error)
 }
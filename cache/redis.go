@@ -53,6 +53,50 @@ func (r *redisCache) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+// SetNX sets the value only if the key does not exist, it tells if the value was set
+func (r *redisCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// RPush appends values to a list and keeps the whole list for ttl
+func (r *redisCache) RPush(ctx context.Context, key string, ttl time.Duration, values ...interface{}) error {
+	pipe := r.client.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// BLPop waits up to timeout for the first value of a list, redis.Nil is returned when none came
+func (r *redisCache) BLPop(ctx context.Context, timeout time.Duration, key string) (string, error) {
+	result, err := r.client.BLPop(ctx, timeout, key).Result()
+	if err != nil {
+		return "", err
+	}
+	return result[1], nil
+}
+
+// BLMove waits up to timeout for the first value of source and moves it to the end of destination in the
+// same step, so the value is never only held by the caller. redis.Nil is returned when none came
+func (r *redisCache) BLMove(ctx context.Context, timeout time.Duration, source string, destination string) (string, error) {
+	return r.client.BLMove(ctx, source, destination, "LEFT", "RIGHT", timeout).Result()
+}
+
+// LRange returns every value of a list
+func (r *redisCache) LRange(ctx context.Context, key string) ([]string, error) {
+	return r.client.LRange(ctx, key, 0, -1).Result()
+}
+
+// LRem removes the first occurrence of value from a list and tells how many were removed
+func (r *redisCache) LRem(ctx context.Context, key string, value interface{}) (int64, error) {
+	return r.client.LRem(ctx, key, 1, value).Result()
+}
+
+// Incr adds one to the counter of key and returns it, a missing key counts from zero
+func (r *redisCache) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, key).Result()
+}
+
 // NewRedisCache create new redis cache
 func NewRedisCache(redisClient *redis.Client) Cache {
 	return &redisCache{redisClient}
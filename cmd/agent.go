@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"github.com/labstack/echo-contrib/prometheus"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/spf13/cobra"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"test-manager/handlers"
+	"time"
+)
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.Flags().String("address", ":10002", "address the agent listens on, the datacenter baseurl points here")
+	agentCmd.Flags().String("keys-file", "agent_keys", "file of \"key_id secret\" lines with the datacenter keys the manager signs with")
+	agentCmd.Flags().String("manager", "", "url of the manager to long poll for jobs, for pull datacenters the manager can not reach")
+	agentCmd.Flags().Int("pull-workers", 4, "jobs a pull agent runs at once")
+}
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "datacenter agent running the checks of the manager",
+	Long: `datacenter agent serving the /v1 endpoints the manager sends curl, netcat, pagespeed, ping, traceroute, tls and dns checks to.
+with --manager it also long polls the manager for the checks of a pull datacenter`,
+	Run: func(cmd *cobra.Command, args []string) {
+		address, err := cmd.Flags().GetString("address")
+		if err != nil {
+			panic(err)
+		}
+		keysFile, err := cmd.Flags().GetString("keys-file")
+		if err != nil {
+			panic(err)
+		}
+		managerUrl, err := cmd.Flags().GetString("manager")
+		if err != nil {
+			panic(err)
+		}
+		pullWorkers, err := cmd.Flags().GetInt("pull-workers")
+		if err != nil {
+			panic(err)
+		}
+		keyring, err := handlers.NewAgentKeyring(keysFile)
+		if err != nil {
+			panic(err)
+		}
+
+		e := echo.New()
+
+		p := prometheus.NewPrometheus("automated_test_agent", nil)
+		p.Use(e)
+		e.Use(middleware.Logger())
+		e.Use(middleware.Recover())
+
+		controllers := handlers.NewAgentControllers()
+		// the health check stays open for the datacenter prober of the scheduler
+		agentAuth := handlers.WithAgentSignature(keyring)
+		e.GET("/v1/health", controllers.Health)
+		e.POST("/v1/curl", controllers.Curl, agentAuth)
+		e.POST("/v1/netcat", controllers.NetCat, agentAuth)
+		e.POST("/v1/pagespeed", controllers.PageSpeed, agentAuth)
+		e.POST("/v1/ping", controllers.Ping, agentAuth)
+		e.POST("/v1/traceroute", controllers.TraceRoute, agentAuth)
+		e.POST("/v1/tls", controllers.TLS, agentAuth)
+		e.POST("/v1/dns", controllers.DNS, agentAuth)
+
+		// Start server
+		go func() {
+			if err := e.Start(address); err != nil && err != http.ErrServerClosed {
+				log.Fatal("shutting down server")
+			}
+		}()
+
+		pullCtx, stopPull := context.WithCancel(context.Background())
+		pullDone := make(chan struct{})
+		if managerUrl != "" {
+			puller := handlers.NewAgentPuller(managerUrl, keyring, controllers, pullWorkers)
+			go func() {
+				puller.Run(pullCtx)
+				close(pullDone)
+			}()
+		} else {
+			close(pullDone)
+		}
+
+		// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt)
+		<-quit
+		stopPull()
+		<-pullDone
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := e.Shutdown(ctx); err != nil {
+			e.Logger.Fatal(err)
+		}
+	},
+}
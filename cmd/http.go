@@ -19,6 +19,7 @@ import (
 	"test-manager/handlers"
 	"test-manager/repos"
 	"test-manager/services/alert_system"
+	"test-manager/services/webhooks"
 	"test-manager/tasks/push"
 	"test-manager/utils"
 	"time"
@@ -102,8 +103,11 @@ var httpCmd = &cobra.Command{
 		pageSpeedRepo := repos.NewPageSpeedRepository(psqlDb)
 		pingRepo := repos.NewPingRepository(psqlDb)
 		traceRouteRepo := repos.NewTraceRouteRepository(psqlDb)
+		tlsMonitorsRepo := repos.NewTLSMonitorsRepository(psqlDb)
+		dnsMonitorsRepo := repos.NewDNSMonitorsRepository(psqlDb)
+		monitorStatesRepo := repos.NewMonitorStatesRepository(psqlDb)
 
-		aggregateRepo := repos.NewAggregateRepository(psqlDb, endpointRepo, netCatRepo, pageSpeedRepo, pingRepo, traceRouteRepo)
+		aggregateRepo := repos.NewAggregateRepository(psqlDb, endpointRepo, netCatRepo, pageSpeedRepo, pingRepo, traceRouteRepo, tlsMonitorsRepo, dnsMonitorsRepo, monitorStatesRepo)
 
 		packageRepo := repos.NewPackagesRepository(psqlDb)
 		dataCenterRepo := repos.NewDataCentersRepositoryRepository(cacheRepo, psqlDb)
@@ -115,6 +119,8 @@ var httpCmd = &cobra.Command{
 		pingStatsRepo := repos.NewPingStatsRepository(psqlDb)
 		traceRouteStatsRepo := repos.NewTraceRouteStatsRepository(psqlDb)
 		pageSpeedStatsRepo := repos.NewPageSpeedStatsRepository(psqlDb)
+		tlsStatsRepo := repos.NewTLSStatsRepository(psqlDb)
+		dnsStatsRepo := repos.NewDNSStatsRepository(psqlDb)
 
 		draftRepo := repos.NewDraftsRepository(psqlDb)
 
@@ -127,17 +133,30 @@ var httpCmd = &cobra.Command{
 
 		faqRepo := repos.NewFaqsRepository(psqlDb)
 		ticketRepo := repos.NewTicketsRepository(psqlDb)
-
-		agentHandler := handlers.NewAgentHandler()
+		incidentsRepo := repos.NewIncidentsRepository(psqlDb)
+
+		maintenanceRepo := repos.NewMaintenanceWindowsRepository(psqlDb)
+		jsonSchemasRepo := repos.NewJsonSchemasRepository(psqlDb)
+		cronMonitorsRepo := repos.NewCronMonitorsRepository(psqlDb)
+		statusPagesRepo := repos.NewStatusPagesRepository(psqlDb)
+		uptimeRepo := repos.NewUptimeRepository(psqlDb)
+		badgesRepo := repos.NewBadgesRepository(psqlDb)
+		webhookDeliveriesRepo := repos.NewWebhookDeliveriesRepository(psqlDb)
+		webhookDispatcher := webhooks.NewDispatcher(projectRepo, webhookDeliveriesRepo, taskPusher)
+
+		datacenterKeysRepo := repos.NewDatacenterKeysRepository(psqlDb)
+		agentHandler := handlers.NewAgentHandler(dataCenterRepo, datacenterKeysRepo, cacheRepo)
 		endpointHandler := handlers.NewEndpointHandler(alertSystem, endpointRepo, dataCenterRepo,
-			projectRepo, endpointStatsRepo, cacheRepo, taskPusher, agentHandler)
-		netCatHandler := handlers.NewNetCatHandler(alertSystem, netCatRepo, dataCenterRepo, projectRepo, netCatStatsRepo, taskPusher, agentHandler)
-		pageSpeedHandler := handlers.NewPageSpeedHandler(alertSystem, pageSpeedRepo, dataCenterRepo, projectRepo, pageSpeedStatsRepo, taskPusher, agentHandler)
-		pingHandler := handlers.NewPingHandler(alertSystem, pingRepo, dataCenterRepo, projectRepo, pingStatsRepo, taskPusher, agentHandler)
-		traceRouteHandler := handlers.NewTraceRouteHandler(alertSystem, traceRouteRepo, dataCenterRepo, projectRepo, traceRouteStatsRepo, taskPusher, agentHandler)
+			projectRepo, endpointStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, jsonSchemasRepo, taskPusher, agentHandler)
+		netCatHandler := handlers.NewNetCatHandler(alertSystem, netCatRepo, dataCenterRepo, projectRepo, netCatStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		pageSpeedHandler := handlers.NewPageSpeedHandler(alertSystem, pageSpeedRepo, dataCenterRepo, projectRepo, pageSpeedStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		pingHandler := handlers.NewPingHandler(alertSystem, pingRepo, dataCenterRepo, projectRepo, pingStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		traceRouteHandler := handlers.NewTraceRouteHandler(alertSystem, traceRouteRepo, dataCenterRepo, projectRepo, traceRouteStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		tlsHandler := handlers.NewTLSHandler(tlsMonitorsRepo, tlsStatsRepo, dataCenterRepo, projectRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		dnsHandler := handlers.NewDNSHandler(dnsMonitorsRepo, dnsStatsRepo, dataCenterRepo, projectRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
 
 		//endpointHandler := handlers.NewEndpointHandler(endpointRepo, dataCenterRepo, taskPusher, agentHandler)
-		ruleHandler := handlers.NewRulesHandler(projectRepo, endpointRepo, netCatRepo, pageSpeedRepo, pingRepo, traceRouteRepo, dataCenterRepo, taskPusher, agentHandler)
+		ruleHandler := handlers.NewRulesHandler(projectRepo, endpointRepo, netCatRepo, pageSpeedRepo, pingRepo, traceRouteRepo, tlsMonitorsRepo, dnsMonitorsRepo, dataCenterRepo, taskPusher, agentHandler)
 		controllers := handlers.NewHttpControllers(
 			ruleHandler,
 			endpointHandler,
@@ -168,7 +187,24 @@ var httpCmd = &cobra.Command{
 			netCatStatsRepo,
 			pageSpeedStatsRepo,
 			pingStatsRepo,
-			traceRouteStatsRepo)
+			traceRouteStatsRepo,
+			incidentsRepo,
+			maintenanceRepo,
+			statusPagesRepo,
+			uptimeRepo,
+			badgesRepo,
+			webhookDeliveriesRepo,
+			webhookDispatcher,
+			jsonSchemasRepo,
+			cronMonitorsRepo,
+			tlsHandler,
+			tlsMonitorsRepo,
+			tlsStatsRepo,
+			dnsHandler,
+			dnsMonitorsRepo,
+			dnsStatsRepo,
+			datacenterKeysRepo,
+			config.Platform.OperatorAccountIds)
 
 		e.GET("/", controllers.Hello)
 		e.POST("/rules/endpoint/register", controllers.RegisterEndpointRules, handlers.WithAuth())
@@ -176,30 +212,40 @@ var httpCmd = &cobra.Command{
 		e.POST("/rules/ping/register", controllers.RegisterPingRules, handlers.WithAuth())
 		e.POST("/rules/traceroute/register", controllers.RegisterTraceRouteRules, handlers.WithAuth())
 		e.POST("/rules/pagespeed/register", controllers.RegisterPageSpeedRules, handlers.WithAuth())
+		e.POST("/rules/tls/register", controllers.RegisterTLSRules, handlers.WithAuth())
+		e.POST("/rules/dns/register", controllers.RegisterDNSRules, handlers.WithAuth())
 
 		e.POST("/rules/endpoint/manual", controllers.ManualRunEndpointRules, handlers.WithAuth())
 		e.POST("/rules/netcat/manual", controllers.ManualRunNetCatRules, handlers.WithAuth())
 		e.POST("/rules/ping/manual", controllers.ManualRunPingRules, handlers.WithAuth())
 		e.POST("/rules/traceroute/manual", controllers.ManualRunTraceRouteRules, handlers.WithAuth())
 		e.POST("/rules/pagespeed/manual", controllers.ManualRunPageSpeedRules, handlers.WithAuth())
+		e.POST("/rules/tls/manual", controllers.ManualRunTLSRules, handlers.WithAuth())
+		e.POST("/rules/dns/manual", controllers.ManualRunDNSRules, handlers.WithAuth())
 
 		e.GET("/rules/endpoint/:project_id/:id", controllers.GetEndpointRules, handlers.WithAuth())
 		e.GET("/rules/netcat/:project_id/:id", controllers.GetNetCatRules, handlers.WithAuth())
 		e.GET("/rules/ping/:project_id/:id", controllers.GetPingRules, handlers.WithAuth())
 		e.GET("/rules/traceroute/:project_id/:id", controllers.GetTraceRouteRules, handlers.WithAuth())
 		e.GET("/rules/pagespeed/:project_id/:id", controllers.GetPageSpeedRules, handlers.WithAuth())
+		e.GET("/rules/tls/:project_id/:id", controllers.GetTLSRules, handlers.WithAuth())
+		e.GET("/rules/dns/:project_id/:id", controllers.GetDNSRules, handlers.WithAuth())
 
 		e.PUT("/rules/endpoint/:id", controllers.UpdateEndpointRules, handlers.WithAuth())
 		e.PUT("/rules/netcat/:id", controllers.UpdateNetCatRules, handlers.WithAuth())
 		e.PUT("/rules/ping/:id", controllers.UpdatePingRules, handlers.WithAuth())
 		e.PUT("/rules/traceroute/:id", controllers.UpdateTraceRouteRules, handlers.WithAuth())
 		e.PUT("/rules/pagespeed/:id", controllers.UpdatePageSpeedRules, handlers.WithAuth())
+		e.PUT("/rules/tls/:id", controllers.UpdateTLSRules, handlers.WithAuth())
+		e.PUT("/rules/dns/:id", controllers.UpdateDNSRules, handlers.WithAuth())
 
 		e.DELETE("/rules/endpoint/:id", controllers.DeleteEndpointRules, handlers.WithAuth())
 		e.DELETE("/rules/netcat/:id", controllers.DeleteNetCatRules, handlers.WithAuth())
 		e.DELETE("/rules/ping/:id", controllers.DeletePingRules, handlers.WithAuth())
 		e.DELETE("/rules/traceroute/:id", controllers.DeleteTraceRouteRules, handlers.WithAuth())
 		e.DELETE("/rules/pagespeed/:id", controllers.DeletePageSpeedRules, handlers.WithAuth())
+		e.DELETE("/rules/tls/:id", controllers.DeleteTLSRules, handlers.WithAuth())
+		e.DELETE("/rules/dns/:id", controllers.DeleteDNSRules, handlers.WithAuth())
 
 		e.GET("/rules/:project_id", controllers.GetRules, handlers.WithAuth())
 
@@ -222,6 +268,8 @@ var httpCmd = &cobra.Command{
 		e.GET("/report/ping/quick", controllers.ReportPingQuickStats, handlers.WithAuth())
 		e.GET("/report/traceroute/quick", controllers.ReportTraceRouteQuickStats, handlers.WithAuth())
 		e.GET("/report/pagespeed/quick", controllers.ReportPageSpeedQuickStats, handlers.WithAuth())
+		e.GET("/report/tls/certificates", controllers.ReportTLSCertificates, handlers.WithAuth())
+		e.GET("/report/dns/answers", controllers.ReportDNSAnswers, handlers.WithAuth())
 
 		e.POST("/email/verification", controllers.VerificationCode)
 		//e.POST("/register", controllers.Register)
@@ -242,6 +290,16 @@ var httpCmd = &cobra.Command{
 		e.POST("/datacenters", controllers.CreateDatacenter, handlers.WithAuth())
 		e.GET("/datacenters/:datacenter_id", controllers.GetDatacenter, handlers.WithAuth())
 		e.PUT("/datacenters/:datacenter_id", controllers.UpdateDatacenter, handlers.WithAuth())
+		e.POST("/datacenters/:datacenter_id/keys", controllers.CreateDatacenterKey, handlers.WithAuth())
+		e.GET("/datacenters/:datacenter_id/keys", controllers.GetDatacenterKeys, handlers.WithAuth())
+		e.DELETE("/datacenters/:datacenter_id/keys/:key_id", controllers.RevokeDatacenterKey, handlers.WithAuth())
+		e.POST("/projects/:project_id/datacenters", controllers.CreateProjectDatacenter, handlers.WithAuth())
+		e.GET("/projects/:project_id/datacenters", controllers.GetProjectDatacenters, handlers.WithAuth())
+
+		// pull agents sign with the keys of their datacenter instead of a user token
+		agentAuth := handlers.WithDatacenterSignature(datacenterKeysRepo, cacheRepo)
+		e.POST("/agent/jobs/poll", controllers.PollAgentJobs, agentAuth)
+		e.POST("/agent/jobs/:job_id/result", controllers.ReportAgentJobResult, agentAuth)
 
 		e.POST("/gateway", controllers.CreateGateway, handlers.WithAuth())
 		e.GET("/gateway/:gateway_id", controllers.GetGateways, handlers.WithAuth())
@@ -253,6 +311,47 @@ var httpCmd = &cobra.Command{
 
 		// TODO: delete alert (with delete all)
 		e.GET("/alert/stats/:projectId", controllers.AlertStats, handlers.WithAuth())
+		e.POST("/alert/incidents/:project_id/:incident_id/acknowledge", controllers.AcknowledgeIncident, handlers.WithAuth())
+		e.POST("/alert/incidents/:project_id/:incident_id/notes", controllers.AddIncidentNote, handlers.WithAuth())
+		e.POST("/alert/incidents/:project_id/:incident_id/resolve", controllers.ResolveIncident, handlers.WithAuth())
+
+		e.GET("/incidents/:project_id", controllers.GetIncidents, handlers.WithAuth())
+		e.GET("/incidents/:project_id/:incident_id", controllers.GetIncident, handlers.WithAuth())
+
+		e.POST("/maintenance", controllers.CreateMaintenanceWindow, handlers.WithAuth())
+		e.GET("/maintenance/:project_id", controllers.GetMaintenanceWindows, handlers.WithAuth())
+		e.PUT("/maintenance/:maintenance_id", controllers.UpdateMaintenanceWindow, handlers.WithAuth())
+		e.DELETE("/maintenance/:maintenance_id", controllers.DeleteMaintenanceWindow, handlers.WithAuth())
+
+		e.POST("/status-pages", controllers.CreateStatusPage, handlers.WithAuth())
+		e.GET("/status-pages/:project_id", controllers.GetStatusPages, handlers.WithAuth())
+		e.PUT("/status-pages/:status_page_id", controllers.UpdateStatusPage, handlers.WithAuth())
+		e.DELETE("/status-pages/:status_page_id", controllers.DeleteStatusPage, handlers.WithAuth())
+		e.GET("/status/:slug", controllers.GetPublicStatusPageHtml)
+		e.GET("/status/:slug/json", controllers.GetPublicStatusPage)
+
+		e.POST("/badges", controllers.EnableBadge, handlers.WithAuth())
+		e.GET("/badges/:project_id", controllers.GetBadges, handlers.WithAuth())
+		e.DELETE("/badges/:badge_id", controllers.DeleteBadge, handlers.WithAuth())
+		e.GET("/badge/:pipeline_id/uptime", controllers.GetUptimeBadge)
+		e.GET("/badge/:pipeline_id/status", controllers.GetStatusBadge)
+
+		e.GET("/webhooks/deliveries/:project_id", controllers.GetWebhookDeliveries, handlers.WithAuth())
+
+		e.POST("/json-schemas", controllers.CreateJsonSchema, handlers.WithAuth())
+		e.GET("/json-schemas/:project_id", controllers.GetJsonSchemas, handlers.WithAuth())
+		e.PUT("/json-schemas/:json_schema_id", controllers.UpdateJsonSchema, handlers.WithAuth())
+		e.DELETE("/json-schemas/:json_schema_id", controllers.DeleteJsonSchema, handlers.WithAuth())
+
+		e.POST("/cron-monitors", controllers.CreateCronMonitor, handlers.WithAuth())
+		e.GET("/cron-monitors/:project_id", controllers.GetCronMonitors, handlers.WithAuth())
+		e.PUT("/cron-monitors/:cron_monitor_id", controllers.UpdateCronMonitor, handlers.WithAuth())
+		e.DELETE("/cron-monitors/:cron_monitor_id", controllers.DeleteCronMonitor, handlers.WithAuth())
+		e.GET("/cron-monitors/:cron_monitor_id/pings", controllers.GetCronMonitorPings, handlers.WithAuth())
+		e.GET("/cron/:token", controllers.PingCronMonitor)
+		e.POST("/cron/:token", controllers.PingCronMonitor)
+		e.GET("/cron/:token/:kind", controllers.PingCronMonitor)
+		e.POST("/cron/:token/:kind", controllers.PingCronMonitor)
 
 		e.POST("/faq", controllers.CreateFaq, handlers.WithAuth())
 		e.GET("/faq/:faq_id", controllers.GetFaq, handlers.WithAuth())
@@ -12,6 +12,7 @@ import (
 	//_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"test-manager/cache"
 	"test-manager/config"
 	"test-manager/handlers"
 	"test-manager/repos"
@@ -67,6 +68,14 @@ var schedulerTasksCmd = &cobra.Command{
 			panic(err)
 		}
 
+		redisCacheClient, err := utils.CreateRedisConnection(context.TODO(), cfg.Database.RedisCache.Host, cfg.Database.RedisCache.Port, cfg.Database.RedisCache.Database, time.Duration(cfg.Database.RedisCache.Timeout)*time.Second)
+		if err != nil {
+			panic(err)
+		}
+		defer redisCacheClient.Close()
+
+		cacheRepo := cache.NewRedisCache(redisCacheClient)
+
 		asynqClient := asynq.NewClient(asynq.RedisClientOpt{
 			Addr:        redisClient.Options().Addr,
 			DialTimeout: redisClient.Options().DialTimeout,
@@ -87,7 +96,14 @@ var schedulerTasksCmd = &cobra.Command{
 		}
 
 		endpointRepo := repos.NewEndpointRepository(psqlDb)
-		heartBeatScheduler := handlers.NewHeartBeatScheduler(endpointRepo, taskPusher, inspector)
+		netCatRepo := repos.NewNetCatRepository(psqlDb)
+		pageSpeedRepo := repos.NewPageSpeedRepository(psqlDb)
+		pingRepo := repos.NewPingRepository(psqlDb)
+		traceRouteRepo := repos.NewTraceRouteRepository(psqlDb)
+		tlsMonitorsRepo := repos.NewTLSMonitorsRepository(psqlDb)
+		dnsMonitorsRepo := repos.NewDNSMonitorsRepository(psqlDb)
+		heartBeatScheduler := handlers.NewHeartBeatScheduler(endpointRepo, netCatRepo, pageSpeedRepo,
+			traceRouteRepo, pingRepo, tlsMonitorsRepo, dnsMonitorsRepo, taskPusher, inspector)
 		if err != nil {
 			panic(err)
 		}
@@ -95,6 +111,16 @@ var schedulerTasksCmd = &cobra.Command{
 		ctx := context.Background()
 		heartBeatScheduler.HeartBeatScheduling(ctx)
 
+		projectRepo := repos.NewProjectsRepository(psqlDb)
+		maintenanceRepo := repos.NewMaintenanceWindowsRepository(psqlDb)
+		cronMonitorsRepo := repos.NewCronMonitorsRepository(psqlDb)
+		cronMonitorChecker := handlers.NewCronMonitorChecker(cronMonitorsRepo, projectRepo, maintenanceRepo, taskPusher)
+		cronMonitorChecker.Run(ctx)
+
+		dataCenterRepo := repos.NewDataCentersRepositoryRepository(cacheRepo, psqlDb)
+		datacenterHealthProber := handlers.NewDatacenterHealthProber(dataCenterRepo)
+		datacenterHealthProber.Run(ctx)
+
 		// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, os.Interrupt)
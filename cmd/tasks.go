@@ -18,6 +18,8 @@ import (
 	"test-manager/handlers"
 	"test-manager/repos"
 	"test-manager/services/alert_system"
+	"test-manager/services/notifier"
+	"test-manager/services/webhooks"
 	"test-manager/tasks"
 	"test-manager/tasks/push"
 	"test-manager/tasks/task_models"
@@ -32,13 +34,16 @@ const (
 var (
 	// list of queues associated with priority, large numbers indicate higher priority
 	queues = map[string]int{
-		task_models.QueueEndpoint: 6,
-		//task_models.QueueNetCats:       0,
-		//task_models.QueuePageSpeeds:    0,
-		//task_models.QueuePings:         0,
-		//task_models.QueueTraceRoutes:   0,
+		task_models.QueueEndpoint:      6,
+		task_models.QueueNetCats:       6,
+		task_models.QueuePageSpeeds:    6,
+		task_models.QueuePings:         6,
+		task_models.QueueTraceRoutes:   6,
+		task_models.QueueTLS:           6,
+		task_models.QueueDNS:           6,
 		task_models.QueueNotification:  6,
 		task_models.QueueEndpointStore: 6,
+		task_models.QueueWebhook:       3,
 	}
 )
 
@@ -130,6 +135,8 @@ var consumeTasksCmd = &cobra.Command{
 				Concurrency: numWorkersAsynq,
 				Logger:      zLogger,
 				Queues:      queues,
+				// only webhook deliveries use their own backoff
+				RetryDelayFunc: tasks.WebhookRetryDelay,
 			},
 		)
 
@@ -143,36 +150,62 @@ var consumeTasksCmd = &cobra.Command{
 
 		// alert system
 		alertSystem := alert_system.NewAlertHandler(config.Services.Alert.BaseUrl)
+		notifiers := notifier.Notifiers{
+			notifier.DriverAlertService: notifier.NewAlertServiceNotifier(alertSystem),
+			notifier.DriverSlack:        notifier.NewSlackNotifier(),
+			notifier.DriverTelegram:     notifier.NewTelegramNotifier(config.Services.Notifiers.Telegram.BaseUrl, config.Services.Notifiers.Telegram.BotToken),
+			notifier.DriverSmtp: notifier.NewSmtpNotifier(config.Services.Notifiers.Smtp.Host, config.Services.Notifiers.Smtp.Port,
+				config.Services.Notifiers.Smtp.Username, config.Services.Notifiers.Smtp.Password, config.Services.Notifiers.Smtp.From),
+			notifier.DriverWebhook:   notifier.NewWebhookNotifier(),
+			notifier.DriverPagerDuty: notifier.NewPagerDutyNotifier(config.Services.Notifiers.PagerDuty.BaseUrl),
+		}
 
 		projectRepo := repos.NewProjectsRepository(psqlDb)
 		endpointRepo := repos.NewEndpointRepository(psqlDb)
-		//netCatRepo := repos.NewNetCatRepository(psqlDb)
-		//pageSpeedRepo := repos.NewPageSpeedRepository(psqlDb)
-		//pingRepo := repos.NewPingRepository(psqlDb)
-		//traceRouteRepo := repos.NewTraceRouteRepository(psqlDb)
+		netCatRepo := repos.NewNetCatRepository(psqlDb)
+		pageSpeedRepo := repos.NewPageSpeedRepository(psqlDb)
+		pingRepo := repos.NewPingRepository(psqlDb)
+		traceRouteRepo := repos.NewTraceRouteRepository(psqlDb)
+		tlsMonitorsRepo := repos.NewTLSMonitorsRepository(psqlDb)
+		dnsMonitorsRepo := repos.NewDNSMonitorsRepository(psqlDb)
 		dataCenterRepo := repos.NewDataCentersRepositoryRepository(cacheRepo, psqlDb)
 		endpointStatsRepo := repos.NewEndpointStatsRepository(psqlDb)
-		//netCatStatsRepo := repos.NewNetCatStatsRepository(psqlDb)
-		//pageSPeedStatsRepo := repos.NewPageSpeedStatsRepository(psqlDb)
-		//pingStatsRepo := repos.NewPingStatsRepository(psqlDb)
-		//traceRouteStatsRepo := repos.NewTraceRouteStatsRepository(psqlDb)
-
-		agentHandler := handlers.NewAgentHandler()
+		netCatStatsRepo := repos.NewNetCatStatsRepository(psqlDb)
+		pageSpeedStatsRepo := repos.NewPageSpeedStatsRepository(psqlDb)
+		pingStatsRepo := repos.NewPingStatsRepository(psqlDb)
+		traceRouteStatsRepo := repos.NewTraceRouteStatsRepository(psqlDb)
+		tlsStatsRepo := repos.NewTLSStatsRepository(psqlDb)
+		dnsStatsRepo := repos.NewDNSStatsRepository(psqlDb)
+		incidentsRepo := repos.NewIncidentsRepository(psqlDb)
+		webhookDeliveriesRepo := repos.NewWebhookDeliveriesRepository(psqlDb)
+		webhookDispatcher := webhooks.NewDispatcher(projectRepo, webhookDeliveriesRepo, taskPusher)
+
+		maintenanceRepo := repos.NewMaintenanceWindowsRepository(psqlDb)
+		monitorStatesRepo := repos.NewMonitorStatesRepository(psqlDb)
+		jsonSchemasRepo := repos.NewJsonSchemasRepository(psqlDb)
+
+		datacenterKeysRepo := repos.NewDatacenterKeysRepository(psqlDb)
+		agentHandler := handlers.NewAgentHandler(dataCenterRepo, datacenterKeysRepo, cacheRepo)
 		endpointHandler := handlers.NewEndpointHandler(alertSystem, endpointRepo, dataCenterRepo,
-			projectRepo, endpointStatsRepo, cacheRepo, taskPusher, agentHandler)
-		//netCatHandler := handlers.NewNetCatHandler(alertSystem, netCatRepo, dataCenterRepo, projectRepo, netCatStatsRepo, taskPusher, agentHandler)
-		//pageSpeedHandler := handlers.NewPageSpeedHandler(alertSystem, pageSpeedRepo, dataCenterRepo, projectRepo, pageSPeedStatsRepo, taskPusher, agentHandler)
-		//pingHandler := handlers.NewPingHandler(alertSystem, pingRepo, dataCenterRepo, projectRepo, pingStatsRepo, taskPusher, agentHandler)
-		//traceRouteHandler := handlers.NewTraceRouteHandler(alertSystem, traceRouteRepo, dataCenterRepo, projectRepo, traceRouteStatsRepo, taskPusher, agentHandler)
+			projectRepo, endpointStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, jsonSchemasRepo, taskPusher, agentHandler)
+		netCatHandler := handlers.NewNetCatHandler(alertSystem, netCatRepo, dataCenterRepo, projectRepo, netCatStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		pageSpeedHandler := handlers.NewPageSpeedHandler(alertSystem, pageSpeedRepo, dataCenterRepo, projectRepo, pageSpeedStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		pingHandler := handlers.NewPingHandler(alertSystem, pingRepo, dataCenterRepo, projectRepo, pingStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		traceRouteHandler := handlers.NewTraceRouteHandler(alertSystem, traceRouteRepo, dataCenterRepo, projectRepo, traceRouteStatsRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		tlsHandler := handlers.NewTLSHandler(tlsMonitorsRepo, tlsStatsRepo, dataCenterRepo, projectRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
+		dnsHandler := handlers.NewDNSHandler(dnsMonitorsRepo, dnsStatsRepo, dataCenterRepo, projectRepo, cacheRepo, maintenanceRepo, monitorStatesRepo, taskPusher, agentHandler)
 
 		mux := asynq.NewServeMux()
 		// handlers
 		mux.Handle(task_models.TypeEndpoint, tasks.NewEndpointTaskHandler(endpointHandler, zLogger))
-		//mux.Handle(task_models.TypeNetCats, tasks.NewNetCatTaskHandler(netCatHandler, zLogger))
-		//mux.Handle(task_models.TypePageSpeeds, tasks.NewPageSpeedTaskHandler(pageSpeedHandler, zLogger))
-		//mux.Handle(task_models.TypePings, tasks.NewPingTaskHandler(pingHandler, zLogger))
-		//mux.Handle(task_models.TypeTraceRoutes, tasks.NewTraceRouteTaskHandler(traceRouteHandler, zLogger))
-		mux.Handle(task_models.TypeNotification, tasks.NewNotificationTaskHandler(alertSystem, projectRepo))
+		mux.Handle(task_models.TypeNetCats, tasks.NewNetCatTaskHandler(netCatHandler, zLogger))
+		mux.Handle(task_models.TypePageSpeeds, tasks.NewPageSpeedTaskHandler(pageSpeedHandler, zLogger))
+		mux.Handle(task_models.TypePings, tasks.NewPingTaskHandler(pingHandler, zLogger))
+		mux.Handle(task_models.TypeTraceRoutes, tasks.NewTraceRouteTaskHandler(traceRouteHandler, zLogger))
+		mux.Handle(task_models.TypeTLS, tasks.NewTLSTaskHandler(tlsHandler, zLogger))
+		mux.Handle(task_models.TypeDNS, tasks.NewDNSTaskHandler(dnsHandler, zLogger))
+		mux.Handle(task_models.TypeNotification, tasks.NewNotificationTaskHandler(notifiers, projectRepo, incidentsRepo, webhookDispatcher))
+		mux.Handle(task_models.TypeWebhookDelivery, tasks.NewWebhookTaskHandler(projectRepo, webhookDeliveriesRepo, zLogger))
 
 		if err := srv.Run(mux); err != nil {
 			zLogger.Fatalf("cant start server: %s", err)
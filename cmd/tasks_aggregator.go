@@ -87,9 +87,15 @@ var consumeTasksAggregatorCmd = &cobra.Command{
 				Username:    redisClient.Options().Username,
 				Password:    redisClient.Options().Password,
 			}, asynq.Config{
-				Logger:           zLogger,
-				Queues:           map[string]int{task_models.QueueEndpointStore: 6},
-				GroupAggregator:  asynq.GroupAggregatorFunc(tasks.AggregateEndpointStats),
+				Logger: zLogger,
+				Queues: map[string]int{
+					task_models.QueueEndpointStore:   6,
+					task_models.QueueNetCatStore:     6,
+					task_models.QueuePageSpeedStore:  6,
+					task_models.QueuePingStore:       6,
+					task_models.QueueTraceRouteStore: 6,
+				},
+				GroupAggregator:  asynq.GroupAggregatorFunc(tasks.AggregateStats),
 				GroupGracePeriod: 2 * time.Second,
 				GroupMaxDelay:    10 * time.Second,
 				GroupMaxSize:     10000,
@@ -102,9 +108,17 @@ var consumeTasksAggregatorCmd = &cobra.Command{
 		}
 		defer psqlDb.Close()
 		endpointStatsRepo := repos.NewEndpointStatsRepository(psqlDb)
+		netCatStatsRepo := repos.NewNetCatStatsRepository(psqlDb)
+		pageSpeedStatsRepo := repos.NewPageSpeedStatsRepository(psqlDb)
+		pingStatsRepo := repos.NewPingStatsRepository(psqlDb)
+		traceRouteStatsRepo := repos.NewTraceRouteStatsRepository(psqlDb)
 
 		mux := asynq.NewServeMux()
 		mux.Handle(task_models.TypeEndpointStore, tasks.NewEndpointStoreHandler(endpointStatsRepo, zLogger))
+		mux.Handle(task_models.TypeNetCatStore, tasks.NewNetCatStoreHandler(netCatStatsRepo, zLogger))
+		mux.Handle(task_models.TypePageSpeedStore, tasks.NewPageSpeedStoreHandler(pageSpeedStatsRepo, zLogger))
+		mux.Handle(task_models.TypePingStore, tasks.NewPingStoreHandler(pingStatsRepo, zLogger))
+		mux.Handle(task_models.TypeTraceRouteStore, tasks.NewTraceRouteStoreHandler(traceRouteStatsRepo, zLogger))
 
 		if err := srv.Run(mux); err != nil {
 			zLogger.Fatalf("cant start server: %s", err)
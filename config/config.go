@@ -8,6 +8,7 @@ type Config struct {
 	Database DatabaseConfig `mapstructure:"database"`
 	Gateways Gateways       `mapstructure:"gateways"`
 	Services Services       `mapstructure:"services"`
+	Platform PlatformConfig `mapstructure:"platform"`
 }
 
 type DatabaseConfig struct {
@@ -58,13 +59,42 @@ type ZarinpalGateway struct {
 }
 
 type Services struct {
-	Alert AlertService `mapstructure:"alert"`
+	Alert     AlertService    `mapstructure:"alert"`
+	Notifiers NotifiersConfig `mapstructure:"notifiers"`
 }
 
 type AlertService struct {
 	BaseUrl string `mapstructure:"base_url"`
 }
 
+type NotifiersConfig struct {
+	Telegram  TelegramNotifier  `mapstructure:"telegram"`
+	Smtp      SmtpNotifier      `mapstructure:"smtp"`
+	PagerDuty PagerDutyNotifier `mapstructure:"pagerduty"`
+}
+
+type TelegramNotifier struct {
+	BaseUrl  string `mapstructure:"base_url"`
+	BotToken string `mapstructure:"bot_token"`
+}
+
+type PagerDutyNotifier struct {
+	BaseUrl string `mapstructure:"base_url"`
+}
+
+type SmtpNotifier struct {
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// PlatformConfig holds the accounts that run the platform, they manage the shared datacenters
+type PlatformConfig struct {
+	OperatorAccountIds []int `mapstructure:"operator_account_ids"`
+}
+
 func ViperConfig() (*viper.Viper, Config, error) {
 	v := viper.New()
 	v.SetEnvPrefix("AT")
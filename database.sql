@@ -149,6 +149,52 @@ create table if not exists datacenters
     deleted_at      TIMESTAMP
 );
 
+create table if not exists datacenter_health
+(
+    datacenter_id        int primary key,
+    status               text             not null,
+    availability         double precision not null default 100,
+    latency              double precision,
+    consecutive_failures int              not null default 0,
+    last_error           text,
+    checked_at           TIMESTAMP        NOT NULL,
+
+    foreign key (datacenter_id) references datacenters (id)
+);
+
+create table if not exists datacenter_keys
+(
+    key_id        text primary key,
+    datacenter_id int       not null,
+    secret        text      not null,
+    created_at    TIMESTAMP NOT NULL,
+    revoked_at    TIMESTAMP,
+
+    foreign key (datacenter_id) references datacenters (id)
+);
+create index if not exists datacenter_keys_datacenter_idx on datacenter_keys (datacenter_id);
+
+create table if not exists datacenter_agents
+(
+    datacenter_id int primary key,
+    mode          text not null default 'push',
+    last_seen_at  TIMESTAMP,
+
+    foreign key (datacenter_id) references datacenters (id)
+);
+
+create table if not exists project_datacenters
+(
+    datacenter_id int primary key,
+    project_id    int       NOT NULL,
+
+    created_at    TIMESTAMP NOT NULL,
+
+    foreign key (datacenter_id) references datacenters (id),
+    foreign key (project_id) references projects (id)
+);
+create index if not exists project_datacenters_project_idx on project_datacenters (project_id);
+
 create table if not exists relation_datacenters
 (
     id            SERIAL primary key,
@@ -232,24 +278,261 @@ create table if not exists faq
     deleted_at TIMESTAMP
 );
 
+create table if not exists incidents
+(
+    id              SERIAL primary key,
+    project_id      int       not null,
+    pipeline_type   text      not null,
+    pipeline_id     int       not null,
+    pipeline_name   text,
+    status          text      not null,
+    address         text,
+    root_cause      text,
+    datacenters     text,
+    started_at      TIMESTAMP not null,
+    resolved_at     TIMESTAMP,
+    duration        int,
+    acknowledged_at TIMESTAMP,
+    acknowledged_by int,
+    resolved_by     int,
+
+    foreign key (project_id) references projects (id),
+    foreign key (acknowledged_by) references accounts (id),
+    foreign key (resolved_by) references accounts (id),
+
+    created_at      TIMESTAMP NOT NULL,
+    updated_at      TIMESTAMP NOT NULL
+);
+
+create index if not exists incidents_pipeline_idx on incidents (pipeline_type, pipeline_id, status);
+
+create table if not exists monitor_states
+(
+    pipeline_type        text      not null,
+    pipeline_id          int       not null,
+    datacenter_id        int       not null,
+    project_id           int       not null,
+    status               text      not null,
+    since                TIMESTAMP not null,
+    last_session_id      text      not null,
+    consecutive_failures int       not null default 0,
+    address              text,
+    root_cause           text,
+    updated_at           TIMESTAMP not null,
+
+    foreign key (project_id) references projects (id),
+    foreign key (datacenter_id) references datacenters (id),
+
+    PRIMARY KEY (pipeline_type, pipeline_id, datacenter_id)
+);
+
+create index if not exists monitor_states_project_idx on monitor_states (project_id);
+
+create table if not exists incident_events
+(
+    id                   SERIAL primary key,
+    incident_id          int       not null,
+    event_type           text      not null,
+    root_cause           text,
+    datacenters          text,
+    resolved_datacenters text,
+    failed_datacenters   text,
+    account_id           int,
+    note                 text,
+
+    foreign key (incident_id) references incidents (id),
+    foreign key (account_id) references accounts (id),
+
+    created_at           TIMESTAMP NOT NULL
+);
+
+create table if not exists maintenance_windows
+(
+    id            SERIAL primary key,
+    project_id    int       not null,
+    pipeline_type text,
+    pipeline_id   int,
+    title         text      not null,
+    starts_at     TIMESTAMP not null,
+    ends_at       TIMESTAMP not null,
+    recurrence    text      not null default '',
+    repeat_until  TIMESTAMP,
+
+    foreign key (project_id) references projects (id),
+
+    created_at    TIMESTAMP NOT NULL,
+    updated_at    TIMESTAMP NOT NULL,
+    deleted_at    TIMESTAMP
+);
+
+create table if not exists status_pages
+(
+    id          SERIAL primary key,
+    project_id  int  not null,
+    slug        text not null,
+    title       text not null,
+    description text,
+
+    foreign key (project_id) references projects (id),
+
+    created_at  TIMESTAMP NOT NULL,
+    updated_at  TIMESTAMP NOT NULL,
+    deleted_at  TIMESTAMP
+);
+
+create unique index if not exists status_pages_slug_idx on status_pages (slug) where deleted_at is null;
+
+create table if not exists status_page_components
+(
+    id             SERIAL primary key,
+    status_page_id int  not null,
+    name           text not null,
+    pipeline_type  text not null,
+    pipeline_id    int  not null,
+    position       int  not null,
+
+    foreign key (status_page_id) references status_pages (id)
+);
+
+create table if not exists badges
+(
+    id            SERIAL primary key,
+    project_id    int  not null,
+    pipeline_type text not null,
+    pipeline_id   int  not null,
+    token         text not null,
+
+    foreign key (project_id) references projects (id),
+
+    created_at    TIMESTAMP NOT NULL,
+    deleted_at    TIMESTAMP
+);
+
+create unique index if not exists badges_token_idx on badges (token);
+create unique index if not exists badges_pipeline_idx on badges (pipeline_type, pipeline_id) where deleted_at is null;
+
+create table if not exists webhook_deliveries
+(
+    id              SERIAL primary key,
+    project_id      int  not null,
+    event_id        text not null,
+    event_type      text not null,
+    url             text not null,
+    payload         text not null,
+    status          text not null,
+    attempts        int  not null default 0,
+    response_status int,
+    error           text,
+    delivered_at    TIMESTAMP,
+
+    foreign key (project_id) references projects (id),
+
+    created_at      TIMESTAMP NOT NULL,
+    updated_at      TIMESTAMP NOT NULL
+);
+
+create index if not exists webhook_deliveries_project_idx on webhook_deliveries (project_id, created_at);
+
+create table if not exists cron_monitors
+(
+    id            SERIAL primary key,
+    project_id    int       not null,
+    name          text      not null,
+    token         text      not null,
+    period        int       not null,
+    grace         int       not null,
+    is_active     bool      not null default true,
+    status        text      not null default 'new',
+    last_ping_at  TIMESTAMP,
+    last_start_at TIMESTAMP,
+    last_fail_at  TIMESTAMP,
+    root_cause    text,
+
+    foreign key (project_id) references projects (id),
+
+    created_at    TIMESTAMP NOT NULL,
+    updated_at    TIMESTAMP NOT NULL,
+    deleted_at    TIMESTAMP
+);
+
+create unique index if not exists cron_monitors_token_idx on cron_monitors (token);
+
+create table if not exists cron_monitor_pings
+(
+    id              SERIAL primary key,
+    cron_monitor_id int       not null,
+    kind            text      not null,
+    remote_addr     text,
+    created_at      TIMESTAMP NOT NULL,
+
+    foreign key (cron_monitor_id) references cron_monitors (id)
+);
+
+create index if not exists cron_monitor_pings_monitor_idx on cron_monitor_pings (cron_monitor_id, created_at);
+
+create table if not exists json_schemas
+(
+    id         SERIAL primary key,
+    project_id int       not null,
+    name       text      not null,
+    schema     jsonb     not null,
+
+    foreign key (project_id) references projects (id),
+
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP
+);
+
+create table if not exists tls_monitors
+(
+    id                 SERIAL primary key,
+    data               jsonb,
+    project_id         int       not null,
+    alerted_thresholds jsonb     not null default '{}',
+
+    updated_at         TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at         TIMESTAMP NOT NULL,
+    deleted_at         TIMESTAMP,
+
+    foreign key (project_id) references projects (id)
+);
+
+create table if not exists dns_monitors
+(
+    id           SERIAL primary key,
+    data         jsonb,
+    project_id   int       not null,
+    last_answers jsonb     not null default '{}',
+
+    updated_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMP NOT NULL,
+    deleted_at   TIMESTAMP,
+
+    foreign key (project_id) references projects (id)
+);
+
 -----------------------------------------------------------------------------------------
 
 create table if not exists endpoint_stats
 (
-    time              TIMESTAMPTZ      NOT NULL,
-    session_id        text             NOT NULL,
-    project_id        int              NOT NULL,
-    endpoint_name     text,
-    endpoint_id       int              not null,
-    url               text,
-    datacenter_id     int              not null,
-    is_heart_beat     bool             not null,
-    success           int              not null,
-    response_time     double precision not null,
-    response_times    text,
-    response_bodies   BYTEA,
-    response_headers  text,
-    response_statuses text,
+    time               TIMESTAMPTZ      NOT NULL,
+    session_id         text             NOT NULL,
+    project_id         int              NOT NULL,
+    endpoint_name      text,
+    endpoint_id        int              not null,
+    url                text,
+    datacenter_id      int              not null,
+    is_heart_beat      bool             not null,
+    success            int              not null,
+    is_maintenance     bool             not null default false,
+    is_agent_error     bool             not null default false,
+    response_time      double precision not null,
+    response_times     text,
+    response_bodies    BYTEA,
+    response_headers   text,
+    response_statuses  text,
+    assertion_failures text,
 
     foreign key (project_id) references projects (id),
     foreign key (endpoint_id) references endpoints (id),
@@ -260,14 +543,16 @@ create table if not exists endpoint_stats
 
 create table if not exists net_cats_stats
 (
-    time          TIMESTAMPTZ NOT NULL,
-    session_id    text        NOT NULL,
-    project_id    int         NOT NULL,
-    netcat_id     int         not null,
-    url           text,
-    datacenter_id int         not null,
-    is_heart_beat bool        not null,
-    success       int         not null,
+    time           TIMESTAMPTZ NOT NULL,
+    session_id     text        NOT NULL,
+    project_id     int         NOT NULL,
+    netcat_id      int         not null,
+    url            text,
+    datacenter_id  int         not null,
+    is_heart_beat  bool        not null,
+    success        int         not null,
+    is_maintenance bool        not null default false,
+    is_agent_error bool        not null default false,
 
     foreign key (project_id) references projects (id),
     foreign key (netcat_id) references net_cats (id),
@@ -278,14 +563,16 @@ create table if not exists net_cats_stats
 
 create table if not exists page_speeds_stats
 (
-    time          TIMESTAMPTZ NOT NULL,
-    session_id    text        NOT NULL,
-    project_id    int         NOT NULL,
-    pagespeed_id  int         not null,
-    url           text,
-    datacenter_id int         not null,
-    is_heart_beat bool        not null,
-    success       int         not null,
+    time           TIMESTAMPTZ NOT NULL,
+    session_id     text        NOT NULL,
+    project_id     int         NOT NULL,
+    pagespeed_id   int         not null,
+    url            text,
+    datacenter_id  int         not null,
+    is_heart_beat  bool        not null,
+    success        int         not null,
+    is_maintenance bool        not null default false,
+    is_agent_error bool        not null default false,
 
     foreign key (project_id) references projects (id),
     foreign key (pagespeed_id) references page_speeds (id),
@@ -296,14 +583,16 @@ create table if not exists page_speeds_stats
 
 create table if not exists pings_stats
 (
-    time          TIMESTAMPTZ NOT NULL,
-    session_id    text        NOT NULL,
-    project_id    int         NOT NULL,
-    ping_id       int         not null,
-    url           text,
-    datacenter_id int         not null,
-    is_heart_beat bool        not null,
-    success       int         not null,
+    time           TIMESTAMPTZ NOT NULL,
+    session_id     text        NOT NULL,
+    project_id     int         NOT NULL,
+    ping_id        int         not null,
+    url            text,
+    datacenter_id  int         not null,
+    is_heart_beat  bool        not null,
+    success        int         not null,
+    is_maintenance bool        not null default false,
+    is_agent_error bool        not null default false,
 
     foreign key (project_id) references projects (id),
     foreign key (ping_id) references pings (id),
@@ -314,14 +603,16 @@ create table if not exists pings_stats
 
 create table if not exists trace_routes_stats
 (
-    time          TIMESTAMPTZ NOT NULL,
-    session_id    text        NOT NULL,
-    project_id    int         NOT NULL,
-    traceroute_id int         not null,
-    url           text,
-    datacenter_id int         not null,
-    is_heart_beat bool        not null,
-    success       int         not null,
+    time           TIMESTAMPTZ NOT NULL,
+    session_id     text        NOT NULL,
+    project_id     int         NOT NULL,
+    traceroute_id  int         not null,
+    url            text,
+    datacenter_id  int         not null,
+    is_heart_beat  bool        not null,
+    success        int         not null,
+    is_maintenance bool        not null default false,
+    is_agent_error bool        not null default false,
 
     foreign key (project_id) references projects (id),
     foreign key (traceroute_id) references trace_routes (id),
@@ -330,11 +621,80 @@ create table if not exists trace_routes_stats
     PRIMARY KEY (time, traceroute_id)
 );
 
+create table if not exists tls_stats
+(
+    time              TIMESTAMPTZ NOT NULL,
+    session_id        text        NOT NULL,
+    project_id        int         NOT NULL,
+    tls_monitor_id    int         not null,
+    address           text        not null,
+    datacenter_id     int         not null,
+    is_heart_beat     bool        not null,
+    success           int         not null,
+    is_maintenance    bool        not null default false,
+    is_agent_error    bool        not null default false,
+    days_until_expiry int,
+    not_after         TIMESTAMPTZ,
+    issuer            text,
+    sans              text,
+    chain             jsonb,
+    hostname_valid    bool,
+    chain_trusted     bool,
+    root_cause        text,
+
+    foreign key (project_id) references projects (id),
+    foreign key (tls_monitor_id) references tls_monitors (id),
+    foreign key (datacenter_id) references datacenters (id),
+
+    PRIMARY KEY (time, tls_monitor_id, datacenter_id, address)
+);
+
+create table if not exists dns_stats
+(
+    time            TIMESTAMPTZ NOT NULL,
+    session_id      text        NOT NULL,
+    project_id      int         NOT NULL,
+    dns_monitor_id  int         not null,
+    name            text        not null,
+    record_type     text        not null,
+    resolver        text,
+    datacenter_id   int         not null,
+    is_heart_beat   bool        not null,
+    success         int         not null,
+    is_maintenance  bool        not null default false,
+    is_agent_error  bool        not null default false,
+    resolution_time double precision,
+    answers         jsonb,
+    root_cause      text,
+
+    foreign key (project_id) references projects (id),
+    foreign key (dns_monitor_id) references dns_monitors (id),
+    foreign key (datacenter_id) references datacenters (id),
+
+    PRIMARY KEY (time, dns_monitor_id, datacenter_id, name, record_type)
+);
+
+alter table endpoint_stats add column if not exists is_maintenance bool not null default false;
+alter table net_cats_stats add column if not exists is_maintenance bool not null default false;
+alter table page_speeds_stats add column if not exists is_maintenance bool not null default false;
+alter table pings_stats add column if not exists is_maintenance bool not null default false;
+alter table trace_routes_stats add column if not exists is_maintenance bool not null default false;
+alter table endpoint_stats add column if not exists is_agent_error bool not null default false;
+alter table net_cats_stats add column if not exists is_agent_error bool not null default false;
+alter table page_speeds_stats add column if not exists is_agent_error bool not null default false;
+alter table pings_stats add column if not exists is_agent_error bool not null default false;
+alter table trace_routes_stats add column if not exists is_agent_error bool not null default false;
+alter table tls_stats add column if not exists is_agent_error bool not null default false;
+alter table dns_stats add column if not exists is_agent_error bool not null default false;
+alter table endpoint_stats add column if not exists assertion_failures text;
+
 SELECT create_hypertable('endpoint_stats', 'time');
 SELECT create_hypertable('net_cats_stats', 'time');
 SELECT create_hypertable('page_speeds_stats', 'time');
 SELECT create_hypertable('pings_stats', 'time');
 SELECT create_hypertable('trace_routes_stats', 'time');
+SELECT create_hypertable('tls_stats', 'time');
+SELECT create_hypertable('dns_stats', 'time');
 
 INSERT INTO accounts (first_name, last_name, phone_number, email, username, password, updated_at, created_at,
                       deleted_at)
@@ -5,155 +5,148 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
+	"github.com/labstack/gommon/log"
 	"io/ioutil"
 	"net/http"
+	"test-manager/cache"
+	"test-manager/repos"
 	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
 )
 
 type AgentHandler interface {
-	SendCurl(ctx context.Context, dataCenterUrl string, request usecase_models.AgentCurlRequest) (response string, responseHeader map[string][]string, status int, responseTime float64, err error)
-	SendNetCat(ctx context.Context, dataCenterUrl string, request usecase_models.AgentNetCatRequest) (response usecase_models.AgentNetCatResponse, err error)
-	SendPageSpeed(ctx context.Context, dataCenterUrl string, request usecase_models.AgentPageSpeedRequest) (response usecase_models.AgentPageSpeedResponse, err error)
-	SendPing(ctx context.Context, dataCenterUrl string, request usecase_models.AgentPingRequest) (response usecase_models.AgentPingResponse, err error)
-	SendTraceRoute(ctx context.Context, dataCenterUrl string, request usecase_models.AgentTraceRouteRequest) (response usecase_models.AgentTraceRouteResponse, err error)
+	SendCurl(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentCurlRequest) (response string, responseHeader map[string][]string, status int, responseTime float64, err error)
+	SendNetCat(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentNetCatRequest) (response usecase_models.AgentNetCatResponse, err error)
+	SendPageSpeed(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentPageSpeedRequest) (response usecase_models.AgentPageSpeedResponse, err error)
+	SendPing(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentPingRequest) (response usecase_models.AgentPingResponse, err error)
+	SendTraceRoute(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentTraceRouteRequest) (response usecase_models.AgentTraceRouteResponse, err error)
+	SendTLS(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentTLSRequest) (response usecase_models.AgentTLSResponse, err error)
+	SendDNS(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentDNSRequest) (response usecase_models.AgentDNSResponse, err error)
 }
 
 type agentHandler struct {
+	dataCentersRepo    repos.DataCentersRepository
+	datacenterKeysRepo repos.DatacenterKeysRepository
+	cacheRepo          cache.Cache
+	client             *http.Client
+	curlClient         *http.Client
 }
 
-func NewAgentHandler() AgentHandler {
-	return &agentHandler{}
+func NewAgentHandler(dataCentersRepo repos.DataCentersRepository, datacenterKeysRepo repos.DatacenterKeysRepository, cacheRepo cache.Cache) AgentHandler {
+	return &agentHandler{
+		dataCentersRepo:    dataCentersRepo,
+		datacenterKeysRepo: datacenterKeysRepo,
+		cacheRepo:          cacheRepo,
+		client:             &http.Client{},
+		curlClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // Ignore invalid certificates
+			},
+		},
+	}
 }
 
-func (a *agentHandler) SendCurl(ctx context.Context, dataCenterUrl string, request usecase_models.AgentCurlRequest) (response string, responseHeader map[string][]string, status int, responseTime float64, err error) {
+// post sends request to the agent of the datacenter and decodes its answer into response.
+// the request is signed with the active keys of the datacenter, see postSigned. a datacenter that never
+// had a key runs an agent from before signing and is sent unsigned requests until its first key is created,
+// once it had one nothing is sent to it without an active key. pull datacenters get it as a job instead
+func (a *agentHandler) post(ctx context.Context, client *http.Client, dataCenter *models.Datacenter, path string, request interface{}, response interface{}) error {
 	reqB, _ := json.Marshal(request)
-	req, err := http.NewRequestWithContext(ctx, "POST", dataCenterUrl+"/v1/curl", bytes.NewBuffer(reqB))
+	mode, err := a.dataCentersRepo.GetDataCenterMode(ctx, dataCenter.ID)
 	if err != nil {
-		return response, responseHeader, status, responseTime, err
+		return fmt.Errorf("problem on getting mode of datacenter: %w", err)
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // Ignore invalid certificates
-		},
+	if mode == usecase_models.DatacenterModePull {
+		respBody, err := a.dispatch(ctx, dataCenter, path, reqB)
+		if err != nil {
+			return fmt.Errorf("problem on calling agent: %w", err)
+		}
+		return json.Unmarshal(respBody, response)
 	}
 
-	resp, err := client.Do(req)
+	keys, err := a.datacenterKeysRepo.GetActiveKeys(ctx, dataCenter.ID)
 	if err != nil {
-		return response, responseHeader, status, responseTime, err
-	}
-	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
-
-	var respM usecase_models.AgentCurlResponse
-	err = json.Unmarshal(respBody, &respM)
+		return fmt.Errorf("problem on getting keys of datacenter: %w", err)
+	}
+	if len(keys) == 0 {
+		allKeys, err := a.datacenterKeysRepo.GetKeys(ctx, dataCenter.ID)
+		if err != nil {
+			return fmt.Errorf("problem on getting keys of datacenter: %w", err)
+		}
+		if len(allKeys) != 0 {
+			return fmt.Errorf("datacenter %s has no active key to sign the request with", dataCenter.Title)
+		}
+		log.Warnf("datacenter %s has never had a key, sending unsigned request", dataCenter.Title)
+		respBody, err := postUnsigned(ctx, client, dataCenter.Baseurl+path, reqB)
+		if err != nil {
+			return fmt.Errorf("problem on calling agent: %w", err)
+		}
+		return json.Unmarshal(respBody, response)
+	}
+
+	_, respBody, err := postSigned(ctx, client, dataCenter.Baseurl+path, reqB, keys)
 	if err != nil {
-		return response, responseHeader, status, responseTime, err
-	}
-
-	if respM.Statistics.StatusCode == 0 {
-		return respM.Message, respM.Statistics.Header, respM.Statistics.StatusCode, respM.Statistics.ResponseTime, nil
+		return fmt.Errorf("problem on calling agent: %w", err)
 	}
-	return respM.Statistics.Body, respM.Statistics.Header, respM.Statistics.StatusCode, respM.Statistics.ResponseTime, nil
+	return json.Unmarshal(respBody, response)
 }
 
-func (a *agentHandler) SendNetCat(ctx context.Context, dataCenterUrl string, request usecase_models.AgentNetCatRequest) (response usecase_models.AgentNetCatResponse, err error) {
-	reqB, _ := json.Marshal(request)
-	req, err := http.NewRequestWithContext(ctx, "POST", dataCenterUrl+"/v1/netcat", bytes.NewBuffer(reqB))
+// postUnsigned posts body to url the way requests were sent before signing
+func postUnsigned(ctx context.Context, client *http.Client, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
-		return response, err
+		return nil, err
 	}
-
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return response, err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
-
-	var respM usecase_models.AgentNetCatResponse
-	err = json.Unmarshal(respBody, &respM)
-	if err != nil {
-		return response, err
-	}
-	return respM, nil
+	return ioutil.ReadAll(resp.Body)
 }
 
-func (a *agentHandler) SendPageSpeed(ctx context.Context, dataCenterUrl string, request usecase_models.AgentPageSpeedRequest) (response usecase_models.AgentPageSpeedResponse, err error) {
-	reqB, _ := json.Marshal(request)
-	req, err := http.NewRequestWithContext(ctx, "POST", dataCenterUrl+"/v1/pagespeed", bytes.NewBuffer(reqB))
+func (a *agentHandler) SendCurl(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentCurlRequest) (response string, responseHeader map[string][]string, status int, responseTime float64, err error) {
+	var respM usecase_models.AgentCurlResponse
+	err = a.post(ctx, a.curlClient, dataCenter, "/v1/curl", request, &respM)
 	if err != nil {
-		return response, err
+		return response, responseHeader, status, responseTime, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return response, err
-	}
-	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
-
-	var respM usecase_models.AgentPageSpeedResponse
-	err = json.Unmarshal(respBody, &respM)
-	if err != nil {
-		return response, err
+	if respM.Statistics.StatusCode == 0 {
+		return respM.Message, respM.Statistics.Header, respM.Statistics.StatusCode, respM.Statistics.ResponseTime, nil
 	}
-	return respM, nil
+	return respM.Statistics.Body, respM.Statistics.Header, respM.Statistics.StatusCode, respM.Statistics.ResponseTime, nil
 }
 
-func (a *agentHandler) SendPing(ctx context.Context, dataCenterUrl string, request usecase_models.AgentPingRequest) (response usecase_models.AgentPingResponse, err error) {
-	reqB, _ := json.Marshal(request)
-	req, err := http.NewRequestWithContext(ctx, "POST", dataCenterUrl+"/v1/ping", bytes.NewBuffer(reqB))
-	if err != nil {
-		return response, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return response, err
-	}
-	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
+func (a *agentHandler) SendNetCat(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentNetCatRequest) (response usecase_models.AgentNetCatResponse, err error) {
+	err = a.post(ctx, a.client, dataCenter, "/v1/netcat", request, &response)
+	return response, err
+}
 
-	var respM usecase_models.AgentPingResponse
-	err = json.Unmarshal(respBody, &respM)
-	if err != nil {
-		return response, err
-	}
-	return respM, nil
+func (a *agentHandler) SendPageSpeed(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentPageSpeedRequest) (response usecase_models.AgentPageSpeedResponse, err error) {
+	err = a.post(ctx, a.client, dataCenter, "/v1/pagespeed", request, &response)
+	return response, err
 }
 
-func (a *agentHandler) SendTraceRoute(ctx context.Context, dataCenterUrl string, request usecase_models.AgentTraceRouteRequest) (response usecase_models.AgentTraceRouteResponse, err error) {
-	reqB, _ := json.Marshal(request)
-	req, err := http.NewRequestWithContext(ctx, "POST", dataCenterUrl+"/v1/traceroute", bytes.NewBuffer(reqB))
-	if err != nil {
-		return response, err
-	}
+func (a *agentHandler) SendPing(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentPingRequest) (response usecase_models.AgentPingResponse, err error) {
+	err = a.post(ctx, a.client, dataCenter, "/v1/ping", request, &response)
+	return response, err
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
+func (a *agentHandler) SendTraceRoute(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentTraceRouteRequest) (response usecase_models.AgentTraceRouteResponse, err error) {
+	err = a.post(ctx, a.client, dataCenter, "/v1/traceroute", request, &response)
+	return response, err
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return response, err
-	}
-	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
+func (a *agentHandler) SendTLS(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentTLSRequest) (response usecase_models.AgentTLSResponse, err error) {
+	err = a.post(ctx, a.client, dataCenter, "/v1/tls", request, &response)
+	return response, err
+}
 
-	var respM usecase_models.AgentTraceRouteResponse
-	err = json.Unmarshal(respBody, &respM)
-	if err != nil {
-		return response, err
-	}
-	return respM, nil
+func (a *agentHandler) SendDNS(ctx context.Context, dataCenter *models.Datacenter, request usecase_models.AgentDNSRequest) (response usecase_models.AgentDNSResponse, err error) {
+	err = a.post(ctx, a.client, dataCenter, "/v1/dns", request, &response)
+	return response, err
 }
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	AgentDefaultPingCount = 4
+	// agentPingInterval is the smallest interval linux allows to unprivileged ping sockets
+	agentPingInterval         = 200 * time.Millisecond
+	AgentDefaultTraceRouteHop = 30
+	// agentTraceRouteHopTimeout is how long each hop is waited for before it is tried again
+	agentTraceRouteHopTimeout = 2 * time.Second
+	agentICMPPayload          = "test-manager"
+)
+
+// icmpProtocol holds what differs between icmp over ipv4 and ipv6
+type icmpProtocol struct {
+	unprivilegedNetwork string
+	privilegedNetwork   string
+	number              int
+	echoRequest         icmp.Type
+	echoReply           icmp.Type
+	timeExceeded        icmp.Type
+	destUnreachable     icmp.Type
+	headerLen           func(packet []byte) int
+}
+
+var icmpV4 = icmpProtocol{
+	unprivilegedNetwork: "udp4",
+	privilegedNetwork:   "ip4:icmp",
+	number:              1,
+	echoRequest:         ipv4.ICMPTypeEcho,
+	echoReply:           ipv4.ICMPTypeEchoReply,
+	timeExceeded:        ipv4.ICMPTypeTimeExceeded,
+	destUnreachable:     ipv4.ICMPTypeDestinationUnreachable,
+	headerLen: func(packet []byte) int {
+		if len(packet) == 0 {
+			return 0
+		}
+		return int(packet[0]&0x0f) * 4
+	},
+}
+
+var icmpV6 = icmpProtocol{
+	unprivilegedNetwork: "udp6",
+	privilegedNetwork:   "ip6:ipv6-icmp",
+	number:              58,
+	echoRequest:         ipv6.ICMPTypeEchoRequest,
+	echoReply:           ipv6.ICMPTypeEchoReply,
+	timeExceeded:        ipv6.ICMPTypeTimeExceeded,
+	destUnreachable:     ipv6.ICMPTypeDestinationUnreachable,
+	headerLen: func(packet []byte) int {
+		return ipv6.HeaderLen
+	},
+}
+
+// icmpConn is an echo socket to one address, it is unprivileged when the kernel allows it and raw otherwise
+type icmpConn struct {
+	conn       *icmp.PacketConn
+	protocol   icmpProtocol
+	target     net.Addr
+	privileged bool
+	id         int
+}
+
+// dialICMP opens an echo socket to ip. traceroute needs a raw socket first, unprivileged sockets
+// do not read the time exceeded answers of the routers on the way
+func dialICMP(ip *net.IPAddr, privilegedFirst bool) (*icmpConn, error) {
+	protocol := icmpV6
+	if ip.IP.To4() != nil {
+		protocol = icmpV4
+	}
+	order := []bool{false, true}
+	if privilegedFirst {
+		order = []bool{true, false}
+	}
+	var err error
+	for _, privileged := range order {
+		c := &icmpConn{protocol: protocol, id: os.Getpid() & 0xffff, privileged: privileged}
+		if privileged {
+			c.conn, err = icmp.ListenPacket(protocol.privilegedNetwork, "")
+			c.target = ip
+		} else {
+			c.conn, err = icmp.ListenPacket(protocol.unprivilegedNetwork, "")
+			c.target = &net.UDPAddr{IP: ip.IP, Zone: ip.Zone}
+		}
+		if err == nil {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("problem on opening icmp socket: %w", err)
+}
+
+func (c *icmpConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *icmpConn) setTTL(ttl int) error {
+	if c.protocol.number == icmpV4.number {
+		return c.conn.IPv4PacketConn().SetTTL(ttl)
+	}
+	return c.conn.IPv6PacketConn().SetHopLimit(ttl)
+}
+
+func (c *icmpConn) sendEcho(seq int) error {
+	message := icmp.Message{
+		Type: c.protocol.echoRequest,
+		Body: &icmp.Echo{ID: c.id, Seq: seq, Data: []byte(agentICMPPayload)},
+	}
+	packet, err := message.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.WriteTo(packet, c.target)
+	return err
+}
+
+// ownEcho tells if an echo is an answer to this connection, unprivileged sockets get their id rewritten by the kernel
+func (c *icmpConn) ownEcho(echo *icmp.Echo, seq int) bool {
+	return echo.Seq == seq && (!c.privileged || echo.ID == c.id)
+}
+
+// quotedEcho returns the echo request quoted in a time exceeded or unreachable message
+func (c *icmpConn) quotedEcho(data []byte) (*icmp.Echo, bool) {
+	headerLen := c.protocol.headerLen(data)
+	if headerLen == 0 || len(data) < headerLen+8 {
+		return nil, false
+	}
+	message, err := icmp.ParseMessage(c.protocol.number, data[headerLen:])
+	if err != nil {
+		// only the first 8 bytes of the request are quoted, which is enough for id and seq
+		quoted := data[headerLen : headerLen+8]
+		return &icmp.Echo{ID: int(quoted[4])<<8 | int(quoted[5]), Seq: int(quoted[6])<<8 | int(quoted[7])}, true
+	}
+	echo, ok := message.Body.(*icmp.Echo)
+	return echo, ok
+}
+
+// waitReply reads until the answer of seq arrives or deadline passes. reached is true when the
+// target itself answered, peer is whoever answered
+func (c *icmpConn) waitReply(seq int, deadline time.Time) (peer net.Addr, reached bool, err error) {
+	buffer := make([]byte, 1500)
+	if err = c.conn.SetReadDeadline(deadline); err != nil {
+		return nil, false, err
+	}
+	for {
+		n, from, err := c.conn.ReadFrom(buffer)
+		if err != nil {
+			return nil, false, err
+		}
+		message, err := icmp.ParseMessage(c.protocol.number, buffer[:n])
+		if err != nil {
+			continue
+		}
+		switch message.Type {
+		case c.protocol.echoReply:
+			if echo, ok := message.Body.(*icmp.Echo); ok && c.ownEcho(echo, seq) {
+				return from, true, nil
+			}
+		case c.protocol.timeExceeded:
+			if body, ok := message.Body.(*icmp.TimeExceeded); ok {
+				if echo, ok := c.quotedEcho(body.Data); ok && c.ownEcho(echo, seq) {
+					return from, false, nil
+				}
+			}
+		case c.protocol.destUnreachable:
+			if body, ok := message.Body.(*icmp.DstUnreach); ok {
+				if echo, ok := c.quotedEcho(body.Data); ok && c.ownEcho(echo, seq) {
+					return from, false, fmt.Errorf("%s is unreachable", addrIP(from))
+				}
+			}
+		}
+	}
+}
+
+func addrIP(addr net.Addr) string {
+	switch value := addr.(type) {
+	case *net.UDPAddr:
+		return value.IP.String()
+	case *net.IPAddr:
+		return value.IP.String()
+	}
+	return addr.String()
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+type pingStatistics struct {
+	ip       *net.IPAddr
+	sent     int
+	received int
+	rtts     time.Duration
+}
+
+// loss is the percent of lost packets
+func (s pingStatistics) loss() int {
+	if s.sent == 0 {
+		return 0
+	}
+	return (s.sent - s.received) * 100 / s.sent
+}
+
+func (s pingStatistics) avgRttMilliseconds() int {
+	if s.received == 0 {
+		return 0
+	}
+	return int((s.rtts / time.Duration(s.received)).Milliseconds())
+}
+
+// ping sends count echo requests to address, each is waited for timeout
+func ping(ctx context.Context, address string, count int, timeout time.Duration) (pingStatistics, error) {
+	var statistics pingStatistics
+	if count <= 0 {
+		count = AgentDefaultPingCount
+	}
+	ip, err := net.ResolveIPAddr("ip", address)
+	if err != nil {
+		return statistics, err
+	}
+	statistics.ip = ip
+	conn, err := dialICMP(ip, false)
+	if err != nil {
+		return statistics, err
+	}
+	defer conn.Close()
+
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			select {
+			case <-ctx.Done():
+				return statistics, ctx.Err()
+			case <-time.After(agentPingInterval):
+			}
+		}
+		start := time.Now()
+		if err = conn.sendEcho(seq); err != nil {
+			return statistics, err
+		}
+		statistics.sent++
+		_, reached, err := conn.waitReply(seq, start.Add(timeout))
+		if err != nil && !isTimeout(err) {
+			return statistics, err
+		}
+		if reached {
+			statistics.received++
+			statistics.rtts += time.Since(start)
+		}
+	}
+	return statistics, nil
+}
+
+// traceRoute sends echo requests with a growing ttl and lists who answered each hop, a silent hop is "*".
+// each hop is tried retry more times before it is given up
+func traceRoute(ctx context.Context, address string, maxHops int, retry int) ([]string, bool, error) {
+	if maxHops <= 0 {
+		maxHops = AgentDefaultTraceRouteHop
+	}
+	if retry < 0 {
+		retry = 0
+	}
+	ip, err := net.ResolveIPAddr("ip", address)
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := dialICMP(ip, true)
+	if err != nil {
+		return nil, false, err
+	}
+	defer conn.Close()
+
+	var hops []string
+	seq := 0
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		if err = conn.setTTL(ttl); err != nil {
+			return hops, false, err
+		}
+		hop := "*"
+		reached := false
+		for attempt := 0; attempt <= retry; attempt++ {
+			if ctx.Err() != nil {
+				return hops, false, ctx.Err()
+			}
+			seq++
+			if err = conn.sendEcho(seq); err != nil {
+				return hops, false, err
+			}
+			peer, ok, err := conn.waitReply(seq, time.Now().Add(agentTraceRouteHopTimeout))
+			if err != nil && isTimeout(err) {
+				continue
+			}
+			if err != nil {
+				return append(hops, hop), false, err
+			}
+			hop = addrIP(peer)
+			reached = ok
+			break
+		}
+		hops = append(hops, hop)
+		if reached {
+			return hops, true, nil
+		}
+	}
+	return hops, false, nil
+}
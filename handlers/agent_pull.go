@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"strings"
+	"sync"
+	"test-manager/cache"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+const (
+	// AgentPullJobTimeout is how long a check waits for a pull agent to take its job and post the result
+	AgentPullJobTimeout = time.Minute
+	// AgentPullPollTimeout is how long a poll of a pull agent is held open when there is no job
+	AgentPullPollTimeout = 25 * time.Second
+	// AgentPullSeenTimeout is how long a pull agent may go without polling before its datacenter counts as failing
+	AgentPullSeenTimeout = time.Minute
+	// AgentPullJobLease is how long a taken job may go without a result before it is handed out again, so a job
+	// is not lost with a poll answer that never reached the agent. a check running longer may run twice, only
+	// its first result is kept
+	AgentPullJobLease = 30 * time.Second
+	// agentPullBackoff is how long a worker of a pull agent waits after the manager could not be reached
+	agentPullBackoff = 5 * time.Second
+
+	agentJobsPrefixCacheKey         = "agent_jobs:"
+	agentJobsInFlightPrefixCacheKey = "agent_jobs_in_flight:"
+	agentJobLeasePrefixCacheKey     = "agent_job_lease:"
+	agentJobOwnerPrefixCacheKey     = "agent_job:"
+	agentResultsPrefixCacheKey      = "agent_results:"
+)
+
+// dispatch hands the check to the pull agent of the datacenter and waits for its answer, which is the
+// body a push agent would answer with, so the Send* methods decode both the same way
+func (a *agentHandler) dispatch(ctx context.Context, dataCenter *models.Datacenter, path string, request []byte) ([]byte, error) {
+	job := usecase_models.AgentJob{
+		JobId:    uuid.NewString(),
+		Path:     path,
+		Request:  request,
+		Deadline: time.Now().Add(AgentPullJobTimeout),
+	}
+	jobB, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	err = a.cacheRepo.Set(ctx, agentJobOwnerPrefixCacheKey+job.JobId, dataCenter.ID, AgentPullJobTimeout)
+	if err != nil {
+		return nil, err
+	}
+	err = a.cacheRepo.RPush(ctx, fmt.Sprintf("%s%d", agentJobsPrefixCacheKey, dataCenter.ID), AgentPullJobTimeout, jobB)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := a.cacheRepo.BLPop(ctx, AgentPullJobTimeout, agentResultsPrefixCacheKey+job.JobId)
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("agent of datacenter %s did not answer in %s", dataCenter.Title, AgentPullJobTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result usecase_models.AgentJobResult
+	if err = json.Unmarshal([]byte(value), &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, errors.New(result.Error)
+	}
+	return result.Response, nil
+}
+
+// takeAgentJob waits for a job of the datacenter. the job is moved to the in flight list of the datacenter
+// instead of being popped, it stays there until its result is reported or its lease runs out
+func takeAgentJob(ctx context.Context, cacheRepo cache.Cache, datacenterId int) (usecase_models.AgentJob, error) {
+	var job usecase_models.AgentJob
+	requeueAgentJobs(ctx, cacheRepo, datacenterId)
+
+	inFlight := fmt.Sprintf("%s%d", agentJobsInFlightPrefixCacheKey, datacenterId)
+	value, err := cacheRepo.BLMove(ctx, AgentPullPollTimeout, fmt.Sprintf("%s%d", agentJobsPrefixCacheKey, datacenterId), inFlight)
+	if err != nil {
+		return job, err
+	}
+	if err = json.Unmarshal([]byte(value), &job); err != nil {
+		_, _ = cacheRepo.LRem(ctx, inFlight, value)
+		return job, err
+	}
+	err = cacheRepo.Set(ctx, agentJobLeasePrefixCacheKey+job.JobId, 1, AgentPullJobLease)
+	if err != nil {
+		log.Warn("problem on keeping lease of agent job: ", err)
+	}
+	return job, nil
+}
+
+// requeueAgentJobs puts the in flight jobs of the datacenter whose lease ran out back in its queue, the
+// ones past their deadline are dropped since no check waits for them anymore
+func requeueAgentJobs(ctx context.Context, cacheRepo cache.Cache, datacenterId int) {
+	inFlight := fmt.Sprintf("%s%d", agentJobsInFlightPrefixCacheKey, datacenterId)
+	values, err := cacheRepo.LRange(ctx, inFlight)
+	if err != nil {
+		log.Warn("problem on reading in flight agent jobs: ", err)
+		return
+	}
+	for _, value := range values {
+		var job usecase_models.AgentJob
+		if err = json.Unmarshal([]byte(value), &job); err == nil {
+			if _, err = cacheRepo.Get(ctx, agentJobLeasePrefixCacheKey+job.JobId); !errors.Is(err, redis.Nil) {
+				continue
+			}
+		}
+		// only the poll that removes the job hands it out again
+		removed, err := cacheRepo.LRem(ctx, inFlight, value)
+		if err != nil || removed == 0 || job.JobId == "" || time.Now().After(job.Deadline) {
+			continue
+		}
+		err = cacheRepo.RPush(ctx, fmt.Sprintf("%s%d", agentJobsPrefixCacheKey, datacenterId), AgentPullJobTimeout, value)
+		if err != nil {
+			log.Error("problem on requeueing agent job: ", err)
+		}
+	}
+}
+
+// ackAgentJob removes a job whose result is reported, also from the queue in case its lease ran out meanwhile
+func ackAgentJob(ctx context.Context, cacheRepo cache.Cache, datacenterId int, jobId string) {
+	for _, prefix := range []string{agentJobsInFlightPrefixCacheKey, agentJobsPrefixCacheKey} {
+		key := fmt.Sprintf("%s%d", prefix, datacenterId)
+		values, err := cacheRepo.LRange(ctx, key)
+		if err != nil {
+			log.Warn("problem on reading agent jobs: ", err)
+			continue
+		}
+		for _, value := range values {
+			var job usecase_models.AgentJob
+			if json.Unmarshal([]byte(value), &job) == nil && job.JobId == jobId {
+				if _, err = cacheRepo.LRem(ctx, key, value); err != nil {
+					log.Warn("problem on removing agent job: ", err)
+				}
+			}
+		}
+	}
+	_ = cacheRepo.Delete(ctx, agentJobLeasePrefixCacheKey+jobId)
+}
+
+// AgentPuller is the agent side of pull mode, for datacenters the manager can not reach. its workers
+// long poll the manager for jobs, run them like the /v1 endpoints would and post the results back.
+// requests are signed with the keys of the keyring, newest first
+type AgentPuller struct {
+	managerUrl  string
+	keyring     *AgentKeyring
+	controllers AgentControllers
+	client      *http.Client
+	workers     int
+}
+
+func NewAgentPuller(managerUrl string, keyring *AgentKeyring, controllers AgentControllers, workers int) *AgentPuller {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &AgentPuller{
+		managerUrl:  strings.TrimSuffix(managerUrl, "/"),
+		keyring:     keyring,
+		controllers: controllers,
+		client:      &http.Client{Timeout: AgentPullPollTimeout + 10*time.Second},
+		workers:     workers,
+	}
+}
+
+// Run polls until ctx is done
+func (p *AgentPuller) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if err := p.pollOnce(ctx); err != nil && ctx.Err() == nil {
+					log.Error("problem on polling manager: ", err)
+					select {
+					case <-ctx.Done():
+					case <-time.After(agentPullBackoff):
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *AgentPuller) pollOnce(ctx context.Context) error {
+	status, body, err := postSigned(ctx, p.client, p.managerUrl+"/agent/jobs/poll", []byte("{}"), p.keyring.Keys())
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNoContent {
+		return nil
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("manager answered poll with %d: %s", status, body)
+	}
+	var resp struct {
+		Data usecase_models.AgentJob `json:"data"`
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	job := resp.Data
+	if time.Now().After(job.Deadline) {
+		log.Warnf("job %s passed its deadline before it was taken", job.JobId)
+		return nil
+	}
+
+	jobCtx, cancel := context.WithDeadline(ctx, job.Deadline)
+	defer cancel()
+	var result usecase_models.AgentJobResult
+	response, err := p.controllers.Run(jobCtx, job.Path, job.Request)
+	if err != nil {
+		result.Error = err.Error()
+	} else if result.Response, err = json.Marshal(response); err != nil {
+		result.Error = err.Error()
+	}
+	resultB, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	status, body, err = postSigned(ctx, p.client, p.managerUrl+"/agent/jobs/"+job.JobId+"/result", resultB, p.keyring.Keys())
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("manager answered result of job %s with %d: %s", job.JobId, status, body)
+	}
+	return nil
+}
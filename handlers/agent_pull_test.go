@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"test-manager/usecase_models"
+	"testing"
+	"time"
+)
+
+func queueAgentJob(t *testing.T, cacheRepo *memoryCache, datacenterId int, job usecase_models.AgentJob) {
+	t.Helper()
+	jobB, err := json.Marshal(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cacheRepo.RPush(context.Background(), fmt.Sprintf("%s%d", agentJobsPrefixCacheKey, datacenterId), AgentPullJobTimeout, jobB); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentJobQueue(t *testing.T) {
+	ctx := context.Background()
+	cacheRepo := newMemoryCache()
+	queueAgentJob(t, cacheRepo, 7, usecase_models.AgentJob{JobId: "job", Path: "/v1/ping", Deadline: time.Now().Add(time.Minute)})
+
+	job, err := takeAgentJob(ctx, cacheRepo, 7)
+	if err != nil || job.JobId != "job" {
+		t.Fatalf("took %+v, %v", job, err)
+	}
+	if _, err = takeAgentJob(ctx, cacheRepo, 7); !errors.Is(err, redis.Nil) {
+		t.Fatalf("a leased job was handed out again: %v", err)
+	}
+
+	// the answer of the poll never reached the agent, the job comes back once its lease runs out
+	_ = cacheRepo.Delete(ctx, agentJobLeasePrefixCacheKey+"job")
+	job, err = takeAgentJob(ctx, cacheRepo, 7)
+	if err != nil || job.JobId != "job" {
+		t.Fatalf("job was not handed out again after its lease ran out: %+v, %v", job, err)
+	}
+
+	ackAgentJob(ctx, cacheRepo, 7, "job")
+	_ = cacheRepo.Delete(ctx, agentJobLeasePrefixCacheKey+"job")
+	if _, err = takeAgentJob(ctx, cacheRepo, 7); !errors.Is(err, redis.Nil) {
+		t.Fatalf("an acked job was handed out again: %v", err)
+	}
+	if inFlight, _ := cacheRepo.LRange(ctx, agentJobsInFlightPrefixCacheKey+"7"); len(inFlight) != 0 {
+		t.Fatalf("in flight jobs left after ack: %v", inFlight)
+	}
+}
+
+func TestAgentJobQueueDropsExpiredJobs(t *testing.T) {
+	ctx := context.Background()
+	cacheRepo := newMemoryCache()
+	queueAgentJob(t, cacheRepo, 7, usecase_models.AgentJob{JobId: "job", Deadline: time.Now().Add(-time.Second)})
+
+	if _, err := takeAgentJob(ctx, cacheRepo, 7); err != nil {
+		t.Fatal(err)
+	}
+	_ = cacheRepo.Delete(ctx, agentJobLeasePrefixCacheKey+"job")
+	if _, err := takeAgentJob(ctx, cacheRepo, 7); !errors.Is(err, redis.Nil) {
+		t.Fatalf("a job past its deadline was handed out again: %v", err)
+	}
+	if inFlight, _ := cacheRepo.LRange(ctx, agentJobsInFlightPrefixCacheKey+"7"); len(inFlight) != 0 {
+		t.Fatalf("in flight jobs left: %v", inFlight)
+	}
+}
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"test-manager/usecase_models"
+	"time"
+)
+
+const (
+	// AgentDefaultTimeOut is used in seconds when a request does not set time_out
+	AgentDefaultTimeOut = 5
+	// AgentCurlTimeout bounds curl and page speed requests, their contracts carry no time out
+	AgentCurlTimeout = 30 * time.Second
+	// AgentMaxBodySize is how much of a curl response body is sent back to the manager
+	AgentMaxBodySize = 1 << 20
+)
+
+// AgentControllers is the datacenter side of the agent protocol, every check answers with
+// the usecase_models.Agent*Response the agentHandler of the manager decodes.
+// a check that ran and failed is answered with status 0 and a message, only a request the
+// agent could not read is answered with an http error.
+type AgentControllers interface {
+	Health(ctx echo.Context) error
+	Curl(ctx echo.Context) error
+	NetCat(ctx echo.Context) error
+	PageSpeed(ctx echo.Context) error
+	Ping(ctx echo.Context) error
+	TraceRoute(ctx echo.Context) error
+	TLS(ctx echo.Context) error
+	DNS(ctx echo.Context) error
+	// Run runs a job a pull agent took from the manager, path is the endpoint a push agent serves the check on
+	Run(ctx context.Context, path string, request []byte) (interface{}, error)
+}
+
+type agentControllers struct {
+	client *http.Client
+}
+
+func NewAgentControllers() AgentControllers {
+	return &agentControllers{
+		client: &http.Client{Timeout: AgentCurlTimeout},
+	}
+}
+
+func agentTimeOut(timeOut int) time.Duration {
+	if timeOut <= 0 {
+		timeOut = AgentDefaultTimeOut
+	}
+	return time.Duration(timeOut) * time.Second
+}
+
+func (a *agentControllers) Health(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (a *agentControllers) Curl(ctx echo.Context) error {
+	req := new(usecase_models.AgentCurlRequest)
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, a.curlCheck(ctx.Request().Context(), *req))
+}
+
+func (a *agentControllers) curlCheck(ctx context.Context, req usecase_models.AgentCurlRequest) (response usecase_models.AgentCurlResponse) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	request, err := http.NewRequestWithContext(ctx, method, req.Url, bytes.NewBufferString(req.Body))
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	for key, values := range req.Header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+
+	start := time.Now()
+	resp, err := a.client.Do(request)
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, AgentMaxBodySize))
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+
+	response.Status = 1
+	response.Message = "ok"
+	response.Statistics.ResponseTime = time.Since(start).Seconds()
+	response.Statistics.StatusCode = resp.StatusCode
+	response.Statistics.Header = resp.Header
+	response.Statistics.Body = string(body)
+	return response
+}
+
+func (a *agentControllers) NetCat(ctx echo.Context) error {
+	req := new(usecase_models.AgentNetCatRequest)
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, a.netCatCheck(ctx.Request().Context(), *req))
+}
+
+func (a *agentControllers) netCatCheck(ctx context.Context, req usecase_models.AgentNetCatRequest) (response usecase_models.AgentNetCatResponse) {
+	err := netCat(ctx, req)
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	response.Status = 1
+	response.Message = "ok"
+	return response
+}
+
+// netCat connects to the port like nc -z, an udp port is open unless the host refuses the datagram
+func netCat(ctx context.Context, request usecase_models.AgentNetCatRequest) error {
+	network := strings.ToLower(request.Type)
+	if network == "" {
+		network = "tcp"
+	}
+	if network != "tcp" && network != "udp" {
+		return fmt.Errorf("type %s is not supported, use tcp or udp", request.Type)
+	}
+	timeout := agentTimeOut(request.TimeOut)
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(request.Address, request.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if network == "tcp" {
+		return nil
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err = conn.Write([]byte{0}); err != nil {
+		return err
+	}
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	if err != nil && errors.As(err, &netErr) && netErr.Timeout() {
+		return nil
+	}
+	return err
+}
+
+func (a *agentControllers) PageSpeed(ctx echo.Context) error {
+	req := new(usecase_models.AgentPageSpeedRequest)
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, a.pageSpeedCheck(ctx.Request().Context(), *req))
+}
+
+func (a *agentControllers) pageSpeedCheck(ctx context.Context, req usecase_models.AgentPageSpeedRequest) (response usecase_models.AgentPageSpeedResponse) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, req.Url, nil)
+	if err != nil {
+		return response
+	}
+	resp, err := a.client.Do(request)
+	if err != nil {
+		return response
+	}
+	defer resp.Body.Close()
+	// the page counts as loaded once the whole document is read
+	_, err = ioutil.ReadAll(resp.Body)
+	if err == nil && resp.StatusCode < http.StatusBadRequest {
+		response.Status = 1
+	}
+	return response
+}
+
+func (a *agentControllers) Ping(ctx echo.Context) error {
+	req := new(usecase_models.AgentPingRequest)
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, a.pingCheck(ctx.Request().Context(), *req))
+}
+
+func (a *agentControllers) pingCheck(ctx context.Context, req usecase_models.AgentPingRequest) (response usecase_models.AgentPingResponse) {
+	response.Statistics.Address = req.Address
+
+	statistics, err := ping(ctx, req.Address, req.Count, agentTimeOut(req.TimeOut))
+	response.Statistics.PacketsSent = statistics.sent
+	response.Statistics.PacketsReceive = statistics.received
+	response.Statistics.PacketLoss = statistics.loss()
+	response.Statistics.AvgRtt = statistics.avgRttMilliseconds()
+	if statistics.ip != nil {
+		response.Statistics.IpAddress.IP = statistics.ip.IP.String()
+		response.Statistics.IpAddress.Zone = statistics.ip.Zone
+	}
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	if statistics.received == 0 {
+		response.Message = fmt.Sprintf("no reply from %s", req.Address)
+		return response
+	}
+	response.Status = 1
+	response.Message = "ok"
+	return response
+}
+
+func (a *agentControllers) TraceRoute(ctx echo.Context) error {
+	req := new(usecase_models.AgentTraceRouteRequest)
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, a.traceRouteCheck(ctx.Request().Context(), *req))
+}
+
+func (a *agentControllers) traceRouteCheck(ctx context.Context, req usecase_models.AgentTraceRouteRequest) (response usecase_models.AgentTraceRouteResponse) {
+	hops, reached, err := traceRoute(ctx, req.Address, req.Hop, req.Retry)
+	response.Hop = hops
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	if !reached {
+		response.Message = fmt.Sprintf("%s was not reached in %d hops", req.Address, len(hops))
+		return response
+	}
+	response.Status = 1
+	response.Message = "ok"
+	return response
+}
+
+func (a *agentControllers) TLS(ctx echo.Context) error {
+	req := new(usecase_models.AgentTLSRequest)
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, a.tlsCheck(ctx.Request().Context(), *req))
+}
+
+func (a *agentControllers) tlsCheck(ctx context.Context, req usecase_models.AgentTLSRequest) (response usecase_models.AgentTLSResponse) {
+	port := req.Port
+	if port == 0 {
+		port = usecase_models.TLSDefaultPort
+	}
+	serverName := req.ServerName
+	if serverName == "" {
+		serverName = req.Address
+	}
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: agentTimeOut(req.TimeOut)},
+		// the chain is verified below, so an untrusted chain is still reported
+		Config: &tls.Config{ServerName: serverName, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(req.Address, strconv.Itoa(port)))
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	defer conn.Close()
+
+	certificates := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		response.Message = "server sent no certificate"
+		return response
+	}
+	for _, certificate := range certificates {
+		response.Statistics.Chain = append(response.Statistics.Chain, usecase_models.AgentTLSCertificate{
+			Subject:      certificate.Subject.String(),
+			Issuer:       certificate.Issuer.String(),
+			SANs:         certificate.DNSNames,
+			SerialNumber: certificate.SerialNumber.String(),
+			NotBefore:    certificate.NotBefore,
+			NotAfter:     certificate.NotAfter,
+		})
+	}
+	leaf := certificates[0]
+	response.Statistics.HostnameValid = leaf.VerifyHostname(serverName) == nil
+	intermediates := x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{Intermediates: intermediates})
+	response.Statistics.ChainTrusted = err == nil
+	if err != nil {
+		response.Statistics.VerifyError = err.Error()
+	}
+	response.Status = 1
+	response.Message = "ok"
+	return response
+}
+
+func (a *agentControllers) DNS(ctx echo.Context) error {
+	req := new(usecase_models.AgentDNSRequest)
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, a.dnsCheck(ctx.Request().Context(), *req))
+}
+
+func (a *agentControllers) dnsCheck(ctx context.Context, req usecase_models.AgentDNSRequest) (response usecase_models.AgentDNSResponse) {
+	response.Statistics.Resolver = req.Resolver
+
+	timeout := agentTimeOut(req.TimeOut)
+	resolver := &net.Resolver{}
+	if req.Resolver != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialer := net.Dialer{Timeout: timeout}
+				return dialer.DialContext(ctx, network, req.Resolver)
+			},
+		}
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	answers, err := lookupRecord(lookupCtx, resolver, req.Name, strings.ToUpper(req.RecordType))
+	response.Statistics.ResolutionTime = time.Since(start).Seconds()
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	response.Statistics.Answers = answers
+	response.Status = 1
+	response.Message = "ok"
+	return response
+}
+
+// lookupRecord answers in the shape normalizeDNSAnswers expects, mx answers are "preference host"
+func lookupRecord(ctx context.Context, resolver *net.Resolver, name string, recordType string) ([]string, error) {
+	var answers []string
+	switch recordType {
+	case usecase_models.DNSRecordA, usecase_models.DNSRecordAAAA:
+		network := "ip4"
+		if recordType == usecase_models.DNSRecordAAAA {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case usecase_models.DNSRecordCNAME:
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case usecase_models.DNSRecordMX:
+		mxs, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case usecase_models.DNSRecordTXT:
+		txts, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, txts...)
+	case usecase_models.DNSRecordNS:
+		nss, err := resolver.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			answers = append(answers, ns.Host)
+		}
+	default:
+		return nil, fmt.Errorf("record type %s is not supported", recordType)
+	}
+	return answers, nil
+}
+
+func (a *agentControllers) Run(ctx context.Context, path string, request []byte) (interface{}, error) {
+	switch path {
+	case "/v1/curl":
+		var req usecase_models.AgentCurlRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, err
+		}
+		return a.curlCheck(ctx, req), nil
+	case "/v1/netcat":
+		var req usecase_models.AgentNetCatRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, err
+		}
+		return a.netCatCheck(ctx, req), nil
+	case "/v1/pagespeed":
+		var req usecase_models.AgentPageSpeedRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, err
+		}
+		return a.pageSpeedCheck(ctx, req), nil
+	case "/v1/ping":
+		var req usecase_models.AgentPingRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, err
+		}
+		return a.pingCheck(ctx, req), nil
+	case "/v1/traceroute":
+		var req usecase_models.AgentTraceRouteRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, err
+		}
+		return a.traceRouteCheck(ctx, req), nil
+	case "/v1/tls":
+		var req usecase_models.AgentTLSRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, err
+		}
+		return a.tlsCheck(ctx, req), nil
+	case "/v1/dns":
+		var req usecase_models.AgentDNSRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, err
+		}
+		return a.dnsCheck(ctx, req), nil
+	}
+	return nil, fmt.Errorf("check %s is not supported", path)
+}
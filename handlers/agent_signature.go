@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"test-manager/cache"
+	"test-manager/repos"
+	"test-manager/usecase_models"
+	"test-manager/utils"
+	"time"
+)
+
+// requests between the manager and an agent are signed with hmac-sha256 of a datacenter key.
+// the signature covers the method, path, timestamp, nonce and body of the request, a request older than
+// AgentSignatureMaxSkew or with a nonce already seen is refused. the agent signs its response with the same
+// key and the nonce of the request, so the manager also knows it talks to the agent it holds the key of.
+const (
+	AgentKeyIdHeader     = "X-Agent-Key-Id"
+	AgentTimestampHeader = "X-Agent-Timestamp"
+	AgentNonceHeader     = "X-Agent-Nonce"
+	AgentSignatureHeader = "X-Agent-Signature"
+	// AgentErrorHeader tells the manager why a request was refused, AgentErrorUnknownKey makes it retry with an older key
+	AgentErrorHeader     = "X-Agent-Error"
+	AgentErrorUnknownKey = "unknown_key"
+
+	AgentSignatureMaxSkew = 5 * time.Minute
+	agentNonceBytes       = 16
+	// agentKeyringReload is how often the agent looks for changes of its keys file
+	agentKeyringReload    = 10 * time.Second
+	agentNonceCachePrefix = "agent_nonce:"
+	// DatacenterIdContextKey holds the datacenter of the key a pull agent signed with
+	DatacenterIdContextKey = "datacenter_id"
+)
+
+func agentBodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func agentHMAC(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func agentRequestSignature(secret, method, path, timestamp, nonce string, body []byte) string {
+	return agentHMAC(secret, method, path, timestamp, nonce, agentBodyHash(body))
+}
+
+func agentResponseSignature(secret, nonce string, status int, body []byte) string {
+	return agentHMAC(secret, "response", nonce, strconv.Itoa(status), agentBodyHash(body))
+}
+
+// signAgentRequest sets the signature headers of req and returns its nonce
+func signAgentRequest(req *http.Request, body []byte, keyId, secret string, now time.Time) (string, error) {
+	nonce, err := utils.GenerateToken(agentNonceBytes)
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(AgentKeyIdHeader, keyId)
+	req.Header.Set(AgentTimestampHeader, timestamp)
+	req.Header.Set(AgentNonceHeader, nonce)
+	req.Header.Set(AgentSignatureHeader, agentRequestSignature(secret, req.Method, req.URL.Path, timestamp, nonce, body))
+	return nonce, nil
+}
+
+// verifyAgentResponse checks the agent signed the response of the request with nonce
+func verifyAgentResponse(resp *http.Response, body []byte, secret, nonce string) error {
+	expected := agentResponseSignature(secret, nonce, resp.StatusCode, body)
+	if !hmac.Equal([]byte(expected), []byte(resp.Header.Get(AgentSignatureHeader))) {
+		return errors.New("response of agent is not signed with the datacenter key")
+	}
+	return nil
+}
+
+// agentNonces remembers the nonces seen inside the skew window, a nonce is accepted once
+type agentNonces struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newAgentNonces() *agentNonces {
+	return &agentNonces{seen: make(map[string]time.Time)}
+}
+
+func (n *agentNonces) use(nonce string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if now.Sub(n.lastSweep) > time.Minute {
+		for key, expiry := range n.seen {
+			if now.After(expiry) {
+				delete(n.seen, key)
+			}
+		}
+		n.lastSweep = now
+	}
+	if _, ok := n.seen[nonce]; ok {
+		return false
+	}
+	n.seen[nonce] = now.Add(2 * AgentSignatureMaxSkew)
+	return true
+}
+
+// verifySignedRequest checks the signature headers of a request against secretOf, it returns the secret
+// the request is signed with. unknownKey is true when secretOf does not know the key of the request
+func verifySignedRequest(header http.Header, method, path string, body []byte, secretOf func(keyId string) (string, bool),
+	useNonce func(nonce string) bool, now time.Time) (secret string, unknownKey bool, err error) {
+	keyId := header.Get(AgentKeyIdHeader)
+	timestamp := header.Get(AgentTimestampHeader)
+	nonce := header.Get(AgentNonceHeader)
+	signature := header.Get(AgentSignatureHeader)
+	if keyId == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", false, errors.New("request is not signed")
+	}
+	secret, ok := secretOf(keyId)
+	if !ok {
+		return "", true, fmt.Errorf("key %s is not known", keyId)
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", false, errors.New("timestamp of request is not valid")
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > AgentSignatureMaxSkew || skew < -AgentSignatureMaxSkew {
+		return "", false, errors.New("timestamp of request is outside of the allowed skew")
+	}
+	expected := agentRequestSignature(secret, method, path, timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", false, errors.New("signature of request is not valid")
+	}
+	if !useNonce(nonce) {
+		return "", false, errors.New("request is replayed")
+	}
+	return secret, false, nil
+}
+
+// AgentKeyring holds the keys an agent accepts, read from a file of "key_id secret" lines.
+// the file is read again when it changes, so keys are added and removed without restarting the agent.
+// a pull agent signs with the last key of the file and falls back to the ones above it
+type AgentKeyring struct {
+	path       string
+	mu         sync.RWMutex
+	keys       map[string]string
+	order      []string
+	modTime    time.Time
+	lastReload time.Time
+}
+
+func NewAgentKeyring(path string) (*AgentKeyring, error) {
+	k := &AgentKeyring{path: path}
+	if err := k.reload(time.Now()); err != nil {
+		return nil, err
+	}
+	if len(k.keys) == 0 {
+		return nil, fmt.Errorf("no key found in %s", path)
+	}
+	return k, nil
+}
+
+func (k *AgentKeyring) reload(now time.Time) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.lastReload = now
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(k.modTime) && k.keys != nil {
+		return nil
+	}
+	file, err := os.Open(k.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	keys := make(map[string]string)
+	var order []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("line of %s must be a key id and a secret", k.path)
+		}
+		if _, ok := keys[fields[0]]; !ok {
+			order = append([]string{fields[0]}, order...)
+		}
+		keys[fields[0]] = fields[1]
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	k.keys = keys
+	k.order = order
+	k.modTime = info.ModTime()
+	return nil
+}
+
+// refresh reads the keys file again once agentKeyringReload passed, a broken keys file keeps the keys read last
+func (k *AgentKeyring) refresh() {
+	k.mu.RLock()
+	stale := time.Since(k.lastReload) > agentKeyringReload
+	k.mu.RUnlock()
+	if stale {
+		if err := k.reload(time.Now()); err != nil {
+			log.Error("problem on reloading agent keys: ", err)
+		}
+	}
+}
+
+// Keys returns the keys newest first, the newest is the last line of the file
+func (k *AgentKeyring) Keys() []usecase_models.DatacenterKey {
+	k.refresh()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]usecase_models.DatacenterKey, 0, len(k.order))
+	for _, keyId := range k.order {
+		keys = append(keys, usecase_models.DatacenterKey{KeyId: keyId, Secret: k.keys[keyId]})
+	}
+	return keys
+}
+
+func (k *AgentKeyring) Secret(keyId string) (string, bool) {
+	k.refresh()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	secret, ok := k.keys[keyId]
+	return secret, ok
+}
+
+// signedResponseWriter holds the response back until the handler is done, so it can be signed as a whole
+type signedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *signedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *signedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// WithAgentSignature lets only requests signed with a key of keyring through and signs their responses
+func WithAgentSignature(keyring *AgentKeyring) echo.MiddlewareFunc {
+	nonces := newAgentNonces()
+	return signedRequests(
+		func(ctx echo.Context, keyId string) (string, bool) {
+			return keyring.Secret(keyId)
+		},
+		func(ctx echo.Context, nonce string) bool {
+			return nonces.use(nonce, time.Now())
+		},
+	)
+}
+
+// WithDatacenterSignature lets pull agents in with an active key of their datacenter and signs the jobs they
+// are given. the datacenter of the key is kept on the context, nonces are kept in cache so every manager sees them
+func WithDatacenterSignature(datacenterKeysRepo repos.DatacenterKeysRepository, cacheRepo cache.Cache) echo.MiddlewareFunc {
+	return signedRequests(
+		func(ctx echo.Context, keyId string) (string, bool) {
+			key, err := datacenterKeysRepo.GetActiveKey(ctx.Request().Context(), keyId)
+			if err != nil {
+				return "", false
+			}
+			ctx.Set(DatacenterIdContextKey, key.DatacenterId)
+			return key.Secret, true
+		},
+		func(ctx echo.Context, nonce string) bool {
+			ok, err := cacheRepo.SetNX(ctx.Request().Context(), agentNonceCachePrefix+nonce, 1, 2*AgentSignatureMaxSkew)
+			if err != nil {
+				log.Error("problem on keeping agent nonce: ", err)
+				return false
+			}
+			return ok
+		},
+	)
+}
+
+// signedRequests checks the signature of requests with the secret of their key and signs the responses with it
+func signedRequests(secretOf func(ctx echo.Context, keyId string) (string, bool), useNonce func(ctx echo.Context, nonce string) bool) echo.MiddlewareFunc {
+	return func(h echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+			body, err := ioutil.ReadAll(request.Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			secret, unknownKey, err := verifySignedRequest(request.Header, request.Method, request.URL.Path, body,
+				func(keyId string) (string, bool) {
+					return secretOf(ctx, keyId)
+				},
+				func(nonce string) bool {
+					return useNonce(ctx, nonce)
+				}, time.Now())
+			if err != nil {
+				if unknownKey {
+					ctx.Response().Header().Set(AgentErrorHeader, AgentErrorUnknownKey)
+				}
+				return ctx.JSON(http.StatusUnauthorized, map[string]string{"message": err.Error()})
+			}
+
+			writer := &signedResponseWriter{ResponseWriter: ctx.Response().Writer}
+			ctx.Response().Writer = writer
+			err = h(ctx)
+			ctx.Response().Writer = writer.ResponseWriter
+			if err != nil {
+				return err
+			}
+			if writer.status == 0 {
+				writer.status = http.StatusOK
+			}
+			writer.Header().Set(AgentSignatureHeader, agentResponseSignature(secret, request.Header.Get(AgentNonceHeader), writer.status, writer.body.Bytes()))
+			writer.ResponseWriter.WriteHeader(writer.status)
+			_, err = writer.ResponseWriter.Write(writer.body.Bytes())
+			return err
+		}
+	}
+}
+
+// postSigned posts body to url signed with the first of keys the other side knows, keys are tried newest first
+// so a key can be added on the signing side before the other side is given it. nothing is sent without a key.
+// the answer is only returned when it is signed with the same key
+func postSigned(ctx context.Context, client *http.Client, url string, body []byte, keys []usecase_models.DatacenterKey) (int, []byte, error) {
+	if len(keys) == 0 {
+		return 0, nil, errors.New("there is no key to sign the request with")
+	}
+	for _, key := range keys {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		nonce, err := signAgentRequest(req, body, key.KeyId, key.Secret, time.Now())
+		if err != nil {
+			return 0, nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		respBody, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return 0, nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get(AgentErrorHeader) == AgentErrorUnknownKey {
+			continue
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return resp.StatusCode, nil, fmt.Errorf("request was refused: %s", respBody)
+		}
+		if err = verifyAgentResponse(resp, respBody, key.Secret, nonce); err != nil {
+			return resp.StatusCode, nil, err
+		}
+		return resp.StatusCode, respBody, nil
+	}
+	return 0, nil, errors.New("none of the keys is known by the other side")
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/volatiletech/null/v8"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"testing"
+	"time"
+)
+
+func signedHeader(t *testing.T, path string, body []byte, keyId, secret string, now time.Time) http.Header {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	if _, err := signAgentRequest(req, body, keyId, secret, now); err != nil {
+		t.Fatal(err)
+	}
+	return req.Header
+}
+
+func TestVerifySignedRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"url":"https://example.com"}`)
+	secrets := map[string]string{"current": "s3cret"}
+	secretOf := func(keyId string) (string, bool) {
+		secret, ok := secrets[keyId]
+		return secret, ok
+	}
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		path       string
+		body       []byte
+		now        time.Time
+		unknownKey bool
+		err        string
+	}{
+		{
+			name:   "valid",
+			header: signedHeader(t, "/v1/curl", body, "current", "s3cret", now),
+			path:   "/v1/curl",
+			body:   body,
+			now:    now,
+		},
+		{
+			name:   "tampered body",
+			header: signedHeader(t, "/v1/curl", body, "current", "s3cret", now),
+			path:   "/v1/curl",
+			body:   []byte(`{"url":"https://attacker.example"}`),
+			now:    now,
+			err:    "signature of request is not valid",
+		},
+		{
+			name:   "tampered path",
+			header: signedHeader(t, "/v1/curl", body, "current", "s3cret", now),
+			path:   "/v1/ping",
+			body:   body,
+			now:    now,
+			err:    "signature of request is not valid",
+		},
+		{
+			name:   "wrong secret",
+			header: signedHeader(t, "/v1/curl", body, "current", "guessed", now),
+			path:   "/v1/curl",
+			body:   body,
+			now:    now,
+			err:    "signature of request is not valid",
+		},
+		{
+			name:   "expired timestamp",
+			header: signedHeader(t, "/v1/curl", body, "current", "s3cret", now.Add(-AgentSignatureMaxSkew-time.Second)),
+			path:   "/v1/curl",
+			body:   body,
+			now:    now,
+			err:    "outside of the allowed skew",
+		},
+		{
+			name:   "timestamp from the future",
+			header: signedHeader(t, "/v1/curl", body, "current", "s3cret", now.Add(AgentSignatureMaxSkew+time.Second)),
+			path:   "/v1/curl",
+			body:   body,
+			now:    now,
+			err:    "outside of the allowed skew",
+		},
+		{
+			name:       "revoked key",
+			header:     signedHeader(t, "/v1/curl", body, "revoked", "old", now),
+			path:       "/v1/curl",
+			body:       body,
+			now:        now,
+			unknownKey: true,
+			err:        "key revoked is not known",
+		},
+		{
+			name:   "unsigned",
+			header: http.Header{},
+			path:   "/v1/curl",
+			body:   body,
+			now:    now,
+			err:    "request is not signed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonces := newAgentNonces()
+			secret, unknownKey, err := verifySignedRequest(tt.header, http.MethodPost, tt.path, tt.body, secretOf,
+				func(nonce string) bool { return nonces.use(nonce, tt.now) }, tt.now)
+			if unknownKey != tt.unknownKey {
+				t.Fatalf("unknownKey = %v, want %v", unknownKey, tt.unknownKey)
+			}
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if secret != "s3cret" {
+					t.Fatalf("secret = %q, want the secret of the key", secret)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifySignedRequestReplayedNonce(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{}`)
+	header := signedHeader(t, "/v1/ping", body, "current", "s3cret", now)
+	secretOf := func(keyId string) (string, bool) { return "s3cret", keyId == "current" }
+	nonces := newAgentNonces()
+	useNonce := func(nonce string) bool { return nonces.use(nonce, now) }
+
+	if _, _, err := verifySignedRequest(header, http.MethodPost, "/v1/ping", body, secretOf, useNonce, now); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	_, _, err := verifySignedRequest(header, http.MethodPost, "/v1/ping", body, secretOf, useNonce, now.Add(time.Second))
+	if err == nil || !strings.Contains(err.Error(), "replayed") {
+		t.Fatalf("replayed request: error = %v, want replayed", err)
+	}
+}
+
+// agentServer answers signed requests with the keys it knows, like an agent with its keys file
+func agentServer(t *testing.T, keys map[string]string) *httptest.Server {
+	t.Helper()
+	nonces := newAgentNonces()
+	e := echo.New()
+	e.POST("/v1/ping", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, map[string]string{"key_id": ctx.Request().Header.Get(AgentKeyIdHeader)})
+	}, signedRequests(
+		func(ctx echo.Context, keyId string) (string, bool) {
+			secret, ok := keys[keyId]
+			return secret, ok
+		},
+		func(ctx echo.Context, nonce string) bool {
+			return nonces.use(nonce, time.Now())
+		},
+	))
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPostSignedRotation(t *testing.T) {
+	oldKey := usecase_models.DatacenterKey{KeyId: "old", Secret: "old-secret"}
+	newKey := usecase_models.DatacenterKey{KeyId: "new", Secret: "new-secret"}
+
+	tests := []struct {
+		name      string
+		agentKeys map[string]string
+		keys      []usecase_models.DatacenterKey
+		usedKey   string
+		err       string
+	}{
+		{
+			name:      "agent not given the new key yet",
+			agentKeys: map[string]string{"old": "old-secret"},
+			keys:      []usecase_models.DatacenterKey{newKey, oldKey},
+			usedKey:   "old",
+		},
+		{
+			name:      "agent knows both keys",
+			agentKeys: map[string]string{"old": "old-secret", "new": "new-secret"},
+			keys:      []usecase_models.DatacenterKey{newKey, oldKey},
+			usedKey:   "new",
+		},
+		{
+			name:      "old key revoked on the manager",
+			agentKeys: map[string]string{"old": "old-secret", "new": "new-secret"},
+			keys:      []usecase_models.DatacenterKey{newKey},
+			usedKey:   "new",
+		},
+		{
+			name:      "old key removed from the agent",
+			agentKeys: map[string]string{"new": "new-secret"},
+			keys:      []usecase_models.DatacenterKey{oldKey},
+			err:       "none of the keys is known",
+		},
+		{
+			name:      "secret does not match the agent",
+			agentKeys: map[string]string{"old": "other-secret"},
+			keys:      []usecase_models.DatacenterKey{oldKey},
+			err:       "request was refused",
+		},
+		{
+			name:      "no key",
+			agentKeys: map[string]string{"old": "old-secret"},
+			err:       "no key to sign",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := agentServer(t, tt.agentKeys)
+			status, body, err := postSigned(context.Background(), server.Client(), server.URL+"/v1/ping", []byte(`{}`), tt.keys)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status != http.StatusOK || !strings.Contains(string(body), `"key_id":"`+tt.usedKey+`"`) {
+				t.Fatalf("got %d %s, want it signed with %s", status, body, tt.usedKey)
+			}
+		})
+	}
+}
+
+func TestPostSignedRejectsForgedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(AgentSignatureHeader, agentResponseSignature("not-the-key", r.Header.Get(AgentNonceHeader), http.StatusOK, []byte(`{}`)))
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	key := usecase_models.DatacenterKey{KeyId: "current", Secret: "s3cret"}
+	_, _, err := postSigned(context.Background(), server.Client(), server.URL+"/v1/ping", []byte(`{}`), []usecase_models.DatacenterKey{key})
+	if err == nil || !strings.Contains(err.Error(), "not signed with the datacenter key") {
+		t.Fatalf("error = %v, want the response refused", err)
+	}
+}
+
+func TestAgentKeyringRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte("old old-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := NewAgentKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key is added below the old one, both are accepted while the manager moves over
+	if err = os.WriteFile(path, []byte("old old-secret\nnew new-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err = keyring.reload(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	keys := keyring.Keys()
+	if len(keys) != 2 || keys[0].KeyId != "new" || keys[1].KeyId != "old" {
+		t.Fatalf("keys = %+v, want new then old", keys)
+	}
+	if _, ok := keyring.Secret("old"); !ok {
+		t.Fatal("old key is not accepted during the overlap")
+	}
+
+	// removing the old line revokes it on the agent
+	if err = os.WriteFile(path, []byte("new new-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	if err = os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err = keyring.reload(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := keyring.Secret("old"); ok {
+		t.Fatal("old key is still accepted after it was removed")
+	}
+}
+
+func (f *fakeDatacentersRepo) GetDataCenterMode(ctx context.Context, id int) (string, error) {
+	return usecase_models.DatacenterModePush, nil
+}
+
+func TestAgentPostUnsignedUntilFirstKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"signed":` + strconv.FormatBool(r.Header.Get(AgentSignatureHeader) != "") + `}`))
+	}))
+	defer server.Close()
+	revoked := usecase_models.DatacenterKey{KeyId: "old", Secret: "old-secret", RevokedAt: null.TimeFrom(time.Now())}
+
+	tests := []struct {
+		name string
+		keys []usecase_models.DatacenterKey
+		err  string
+	}{
+		{name: "datacenter that never had a key is sent unsigned requests"},
+		{name: "datacenter whose keys are all revoked is sent nothing", keys: []usecase_models.DatacenterKey{revoked}, err: "has no active key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := &agentHandler{
+				dataCentersRepo:    &fakeDatacentersRepo{},
+				datacenterKeysRepo: &fakeDatacenterKeysRepo{keys: tt.keys},
+				client:             server.Client(),
+			}
+			var response map[string]bool
+			err := agent.post(context.Background(), server.Client(), &models.Datacenter{ID: 1, Title: "dc", Baseurl: server.URL}, "/v1/ping", struct{}{}, &response)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response["signed"] {
+				t.Fatal("request of a datacenter without keys was signed")
+			}
+		})
+	}
+}
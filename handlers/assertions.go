@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/tidwall/gjson"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"test-manager/usecase_models"
+)
+
+// validateAssertions checks operators and values of the assertions before a rule is saved
+func validateAssertions(acceptRules usecase_models.AcceptanceModel) error {
+	if acceptRules.MaxResponseTime < 0 {
+		return fmt.Errorf("max response time can not be negative")
+	}
+	for _, assertion := range acceptRules.ResponseHeaders {
+		switch assertion.Operator {
+		case usecase_models.AssertionOpTypeOf, usecase_models.AssertionOpLength:
+			return fmt.Errorf("operator %s is not supported on header %s", assertion.Operator, assertion.Key)
+		}
+	}
+	if err := validateAssertionGroup(usecase_models.AssertionGroup{Assertions: acceptRules.ResponseHeaders}); err != nil {
+		return err
+	}
+	return validateAssertionGroup(usecase_models.AssertionGroup{
+		Logic:      acceptRules.Logic,
+		Assertions: acceptRules.ResponseBodies,
+		Groups:     acceptRules.BodyGroups,
+	})
+}
+
+func validateAssertionGroup(group usecase_models.AssertionGroup) error {
+	switch group.Logic {
+	case "", usecase_models.AssertionLogicAnd, usecase_models.AssertionLogicOr:
+	default:
+		return fmt.Errorf("unknown assertion logic: %s", group.Logic)
+	}
+	for _, assertion := range group.Assertions {
+		switch assertion.Operator {
+		case "", usecase_models.AssertionOpExists, usecase_models.AssertionOpEquals, usecase_models.AssertionOpNotEquals,
+			usecase_models.AssertionOpContains, usecase_models.AssertionOpTypeOf:
+		case usecase_models.AssertionOpRegex:
+			if _, err := regexp.Compile(assertion.Value); err != nil {
+				return fmt.Errorf("not valid regex for %s: %w", assertion.Key, err)
+			}
+		case usecase_models.AssertionOpGreaterThan, usecase_models.AssertionOpLessThan:
+			if _, err := strconv.ParseFloat(assertion.Value, 64); err != nil {
+				return fmt.Errorf("value of %s must be a number for %s", assertion.Key, assertion.Operator)
+			}
+		case usecase_models.AssertionOpLength:
+			if _, err := strconv.Atoi(assertion.Value); err != nil {
+				return fmt.Errorf("value of %s must be an integer for %s", assertion.Key, assertion.Operator)
+			}
+		default:
+			return fmt.Errorf("unknown assertion operator: %s", assertion.Operator)
+		}
+	}
+	for _, nested := range group.Groups {
+		if err := validateAssertionGroup(nested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateAssertionGroup returns the failures that made the group fail, nil when the group passes
+func evaluateAssertionGroup(body string, group usecase_models.AssertionGroup) []usecase_models.AssertionFailure {
+	var failures []usecase_models.AssertionFailure
+	checks := 0
+	for _, assertion := range group.Assertions {
+		checks++
+		if failure, ok := evaluateAssertion(body, assertion); !ok {
+			failures = append(failures, failure)
+		} else if group.Logic == usecase_models.AssertionLogicOr {
+			return nil
+		}
+	}
+	for _, nested := range group.Groups {
+		checks++
+		if nestedFailures := evaluateAssertionGroup(body, nested); len(nestedFailures) != 0 {
+			failures = append(failures, nestedFailures...)
+		} else if group.Logic == usecase_models.AssertionLogicOr {
+			return nil
+		}
+	}
+	if checks == 0 {
+		return nil
+	}
+	return failures
+}
+
+func evaluateAssertion(body string, assertion usecase_models.KeyValueModel) (usecase_models.AssertionFailure, bool) {
+	return assertResult(gjson.Get(body, assertion.Key), assertion.Key, assertion)
+}
+
+// evaluateHeaderAssertion asserts on the comma joined values of a header, numeric values can be compared
+func evaluateHeaderAssertion(header map[string][]string, assertion usecase_models.KeyValueModel) (usecase_models.AssertionFailure, bool) {
+	var result gjson.Result
+	if values := http.Header(header).Values(assertion.Key); len(values) != 0 {
+		value := strings.Join(values, ",")
+		result = gjson.Result{Type: gjson.String, Str: value, Raw: strconv.Quote(value)}
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			result = gjson.Result{Type: gjson.Number, Num: number, Raw: value}
+		}
+	}
+	failure, ok := assertResult(result, "header "+assertion.Key, assertion)
+	failure.Key = "header." + assertion.Key
+	return failure, ok
+}
+
+// evaluateResponseTime fails when the step was slower than the accepted maximum
+func evaluateResponseTime(responseTime float64, maxResponseTime float64) (usecase_models.AssertionFailure, bool) {
+	failure := usecase_models.AssertionFailure{
+		Key:      "response_time",
+		Operator: usecase_models.AssertionOpLessThan,
+		Expected: strconv.FormatFloat(maxResponseTime, 'f', -1, 64),
+		Actual:   strconv.FormatFloat(responseTime, 'f', -1, 64),
+	}
+	if maxResponseTime == 0 || responseTime <= maxResponseTime {
+		return failure, true
+	}
+	failure.Message = fmt.Sprintf("response time %s is above the maximum %s", failure.Actual, failure.Expected)
+	return failure, false
+}
+
+// assertResult checks a looked up value against the assertion, name is how the value is called in the failure message
+func assertResult(result gjson.Result, name string, assertion usecase_models.KeyValueModel) (usecase_models.AssertionFailure, bool) {
+	operator := assertion.Operator
+	if operator == "" {
+		operator = usecase_models.AssertionOpExists
+	}
+	failure := usecase_models.AssertionFailure{
+		Key:      assertion.Key,
+		Operator: operator,
+		Expected: assertion.Value,
+		Actual:   result.String(),
+	}
+	if !result.Exists() {
+		failure.Message = fmt.Sprintf("%s does not exist", name)
+		return failure, false
+	}
+
+	switch operator {
+	case usecase_models.AssertionOpExists:
+		return failure, true
+	case usecase_models.AssertionOpEquals:
+		if result.String() == assertion.Value {
+			return failure, true
+		}
+		failure.Message = fmt.Sprintf("%s is %q, expected %q", name, result.String(), assertion.Value)
+	case usecase_models.AssertionOpNotEquals:
+		if result.String() != assertion.Value {
+			return failure, true
+		}
+		failure.Message = fmt.Sprintf("%s must not be %q", name, assertion.Value)
+	case usecase_models.AssertionOpContains:
+		if strings.Contains(result.String(), assertion.Value) {
+			return failure, true
+		}
+		failure.Message = fmt.Sprintf("%s does not contain %q", name, assertion.Value)
+	case usecase_models.AssertionOpRegex:
+		re, err := regexp.Compile(assertion.Value)
+		if err == nil && re.MatchString(result.String()) {
+			return failure, true
+		}
+		failure.Message = fmt.Sprintf("%s does not match %s", name, assertion.Value)
+	case usecase_models.AssertionOpGreaterThan, usecase_models.AssertionOpLessThan:
+		expected, err := strconv.ParseFloat(assertion.Value, 64)
+		if err != nil || result.Type != gjson.Number {
+			failure.Message = fmt.Sprintf("%s is not comparable with %s", name, assertion.Value)
+			return failure, false
+		}
+		if operator == usecase_models.AssertionOpGreaterThan && result.Float() > expected ||
+			operator == usecase_models.AssertionOpLessThan && result.Float() < expected {
+			return failure, true
+		}
+		failure.Message = fmt.Sprintf("%s is %s, expected %s %s", name, result.String(), strings.ReplaceAll(operator, "_", " "), assertion.Value)
+	case usecase_models.AssertionOpTypeOf:
+		actualType := gjsonType(result)
+		failure.Actual = actualType
+		if actualType == assertion.Value {
+			return failure, true
+		}
+		failure.Message = fmt.Sprintf("%s is of type %s, expected %s", name, actualType, assertion.Value)
+	case usecase_models.AssertionOpLength:
+		expected, err := strconv.Atoi(assertion.Value)
+		if !result.IsArray() || err != nil {
+			failure.Message = fmt.Sprintf("%s is not an array", name)
+			return failure, false
+		}
+		length := len(result.Array())
+		failure.Actual = strconv.Itoa(length)
+		if length == expected {
+			return failure, true
+		}
+		failure.Message = fmt.Sprintf("%s has %d items, expected %d", name, length, expected)
+	default:
+		failure.Message = fmt.Sprintf("unknown assertion operator: %s", operator)
+	}
+	return failure, false
+}
+
+func gjsonType(result gjson.Result) string {
+	switch result.Type {
+	case gjson.Null:
+		return "null"
+	case gjson.True, gjson.False:
+		return "boolean"
+	case gjson.Number:
+		return "number"
+	case gjson.String:
+		return "string"
+	}
+	if result.IsArray() {
+		return "array"
+	}
+	return "object"
+}
+
+// assertionFailuresRootCause joins the failures of all steps into one line for the notifications.
+// steps are sorted by name so the same failures always give the same line
+func assertionFailuresRootCause(failures map[string][]usecase_models.AssertionFailure) string {
+	var endpointNames []string
+	for endpointName := range failures {
+		endpointNames = append(endpointNames, endpointName)
+	}
+	sort.Strings(endpointNames)
+
+	var causes []string
+	for _, endpointName := range endpointNames {
+		for _, failure := range failures[endpointName] {
+			causes = append(causes, fmt.Sprintf("%s: %s", endpointName, failure.Message))
+		}
+	}
+	return strings.Join(causes, "; ")
+}
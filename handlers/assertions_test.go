@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"reflect"
+	"test-manager/usecase_models"
+	"testing"
+)
+
+const assertionsBody = `{"user":{"name":"alice","age":31,"active":true,"email":null},"items":[1,2,3],"version":"v1.4.2"}`
+
+func TestCurlAcceptanceCriteriaBodyOperators(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion usecase_models.KeyValueModel
+		failure   *usecase_models.AssertionFailure
+	}{
+		{
+			name:      "no operator checks the key exists",
+			assertion: usecase_models.KeyValueModel{Key: "user.name"},
+		},
+		{
+			name:      "no operator on a missing key",
+			assertion: usecase_models.KeyValueModel{Key: "user.phone"},
+			failure:   &usecase_models.AssertionFailure{Key: "user.phone", Operator: "exists", Message: "user.phone does not exist"},
+		},
+		{
+			name:      "exists",
+			assertion: usecase_models.KeyValueModel{Key: "user.email", Operator: usecase_models.AssertionOpExists},
+		},
+		{
+			name:      "equals",
+			assertion: usecase_models.KeyValueModel{Key: "user.name", Operator: usecase_models.AssertionOpEquals, Value: "alice"},
+		},
+		{
+			name:      "equals mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "user.name", Operator: usecase_models.AssertionOpEquals, Value: "bob"},
+			failure: &usecase_models.AssertionFailure{Key: "user.name", Operator: "equals", Expected: "bob", Actual: "alice",
+				Message: `user.name is "alice", expected "bob"`},
+		},
+		{
+			name:      "equals on a missing key",
+			assertion: usecase_models.KeyValueModel{Key: "user.nickname", Operator: usecase_models.AssertionOpEquals, Value: "al"},
+			failure: &usecase_models.AssertionFailure{Key: "user.nickname", Operator: "equals", Expected: "al",
+				Message: "user.nickname does not exist"},
+		},
+		{
+			name:      "not_equals",
+			assertion: usecase_models.KeyValueModel{Key: "user.name", Operator: usecase_models.AssertionOpNotEquals, Value: "bob"},
+		},
+		{
+			name:      "not_equals mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "user.name", Operator: usecase_models.AssertionOpNotEquals, Value: "alice"},
+			failure: &usecase_models.AssertionFailure{Key: "user.name", Operator: "not_equals", Expected: "alice", Actual: "alice",
+				Message: `user.name must not be "alice"`},
+		},
+		{
+			name:      "contains",
+			assertion: usecase_models.KeyValueModel{Key: "version", Operator: usecase_models.AssertionOpContains, Value: "1.4"},
+		},
+		{
+			name:      "contains mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "version", Operator: usecase_models.AssertionOpContains, Value: "2.0"},
+			failure: &usecase_models.AssertionFailure{Key: "version", Operator: "contains", Expected: "2.0", Actual: "v1.4.2",
+				Message: `version does not contain "2.0"`},
+		},
+		{
+			name:      "regex",
+			assertion: usecase_models.KeyValueModel{Key: "version", Operator: usecase_models.AssertionOpRegex, Value: `^v\d+\.\d+\.\d+$`},
+		},
+		{
+			name:      "regex mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "version", Operator: usecase_models.AssertionOpRegex, Value: `^\d+$`},
+			failure: &usecase_models.AssertionFailure{Key: "version", Operator: "regex", Expected: `^\d+$`, Actual: "v1.4.2",
+				Message: `version does not match ^\d+$`},
+		},
+		{
+			name:      "greater_than",
+			assertion: usecase_models.KeyValueModel{Key: "user.age", Operator: usecase_models.AssertionOpGreaterThan, Value: "30"},
+		},
+		{
+			name:      "greater_than mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "user.age", Operator: usecase_models.AssertionOpGreaterThan, Value: "31"},
+			failure: &usecase_models.AssertionFailure{Key: "user.age", Operator: "greater_than", Expected: "31", Actual: "31",
+				Message: "user.age is 31, expected greater than 31"},
+		},
+		{
+			name:      "less_than",
+			assertion: usecase_models.KeyValueModel{Key: "user.age", Operator: usecase_models.AssertionOpLessThan, Value: "31.5"},
+		},
+		{
+			name:      "less_than mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "user.age", Operator: usecase_models.AssertionOpLessThan, Value: "18"},
+			failure: &usecase_models.AssertionFailure{Key: "user.age", Operator: "less_than", Expected: "18", Actual: "31",
+				Message: "user.age is 31, expected less than 18"},
+		},
+		{
+			name:      "less_than on a string",
+			assertion: usecase_models.KeyValueModel{Key: "user.name", Operator: usecase_models.AssertionOpLessThan, Value: "5"},
+			failure: &usecase_models.AssertionFailure{Key: "user.name", Operator: "less_than", Expected: "5", Actual: "alice",
+				Message: "user.name is not comparable with 5"},
+		},
+		{
+			name:      "type_of",
+			assertion: usecase_models.KeyValueModel{Key: "user.active", Operator: usecase_models.AssertionOpTypeOf, Value: "boolean"},
+		},
+		{
+			name:      "type_of null",
+			assertion: usecase_models.KeyValueModel{Key: "user.email", Operator: usecase_models.AssertionOpTypeOf, Value: "null"},
+		},
+		{
+			name:      "type_of mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "items", Operator: usecase_models.AssertionOpTypeOf, Value: "object"},
+			failure: &usecase_models.AssertionFailure{Key: "items", Operator: "type_of", Expected: "object", Actual: "array",
+				Message: "items is of type array, expected object"},
+		},
+		{
+			name:      "length",
+			assertion: usecase_models.KeyValueModel{Key: "items", Operator: usecase_models.AssertionOpLength, Value: "3"},
+		},
+		{
+			name:      "length mismatch",
+			assertion: usecase_models.KeyValueModel{Key: "items", Operator: usecase_models.AssertionOpLength, Value: "2"},
+			failure: &usecase_models.AssertionFailure{Key: "items", Operator: "length", Expected: "2", Actual: "3",
+				Message: "items has 3 items, expected 2"},
+		},
+		{
+			name:      "length of a non array",
+			assertion: usecase_models.KeyValueModel{Key: "user", Operator: usecase_models.AssertionOpLength, Value: "4"},
+			failure: &usecase_models.AssertionFailure{Key: "user", Operator: "length", Expected: "4",
+				Actual:  `{"name":"alice","age":31,"active":true,"email":null}`,
+				Message: "user is not an array"},
+		},
+		{
+			name:      "unknown operator",
+			assertion: usecase_models.KeyValueModel{Key: "user.name", Operator: "starts_with", Value: "a"},
+			failure: &usecase_models.AssertionFailure{Key: "user.name", Operator: "starts_with", Expected: "a", Actual: "alice",
+				Message: "unknown assertion operator: starts_with"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acceptRules := usecase_models.AcceptanceModel{
+				Statuses:       []string{"200"},
+				ResponseBodies: []usecase_models.KeyValueModel{tt.assertion},
+			}
+			failures := curlAcceptanceCriteria("200", []byte(assertionsBody), nil, 0, acceptRules, nil)
+			var want []usecase_models.AssertionFailure
+			if tt.failure != nil {
+				want = []usecase_models.AssertionFailure{*tt.failure}
+			}
+			if !reflect.DeepEqual(failures, want) {
+				t.Fatalf("failures = %+v, want %+v", failures, want)
+			}
+		})
+	}
+}
+
+func TestCurlAcceptanceCriteria(t *testing.T) {
+	header := map[string][]string{"Content-Type": {"application/json"}, "X-Rate-Limit": {"120"}}
+	tests := []struct {
+		name         string
+		status       string
+		responseTime float64
+		acceptRules  usecase_models.AcceptanceModel
+		failures     []usecase_models.AssertionFailure
+	}{
+		{
+			name:        "status not accepted skips the other checks",
+			status:      "503",
+			acceptRules: usecase_models.AcceptanceModel{Statuses: []string{"200", "201"}, MaxResponseTime: 1},
+			failures: []usecase_models.AssertionFailure{{Key: "status", Operator: "equals", Expected: "200,201", Actual: "503",
+				Message: "status 503 is not accepted"}},
+		},
+		{
+			name:         "response time within the maximum",
+			status:       "200",
+			responseTime: 0.3,
+			acceptRules:  usecase_models.AcceptanceModel{Statuses: []string{"200"}, MaxResponseTime: 0.5},
+		},
+		{
+			name:         "response time above the maximum",
+			status:       "200",
+			responseTime: 0.75,
+			acceptRules:  usecase_models.AcceptanceModel{Statuses: []string{"200"}, MaxResponseTime: 0.5},
+			failures: []usecase_models.AssertionFailure{{Key: "response_time", Operator: "less_than", Expected: "0.5", Actual: "0.75",
+				Message: "response time 0.75 is above the maximum 0.5"}},
+		},
+		{
+			name:   "header operators",
+			status: "200",
+			acceptRules: usecase_models.AcceptanceModel{Statuses: []string{"200"}, ResponseHeaders: []usecase_models.KeyValueModel{
+				{Key: "content-type", Operator: usecase_models.AssertionOpContains, Value: "json"},
+				{Key: "X-Rate-Limit", Operator: usecase_models.AssertionOpGreaterThan, Value: "100"},
+			}},
+		},
+		{
+			name:   "header mismatches",
+			status: "200",
+			acceptRules: usecase_models.AcceptanceModel{Statuses: []string{"200"}, ResponseHeaders: []usecase_models.KeyValueModel{
+				{Key: "Content-Type", Operator: usecase_models.AssertionOpEquals, Value: "text/html"},
+				{Key: "X-Rate-Limit", Operator: usecase_models.AssertionOpLessThan, Value: "100"},
+				{Key: "X-Request-Id"},
+			}},
+			failures: []usecase_models.AssertionFailure{
+				{Key: "header.Content-Type", Operator: "equals", Expected: "text/html", Actual: "application/json",
+					Message: `header Content-Type is "application/json", expected "text/html"`},
+				{Key: "header.X-Rate-Limit", Operator: "less_than", Expected: "100", Actual: "120",
+					Message: "header X-Rate-Limit is 120, expected less than 100"},
+				{Key: "header.X-Request-Id", Operator: "exists", Message: "header X-Request-Id does not exist"},
+			},
+		},
+		{
+			name:   "or passes with one passing assertion",
+			status: "200",
+			acceptRules: usecase_models.AcceptanceModel{Statuses: []string{"200"}, Logic: usecase_models.AssertionLogicOr,
+				ResponseBodies: []usecase_models.KeyValueModel{
+					{Key: "user.name", Operator: usecase_models.AssertionOpEquals, Value: "bob"},
+					{Key: "user.age", Operator: usecase_models.AssertionOpGreaterThan, Value: "18"},
+				}},
+		},
+		{
+			name:   "or fails with every failure",
+			status: "200",
+			acceptRules: usecase_models.AcceptanceModel{Statuses: []string{"200"}, Logic: usecase_models.AssertionLogicOr,
+				ResponseBodies: []usecase_models.KeyValueModel{
+					{Key: "user.name", Operator: usecase_models.AssertionOpEquals, Value: "bob"},
+				},
+				BodyGroups: []usecase_models.AssertionGroup{{Assertions: []usecase_models.KeyValueModel{
+					{Key: "items", Operator: usecase_models.AssertionOpLength, Value: "5"},
+				}}}},
+			failures: []usecase_models.AssertionFailure{
+				{Key: "user.name", Operator: "equals", Expected: "bob", Actual: "alice", Message: `user.name is "alice", expected "bob"`},
+				{Key: "items", Operator: "length", Expected: "5", Actual: "3", Message: "items has 3 items, expected 5"},
+			},
+		},
+		{
+			name:   "and with a passing nested or group",
+			status: "200",
+			acceptRules: usecase_models.AcceptanceModel{Statuses: []string{"200"},
+				ResponseBodies: []usecase_models.KeyValueModel{{Key: "user.active", Operator: usecase_models.AssertionOpEquals, Value: "true"}},
+				BodyGroups: []usecase_models.AssertionGroup{{Logic: usecase_models.AssertionLogicOr, Assertions: []usecase_models.KeyValueModel{
+					{Key: "version", Operator: usecase_models.AssertionOpEquals, Value: "v2"},
+					{Key: "version", Operator: usecase_models.AssertionOpRegex, Value: "^v1"},
+				}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := curlAcceptanceCriteria(tt.status, []byte(assertionsBody), header, tt.responseTime, tt.acceptRules, nil)
+			if !reflect.DeepEqual(failures, tt.failures) {
+				t.Fatalf("failures = %+v, want %+v", failures, tt.failures)
+			}
+		})
+	}
+}
+
+func TestAssertionFailuresRootCause(t *testing.T) {
+	failures := map[string][]usecase_models.AssertionFailure{
+		"login":    {{Message: "status 500 is not accepted"}},
+		"checkout": {{Message: "total does not exist"}, {Message: `currency is "EUR", expected "USD"`}},
+		"cart":     {{Message: "items has 0 items, expected 1"}},
+	}
+	want := `cart: items has 0 items, expected 1; checkout: total does not exist; checkout: currency is "EUR", expected "USD"; login: status 500 is not accepted`
+	// map order changes between runs, the line must not
+	for i := 0; i < 20; i++ {
+		if got := assertionFailuresRootCause(failures); got != want {
+			t.Fatalf("root cause = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"test-manager/repos"
+	"time"
+)
+
+const (
+	BadgeDefaultWindow = 30 * 24 * time.Hour
+	BadgeMaxWindow     = 90 * 24 * time.Hour
+	// BadgeStatusWindow is how far back the last session is looked up for the status badge
+	BadgeStatusWindow = 24 * time.Hour
+	BadgeCacheControl = "max-age=60"
+)
+
+const (
+	badgeColorGreen  = "#4c1"
+	badgeColorYellow = "#dfb317"
+	badgeColorRed    = "#e05d44"
+	badgeColorGrey   = "#9f9f9f"
+)
+
+var badgeTemplate = template.Must(template.New("badge").Parse(
+	`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">` +
+		`<title>{{.Label}}: {{.Message}}</title>` +
+		`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` +
+		`<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>` +
+		`<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/>` +
+		`<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>` +
+		`<rect width="{{.Width}}" height="20" fill="url(#s)"/></g>` +
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+		`<text x="{{.LabelX}}" y="14">{{.Label}}</text><text x="{{.MessageX}}" y="14">{{.Message}}</text></g></svg>`))
+
+type badge struct {
+	Label        string
+	Message      string
+	Color        string
+	Width        int
+	LabelWidth   int
+	MessageWidth int
+	LabelX       int
+	MessageX     int
+}
+
+// renderBadge draws a shields style badge, text width is estimated as fonts are not available here
+func renderBadge(label string, message string, color string) (string, error) {
+	b := badge{
+		Label:        label,
+		Message:      message,
+		Color:        color,
+		LabelWidth:   len(label)*7 + 10,
+		MessageWidth: len(message)*7 + 10,
+	}
+	b.Width = b.LabelWidth + b.MessageWidth
+	b.LabelX = b.LabelWidth / 2
+	b.MessageX = b.LabelWidth + b.MessageWidth/2
+
+	var svg strings.Builder
+	if err := badgeTemplate.Execute(&svg, b); err != nil {
+		return "", err
+	}
+	return svg.String(), nil
+}
+
+// parseBadgeWindow accepts windows like 24h, 7d or 30d
+func parseBadgeWindow(window string) (time.Duration, error) {
+	if window == "" {
+		return BadgeDefaultWindow, nil
+	}
+	var duration time.Duration
+	if strings.HasSuffix(window, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+		if err != nil {
+			return 0, err
+		}
+		duration = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		duration, err = time.ParseDuration(window)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if duration <= 0 || duration > BadgeMaxWindow {
+		return 0, fmt.Errorf("window must be between 0 and %s", BadgeMaxWindow)
+	}
+	return duration, nil
+}
+
+// getSessionSuccesses returns the success of each session of a pipeline since the given time, newest first
+func (hc *httpControllers) getSessionSuccesses(ctx context.Context, pipelineType string, pipelineId int, since time.Time, withMaintenance bool) ([]bool, error) {
+	var successes []bool
+	filters := repos.Filters{
+		repos.Filter{Field: "time", Op: repos.FilterOpGt, Value: since},
+		repos.Filter{Field: repos.StatsColumnIsAgentError, Op: repos.FilterOpEq, Value: false},
+	}
+	if !withMaintenance {
+		filters = append(filters, repos.Filter{Field: repos.StatsColumnIsMaintenance, Op: repos.FilterOpEq, Value: false})
+	}
+
+	switch pipelineType {
+	case "endpoint":
+		data, err := hc.endpointStatsRepository.GetSessionSuccessions(ctx, append(filters, repos.Filter{Field: "endpoint_id", Op: repos.FilterOpEq, Value: pipelineId}))
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range data {
+			successes = append(successes, value.Success)
+		}
+	case "netcat":
+		data, err := hc.netcatStatsRepository.GetSessionSuccessions(ctx, append(filters, repos.Filter{Field: "netcat_id", Op: repos.FilterOpEq, Value: pipelineId}))
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range data {
+			successes = append(successes, value.Success)
+		}
+	case "pagespeed":
+		data, err := hc.pageSpeedStatsRepository.GetSessionSuccessions(ctx, append(filters, repos.Filter{Field: "pagespeed_id", Op: repos.FilterOpEq, Value: pipelineId}))
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range data {
+			successes = append(successes, value.Success)
+		}
+	case "ping":
+		data, err := hc.pingStatsRepository.GetSessionSuccessions(ctx, append(filters, repos.Filter{Field: "ping_id", Op: repos.FilterOpEq, Value: pipelineId}))
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range data {
+			successes = append(successes, value.Success)
+		}
+	case "traceroute":
+		data, err := hc.traceRouteStatsRepository.GetSessionSuccessions(ctx, append(filters, repos.Filter{Field: "traceroute_id", Op: repos.FilterOpEq, Value: pipelineId}))
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range data {
+			successes = append(successes, value.Success)
+		}
+	default:
+		return nil, fmt.Errorf("unknown pipeline type: %s", pipelineType)
+	}
+	return successes, nil
+}
+
+func uptimeBadgeColor(uptime float64) string {
+	switch {
+	case uptime >= 99:
+		return badgeColorGreen
+	case uptime >= 95:
+		return badgeColorYellow
+	}
+	return badgeColorRed
+}
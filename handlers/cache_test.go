@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"sync"
+	"test-manager/cache"
+	"time"
+)
+
+// memoryCache keeps values and lists in memory, expiry is left to the tests. blocking reads return at once
+type memoryCache struct {
+	cache.Cache
+	mu     sync.Mutex
+	values map[string]string
+	lists  map[string][]string
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string]string), lists: make(map[string][]string)}
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = toString(value)
+	return nil
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return value, nil
+}
+
+func (m *memoryCache) HSet(ctx context.Context, key string, values ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := 0; i+1 < len(values); i += 2 {
+		m.values[key+"."+toString(values[i])] = toString(values[i+1])
+	}
+	return nil
+}
+
+func (m *memoryCache) HGet(ctx context.Context, key string, field string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key+"."+field]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (m *memoryCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	delete(m.lists, key)
+	return nil
+}
+
+func (m *memoryCache) Incr(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, _ := strconv.ParseInt(m.values[key], 10, 64)
+	value++
+	m.values[key] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func (m *memoryCache) RPush(ctx context.Context, key string, ttl time.Duration, values ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, value := range values {
+		m.lists[key] = append(m.lists[key], toString(value))
+	}
+	return nil
+}
+
+func (m *memoryCache) BLPop(ctx context.Context, timeout time.Duration, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.lists[key]) == 0 {
+		return "", redis.Nil
+	}
+	value := m.lists[key][0]
+	m.lists[key] = m.lists[key][1:]
+	return value, nil
+}
+
+func (m *memoryCache) BLMove(ctx context.Context, timeout time.Duration, source string, destination string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.lists[source]) == 0 {
+		return "", redis.Nil
+	}
+	value := m.lists[source][0]
+	m.lists[source] = m.lists[source][1:]
+	m.lists[destination] = append(m.lists[destination], value)
+	return value, nil
+}
+
+func (m *memoryCache) LRange(ctx context.Context, key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.lists[key]...), nil
+}
+
+func (m *memoryCache) LRem(ctx context.Context, key string, value interface{}) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, v := range m.lists[key] {
+		if v == toString(value) {
+			m.lists[key] = append(m.lists[key][:i], m.lists[key][i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	}
+	return ""
+}
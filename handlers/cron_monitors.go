@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"strings"
+	"test-manager/repos"
+	"test-manager/tasks/push"
+	"test-manager/tasks/task_models"
+	"test-manager/usecase_models"
+	"time"
+)
+
+const (
+	CronMonitorCheckInterval = 30 * time.Second
+	CronMonitorMinPeriod     = 60
+	// CronMonitorPingsLimit is how many of the last pings are listed for a monitor
+	CronMonitorPingsLimit = 100
+)
+
+// CronMonitorChecker raises down and up notifications of inbound heartbeat monitors, it runs in the scheduler command
+type CronMonitorChecker struct {
+	cronMonitorsRepo repos.CronMonitorsRepository
+	projectRepo      repos.ProjectsRepository
+	maintenanceRepo  repos.MaintenanceWindowsRepository
+	taskPusher       push.TaskPusher
+}
+
+func NewCronMonitorChecker(
+	cronMonitorsRepo repos.CronMonitorsRepository,
+	projectRepo repos.ProjectsRepository,
+	maintenanceRepo repos.MaintenanceWindowsRepository,
+	taskPusher push.TaskPusher,
+) *CronMonitorChecker {
+	return &CronMonitorChecker{
+		cronMonitorsRepo: cronMonitorsRepo,
+		projectRepo:      projectRepo,
+		maintenanceRepo:  maintenanceRepo,
+		taskPusher:       taskPusher,
+	}
+}
+
+// Run checks every monitor each CronMonitorCheckInterval until ctx is done
+func (c *CronMonitorChecker) Run(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(CronMonitorCheckInterval)
+		defer ticker.Stop()
+		for {
+			c.check(ctx, time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (c *CronMonitorChecker) check(ctx context.Context, now time.Time) {
+	monitors, err := c.cronMonitorsRepo.GetActiveCronMonitors(ctx)
+	if err != nil {
+		log.Error("problem on getting cron monitors: ", err)
+		return
+	}
+	for _, monitor := range monitors {
+		status, rootCause := cronMonitorState(monitor, now)
+		if status == monitor.Status {
+			continue
+		}
+		// like outbound monitors the state before a maintenance window is compared with the first one after it
+		maintenance, err := c.maintenanceRepo.IsUnderMaintenance(ctx, monitor.ProjectId, usecase_models.CronMonitorType, monitor.ID, now)
+		if err != nil {
+			log.Warn("problem on checking maintenance windows: ", err)
+		}
+		if maintenance {
+			continue
+		}
+
+		changed, err := c.cronMonitorsRepo.SetStatus(ctx, monitor.ID, monitor.Status, status, rootCause)
+		if err != nil {
+			log.Error("problem on setting cron monitor status: ", err)
+			continue
+		}
+		// the first ping of a new monitor is not a recovery
+		if !changed || (monitor.Status == usecase_models.CronMonitorStatusNew && status == usecase_models.CronMonitorStatusUp) {
+			continue
+		}
+		if status == usecase_models.CronMonitorStatusUp {
+			rootCause = monitor.RootCause.String
+		}
+		if err = c.notify(ctx, monitor, status, rootCause, now); err != nil {
+			log.Error("problem on pushing cron monitor notification: ", err)
+		}
+	}
+}
+
+func (c *CronMonitorChecker) notify(ctx context.Context, monitor usecase_models.CronMonitor, state string, rootCause string, now time.Time) error {
+	project, err := c.projectRepo.GetProjectWithLoads(ctx, monitor.ProjectId)
+	if err != nil {
+		return err
+	}
+	payload := task_models.NotificationsPayload{
+		Type:         usecase_models.CronMonitorType,
+		ProjectId:    monitor.ProjectId,
+		PipelineId:   monitor.ID,
+		PipelineName: monitor.Name,
+		State:        state,
+		Time:         now.String(),
+		RootCause:    rootCause,
+	}
+	if project.R != nil && project.R.Account != nil {
+		payload.Username = project.R.Account.Username.String
+	}
+	_, err = c.taskPusher.PushNotifications(ctx, payload)
+	return err
+}
+
+// cronMonitorState derives the status of a monitor from its last pings, a fail or a success
+// finishes the last start and whichever of them is the latest decides the result.
+func cronMonitorState(monitor usecase_models.CronMonitor, now time.Time) (string, string) {
+	grace := time.Duration(monitor.Grace) * time.Second
+	if monitor.LastFailAt.Valid && (!monitor.LastPingAt.Valid || !monitor.LastFailAt.Time.Before(monitor.LastPingAt.Time)) {
+		return usecase_models.CronMonitorStatusDown, fmt.Sprintf("job reported a failure at %s", monitor.LastFailAt.Time.Format(time.DateTime))
+	}
+	if monitor.LastStartAt.Valid && (!monitor.LastPingAt.Valid || monitor.LastStartAt.Time.After(monitor.LastPingAt.Time)) &&
+		now.After(monitor.LastStartAt.Time.Add(grace)) {
+		return usecase_models.CronMonitorStatusDown, fmt.Sprintf("job started at %s and did not finish within the grace time", monitor.LastStartAt.Time.Format(time.DateTime))
+	}
+	if !monitor.LastPingAt.Valid {
+		return monitor.Status, ""
+	}
+	if now.After(monitor.LastPingAt.Time.Add(time.Duration(monitor.Period)*time.Second + grace)) {
+		return usecase_models.CronMonitorStatusDown, fmt.Sprintf("no ping received since %s", monitor.LastPingAt.Time.Format(time.DateTime))
+	}
+	return usecase_models.CronMonitorStatusUp, ""
+}
+
+func cronMonitorFromRequest(req usecase_models.CronMonitorRequest) (usecase_models.CronMonitor, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return usecase_models.CronMonitor{}, errors.New("name is required")
+	}
+	if req.Period < CronMonitorMinPeriod {
+		return usecase_models.CronMonitor{}, fmt.Errorf("period must be at least %d seconds", CronMonitorMinPeriod)
+	}
+	if req.Grace < 0 {
+		return usecase_models.CronMonitor{}, errors.New("grace can not be negative")
+	}
+	return usecase_models.CronMonitor{
+		ProjectId: req.ProjectId,
+		Name:      req.Name,
+		Period:    req.Period,
+		Grace:     req.Grace,
+		IsActive:  req.IsActive,
+	}, nil
+}
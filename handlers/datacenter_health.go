@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/volatiletech/null/v8"
+	"net/http"
+	"strconv"
+	"sync"
+	"test-manager/monitoring"
+	"test-manager/repos"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+const (
+	DatacenterProbeInterval = 30 * time.Second
+	DatacenterProbeTimeout  = 5 * time.Second
+	// DatacenterOfflineFailures consecutive failed probes take a datacenter out of the selection
+	DatacenterOfflineFailures = 3
+	// a datacenter below this availability or above this latency is degraded but still selected
+	DatacenterDegradedAvailability = 90
+	DatacenterDegradedLatency      = 2 * time.Second
+	// datacenterAvailabilityWeight is the weight of the last probe in the moving availability
+	datacenterAvailabilityWeight = 0.1
+)
+
+// DatacenterHealthProber probes the agent of every datacenter and publishes the offline ones, it runs in the scheduler command
+type DatacenterHealthProber struct {
+	dataCentersRepo repos.DataCentersRepository
+	client          *http.Client
+}
+
+func NewDatacenterHealthProber(dataCentersRepo repos.DataCentersRepository) *DatacenterHealthProber {
+	return &DatacenterHealthProber{
+		dataCentersRepo: dataCentersRepo,
+		client:          &http.Client{Timeout: DatacenterProbeTimeout},
+	}
+}
+
+// Run probes every datacenter each DatacenterProbeInterval until ctx is done
+func (c *DatacenterHealthProber) Run(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(DatacenterProbeInterval)
+		defer ticker.Stop()
+		for {
+			c.probeAll(ctx, time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (c *DatacenterHealthProber) probeAll(ctx context.Context, now time.Time) {
+	datacenters, err := c.dataCentersRepo.GetDataCenters(ctx)
+	if err != nil {
+		log.Error("problem on getting datacenters to probe: ", err)
+		return
+	}
+	healths, err := c.dataCentersRepo.GetDataCentersHealth(ctx)
+	if err != nil {
+		log.Warn("problem on getting datacenters health: ", err)
+	}
+	var lastHealth = make(map[int]usecase_models.DatacenterHealth)
+	for _, health := range healths {
+		lastHealth[health.DatacenterId] = health
+	}
+	agents, err := c.dataCentersRepo.GetDataCenterAgents(ctx)
+	if err != nil {
+		log.Warn("problem on getting datacenter agents: ", err)
+	}
+	// private datacenters are probed by their polls whatever their mode, the manager never calls them
+	owners, err := c.dataCentersRepo.GetDataCenterProjects(ctx)
+	if err != nil {
+		log.Error("problem on getting private datacenters to probe: ", err)
+		return
+	}
+	var pullAgents = make(map[int]usecase_models.DatacenterAgent)
+	for _, agent := range agents {
+		if _, private := owners[agent.DatacenterId]; agent.Mode == usecase_models.DatacenterModePull || private {
+			pullAgents[agent.DatacenterId] = agent
+		}
+	}
+
+	results := make([]usecase_models.DatacenterHealth, len(datacenters))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(datacenters))
+	for i, datacenter := range datacenters {
+		go func(i int, datacenter *models.Datacenter) {
+			defer waitGroup.Done()
+			var latency time.Duration
+			var err error
+			if agent, ok := pullAgents[datacenter.ID]; ok {
+				err = probePullAgent(agent, now)
+			} else if _, private := owners[datacenter.ID]; private {
+				err = fmt.Errorf("agent has never polled")
+			} else {
+				latency, err = c.probe(ctx, datacenter.Baseurl)
+			}
+			last, ok := lastHealth[datacenter.ID]
+			if !ok {
+				last = usecase_models.DatacenterHealth{DatacenterId: datacenter.ID, Status: usecase_models.DatacenterStatusHealthy, Availability: 100}
+			}
+			results[i] = nextDatacenterHealth(last, latency, err, now)
+		}(i, datacenter)
+	}
+	waitGroup.Wait()
+
+	var offline []int
+	for i, health := range results {
+		datacenter := datacenters[i]
+		if last, ok := lastHealth[health.DatacenterId]; ok && last.Status != health.Status {
+			log.Warnf("datacenter %s is %s: %s", datacenter.Title, health.Status, health.LastError.String)
+		}
+		err = c.dataCentersRepo.SaveDataCenterHealth(ctx, health)
+		if err != nil {
+			log.Error("problem on saving datacenter health: ", err)
+		}
+		if health.Status == usecase_models.DatacenterStatusOffline {
+			offline = append(offline, health.DatacenterId)
+		}
+		reportDatacenterHealth(datacenter, health)
+	}
+
+	err = c.dataCentersRepo.SetOfflineDataCenterIds(ctx, offline, 3*DatacenterProbeInterval)
+	if err != nil {
+		log.Error("problem on publishing offline datacenters: ", err)
+	}
+}
+
+// probe calls the health endpoint of the agent, only a 2xx answer means the agent is serving
+func (c *DatacenterHealthProber) probe(ctx context.Context, baseUrl string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseUrl+"/v1/health", nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return latency, fmt.Errorf("agent answered with status %d", resp.StatusCode)
+	}
+	return latency, nil
+}
+
+// probePullAgent checks a pull datacenter by the last poll of its agent, the manager can not call it
+func probePullAgent(agent usecase_models.DatacenterAgent, now time.Time) error {
+	if !agent.LastSeenAt.Valid {
+		return fmt.Errorf("agent has never polled")
+	}
+	if now.Sub(agent.LastSeenAt.Time) > AgentPullSeenTimeout {
+		return fmt.Errorf("agent has not polled since %s", agent.LastSeenAt.Time.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// nextDatacenterHealth folds one probe into the last health of a datacenter, a probe without latency keeps the last one
+func nextDatacenterHealth(last usecase_models.DatacenterHealth, latency time.Duration, probeErr error, now time.Time) usecase_models.DatacenterHealth {
+	health := usecase_models.DatacenterHealth{
+		DatacenterId: last.DatacenterId,
+		Latency:      last.Latency,
+		CheckedAt:    now,
+	}
+	result := float64(100)
+	if probeErr != nil {
+		result = 0
+		health.ConsecutiveFailures = last.ConsecutiveFailures + 1
+		health.LastError = null.StringFrom(probeErr.Error())
+	} else if latency > 0 {
+		health.Latency = null.Float64From(latency.Seconds())
+	}
+	health.Availability = last.Availability*(1-datacenterAvailabilityWeight) + result*datacenterAvailabilityWeight
+
+	switch {
+	case health.ConsecutiveFailures >= DatacenterOfflineFailures:
+		health.Status = usecase_models.DatacenterStatusOffline
+	case probeErr != nil, health.Availability < DatacenterDegradedAvailability, latency > DatacenterDegradedLatency:
+		health.Status = usecase_models.DatacenterStatusDegraded
+	default:
+		health.Status = usecase_models.DatacenterStatusHealthy
+	}
+	return health
+}
+
+func reportDatacenterHealth(datacenter *models.Datacenter, health usecase_models.DatacenterHealth) {
+	id := strconv.Itoa(datacenter.ID)
+	status := float64(0)
+	switch health.Status {
+	case usecase_models.DatacenterStatusHealthy:
+		status = 2
+	case usecase_models.DatacenterStatusDegraded:
+		status = 1
+	}
+	monitoring.DatacenterStatusGauge.WithLabelValues(id, datacenter.Title).Set(status)
+	monitoring.DatacenterAvailabilityGauge.WithLabelValues(id, datacenter.Title).Set(health.Availability)
+	if health.Latency.Valid {
+		monitoring.DatacenterProbeLatencyGauge.WithLabelValues(id, datacenter.Title).Set(health.Latency.Float64)
+	}
+}
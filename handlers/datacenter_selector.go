@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"math/rand"
+	"sort"
+	"strings"
+	"test-manager/cache"
+	"test-manager/repos"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+)
+
+// DatacenterRoundRobinPrefixCacheKey keeps how many times each rule ran with the round_robin strategy
+const DatacenterRoundRobinPrefixCacheKey = "datacenter_round_robin:"
+
+// dataCenterSelector picks the datacenters a run of a rule goes to, it is shared by every monitor type
+type dataCenterSelector struct {
+	dataCentersRepo repos.DataCentersRepository
+	cacheRepo       cache.Cache
+	// intn draws the random strategies, it is rand.Intn outside of tests
+	intn func(n int) int
+}
+
+func newDataCenterSelector(dataCentersRepo repos.DataCentersRepository, cacheRepo cache.Cache) *dataCenterSelector {
+	return &dataCenterSelector{
+		dataCentersRepo: dataCentersRepo,
+		cacheRepo:       cacheRepo,
+		intn:            rand.Intn,
+	}
+}
+
+// selectDataCenters returns the ids of the datacenters to run the rule from. the candidates are the
+// DataCentersIds of the rule the project may use, or the platform datacenters when none is named, and
+// the ones the health prober found offline are skipped unless all of them are
+func (s *dataCenterSelector) selectDataCenters(ctx context.Context, monitorType string, scheduling usecase_models.Scheduling) ([]int, error) {
+	selection := scheduling.DataCenterSelection
+	legacyRandom := len(scheduling.DataCentersIds) == 1 && scheduling.DataCentersIds[0] == 0
+	pinned := len(scheduling.DataCentersIds) != 0 && !legacyRandom
+	if selection.Strategy == "" {
+		selection.Strategy = usecase_models.DataCenterStrategyAll
+		if legacyRandom {
+			selection.Strategy = usecase_models.DataCenterStrategyRandomN
+		}
+	}
+
+	var ids []int
+	if pinned {
+		ids = s.dataCentersRepo.FilterProjectDataCenterIds(ctx, scheduling.ProjectId, scheduling.DataCentersIds)
+		ids = s.dataCentersRepo.FilterAvailableDataCenterIds(ctx, ids)
+		if len(ids) == 0 {
+			// running with none would read as an up session and overwrite the last one
+			return nil, errors.New("there is no datacenter to run the rule from")
+		}
+		if selection.Strategy == usecase_models.DataCenterStrategyAll {
+			// named datacenters are kept even if they can not be read, their checks are recorded as agent errors
+			return ids, nil
+		}
+	}
+	datacenters, err := s.candidates(ctx, pinned, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(datacenters) == 0 {
+		return nil, errors.New("there is no datacenter to run the rule from")
+	}
+	sort.Slice(datacenters, func(i, j int) bool {
+		return datacenters[i].ID < datacenters[j].ID
+	})
+
+	count := selection.Count
+	if count <= 0 {
+		count = 1
+	}
+	if count > len(datacenters) {
+		count = len(datacenters)
+	}
+
+	var selected []*models.Datacenter
+	switch selection.Strategy {
+	case usecase_models.DataCenterStrategyAll:
+		selected = datacenters
+	case usecase_models.DataCenterStrategyRandomN:
+		// the first count places of a shuffle
+		picked := append([]*models.Datacenter{}, datacenters...)
+		for i := 0; i < count; i++ {
+			j := i + s.intn(len(picked)-i)
+			picked[i], picked[j] = picked[j], picked[i]
+		}
+		selected = picked[:count]
+	case usecase_models.DataCenterStrategyRoundRobin:
+		cursor, err := s.cacheRepo.Incr(ctx, fmt.Sprintf("%s%s:%d", DatacenterRoundRobinPrefixCacheKey, monitorType, scheduling.PipelineId))
+		if err != nil {
+			log.Warn("problem on moving round robin of rule: ", err)
+			cursor = int64(s.intn(len(datacenters))) + 1
+		}
+		start := int(((cursor - 1) * int64(count)) % int64(len(datacenters)))
+		for i := 0; i < count; i++ {
+			selected = append(selected, datacenters[(start+i)%len(datacenters)])
+		}
+	case usecase_models.DataCenterStrategyRegion:
+		for _, datacenter := range datacenters {
+			if inRegion(datacenter, selection) {
+				selected = append(selected, datacenter)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("there is no datacenter in country %q and location %q", selection.CountryName, selection.LocationName)
+		}
+	case usecase_models.DataCenterStrategyWeighted:
+		selected = weightedDataCenters(datacenters, count, s.intn)
+	default:
+		return nil, fmt.Errorf("data center strategy %s is not valid", selection.Strategy)
+	}
+
+	ids = make([]int, 0, len(selected))
+	for _, datacenter := range selected {
+		ids = append(ids, datacenter.ID)
+	}
+	return ids, nil
+}
+
+// candidates reads the named datacenters, or the available platform datacenters when the rule names none
+func (s *dataCenterSelector) candidates(ctx context.Context, pinned bool, ids []int) ([]*models.Datacenter, error) {
+	if !pinned {
+		return s.dataCentersRepo.GetAvailableDataCentersWithCache(ctx)
+	}
+	var datacenters []*models.Datacenter
+	for _, id := range ids {
+		datacenter, err := s.dataCentersRepo.GetDataCenterWithCache(ctx, id)
+		if err != nil {
+			log.Error("error on getting data center in selecting datacenters: ", err)
+			continue
+		}
+		datacenters = append(datacenters, datacenter)
+	}
+	return datacenters, nil
+}
+
+// inRegion matches the country and location of the selection, an empty one matches every datacenter
+func inRegion(datacenter *models.Datacenter, selection usecase_models.DataCenterSelection) bool {
+	if selection.CountryName != "" && !strings.EqualFold(datacenter.CountryName.String, selection.CountryName) {
+		return false
+	}
+	if selection.LocationName != "" && !strings.EqualFold(datacenter.LocationName.String, selection.LocationName) {
+		return false
+	}
+	return true
+}
+
+// weightedDataCenters draws count datacenters without replacement, weighted by their connection rate.
+// a datacenter without a rate still weighs one so it can be drawn
+func weightedDataCenters(datacenters []*models.Datacenter, count int, intn func(n int) int) []*models.Datacenter {
+	left := append([]*models.Datacenter{}, datacenters...)
+	var selected []*models.Datacenter
+	for len(selected) < count && len(left) != 0 {
+		total := 0
+		for _, datacenter := range left {
+			total += dataCenterWeight(datacenter)
+		}
+		draw := intn(total)
+		for i, datacenter := range left {
+			draw -= dataCenterWeight(datacenter)
+			if draw < 0 {
+				selected = append(selected, datacenter)
+				left = append(left[:i], left[i+1:]...)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+func dataCenterWeight(datacenter *models.Datacenter) int {
+	if datacenter.ConnectionRate.Valid && datacenter.ConnectionRate.Int > 0 {
+		return datacenter.ConnectionRate.Int
+	}
+	return 1
+}
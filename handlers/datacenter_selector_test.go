@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/volatiletech/null/v8"
+	"reflect"
+	"sort"
+	"strings"
+	"test-manager/repos"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"testing"
+)
+
+// selectorRepo is the datacenters repository reading from the cache only, the datacenters by id are
+// read from a map since the repository reads those from postgres
+type selectorRepo struct {
+	repos.DataCentersRepository
+	datacenters map[int]*models.Datacenter
+}
+
+func (r *selectorRepo) GetDataCenterWithCache(ctx context.Context, id int) (*models.Datacenter, error) {
+	datacenter, ok := r.datacenters[id]
+	if !ok {
+		return nil, errors.New("datacenter not found")
+	}
+	return datacenter, nil
+}
+
+// sequence returns the draws in order, each taken modulo n
+func sequence(draws ...int) func(n int) int {
+	return func(n int) int {
+		draw := draws[0] % n
+		draws = append(draws[1:], draws[0])
+		return draw
+	}
+}
+
+func testSelector(t *testing.T, datacenters []*models.Datacenter, owners map[int]int, offline []int, intn func(n int) int) *dataCenterSelector {
+	t.Helper()
+	ctx := context.Background()
+	cacheRepo := newMemoryCache()
+	datacentersB, _ := json.Marshal(datacenters)
+	ownersB, _ := json.Marshal(owners)
+	offlineB, _ := json.Marshal(offline)
+	_ = cacheRepo.HSet(ctx, repos.DatacenterIdsCacheKey, "data", datacentersB)
+	_ = cacheRepo.Set(ctx, repos.DatacenterProjectsCacheKey, ownersB, 0)
+	_ = cacheRepo.Set(ctx, repos.DatacenterOfflineIdsCacheKey, offlineB, 0)
+
+	byId := make(map[int]*models.Datacenter)
+	for _, datacenter := range datacenters {
+		byId[datacenter.ID] = datacenter
+	}
+	selector := newDataCenterSelector(&selectorRepo{
+		DataCentersRepository: repos.NewDataCentersRepositoryRepository(cacheRepo, nil),
+		datacenters:           byId,
+	}, cacheRepo)
+	selector.intn = intn
+	return selector
+}
+
+func testDatacenters() []*models.Datacenter {
+	datacenter := func(id int, country, location string, rate int) *models.Datacenter {
+		return &models.Datacenter{
+			ID:             id,
+			CountryName:    null.StringFrom(country),
+			LocationName:   null.StringFrom(location),
+			ConnectionRate: null.NewInt(rate, rate != 0),
+		}
+	}
+	return []*models.Datacenter{
+		datacenter(4, "Iran", "Tehran", 0),
+		datacenter(1, "Germany", "Frankfurt", 10),
+		datacenter(3, "Germany", "Nuremberg", 90),
+		datacenter(2, "Netherlands", "Amsterdam", 0),
+		datacenter(5, "Iran", "Tabriz", 100),
+	}
+}
+
+func TestSelectDataCenters(t *testing.T) {
+	// datacenter 5 is private to project 10
+	owners := map[int]int{5: 10}
+	selection := func(strategy string, count int) usecase_models.DataCenterSelection {
+		return usecase_models.DataCenterSelection{Strategy: strategy, Count: count}
+	}
+
+	tests := []struct {
+		name        string
+		datacenters []*models.Datacenter
+		offline     []int
+		draws       []int
+		scheduling  usecase_models.Scheduling
+		ids         []int
+		sorted      bool
+		err         string
+	}{
+		{
+			name:       "no strategy runs from every platform datacenter",
+			scheduling: usecase_models.Scheduling{ProjectId: 10},
+			ids:        []int{1, 2, 3, 4},
+		},
+		{
+			name:       "offline datacenters are skipped",
+			offline:    []int{2, 4},
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyAll, 0)},
+			ids:        []int{1, 3},
+		},
+		{
+			name:       "every datacenter offline falls back to all of them",
+			offline:    []int{1, 2, 3, 4},
+			scheduling: usecase_models.Scheduling{},
+			ids:        []int{1, 2, 3, 4},
+		},
+		{
+			name:        "empty pool",
+			datacenters: []*models.Datacenter{},
+			scheduling:  usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyRandomN, 1)},
+			err:         "there is no datacenter to run the rule from",
+		},
+		{
+			name:       "legacy zero id is one random datacenter",
+			draws:      []int{2},
+			scheduling: usecase_models.Scheduling{DataCentersIds: []int{0}},
+			ids:        []int{3},
+		},
+		{
+			name:       "random_n draws without replacement",
+			draws:      []int{3, 0},
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyRandomN, 2)},
+			ids:        []int{4, 2},
+		},
+		{
+			name:       "random_n count above the pool is capped",
+			draws:      []int{1},
+			offline:    []int{3},
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyRandomN, 10)},
+			ids:        []int{1, 2, 4},
+			sorted:     true,
+		},
+		{
+			name:       "random_n zero count picks one",
+			draws:      []int{0},
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyRandomN, 0)},
+			ids:        []int{1},
+		},
+		{
+			name: "region matches country and location ignoring case",
+			scheduling: usecase_models.Scheduling{DataCenterSelection: usecase_models.DataCenterSelection{
+				Strategy: usecase_models.DataCenterStrategyRegion, CountryName: "germany", LocationName: "FRANKFURT"}},
+			ids: []int{1},
+		},
+		{
+			name: "region without a datacenter in it",
+			scheduling: usecase_models.Scheduling{DataCenterSelection: usecase_models.DataCenterSelection{
+				Strategy: usecase_models.DataCenterStrategyRegion, CountryName: "France"}},
+			err: `there is no datacenter in country "France"`,
+		},
+		{
+			name: "region skips private datacenters not named by the rule",
+			scheduling: usecase_models.Scheduling{ProjectId: 10, DataCenterSelection: usecase_models.DataCenterSelection{
+				Strategy: usecase_models.DataCenterStrategyRegion, CountryName: "Iran"}},
+			ids: []int{4},
+		},
+		{
+			// weights are 10, 1, 90 and 1 for datacenters 1 to 4
+			name:       "weighted draws by connection rate",
+			draws:      []int{50},
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyWeighted, 1)},
+			ids:        []int{3},
+		},
+		{
+			name:       "weighted datacenter without a rate can still be drawn",
+			draws:      []int{10},
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyWeighted, 1)},
+			ids:        []int{2},
+		},
+		{
+			name:       "weighted draws without replacement",
+			draws:      []int{5, 5},
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection(usecase_models.DataCenterStrategyWeighted, 2)},
+			ids:        []int{1, 3},
+		},
+		{
+			name:       "pinned datacenters of the project with all",
+			scheduling: usecase_models.Scheduling{ProjectId: 10, DataCentersIds: []int{5, 2}},
+			ids:        []int{5, 2},
+		},
+		{
+			name:       "pinned private datacenter of another project is dropped",
+			scheduling: usecase_models.Scheduling{ProjectId: 20, DataCentersIds: []int{5, 2}},
+			ids:        []int{2},
+		},
+		{
+			name:       "pinned datacenters that are all dropped do not run the rule",
+			scheduling: usecase_models.Scheduling{ProjectId: 20, DataCentersIds: []int{5}},
+			err:        "there is no datacenter to run the rule from",
+		},
+		{
+			name:       "pinned datacenters that are all offline are kept",
+			offline:    []int{1, 2},
+			scheduling: usecase_models.Scheduling{DataCentersIds: []int{1, 2}},
+			ids:        []int{1, 2},
+		},
+		{
+			name:       "pinned random_n picks among the named datacenters",
+			draws:      []int{1},
+			scheduling: usecase_models.Scheduling{DataCentersIds: []int{4, 1}, DataCenterSelection: selection(usecase_models.DataCenterStrategyRandomN, 1)},
+			ids:        []int{4},
+		},
+		{
+			name:       "pinned datacenters that can not be read leave an empty pool",
+			scheduling: usecase_models.Scheduling{DataCentersIds: []int{8, 9}, DataCenterSelection: selection(usecase_models.DataCenterStrategyRandomN, 1)},
+			err:        "there is no datacenter to run the rule from",
+		},
+		{
+			name:       "unknown strategy",
+			scheduling: usecase_models.Scheduling{DataCenterSelection: selection("closest", 1)},
+			err:        "data center strategy closest is not valid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			datacenters := tt.datacenters
+			if datacenters == nil {
+				datacenters = testDatacenters()
+			}
+			draws := tt.draws
+			if draws == nil {
+				draws = []int{0}
+			}
+			selector := testSelector(t, datacenters, owners, tt.offline, sequence(draws...))
+			ids, err := selector.selectDataCenters(context.Background(), "endpoint", tt.scheduling)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.sorted {
+				sort.Ints(ids)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Fatalf("ids = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestSelectDataCentersRoundRobin(t *testing.T) {
+	selector := testSelector(t, testDatacenters(), map[int]int{5: 10}, nil, sequence(0))
+	scheduling := usecase_models.Scheduling{PipelineId: 7, DataCenterSelection: usecase_models.DataCenterSelection{
+		Strategy: usecase_models.DataCenterStrategyRoundRobin, Count: 3}}
+
+	// four platform datacenters taken three at a time wrap around
+	for i, want := range [][]int{{1, 2, 3}, {4, 1, 2}, {3, 4, 1}, {2, 3, 4}, {1, 2, 3}} {
+		ids, err := selector.selectDataCenters(context.Background(), "endpoint", scheduling)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Fatalf("run %d: ids = %v, want %v", i, ids, want)
+		}
+	}
+
+	// every rule has a cursor of its own
+	other := scheduling
+	other.PipelineId = 8
+	ids, err := selector.selectDataCenters(context.Background(), "endpoint", other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2, 3}) {
+		t.Fatalf("other rule ids = %v, want it to start from the first datacenter", ids)
+	}
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"github.com/volatiletech/null/v8"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"test-manager/cache"
+	"test-manager/repos"
+	"test-manager/tasks/push"
+	"test-manager/tasks/task_models"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+// DNSDefaultResolverPort is added to resolvers given without a port
+const DNSDefaultResolverPort = "53"
+
+type DNSHandler interface {
+	ExecuteDNSRule(ctx context.Context, dnsRules usecase_models.DNSMonitors) error
+}
+
+type dnsHandler struct {
+	dnsMonitorsRepo    repos.DNSMonitorsRepository
+	dnsStatsRepo       repos.DNSStatsRepository
+	dataCentersRepo    repos.DataCentersRepository
+	projectRepo        repos.ProjectsRepository
+	cacheRepo          cache.Cache
+	maintenanceRepo    repos.MaintenanceWindowsRepository
+	taskPusher         push.TaskPusher
+	agentHandler       AgentHandler
+	sessionNotifier    *sessionNotifier
+	dataCenterSelector *dataCenterSelector
+}
+
+func NewDNSHandler(
+	dnsMonitorsRepo repos.DNSMonitorsRepository,
+	dnsStatsRepo repos.DNSStatsRepository,
+	dataCentersRepo repos.DataCentersRepository,
+	projectRepo repos.ProjectsRepository,
+	cacheRepo cache.Cache,
+	maintenanceRepo repos.MaintenanceWindowsRepository,
+	monitorStatesRepo repos.MonitorStatesRepository,
+	taskPusher push.TaskPusher,
+	agentHandler AgentHandler,
+) DNSHandler {
+	return &dnsHandler{
+		dnsMonitorsRepo:    dnsMonitorsRepo,
+		dnsStatsRepo:       dnsStatsRepo,
+		dataCentersRepo:    dataCentersRepo,
+		projectRepo:        projectRepo,
+		cacheRepo:          cacheRepo,
+		maintenanceRepo:    maintenanceRepo,
+		taskPusher:         taskPusher,
+		agentHandler:       agentHandler,
+		sessionNotifier:    newSessionNotifier(projectRepo, dataCentersRepo, maintenanceRepo, monitorStatesRepo, cacheRepo, taskPusher),
+		dataCenterSelector: newDataCenterSelector(dataCentersRepo, cacheRepo),
+	}
+}
+
+func (e *dnsHandler) ExecuteDNSRule(ctx context.Context, dnsRules usecase_models.DNSMonitors) error {
+	dataCentersIds, err := e.dataCenterSelector.selectDataCenters(ctx, usecase_models.DNSMonitorType, dnsRules.Scheduling)
+	if err != nil {
+		return err
+	}
+	dnsRules.Scheduling.DataCentersIds = dataCentersIds
+
+	maintenance := e.sessionNotifier.underMaintenance(ctx, usecase_models.DNSMonitorType, dnsRules.Scheduling)
+	session, _ := uuid.NewUUID()
+	sessionResults := make([]monitorSession, len(dnsRules.Scheduling.DataCentersIds))
+	sessionStats := make([][]repos.WriteDNSStatsOptions, len(dnsRules.Scheduling.DataCentersIds))
+	sessionIsValid := make([]bool, len(dnsRules.Scheduling.DataCentersIds))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(dnsRules.Scheduling.DataCentersIds))
+	for i, dataC := range dnsRules.Scheduling.DataCentersIds {
+		go func(i int, dataCenterId int) {
+			defer waitGroup.Done()
+			dataCenter, err := e.dataCentersRepo.GetDataCenterWithCache(ctx, dataCenterId)
+			if err != nil {
+				log.Error("error on getting data center in executing dns rule: ", err)
+				return
+			}
+			sessionIsValid[i] = true
+
+			success := 1
+			agentError := false
+			var rootCauses []string
+			var recordsCalled []string
+			for _, rule := range dnsRules.DNSMonitors {
+				stat := e.resolveRecord(ctx, dataCenter, rule)
+				stat.Time = time.Now()
+				stat.SessionId = session.String()
+				stat.ProjectId = dnsRules.Scheduling.ProjectId
+				stat.DNSMonitorId = dnsRules.Scheduling.PipelineId
+				stat.IsHeartBeat = dnsRules.Scheduling.IsHeartBeat
+				stat.IsMaintenance = maintenance
+				stat.DatacenterId = dataCenter.ID
+				if stat.IsAgentError {
+					agentError = true
+				} else if stat.Success == 0 {
+					success = 0
+				}
+				if stat.Success == 0 {
+					rootCauses = append(rootCauses, fmt.Sprintf("%s %s: %s", stat.Name, stat.RecordType, stat.RootCause))
+				}
+				recordsCalled = append(recordsCalled, stat.Name)
+				sessionStats[i] = append(sessionStats[i], stat)
+			}
+
+			// the agent failing on some records says nothing about the others, it is only unknown when nothing else failed
+			agentError = agentError && success == 1
+			if agentError {
+				success = 0
+			}
+			sessionResults[i] = monitorSession{
+				DatacenterId: dataCenter.ID,
+				Success:      success,
+				Url:          strings.Join(recordsCalled, ","),
+				RootCause:    strings.Join(rootCauses, "; "),
+				AgentError:   agentError,
+			}
+		}(i, dataC)
+	}
+	waitGroup.Wait()
+
+	var stats []repos.WriteDNSStatsOptions
+	for _, value := range sessionStats {
+		stats = append(stats, value...)
+	}
+	if len(stats) != 0 {
+		err := e.dnsStatsRepo.WriteBulk(ctx, stats)
+		if err != nil {
+			log.Info("error on writing dns stats in executing rule: ", err)
+		}
+	}
+
+	for _, valid := range sessionIsValid {
+		if !valid {
+			log.Warn("this session was invalid: ", session.String())
+			return nil
+		}
+	}
+
+	if !maintenance {
+		e.notifyChanges(ctx, dnsRules.Scheduling, stats)
+	}
+	return e.sessionNotifier.notifyTransitions(ctx, usecase_models.DNSMonitorType, repos.DNSSessionCachePrefix, dnsRules.Scheduling, session.String(), sessionResults, maintenance)
+}
+
+// resolveRecord asks the agent for the answers of the rule record and asserts them against the expected values
+func (e *dnsHandler) resolveRecord(ctx context.Context, dataCenter *models.Datacenter, rule usecase_models.DNSRules) repos.WriteDNSStatsOptions {
+	request := rule.AgentDNSRequest
+	request.RecordType = strings.ToUpper(request.RecordType)
+	request.Resolver = dnsResolverAddress(request.Resolver)
+	stat := repos.WriteDNSStatsOptions{
+		Name:       request.Name,
+		RecordType: request.RecordType,
+		Resolver:   request.Resolver,
+		Success:    1,
+	}
+
+	response, err := e.agentHandler.SendDNS(ctx, dataCenter, request)
+	if err != nil {
+		log.Info("error on sending dns in executing rule: ", err)
+		stat.Success = 0
+		stat.IsAgentError = true
+		stat.RootCause = fmt.Sprintf("error on sending dns in executing rule: %s", err.Error())
+		return stat
+	}
+	if response.Status == 0 {
+		stat.Success = 0
+		stat.RootCause = response.Message
+		return stat
+	}
+
+	stat.ResolutionTime = null.Float64From(response.Statistics.ResolutionTime)
+	stat.Answers = normalizeDNSAnswers(request.RecordType, response.Statistics.Answers)
+	if response.Statistics.Resolver != "" {
+		stat.Resolver = response.Statistics.Resolver
+	}
+	if len(rule.Expected) == 0 {
+		return stat
+	}
+
+	expected := normalizeDNSAnswers(request.RecordType, rule.Expected)
+	if !dnsAnswersMatch(rule.Match, expected, stat.Answers) {
+		stat.Success = 0
+		stat.RootCause = fmt.Sprintf("answers [%s] do not match expected [%s]", strings.Join(stat.Answers, ", "), strings.Join(expected, ", "))
+	}
+	return stat
+}
+
+// notifyChanges compares the answers of each record on each datacenter with the previous session and sends
+// one changed notification for all records that differ. records without an answer do not count as a change
+func (e *dnsHandler) notifyChanges(ctx context.Context, scheduling usecase_models.Scheduling, stats []repos.WriteDNSStatsOptions) {
+	lastAnswers, err := e.dnsMonitorsRepo.GetLastAnswers(ctx, scheduling.PipelineId)
+	if err != nil {
+		log.Warn("problem on getting last dns answers: ", err)
+		return
+	}
+
+	var changes []string
+	var addresses []string
+	var changedDatacenters []int
+	for _, stat := range stats {
+		if stat.Answers == nil {
+			continue
+		}
+		key := fmt.Sprintf("%d/%s/%s", stat.DatacenterId, stat.Name, stat.RecordType)
+		old, ok := lastAnswers[key]
+		lastAnswers[key] = stat.Answers
+		if !ok || strings.Join(old, ",") == strings.Join(stat.Answers, ",") {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s %s changed from [%s] to [%s]", stat.Name, stat.RecordType,
+			strings.Join(old, ", "), strings.Join(stat.Answers, ", ")))
+		addresses = append(addresses, stat.Name)
+		changedDatacenters = append(changedDatacenters, stat.DatacenterId)
+	}
+
+	err = e.dnsMonitorsRepo.SetLastAnswers(ctx, scheduling.PipelineId, lastAnswers)
+	if err != nil {
+		log.Warn("problem on setting last dns answers: ", err)
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	err = e.pushChanged(ctx, scheduling, strings.Join(addresses, ","), strings.Join(changes, "; "), changedDatacenters)
+	if err != nil {
+		log.Info("error on pushing dns change notification: ", err)
+	}
+}
+
+func (e *dnsHandler) pushChanged(ctx context.Context, scheduling usecase_models.Scheduling, address string, rootCause string, datacenterIds []int) error {
+	project, err := e.projectRepo.GetProjectWithLoads(ctx, scheduling.ProjectId)
+	if err != nil {
+		return err
+	}
+	if project.R == nil || project.R.Account == nil {
+		return errors.New("project has no account")
+	}
+
+	payload := task_models.NotificationsPayload{
+		Type:         usecase_models.DNSMonitorType,
+		State:        usecase_models.DNSNotificationStateChanged,
+		ProjectId:    scheduling.ProjectId,
+		PipelineId:   scheduling.PipelineId,
+		PipelineName: scheduling.PipelineName,
+		Username:     project.R.Account.Username.String,
+		Address:      address,
+		Time:         time.Now().String(),
+		RootCause:    rootCause,
+	}
+	dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
+	if err != nil {
+		log.Info("problem in getting datacenters in sending alerts: ", err.Error())
+	}
+	payload.Datacenters = strings.Join(uniqueStrings(datacenterTitles(dcs, datacenterIds)), ",")
+
+	_, err = e.taskPusher.PushNotifications(ctx, payload)
+	return err
+}
+
+// normalizeDNSAnswers sorts the answers and drops the trailing dot of names, names are compared case insensitive
+func normalizeDNSAnswers(recordType string, answers []string) []string {
+	normalized := make([]string, 0, len(answers))
+	for _, answer := range answers {
+		answer = strings.TrimSpace(answer)
+		if recordType != usecase_models.DNSRecordTXT {
+			answer = strings.ToLower(strings.TrimSuffix(answer, "."))
+		}
+		normalized = append(normalized, answer)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func dnsAnswersMatch(match string, expected []string, answers []string) bool {
+	if match == usecase_models.DNSMatchContains {
+		for _, value := range expected {
+			if !containsString(answers, value) {
+				return false
+			}
+		}
+		return true
+	}
+	return strings.Join(expected, ",") == strings.Join(answers, ",")
+}
+
+func dnsResolverAddress(resolver string) string {
+	if resolver == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(strings.Trim(resolver, "[]"), DNSDefaultResolverPort)
+}
+
+func containsString(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(s []string) []string {
+	var unique []string
+	for _, value := range s {
+		if !containsString(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// validateDNSRules checks the records and resolvers of a dns monitor before it is saved
+func validateDNSRules(dnsRules usecase_models.DNSMonitors) error {
+	for _, rule := range dnsRules.DNSMonitors {
+		if strings.TrimSpace(rule.Name) == "" {
+			return errors.New("name of dns record is required")
+		}
+		switch strings.ToUpper(rule.RecordType) {
+		case usecase_models.DNSRecordA, usecase_models.DNSRecordAAAA, usecase_models.DNSRecordCNAME,
+			usecase_models.DNSRecordMX, usecase_models.DNSRecordTXT, usecase_models.DNSRecordNS:
+		default:
+			return fmt.Errorf("record type %s of %s is not supported", rule.RecordType, rule.Name)
+		}
+		switch rule.Match {
+		case "", usecase_models.DNSMatchExact, usecase_models.DNSMatchContains:
+		default:
+			return fmt.Errorf("match of %s must be exact or contains", rule.Name)
+		}
+		if rule.Resolver != "" {
+			host, port, err := net.SplitHostPort(dnsResolverAddress(rule.Resolver))
+			if err != nil || host == "" {
+				return fmt.Errorf("resolver of %s is not valid", rule.Name)
+			}
+			if _, err := strconv.Atoi(port); err != nil {
+				return fmt.Errorf("resolver port of %s is not valid", rule.Name)
+			}
+		}
+	}
+	return nil
+}
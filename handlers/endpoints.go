@@ -4,13 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/labstack/gommon/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tidwall/gjson"
-	"math/rand"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,7 +17,6 @@ import (
 	"test-manager/repos"
 	"test-manager/services/alert_system"
 	"test-manager/tasks/push"
-	"test-manager/tasks/task_models"
 	"test-manager/usecase_models"
 	"time"
 	"unicode/utf8"
@@ -30,14 +27,18 @@ type EndpointHandler interface {
 }
 
 type endpointHandler struct {
-	alertSystem     alert_system.AlertHandler
-	endpointRepo    repos.EndpointRepository
-	dataCentersRepo repos.DataCentersRepository
-	projectRepo     repos.ProjectsRepository
-	endpointStats   repos.EndpointStatsRepository
-	cacheRepo       cache.Cache
-	taskPusher      push.TaskPusher
-	agentHandler    AgentHandler
+	alertSystem        alert_system.AlertHandler
+	endpointRepo       repos.EndpointRepository
+	dataCentersRepo    repos.DataCentersRepository
+	projectRepo        repos.ProjectsRepository
+	endpointStats      repos.EndpointStatsRepository
+	cacheRepo          cache.Cache
+	maintenanceRepo    repos.MaintenanceWindowsRepository
+	jsonSchemasRepo    repos.JsonSchemasRepository
+	taskPusher         push.TaskPusher
+	agentHandler       AgentHandler
+	sessionNotifier    *sessionNotifier
+	dataCenterSelector *dataCenterSelector
 }
 
 func NewEndpointHandler(alertSystem alert_system.AlertHandler,
@@ -46,38 +47,35 @@ func NewEndpointHandler(alertSystem alert_system.AlertHandler,
 	projectRepo repos.ProjectsRepository,
 	endpointStats repos.EndpointStatsRepository,
 	cacheRepo cache.Cache,
+	maintenanceRepo repos.MaintenanceWindowsRepository,
+	monitorStatesRepo repos.MonitorStatesRepository,
+	jsonSchemasRepo repos.JsonSchemasRepository,
 	taskPusher push.TaskPusher,
 	agentHandler AgentHandler) EndpointHandler {
 	return &endpointHandler{
-		alertSystem:     alertSystem,
-		endpointRepo:    endpointRepo,
-		dataCentersRepo: dataCentersRepo,
-		endpointStats:   endpointStats,
-		projectRepo:     projectRepo,
-		cacheRepo:       cacheRepo,
-		taskPusher:      taskPusher,
-		agentHandler:    agentHandler,
+		alertSystem:        alertSystem,
+		endpointRepo:       endpointRepo,
+		dataCentersRepo:    dataCentersRepo,
+		endpointStats:      endpointStats,
+		projectRepo:        projectRepo,
+		cacheRepo:          cacheRepo,
+		maintenanceRepo:    maintenanceRepo,
+		jsonSchemasRepo:    jsonSchemasRepo,
+		taskPusher:         taskPusher,
+		agentHandler:       agentHandler,
+		sessionNotifier:    newSessionNotifier(projectRepo, dataCentersRepo, maintenanceRepo, monitorStatesRepo, cacheRepo, taskPusher),
+		dataCenterSelector: newDataCenterSelector(dataCentersRepo, cacheRepo),
 	}
 }
 
 func (e *endpointHandler) ExecuteEndpointRule(ctx context.Context, endpointRules usecase_models.Endpoints) error {
-	if len(endpointRules.Scheduling.DataCentersIds) == 1 && endpointRules.Scheduling.DataCentersIds[0] == 0 {
-		datacenters, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err == nil {
-			// random number between one and the number of datacenters
-			endpointRules.Scheduling.DataCentersIds = []int{}
-			endpointRules.Scheduling.DataCentersIds = append(endpointRules.Scheduling.DataCentersIds, datacenters[rand.Intn(len(datacenters))].ID)
-		}
-	} else if len(endpointRules.Scheduling.DataCentersIds) == 0 {
-		datacenters, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err == nil {
-			// all datacenters
-			endpointRules.Scheduling.DataCentersIds = []int{}
-			for _, value := range datacenters {
-				endpointRules.Scheduling.DataCentersIds = append(endpointRules.Scheduling.DataCentersIds, value.ID)
-			}
-		}
+	dataCentersIds, err := e.dataCenterSelector.selectDataCenters(ctx, "endpoint", endpointRules.Scheduling)
+	if err != nil {
+		return err
 	}
+	endpointRules.Scheduling.DataCentersIds = dataCentersIds
+	maintenance := e.sessionNotifier.underMaintenance(ctx, "endpoint", endpointRules.Scheduling)
+	schemas := e.loadResponseSchemas(ctx, endpointRules)
 	session, _ := uuid.NewUUID()
 	sessionIsValid := make(chan bool, 1)
 	sessionIsValid <- true
@@ -277,7 +275,7 @@ func (e *endpointHandler) ExecuteEndpointRule(ctx context.Context, endpointRules
 					}
 				}
 
-				respBody, respHeader, respStatus, respTime, err := e.agentHandler.SendCurl(ctx, dataCenter.Baseurl, usecase_models.AgentCurlRequest{
+				respBody, respHeader, respStatus, respTime, err := e.agentHandler.SendCurl(ctx, dataCenter, usecase_models.AgentCurlRequest{
 					Url:    rule.Url,
 					Method: rule.Method,
 					Header: newHeader,
@@ -301,9 +299,11 @@ func (e *endpointHandler) ExecuteEndpointRule(ctx context.Context, endpointRules
 						EndpointName:     strings.Join(endpointNamesCalled, ","),
 						EndpointId:       endpointRules.Scheduling.PipelineId,
 						IsHeartBeat:      endpointRules.Scheduling.IsHeartBeat,
+						IsMaintenance:    maintenance,
 						Url:              strings.Join(urlsCalled, ","),
 						DatacenterId:     dataCenter.ID,
 						Success:          0,
+						IsAgentError:     true,
 						ResponseTime:     avgResTime,
 						ResponseTimes:    string(rt),
 						ResponseBodies:   string(rb),
@@ -325,7 +325,7 @@ func (e *endpointHandler) ExecuteEndpointRule(ctx context.Context, endpointRules
 				responses.HeaderResponses[rule.EndpointName] = respHeader
 				responses.TimeResponses[rule.EndpointName] = respTime
 				responses.StatusResponses[rule.EndpointName] = respStatus
-				if !curlAcceptanceCriteria(strconv.Itoa(respStatus), []byte(respBody), rule.AcceptanceModel) {
+				if failures := curlAcceptanceCriteria(strconv.Itoa(respStatus), []byte(respBody), respHeader, respTime, rule.AcceptanceModel, schemas[i]); len(failures) != 0 {
 					avgResTime = float64(0)
 					c := float64(0)
 					for _, value := range responses.TimeResponses {
@@ -341,21 +341,24 @@ func (e *endpointHandler) ExecuteEndpointRule(ctx context.Context, endpointRules
 					rb, _ := json.Marshal(responses.BodyResponses)
 					rh, _ := json.Marshal(responses.HeaderResponses)
 					rs, _ := json.Marshal(responses.StatusResponses)
+					af, _ := json.Marshal(map[string][]usecase_models.AssertionFailure{rule.EndpointName: failures})
 					sessionR = repos.WriteEndpointStatsOptions{
-						Time:             time.Now(),
-						SessionId:        session.String(),
-						ProjectId:        endpointRules.Scheduling.ProjectId,
-						EndpointName:     strings.Join(endpointNamesCalled, ","),
-						EndpointId:       endpointRules.Scheduling.PipelineId,
-						IsHeartBeat:      endpointRules.Scheduling.IsHeartBeat,
-						Url:              strings.Join(urlsCalled, ","),
-						DatacenterId:     dataCenter.ID,
-						Success:          0,
-						ResponseTime:     avgResTime,
-						ResponseTimes:    string(rt),
-						ResponseBodies:   string(rb),
-						ResponseHeaders:  string(rh),
-						ResponseStatuses: string(rs),
+						Time:              time.Now(),
+						SessionId:         session.String(),
+						ProjectId:         endpointRules.Scheduling.ProjectId,
+						EndpointName:      strings.Join(endpointNamesCalled, ","),
+						EndpointId:        endpointRules.Scheduling.PipelineId,
+						IsHeartBeat:       endpointRules.Scheduling.IsHeartBeat,
+						IsMaintenance:     maintenance,
+						Url:               strings.Join(urlsCalled, ","),
+						DatacenterId:      dataCenter.ID,
+						Success:           0,
+						ResponseTime:      avgResTime,
+						ResponseTimes:     string(rt),
+						ResponseBodies:    string(rb),
+						ResponseHeaders:   string(rh),
+						ResponseStatuses:  string(rs),
+						AssertionFailures: string(af),
 					}
 					_, err = e.taskPusher.PushEndpointStore(ctx, sessionR)
 					if err != nil {
@@ -389,6 +392,7 @@ func (e *endpointHandler) ExecuteEndpointRule(ctx context.Context, endpointRules
 					EndpointName:     strings.Join(endpointNamesCalled, ","),
 					EndpointId:       endpointRules.Scheduling.PipelineId,
 					IsHeartBeat:      endpointRules.Scheduling.IsHeartBeat,
+					IsMaintenance:    maintenance,
 					Url:              strings.Join(urlsCalled, ","),
 					DatacenterId:     dataCenterId,
 					Success:          1,
@@ -418,209 +422,26 @@ func (e *endpointHandler) ExecuteEndpointRule(ctx context.Context, endpointRules
 		newSession = append(newSession, <-sessionResults)
 	}
 
-	// rosin means nothing
-	rosin, err := e.cacheRepo.Get(ctx, repos.EndpointSessionCachePrefix+strconv.Itoa(endpointRules.Scheduling.PipelineId))
-	if err != nil {
-		log.Warn("could not find old session on cache: ", err)
-	}
-	temp, _ := json.Marshal(newSession)
-	err = e.cacheRepo.Set(ctx, repos.EndpointSessionCachePrefix+strconv.Itoa(endpointRules.Scheduling.PipelineId),
-		temp, time.Duration(2*endpointRules.Scheduling.Duration)*time.Minute)
-	if err != nil {
-		log.Warn("problem on setting new session on cache: ", err)
-	}
-
-	if rosin == "" {
-		return errors.New(fmt.Sprintf("first cycle of session started on endpoint id: %d", endpointRules.Scheduling.PipelineId))
-	}
-
-	var oldSession []repos.WriteEndpointStatsOptions
-	rosinStr, ok := rosin.(string)
-	if !ok {
-		log.Error("problem on casting old session: ")
-		return errors.New(fmt.Sprintf("problem on casting old session: %v", rosin))
-	}
-	err = json.Unmarshal([]byte(rosinStr), &oldSession)
-	if err != nil {
-		log.Error("problem on unmarshalling rosinByte: ", err)
-		return errors.New(fmt.Sprintf("problem on casting old session: %v", rosinStr))
-	}
-
-	resolved, failed, newSuccess, oldSuccess := calculateEndpointStatState(newSession, oldSession)
-	if oldSuccess && !newSuccess {
-		project, err2 := e.projectRepo.GetProjectWithLoads(ctx, endpointRules.Scheduling.ProjectId)
-		if err2 != nil {
-			log.Info("error on getting project in executing rule: ", err2)
-			return err2
-		}
-		var notifications usecase_models.Notifications
-		err2 = json.Unmarshal(project.Notifications.JSON, &notifications)
-		if err2 != nil {
-			log.Info("can not unmarshal notification")
-			return err2
-		}
-
-		// send fail
-		address := ""
-		rootCause := "working on it"
-		for _, value := range oldSession {
-			if value.Success == 1 {
-				t := strings.Split(value.Url, ",")
-				address = t[len(t)-1]
-				break
-			}
-		}
-		for _, value := range newSession {
-			if value.Success == 0 {
-				rootCause = value.ResponseStatuses
-			}
-		}
-
-		dcs, err2 := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err2 != nil {
-			log.Info("problem in getting datacenters in sending alerts: ", err2.Error())
-		}
-		var dcTitles []string
-		for _, value := range failed {
-			for _, dc := range dcs {
-				if dc.ID == value {
-					dcTitles = append(dcTitles, dc.Title)
-				}
-			}
-		}
-		_, err2 = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-			Type:                "endpoint",
-			State:               "down",
-			ProjectId:           endpointRules.Scheduling.ProjectId,
-			Username:            project.R.Account.Username.String,
-			PipelineName:        endpointRules.Scheduling.PipelineName,
-			Time:                time.Now().String(),
-			Address:             address,
-			Datacenters:         strings.Join(dcTitles, ","),
-			RootCause:           rootCause,
-			ResolvedDatacenters: "",
-			FailedDatacenters:   "",
-		})
-		if err2 != nil {
-			log.Info(err2.Error())
-		}
-	}
-	if !oldSuccess && newSuccess {
-		project, err2 := e.projectRepo.GetProjectWithLoads(ctx, endpointRules.Scheduling.ProjectId)
-		if err2 != nil {
-			log.Info("error on getting project in executing rule: ", err2)
-			return err2
-		}
-		var notifications usecase_models.Notifications
-		err2 = json.Unmarshal(project.Notifications.JSON, &notifications)
-		if err2 != nil {
-			log.Info("can not unmarshal notification")
-			return err2
-		}
-
-		// send success
-		address := ""
-		rootCause := "working on it"
-		for _, value := range oldSession {
-			if value.Success == 0 {
-				rootCause = value.ResponseStatuses
-				t := strings.Split(value.Url, ",")
-				address = t[len(t)-1]
-				break
-			}
-		}
-		dcs, err2 := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err2 != nil {
-			log.Info("problem in getting datacenters in sending alerts: ", err2.Error())
-		}
-		var dcTitles []string
-		for _, value := range resolved {
-			for _, dc := range dcs {
-				if dc.ID == value {
-					dcTitles = append(dcTitles, dc.Title)
-				}
-			}
-		}
-		_, err2 = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-			Type:                "endpoint",
-			State:               "up",
-			ProjectId:           endpointRules.Scheduling.ProjectId,
-			Username:            project.R.Account.Username.String,
-			PipelineName:        endpointRules.Scheduling.PipelineName,
-			Time:                time.Now().String(),
-			Address:             address,
-			Datacenters:         strings.Join(dcTitles, ","),
-			RootCause:           rootCause,
-			ResolvedDatacenters: "",
-			FailedDatacenters:   "",
-		})
-		if err2 != nil {
-			log.Info(err2.Error())
-		}
-	}
-	if !oldSuccess && !newSuccess && (len(resolved) > 0 || len(failed) > 0) {
-		project, err2 := e.projectRepo.GetProjectWithLoads(ctx, endpointRules.Scheduling.ProjectId)
-		if err2 != nil {
-			log.Info("error on getting project in executing rule: ", err2)
-			return err2
-		}
-		var notifications usecase_models.Notifications
-		err2 = json.Unmarshal(project.Notifications.JSON, &notifications)
-		if err2 != nil {
-			log.Info("can not unmarshal notification")
-			return err2
-		}
-
-		// send diff
-		address := ""
-		rootCause := "working on it"
-		for _, value := range oldSession {
-			rootCause = value.ResponseStatuses
-			t := strings.Split(value.Url, ",")
-			address = t[len(t)-1]
-			break
-		}
-		dcs, err2 := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err2 != nil {
-			log.Info("problem in getting datacenters in sending alerts: ", err2.Error())
-		}
-		var rTitles []string
-		var fTitles []string
-		for _, value := range resolved {
-			for _, dc := range dcs {
-				if dc.ID == value {
-					rTitles = append(rTitles, dc.Title)
-				}
-			}
-		}
-		for _, value := range failed {
-			for _, dc := range dcs {
-				if dc.ID == value {
-					fTitles = append(fTitles, dc.Title)
-				}
-			}
+	var results []monitorSession
+	for _, value := range newSession {
+		rootCause := value.ResponseStatuses
+		failures := make(map[string][]usecase_models.AssertionFailure)
+		if err := json.Unmarshal([]byte(value.AssertionFailures), &failures); err == nil && len(failures) != 0 {
+			rootCause = assertionFailuresRootCause(failures)
 		}
-		_, err2 = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-			Type:                "endpoint",
-			State:               "diff",
-			ProjectId:           endpointRules.Scheduling.ProjectId,
-			Username:            project.R.Account.Username.String,
-			PipelineName:        endpointRules.Scheduling.PipelineName,
-			Time:                time.Now().String(),
-			Address:             address,
-			Datacenters:         "",
-			RootCause:           rootCause,
-			ResolvedDatacenters: strings.Join(rTitles, ","),
-			FailedDatacenters:   strings.Join(fTitles, ","),
+		results = append(results, monitorSession{
+			DatacenterId: value.DatacenterId,
+			Success:      value.Success,
+			Url:          value.Url,
+			RootCause:    rootCause,
+			AgentError:   value.IsAgentError,
 		})
-		if err2 != nil {
-			log.Info(err2.Error())
-		}
 	}
-	return nil
+	return e.sessionNotifier.notifyTransitions(ctx, "endpoint", repos.EndpointSessionCachePrefix, endpointRules.Scheduling, session.String(), results, maintenance)
 }
 
-func curlAcceptanceCriteria(status string, body []byte, acceptRules usecase_models.AcceptanceModel) bool {
+// curlAcceptanceCriteria returns the failed checks of a step response, the step is accepted when there are none
+func curlAcceptanceCriteria(status string, body []byte, header map[string][]string, responseTime float64, acceptRules usecase_models.AcceptanceModel, schema *responseSchema) []usecase_models.AssertionFailure {
 	statusCheck := false
 	for _, val := range acceptRules.Statuses {
 		if val == status {
@@ -629,71 +450,30 @@ func curlAcceptanceCriteria(status string, body []byte, acceptRules usecase_mode
 		}
 	}
 	if !statusCheck {
-		return false
-	}
-
-	var respbody map[string]interface{}
-	json.Unmarshal(body, &respbody)
-
-	if len(acceptRules.ResponseBodies) == 0 {
-		return true
-	}
-	bodyCheck := true
-	for _, val := range acceptRules.ResponseBodies {
-		_, ok := respbody[val.Key]
-		if !ok {
-			bodyCheck = false
-			break
-		}
-		//if reflect.TypeOf(value).String() != val.Value {
-		//	bodyCheck = false
-		//	break
-		//}
+		return []usecase_models.AssertionFailure{{
+			Key:      "status",
+			Operator: usecase_models.AssertionOpEquals,
+			Expected: strings.Join(acceptRules.Statuses, ","),
+			Actual:   status,
+			Message:  fmt.Sprintf("status %s is not accepted", status),
+		}}
 	}
 
-	if !bodyCheck {
-		return false
+	var failures []usecase_models.AssertionFailure
+	if failure, ok := evaluateResponseTime(responseTime, acceptRules.MaxResponseTime); !ok {
+		failures = append(failures, failure)
 	}
-
-	return true
-}
-
-func calculateEndpointStatState(newSession, oldSession []repos.WriteEndpointStatsOptions) ([]int, []int, bool, bool) {
-	var mapNDcSuccess = make(map[int]bool)
-	var mapODcSuccess = make(map[int]bool)
-	var newSuccess = true
-	var oldSuccess = true
-	for _, value := range newSession {
-		mapNDcSuccess[value.DatacenterId] = value.Success != 0
-	}
-	for _, value := range oldSession {
-		if _, ok := mapNDcSuccess[value.DatacenterId]; !ok {
-			mapNDcSuccess[value.DatacenterId] = true
-		}
-		mapODcSuccess[value.DatacenterId] = value.Success != 0
-	}
-
-	var resolved []int
-	var failed []int
-	for key, newValue := range mapNDcSuccess {
-		oldValue, ok := mapODcSuccess[key]
-		if !ok {
-			oldValue = true
-		}
-		newSuccess = newSuccess && newValue
-		oldSuccess = oldSuccess && oldValue
-
-		if newValue && !oldValue {
-			// resolved
-			resolved = append(resolved, key)
-		} else if !newValue && oldValue {
-			// error detected
-			failed = append(failed, key)
-		} else {
-			// same shit
+	for _, assertion := range acceptRules.ResponseHeaders {
+		if failure, ok := evaluateHeaderAssertion(header, assertion); !ok {
+			failures = append(failures, failure)
 		}
 	}
-	return resolved, failed, newSuccess, oldSuccess
+	failures = append(failures, evaluateAssertionGroup(string(body), usecase_models.AssertionGroup{
+		Logic:      acceptRules.Logic,
+		Assertions: acceptRules.ResponseBodies,
+		Groups:     acceptRules.BodyGroups,
+	})...)
+	return append(failures, schema.violations(string(body))...)
 }
 
 func GetStringInBetweenTwoString(str string, startS string, endS string) (result string, found bool) {
@@ -10,6 +10,8 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/friendsofgo/errors"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/redis/go-redis/v9"
 	"github.com/volatiletech/null/v8"
 	"math"
 	"net/http"
@@ -19,6 +21,8 @@ import (
 	"test-manager/gateway"
 	"test-manager/repos"
 	"test-manager/services/alert_system"
+	"test-manager/services/notifier"
+	"test-manager/services/webhooks"
 	"test-manager/usecase_models"
 	models "test-manager/usecase_models/boiler"
 	"test-manager/utils"
@@ -98,6 +102,13 @@ type HttpControllers interface {
 	CreateDatacenter(ctx echo.Context) error
 	GetDatacenter(ctx echo.Context) error
 	UpdateDatacenter(ctx echo.Context) error
+	CreateDatacenterKey(ctx echo.Context) error
+	GetDatacenterKeys(ctx echo.Context) error
+	RevokeDatacenterKey(ctx echo.Context) error
+	CreateProjectDatacenter(ctx echo.Context) error
+	GetProjectDatacenters(ctx echo.Context) error
+	PollAgentJobs(ctx echo.Context) error
+	ReportAgentJobResult(ctx echo.Context) error
 
 	Register(ctx echo.Context) error
 	Auth(ctx echo.Context) error
@@ -114,6 +125,56 @@ type HttpControllers interface {
 
 	AlertStats(ctx echo.Context) error
 
+	GetIncidents(ctx echo.Context) error
+	GetIncident(ctx echo.Context) error
+	AcknowledgeIncident(ctx echo.Context) error
+	AddIncidentNote(ctx echo.Context) error
+	ResolveIncident(ctx echo.Context) error
+
+	CreateMaintenanceWindow(ctx echo.Context) error
+	GetMaintenanceWindows(ctx echo.Context) error
+	UpdateMaintenanceWindow(ctx echo.Context) error
+	DeleteMaintenanceWindow(ctx echo.Context) error
+
+	CreateStatusPage(ctx echo.Context) error
+	GetStatusPages(ctx echo.Context) error
+	UpdateStatusPage(ctx echo.Context) error
+	DeleteStatusPage(ctx echo.Context) error
+	GetPublicStatusPage(ctx echo.Context) error
+	GetPublicStatusPageHtml(ctx echo.Context) error
+
+	EnableBadge(ctx echo.Context) error
+	GetBadges(ctx echo.Context) error
+	DeleteBadge(ctx echo.Context) error
+	GetUptimeBadge(ctx echo.Context) error
+	GetStatusBadge(ctx echo.Context) error
+
+	GetWebhookDeliveries(ctx echo.Context) error
+
+	CreateJsonSchema(ctx echo.Context) error
+	GetJsonSchemas(ctx echo.Context) error
+	UpdateJsonSchema(ctx echo.Context) error
+	DeleteJsonSchema(ctx echo.Context) error
+
+	CreateCronMonitor(ctx echo.Context) error
+	GetCronMonitors(ctx echo.Context) error
+	UpdateCronMonitor(ctx echo.Context) error
+	DeleteCronMonitor(ctx echo.Context) error
+	GetCronMonitorPings(ctx echo.Context) error
+	PingCronMonitor(ctx echo.Context) error
+	RegisterTLSRules(ctx echo.Context) error
+	ManualRunTLSRules(ctx echo.Context) error
+	GetTLSRules(ctx echo.Context) error
+	UpdateTLSRules(ctx echo.Context) error
+	DeleteTLSRules(ctx echo.Context) error
+	ReportTLSCertificates(ctx echo.Context) error
+	RegisterDNSRules(ctx echo.Context) error
+	ManualRunDNSRules(ctx echo.Context) error
+	GetDNSRules(ctx echo.Context) error
+	UpdateDNSRules(ctx echo.Context) error
+	DeleteDNSRules(ctx echo.Context) error
+	ReportDNSAnswers(ctx echo.Context) error
+
 	CreateFaq(ctx echo.Context) error
 	GetFaq(ctx echo.Context) error
 	UpdateFaq(ctx echo.Context) error
@@ -154,6 +215,23 @@ type httpControllers struct {
 	pageSpeedStatsRepository  repos.PageSpeedStatsRepository
 	pingStatsRepository       repos.PingStatsRepository
 	traceRouteStatsRepository repos.TraceRouteStatsRepository
+	incidentsRepository       repos.IncidentsRepository
+	maintenanceRepository     repos.MaintenanceWindowsRepository
+	statusPagesRepository     repos.StatusPagesRepository
+	uptimeRepository          repos.UptimeRepository
+	badgesRepository          repos.BadgesRepository
+	webhookDeliveriesRepo     repos.WebhookDeliveriesRepository
+	webhookDispatcher         webhooks.Dispatcher
+	jsonSchemasRepository     repos.JsonSchemasRepository
+	cronMonitorsRepository    repos.CronMonitorsRepository
+	tlsHandler                TLSHandler
+	tlsMonitorsRepository     repos.TLSMonitorsRepository
+	tlsStatsRepository        repos.TLSStatsRepository
+	dnsHandler                DNSHandler
+	dnsMonitorsRepository     repos.DNSMonitorsRepository
+	dnsStatsRepository        repos.DNSStatsRepository
+	datacenterKeysRepository  repos.DatacenterKeysRepository
+	operatorAccountIds        []int
 }
 
 func NewHttpControllers(rulesHandler RulesHandler,
@@ -185,7 +263,24 @@ func NewHttpControllers(rulesHandler RulesHandler,
 	netcatStatsRepository repos.NetCatStatsRepository,
 	pageSpeedStatsRepository repos.PageSpeedStatsRepository,
 	pingStatsRepository repos.PingStatsRepository,
-	traceRouteStatsRepository repos.TraceRouteStatsRepository) HttpControllers {
+	traceRouteStatsRepository repos.TraceRouteStatsRepository,
+	incidentsRepository repos.IncidentsRepository,
+	maintenanceRepository repos.MaintenanceWindowsRepository,
+	statusPagesRepository repos.StatusPagesRepository,
+	uptimeRepository repos.UptimeRepository,
+	badgesRepository repos.BadgesRepository,
+	webhookDeliveriesRepo repos.WebhookDeliveriesRepository,
+	webhookDispatcher webhooks.Dispatcher,
+	jsonSchemasRepository repos.JsonSchemasRepository,
+	cronMonitorsRepository repos.CronMonitorsRepository,
+	tlsHandler TLSHandler,
+	tlsMonitorsRepository repos.TLSMonitorsRepository,
+	tlsStatsRepository repos.TLSStatsRepository,
+	dnsHandler DNSHandler,
+	dnsMonitorsRepository repos.DNSMonitorsRepository,
+	dnsStatsRepository repos.DNSStatsRepository,
+	datacenterKeysRepository repos.DatacenterKeysRepository,
+	operatorAccountIds []int) HttpControllers {
 	return &httpControllers{
 		rulesHandler:              rulesHandler,
 		endpointHandler:           endpointHandler,
@@ -217,6 +312,23 @@ func NewHttpControllers(rulesHandler RulesHandler,
 		pageSpeedStatsRepository:  pageSpeedStatsRepository,
 		pingStatsRepository:       pingStatsRepository,
 		traceRouteStatsRepository: traceRouteStatsRepository,
+		incidentsRepository:       incidentsRepository,
+		maintenanceRepository:     maintenanceRepository,
+		statusPagesRepository:     statusPagesRepository,
+		uptimeRepository:          uptimeRepository,
+		badgesRepository:          badgesRepository,
+		webhookDeliveriesRepo:     webhookDeliveriesRepo,
+		webhookDispatcher:         webhookDispatcher,
+		jsonSchemasRepository:     jsonSchemasRepository,
+		cronMonitorsRepository:    cronMonitorsRepository,
+		tlsHandler:                tlsHandler,
+		tlsMonitorsRepository:     tlsMonitorsRepository,
+		tlsStatsRepository:        tlsStatsRepository,
+		dnsHandler:                dnsHandler,
+		dnsMonitorsRepository:     dnsMonitorsRepository,
+		dnsStatsRepository:        dnsStatsRepository,
+		datacenterKeysRepository:  datacenterKeysRepository,
+		operatorAccountIds:        operatorAccountIds,
 	}
 }
 
@@ -403,6 +515,13 @@ func (hc *httpControllers) CreateProject(ctx echo.Context) error {
 			})
 		}
 	}
+	if !validNotificationTargets(req.Notifications) {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Notification targets"),
+			Status:  400,
+			Data:    "",
+		})
+	}
 	notif, err := json.Marshal(req.Notifications)
 	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
@@ -591,6 +710,13 @@ func (hc *httpControllers) UpdateProject(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if !validNotificationTargets(req.Notifications) {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Notification targets"),
+			Status:  400,
+			Data:    "",
+		})
+	}
 
 	notifications, err := json.Marshal(req.Notifications)
 	if err != nil {
@@ -819,7 +945,15 @@ func (hc *httpControllers) UpdatePackage(ctx echo.Context) error {
 	})
 }
 
+// CreateDatacenter adds a datacenter of the platform, projects add theirs with CreateProjectDatacenter
 func (hc *httpControllers) CreateDatacenter(ctx echo.Context) error {
+	if !hc.isOperator() {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "only operators of the platform can add datacenters",
+		})
+	}
 	req := new(usecase_models.Datacenter)
 	if err := ctx.Bind(req); err != nil {
 		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
@@ -828,6 +962,13 @@ func (hc *httpControllers) CreateDatacenter(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if req.Mode != "" && req.Mode != usecase_models.DatacenterModePush && req.Mode != usecase_models.DatacenterModePull {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Mode"),
+			Status:  400,
+			Data:    "",
+		})
+	}
 
 	datacenterId, err := hc.datacenterRepo.SaveDataCenters(ctx.Request().Context(), models.Datacenter{
 		Baseurl:        req.Baseurl,
@@ -846,6 +987,16 @@ func (hc *httpControllers) CreateDatacenter(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if req.Mode != "" {
+		err = hc.datacenterRepo.SetDataCenterMode(ctx.Request().Context(), datacenterId, req.Mode)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+				Message: utils.ProblemInSystem,
+				Status:  500,
+				Data:    err.Error(),
+			})
+		}
+	}
 	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
 		Message: utils.Ok,
 		Status:  200,
@@ -867,8 +1018,24 @@ func (hc *httpControllers) GetDatacenter(ctx echo.Context) error {
 		}
 	}
 
+	owners, err := hc.datacenterRepo.GetDataCenterProjects(ctx.Request().Context())
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
 	var datacenters []*models.Datacenter
 	if datacenterId != 0 {
+		if !hc.hasDatacenterAccess(ctx.Request().Context(), datacenterId) {
+			return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+				Message: utils.NotFound,
+				Status:  404,
+				Data:    "",
+			})
+		}
 		datacenter, err := hc.datacenterRepo.GetDataCenterWithCache(ctx.Request().Context(), datacenterId)
 		if err != nil {
 			return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
@@ -887,11 +1054,51 @@ func (hc *httpControllers) GetDatacenter(ctx echo.Context) error {
 				Data:    err.Error(),
 			})
 		}
-		datacenters = append(datacenters, datacenterss...)
+		// private datacenters are listed by GetProjectDatacenters of their project
+		for _, datacenter := range datacenterss {
+			if _, private := owners[datacenter.ID]; !private {
+				datacenters = append(datacenters, datacenter)
+			}
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    hc.datacentersResponse(ctx.Request().Context(), datacenters, owners),
+	})
+}
+
+// datacentersResponse adds the health and mode of each datacenter
+func (hc *httpControllers) datacentersResponse(ctx context.Context, datacenters []*models.Datacenter, owners map[int]int) []usecase_models.Datacenter {
+	healths, err := hc.datacenterRepo.GetDataCentersHealth(ctx)
+	if err != nil {
+		log.Warn("problem on getting datacenters health: ", err)
+	}
+	var datacenterHealth = make(map[int]usecase_models.DatacenterHealth)
+	for _, health := range healths {
+		datacenterHealth[health.DatacenterId] = health
+	}
+
+	agents, err := hc.datacenterRepo.GetDataCenterAgents(ctx)
+	if err != nil {
+		log.Warn("problem on getting datacenter agents: ", err)
+	}
+	var datacenterMode = make(map[int]string)
+	for _, agent := range agents {
+		datacenterMode[agent.DatacenterId] = agent.Mode
 	}
 
 	var datacentersResponse []usecase_models.Datacenter
 	for _, datacenter := range datacenters {
+		var health *usecase_models.DatacenterHealth
+		if value, ok := datacenterHealth[datacenter.ID]; ok {
+			health = &value
+		}
+		mode := usecase_models.DatacenterModePush
+		if value, ok := datacenterMode[datacenter.ID]; ok {
+			mode = value
+		}
 		datacentersResponse = append(datacentersResponse, usecase_models.Datacenter{
 			ID:             datacenter.ID,
 			Baseurl:        datacenter.Baseurl,
@@ -904,14 +1111,12 @@ func (hc *httpControllers) GetDatacenter(ctx echo.Context) error {
 			UpdatedAt:      datacenter.UpdatedAt,
 			CreatedAt:      datacenter.CreatedAt,
 			DeletedAt:      datacenter.DeletedAt,
+			Health:         health,
+			Mode:           mode,
+			ProjectId:      owners[datacenter.ID],
 		})
 	}
-
-	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
-		Message: utils.Ok,
-		Status:  200,
-		Data:    datacentersResponse,
-	})
+	return datacentersResponse
 }
 
 func (hc *httpControllers) UpdateDatacenter(ctx echo.Context) error {
@@ -923,6 +1128,13 @@ func (hc *httpControllers) UpdateDatacenter(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if req.Mode != "" && req.Mode != usecase_models.DatacenterModePush && req.Mode != usecase_models.DatacenterModePull {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Mode"),
+			Status:  400,
+			Data:    "",
+		})
+	}
 
 	datacenterId, err := strconv.Atoi(ctx.Param("datacenter_id"))
 	if err != nil {
@@ -932,6 +1144,28 @@ func (hc *httpControllers) UpdateDatacenter(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if !hc.canManageDatacenter(ctx.Request().Context(), datacenterId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this datacenter",
+		})
+	}
+	owners, err := hc.datacenterRepo.GetDataCenterProjects(ctx.Request().Context())
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	if _, private := owners[datacenterId]; private && ((req.Mode != "" && req.Mode != usecase_models.DatacenterModePull) || req.Baseurl != "") {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Mode"),
+			Status:  400,
+			Data:    "datacenters of a project are pull datacenters, their agent polls the manager",
+		})
+	}
 	err = hc.datacenterRepo.UpdateDataCenters(ctx.Request().Context(), models.Datacenter{
 		ID:             datacenterId,
 		Baseurl:        req.Baseurl,
@@ -949,6 +1183,16 @@ func (hc *httpControllers) UpdateDatacenter(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if req.Mode != "" {
+		err = hc.datacenterRepo.SetDataCenterMode(ctx.Request().Context(), datacenterId, req.Mode)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+				Message: utils.ProblemInSystem,
+				Status:  500,
+				Data:    err.Error(),
+			})
+		}
+	}
 
 	return ctx.JSON(http.StatusCreated, utils.StandardHttpResponse{
 		Message: utils.Ok,
@@ -957,65 +1201,119 @@ func (hc *httpControllers) UpdateDatacenter(ctx echo.Context) error {
 	})
 }
 
-func (hc *httpControllers) VerificationCode(ctx echo.Context) error {
-	req := new(usecase_models.EmailVerificationRequest)
-	if err := ctx.Bind(req); err != nil {
+// CreateDatacenterKey adds a signing key to the datacenter, its secret is only returned here and has to be
+// added to the keys file of the agent. requests are signed with it once the agent knows it. the first key
+// ends the unsigned requests a datacenter from before signing is sent, so its agent has to be given it right away
+func (hc *httpControllers) CreateDatacenterKey(ctx echo.Context) error {
+	datacenterId, err := strconv.Atoi(ctx.Param("datacenter_id"))
+	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
-			Message: utils.NotValidData,
+			Message: fmt.Sprintf(utils.NotValidField, "Datacenter ID"),
 			Status:  400,
 			Data:    err.Error(),
 		})
 	}
-
-	if plainText, ttl, err := hc.redisCache.GetWithTTL(ctx.Request().Context(), req.Email); err != nil {
-		if plainText != nil && plainText.(string) != "" && VerificationCodeTTL.Seconds()-ttl.Seconds() < 120 {
-			return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
-				Message: fmt.Sprintf(utils.CustomMessage, "Can not send again right now, wait for two minutes then try again!"),
-				Status:  400,
-				Data:    err.Error(),
-			})
-		}
+	if !hc.canManageDatacenter(ctx.Request().Context(), datacenterId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this datacenter",
+		})
+	}
+	_, err = hc.datacenterRepo.GetDataCenter(ctx.Request().Context(), datacenterId)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  400,
+			Data:    err.Error(),
+		})
 	}
 
-	code := utils.GenerateCode(6)
-	err := hc.redisCache.Set(ctx.Request().Context(), req.Email, code, VerificationCodeTTL)
+	key, err := hc.datacenterKeysRepository.CreateKey(ctx.Request().Context(), datacenterId)
 	if err != nil {
-		return ctx.JSON(http.StatusBadGateway, utils.StandardHttpResponse{
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
 			Message: utils.ProblemInSystem,
 			Status:  500,
 			Data:    err.Error(),
 		})
 	}
-	err = hc.alertSystem.SendAlert(ctx.Request().Context(), alert_system.AlertRequest{
-		AlertType:  "email",
-		UserId:     req.Email,
-		Targets:    []string{req.Email},
-		Subject:    "Verification Code",
-		Message:    "verification code sent",
-		IsTemplate: true,
-		Template:   "verification_code",
-		TemplateKeyPairs: map[string]string{
-			"verification_code": code,
-			"expire_time":       strconv.Itoa(int(VerificationCodeTTL.Minutes())),
-		},
-		AdditionalData: nil,
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    key,
 	})
+}
+
+func (hc *httpControllers) GetDatacenterKeys(ctx echo.Context) error {
+	datacenterId, err := strconv.Atoi(ctx.Param("datacenter_id"))
 	if err != nil {
-		return ctx.JSON(http.StatusBadGateway, utils.StandardHttpResponse{
-			Message: utils.ProblemInSystem,
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Datacenter ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.canManageDatacenter(ctx.Request().Context(), datacenterId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this datacenter",
+		})
+	}
+
+	keys, err := hc.datacenterKeysRepository.GetKeys(ctx.Request().Context(), datacenterId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
 			Status:  500,
 			Data:    err.Error(),
 		})
 	}
-	return ctx.JSON(http.StatusCreated, utils.StandardHttpResponse{
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    keys,
+	})
+}
+
+// RevokeDatacenterKey stops signing with the key, it is removed from the keys file of the agent afterwards
+func (hc *httpControllers) RevokeDatacenterKey(ctx echo.Context) error {
+	datacenterId, err := strconv.Atoi(ctx.Param("datacenter_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Datacenter ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.canManageDatacenter(ctx.Request().Context(), datacenterId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this datacenter",
+		})
+	}
+
+	err = hc.datacenterKeysRepository.RevokeKey(ctx.Request().Context(), datacenterId, ctx.Param("key_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
 		Message: utils.Ok,
 		Status:  200,
 		Data:    "",
 	})
 }
 
-func (hc *httpControllers) Register(ctx echo.Context) error {
-	req := new(usecase_models.RegisterAccountRequest)
+// CreateProjectDatacenter registers a private datacenter of the project, it is only selectable in the rules of
+// the project and hidden from the others. it is always a pull datacenter, the manager never calls an address a
+// project chose. the returned key is the token its agent signs with
+func (hc *httpControllers) CreateProjectDatacenter(ctx echo.Context) error {
+	req := new(usecase_models.Datacenter)
 	if err := ctx.Bind(req); err != nil {
 		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
 			Message: utils.NotValidData,
@@ -1023,40 +1321,42 @@ func (hc *httpControllers) Register(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
-
-	accountExists, err := hc.accountRepo.AccountExistsByEmail(ctx.Request().Context(), req.Username)
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
 	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
-			Message: utils.NotValidData,
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
 			Status:  400,
 			Data:    err.Error(),
 		})
 	}
-	if accountExists {
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+	if (req.Mode != "" && req.Mode != usecase_models.DatacenterModePull) || req.Baseurl != "" {
 		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
-			Message: fmt.Sprintf(utils.CustomMessage, "Username already exists!"),
+			Message: fmt.Sprintf(utils.NotValidField, "Mode"),
 			Status:  400,
-			Data:    "",
+			Data:    "datacenters of a project are pull datacenters, their agent polls the manager",
 		})
 	}
-
-	if code, err := hc.redisCache.Get(ctx.Request().Context(), req.Email); err != nil || code != req.EmailVerificationCode {
-		if req.EmailVerificationCode != "123456" {
-			return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
-				Message: fmt.Sprintf(utils.CustomMessage, "Not a valid email verification"),
-				Status:  400,
-				Data:    "",
-			})
-		}
+	if req.Title == "" {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    "title is required",
+		})
 	}
 
-	accountId, err := hc.accountRepo.SaveAccounts(ctx.Request().Context(), models.Account{
-		FirstName:   null.NewString(req.FirstName, true),
-		LastName:    null.NewString(req.LastName, true),
-		PhoneNumber: null.NewString(req.PhoneNumber, true),
-		Email:       null.NewString(req.Email, true),
-		Username:    null.NewString(req.Username, true),
-		Password:    null.NewString(req.Password, true),
+	datacenterId, err := hc.datacenterRepo.SaveProjectDataCenter(ctx.Request().Context(), projectId, models.Datacenter{
+		Title:        req.Title,
+		Lat:          req.Lat,
+		LNG:          req.LNG,
+		LocationName: req.LocationName,
+		CountryName:  req.CountryName,
 	})
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
@@ -1065,41 +1365,284 @@ func (hc *httpControllers) Register(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
-
-	token, err := NewJWTToken(jwt.StandardClaims{
-		Audience:  Aud,
-		Id:        strconv.Itoa(accountId),
-		ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
-	})
+	err = hc.datacenterRepo.SetDataCenterMode(ctx.Request().Context(), datacenterId, usecase_models.DatacenterModePull)
 	if err != nil {
-		return ctx.JSON(http.StatusUnauthorized, utils.StandardHttpResponse{
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	key, err := hc.datacenterKeysRepository.CreateKey(ctx.Request().Context(), datacenterId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
 			Message: utils.ProblemInSystem,
 			Status:  500,
 			Data:    err.Error(),
 		})
 	}
-
 	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
 		Message: utils.Ok,
 		Status:  200,
-		Data: usecase_models.RegisterAccountResponse{
-			AccountId: accountId,
-			Token:     token,
-		}})
+		Data:    usecase_models.CreateProjectDatacenterResponse{DatacenterId: datacenterId, Key: key},
+	})
 }
 
-func (hc *httpControllers) Auth(ctx echo.Context) error {
-	req := new(usecase_models.Auth)
-	if err := ctx.Bind(req); err != nil {
+func (hc *httpControllers) GetProjectDatacenters(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
-			Message: utils.NotValidData,
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
 			Status:  400,
 			Data:    err.Error(),
 		})
 	}
-	if req.Email == "" {
-		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
-			Message: fmt.Sprintf(utils.NotValidField, "Email"),
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	datacenters, err := hc.datacenterRepo.GetProjectDataCenters(ctx.Request().Context(), projectId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	owners := make(map[int]int)
+	for _, datacenter := range datacenters {
+		owners[datacenter.ID] = projectId
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    hc.datacentersResponse(ctx.Request().Context(), datacenters, owners),
+	})
+}
+
+// PollAgentJobs hands the next job of its datacenter to a pull agent, the poll is held open for
+// AgentPullPollTimeout and answered with no content when no job came. the job is kept in flight until
+// its result is reported, see takeAgentJob
+func (hc *httpControllers) PollAgentJobs(ctx echo.Context) error {
+	datacenterId, _ := ctx.Get(DatacenterIdContextKey).(int)
+	err := hc.datacenterRepo.TouchDataCenterAgent(ctx.Request().Context(), datacenterId, time.Now())
+	if err != nil {
+		log.Warn("problem on keeping last poll of agent: ", err)
+	}
+
+	job, err := takeAgentJob(ctx.Request().Context(), hc.redisCache, datacenterId)
+	if errors.Is(err, redis.Nil) {
+		return ctx.NoContent(http.StatusNoContent)
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    job,
+	})
+}
+
+// ReportAgentJobResult passes the result of a job to the check waiting for it, only the datacenter
+// the job was handed to can report it
+func (hc *httpControllers) ReportAgentJobResult(ctx echo.Context) error {
+	req := new(usecase_models.AgentJobResult)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	jobId := ctx.Param("job_id")
+	datacenterId, _ := ctx.Get(DatacenterIdContextKey).(int)
+	owner, err := hc.redisCache.Get(ctx.Request().Context(), agentJobOwnerPrefixCacheKey+jobId)
+	if err != nil || owner != strconv.Itoa(datacenterId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    "",
+		})
+	}
+
+	resultB, err := json.Marshal(req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	err = hc.redisCache.RPush(ctx.Request().Context(), agentResultsPrefixCacheKey+jobId, AgentPullJobTimeout, resultB)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	_ = hc.redisCache.Delete(ctx.Request().Context(), agentJobOwnerPrefixCacheKey+jobId)
+	ackAgentJob(ctx.Request().Context(), hc.redisCache, datacenterId, jobId)
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "",
+	})
+}
+
+func (hc *httpControllers) VerificationCode(ctx echo.Context) error {
+	req := new(usecase_models.EmailVerificationRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	if plainText, ttl, err := hc.redisCache.GetWithTTL(ctx.Request().Context(), req.Email); err != nil {
+		if plainText != nil && plainText.(string) != "" && VerificationCodeTTL.Seconds()-ttl.Seconds() < 120 {
+			return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+				Message: fmt.Sprintf(utils.CustomMessage, "Can not send again right now, wait for two minutes then try again!"),
+				Status:  400,
+				Data:    err.Error(),
+			})
+		}
+	}
+
+	code := utils.GenerateCode(6)
+	err := hc.redisCache.Set(ctx.Request().Context(), req.Email, code, VerificationCodeTTL)
+	if err != nil {
+		return ctx.JSON(http.StatusBadGateway, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	err = hc.alertSystem.SendAlert(ctx.Request().Context(), alert_system.AlertRequest{
+		AlertType:  "email",
+		UserId:     req.Email,
+		Targets:    []string{req.Email},
+		Subject:    "Verification Code",
+		Message:    "verification code sent",
+		IsTemplate: true,
+		Template:   "verification_code",
+		TemplateKeyPairs: map[string]string{
+			"verification_code": code,
+			"expire_time":       strconv.Itoa(int(VerificationCodeTTL.Minutes())),
+		},
+		AdditionalData: nil,
+	})
+	if err != nil {
+		return ctx.JSON(http.StatusBadGateway, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusCreated, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "",
+	})
+}
+
+func (hc *httpControllers) Register(ctx echo.Context) error {
+	req := new(usecase_models.RegisterAccountRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	accountExists, err := hc.accountRepo.AccountExistsByEmail(ctx.Request().Context(), req.Username)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if accountExists {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.CustomMessage, "Username already exists!"),
+			Status:  400,
+			Data:    "",
+		})
+	}
+
+	if code, err := hc.redisCache.Get(ctx.Request().Context(), req.Email); err != nil || code != req.EmailVerificationCode {
+		if req.EmailVerificationCode != "123456" {
+			return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+				Message: fmt.Sprintf(utils.CustomMessage, "Not a valid email verification"),
+				Status:  400,
+				Data:    "",
+			})
+		}
+	}
+
+	accountId, err := hc.accountRepo.SaveAccounts(ctx.Request().Context(), models.Account{
+		FirstName:   null.NewString(req.FirstName, true),
+		LastName:    null.NewString(req.LastName, true),
+		PhoneNumber: null.NewString(req.PhoneNumber, true),
+		Email:       null.NewString(req.Email, true),
+		Username:    null.NewString(req.Username, true),
+		Password:    null.NewString(req.Password, true),
+	})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	token, err := NewJWTToken(jwt.StandardClaims{
+		Audience:  Aud,
+		Id:        strconv.Itoa(accountId),
+		ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
+	})
+	if err != nil {
+		return ctx.JSON(http.StatusUnauthorized, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data: usecase_models.RegisterAccountResponse{
+			AccountId: accountId,
+			Token:     token,
+		}})
+}
+
+func (hc *httpControllers) Auth(ctx echo.Context) error {
+	req := new(usecase_models.Auth)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if req.Email == "" {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Email"),
 			Status:  400,
 			Data:    "",
 		})
@@ -1688,6 +2231,45 @@ func (hc *httpControllers) UpdateEndpointRules(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if err := validateScheduling(req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "scheduling"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.endpointRepository.GetEndpoint(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.Scheduling.ProjectId = old.Scheduling.ProjectId
+	if err := validateSchedulingDataCenters(ctx.Request().Context(), hc.datacenterRepo, old.Scheduling.ProjectId, req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "data_centers"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	for _, endpoint := range req.Endpoints {
+		if err := validateAssertions(endpoint.AcceptanceModel); err != nil {
+			return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+				Message: fmt.Sprintf(utils.NotValidField, "acceptance_model"),
+				Status:  400,
+				Data:    err.Error(),
+			})
+		}
+		if err := validateResponseSchema(endpoint); err != nil {
+			return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+				Message: fmt.Sprintf(utils.NotValidField, "response_schema"),
+				Status:  400,
+				Data:    err.Error(),
+			})
+		}
+	}
 
 	data, _ := json.Marshal(req)
 	err = hc.endpointRepository.UpdateEndpoint(ctx.Request().Context(), models.Endpoint{
@@ -1727,18 +2309,41 @@ func (hc *httpControllers) UpdateNetCatRules(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
-
-	data, _ := json.Marshal(req)
-	err = hc.netCatRepository.UpdateNetCat(ctx.Request().Context(), models.NetCat{
-		ID:        id,
-		Data:      null.NewJSON(data, true),
-		ProjectID: req.Scheduling.ProjectId,
-	})
-	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
-			Message: utils.ProblemInSystem,
-			Status:  500,
-			Data:    err.Error(),
+	if err := validateScheduling(req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "scheduling"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.netCatRepository.GetNetCat(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.Scheduling.ProjectId = old.Scheduling.ProjectId
+	if err := validateSchedulingDataCenters(ctx.Request().Context(), hc.datacenterRepo, old.Scheduling.ProjectId, req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "data_centers"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	data, _ := json.Marshal(req)
+	err = hc.netCatRepository.UpdateNetCat(ctx.Request().Context(), models.NetCat{
+		ID:        id,
+		Data:      null.NewJSON(data, true),
+		ProjectID: req.Scheduling.ProjectId,
+	})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
 		})
 	}
 
@@ -1766,6 +2371,29 @@ func (hc *httpControllers) UpdatePingRules(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if err := validateScheduling(req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "scheduling"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.pingRepository.GetPing(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.Scheduling.ProjectId = old.Scheduling.ProjectId
+	if err := validateSchedulingDataCenters(ctx.Request().Context(), hc.datacenterRepo, old.Scheduling.ProjectId, req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "data_centers"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
 
 	data, _ := json.Marshal(req)
 	err = hc.pingRepository.UpdatePing(ctx.Request().Context(), models.Ping{
@@ -1805,6 +2433,29 @@ func (hc *httpControllers) UpdateTraceRouteRules(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if err := validateScheduling(req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "scheduling"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.traceRouteRepository.GetTraceRoute(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.Scheduling.ProjectId = old.Scheduling.ProjectId
+	if err := validateSchedulingDataCenters(ctx.Request().Context(), hc.datacenterRepo, old.Scheduling.ProjectId, req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "data_centers"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
 
 	data, _ := json.Marshal(req)
 	err = hc.traceRouteRepository.UpdateTraceRoute(ctx.Request().Context(), models.TraceRoute{
@@ -1844,6 +2495,29 @@ func (hc *httpControllers) UpdatePageSpeedRules(ctx echo.Context) error {
 			Data:    err.Error(),
 		})
 	}
+	if err := validateScheduling(req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "scheduling"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.pageSpeedRepository.GetPageSpeed(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.Scheduling.ProjectId = old.Scheduling.ProjectId
+	if err := validateSchedulingDataCenters(ctx.Request().Context(), hc.datacenterRepo, old.Scheduling.ProjectId, req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "data_centers"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
 
 	data, _ := json.Marshal(req)
 	err = hc.pageSpeedRepository.UpdatePageSpeed(ctx.Request().Context(), models.PageSpeed{
@@ -2956,18 +3630,19 @@ type EndpointDetailsResponse struct {
 }
 
 type EndpointDetails struct {
-	Time                time.Time              `json:"time"`
-	ProjectID           int                    `json:"project_id"`
-	EndpointName        string                 `json:"endpoint_name"`
-	EndpointID          int                    `json:"endpoint_id"`
-	URL                 []string               `json:"url"`
-	IsHeartBeat         bool                   `json:"is_heart_beat"`
-	Success             int                    `json:"success"`
-	AverageResponseTime float64                `json:"average_response_time"`
-	ResponseTimes       map[string]interface{} `json:"response_times"`
-	ResponseBodies      map[string]interface{} `json:"response_bodies"`
-	ResponseHeaders     map[string]interface{} `json:"response_headers"`
-	ResponseStatuses    map[string]int         `json:"response_statuses"`
+	Time                time.Time                                    `json:"time"`
+	ProjectID           int                                          `json:"project_id"`
+	EndpointName        string                                       `json:"endpoint_name"`
+	EndpointID          int                                          `json:"endpoint_id"`
+	URL                 []string                                     `json:"url"`
+	IsHeartBeat         bool                                         `json:"is_heart_beat"`
+	Success             int                                          `json:"success"`
+	AverageResponseTime float64                                      `json:"average_response_time"`
+	ResponseTimes       map[string]interface{}                       `json:"response_times"`
+	ResponseBodies      map[string]interface{}                       `json:"response_bodies"`
+	ResponseHeaders     map[string]interface{}                       `json:"response_headers"`
+	ResponseStatuses    map[string]int                               `json:"response_statuses"`
+	AssertionFailures   map[string][]usecase_models.AssertionFailure `json:"assertion_failures"`
 	Datacenter          struct {
 		DatacenterId int    `json:"datacenter_id"`
 		Title        string `json:"title"`
@@ -3032,6 +3707,7 @@ func (hc *httpControllers) ReportEndpointDetails(ctx echo.Context) error {
 	total, data, err := hc.endpointStatsRepository.Read(ctx.Request().Context(),
 		[]string{
 			"time",
+			"session_id",
 			"project_id",
 			"endpoint_name",
 			"endpoint_id",
@@ -3059,8 +3735,19 @@ func (hc *httpControllers) ReportEndpointDetails(ctx echo.Context) error {
 		})
 	}
 
+	assertionFailures, err := hc.endpointStatsRepository.GetAssertionFailures(ctx.Request().Context(), data)
+	if err != nil {
+		return ctx.JSON(http.StatusBadGateway, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
 	responseData := make([]EndpointDetails, 0)
 	for _, point := range data {
+		af := make(map[string][]usecase_models.AssertionFailure)
+		json.Unmarshal([]byte(assertionFailures[repos.AssertionFailuresKey(point.SessionID, point.DatacenterID)]), &af)
 		rt := make(map[string]interface{})
 		json.Unmarshal([]byte(point.ResponseTimes.String), &rt)
 		rb := make(map[string]interface{})
@@ -3082,6 +3769,7 @@ func (hc *httpControllers) ReportEndpointDetails(ctx echo.Context) error {
 			ResponseBodies:      rb,
 			ResponseHeaders:     rh,
 			ResponseStatuses:    rs,
+			AssertionFailures:   af,
 			Datacenter: struct {
 				DatacenterId int    `json:"datacenter_id"`
 				Title        string `json:"title"`
@@ -3138,6 +3826,9 @@ func (hc *httpControllers) ReportEndpointQuickStats(ctx echo.Context) error {
 
 	filter := repos.Filters{
 		repos.Filter{Field: "time", Op: repos.FilterOpGt, Value: time.Now().Add(-time.Duration(timeframe) * time.Minute)},
+		// checks the datacenter agent could not run are not counted against uptime,
+		// maintenance sessions are read to cut down time at the windows below
+		repos.Filter{Field: repos.StatsColumnIsAgentError, Op: repos.FilterOpEq, Value: false},
 	}
 
 	if datacenterId := ctx.QueryParam("datacenter_id"); datacenterId != "" {
@@ -3179,12 +3870,23 @@ func (hc *httpControllers) ReportEndpointQuickStats(ctx echo.Context) error {
 	success := 0
 	total := 0
 	up := true
+	current := true
 	var downTime = 0 * time.Second
 	temp := time.Time{}
 	pipelineStats := map[int]float64{}
 	pipelineTotals := map[int]float64{}
 	pipelineNames := map[int]string{}
 	for i := len(data) - 1; i >= 0; i-- {
+		if data[i].IsMaintenance {
+			// planned downtime is not counted against uptime, a down streak ends where the window starts
+			// and a new one starts with the first failure after it
+			if !up {
+				up = true
+				downTime = downTime + time.Duration(data[i].MinTime.Sub(temp).Seconds())*time.Second
+			}
+			continue
+		}
+		current = data[i].Success
 		if data[i].Success {
 			success += 1
 		}
@@ -3230,6 +3932,10 @@ func (hc *httpControllers) ReportEndpointQuickStats(ctx echo.Context) error {
 	if !up {
 		downTime = downTime + time.Duration(data[0].MaxTime.Sub(temp).Seconds())*time.Second
 	}
+	upTimePercent := 0.0
+	if total != 0 {
+		upTimePercent = float64(success) * 100 / float64(total)
+	}
 
 	var pipelineStatsResponse []struct {
 		PipelineName string  `json:"pipeline_name"`
@@ -3250,8 +3956,8 @@ func (hc *httpControllers) ReportEndpointQuickStats(ctx echo.Context) error {
 		Status:  200,
 		Data: ReportEndpointQuickStatsResponse{
 			DownTime:          downTime.Minutes(),
-			UpTimePercent:     float64(success) * 100 / float64(total),
-			CurrentStatus:     up,
+			UpTimePercent:     upTimePercent,
+			CurrentStatus:     current,
 			StatusPerPipeline: pipelineStatsResponse,
 		}})
 }
@@ -3274,6 +3980,9 @@ func (hc *httpControllers) ReportNetCatQuickStats(ctx echo.Context) error {
 
 	filter := repos.Filters{
 		repos.Filter{Field: "time", Op: repos.FilterOpGt, Value: time.Now().Add(-time.Duration(timeframe) * time.Minute)},
+		// checks the datacenter agent could not run are not counted against uptime,
+		// maintenance sessions are read to cut down time at the windows below
+		repos.Filter{Field: repos.StatsColumnIsAgentError, Op: repos.FilterOpEq, Value: false},
 	}
 
 	if datacenterId := ctx.QueryParam("datacenter_id"); datacenterId != "" {
@@ -3316,12 +4025,23 @@ func (hc *httpControllers) ReportNetCatQuickStats(ctx echo.Context) error {
 	success := 0
 	total := 0
 	up := true
+	current := true
 	var downTime = 0 * time.Second
 	temp := time.Time{}
 	pipelineStats := map[int]float64{}
 	pipelineTotals := map[int]float64{}
 	pipelineNames := map[int]string{}
 	for i := len(data) - 1; i >= 0; i-- {
+		if data[i].IsMaintenance {
+			// planned downtime is not counted against uptime, a down streak ends where the window starts
+			// and a new one starts with the first failure after it
+			if !up {
+				up = true
+				downTime = downTime + time.Duration(data[i].MinTime.Sub(temp).Seconds())*time.Second
+			}
+			continue
+		}
+		current = data[i].Success
 		if data[i].Success {
 			success += 1
 		}
@@ -3367,6 +4087,10 @@ func (hc *httpControllers) ReportNetCatQuickStats(ctx echo.Context) error {
 	if !up {
 		downTime = downTime + time.Duration(data[0].MaxTime.Sub(temp).Seconds())*time.Second
 	}
+	upTimePercent := 0.0
+	if total != 0 {
+		upTimePercent = float64(success) * 100 / float64(total)
+	}
 
 	var pipelineStatsResponse []struct {
 		PipelineName string  `json:"pipeline_name"`
@@ -3387,8 +4111,8 @@ func (hc *httpControllers) ReportNetCatQuickStats(ctx echo.Context) error {
 		Status:  200,
 		Data: ReportNetCatQuickStatsResponse{
 			DownTime:          downTime.Minutes(),
-			UpTimePercent:     float64(success) * 100 / float64(total),
-			CurrentStatus:     up,
+			UpTimePercent:     upTimePercent,
+			CurrentStatus:     current,
 			StatusPerPipeline: pipelineStatsResponse,
 		}})
 }
@@ -3411,6 +4135,9 @@ func (hc *httpControllers) ReportPageSpeedQuickStats(ctx echo.Context) error {
 
 	filter := repos.Filters{
 		repos.Filter{Field: "time", Op: repos.FilterOpGt, Value: time.Now().Add(-time.Duration(timeframe) * time.Minute)},
+		// checks the datacenter agent could not run are not counted against uptime,
+		// maintenance sessions are read to cut down time at the windows below
+		repos.Filter{Field: repos.StatsColumnIsAgentError, Op: repos.FilterOpEq, Value: false},
 	}
 
 	if datacenterId := ctx.QueryParam("datacenter_id"); datacenterId != "" {
@@ -3452,12 +4179,23 @@ func (hc *httpControllers) ReportPageSpeedQuickStats(ctx echo.Context) error {
 	success := 0
 	total := 0
 	up := true
+	current := true
 	var downTime = 0 * time.Second
 	temp := time.Time{}
 	pipelineStats := map[int]float64{}
 	pipelineTotals := map[int]float64{}
 	pipelineNames := map[int]string{}
 	for i := len(data) - 1; i >= 0; i-- {
+		if data[i].IsMaintenance {
+			// planned downtime is not counted against uptime, a down streak ends where the window starts
+			// and a new one starts with the first failure after it
+			if !up {
+				up = true
+				downTime = downTime + time.Duration(data[i].MinTime.Sub(temp).Seconds())*time.Second
+			}
+			continue
+		}
+		current = data[i].Success
 		if data[i].Success {
 			success += 1
 		}
@@ -3503,6 +4241,10 @@ func (hc *httpControllers) ReportPageSpeedQuickStats(ctx echo.Context) error {
 	if !up {
 		downTime = downTime + time.Duration(data[0].MaxTime.Sub(temp).Seconds())*time.Second
 	}
+	upTimePercent := 0.0
+	if total != 0 {
+		upTimePercent = float64(success) * 100 / float64(total)
+	}
 
 	var pipelineStatsResponse []struct {
 		PipelineName string  `json:"pipeline_name"`
@@ -3523,8 +4265,8 @@ func (hc *httpControllers) ReportPageSpeedQuickStats(ctx echo.Context) error {
 		Status:  200,
 		Data: ReportPageSpeedQuickStatsResponse{
 			DownTime:          downTime.Minutes(),
-			UpTimePercent:     float64(success) * 100 / float64(total),
-			CurrentStatus:     up,
+			UpTimePercent:     upTimePercent,
+			CurrentStatus:     current,
 			StatusPerPipeline: pipelineStatsResponse,
 		}})
 }
@@ -3547,6 +4289,9 @@ func (hc *httpControllers) ReportPingQuickStats(ctx echo.Context) error {
 
 	filter := repos.Filters{
 		repos.Filter{Field: "time", Op: repos.FilterOpGt, Value: time.Now().Add(-time.Duration(timeframe) * time.Minute)},
+		// checks the datacenter agent could not run are not counted against uptime,
+		// maintenance sessions are read to cut down time at the windows below
+		repos.Filter{Field: repos.StatsColumnIsAgentError, Op: repos.FilterOpEq, Value: false},
 	}
 
 	if datacenterId := ctx.QueryParam("datacenter_id"); datacenterId != "" {
@@ -3588,12 +4333,23 @@ func (hc *httpControllers) ReportPingQuickStats(ctx echo.Context) error {
 	success := 0
 	total := 0
 	up := true
+	current := true
 	var downTime = 0 * time.Second
 	temp := time.Time{}
 	pipelineStats := map[int]float64{}
 	pipelineTotals := map[int]float64{}
 	pipelineNames := map[int]string{}
 	for i := len(data) - 1; i >= 0; i-- {
+		if data[i].IsMaintenance {
+			// planned downtime is not counted against uptime, a down streak ends where the window starts
+			// and a new one starts with the first failure after it
+			if !up {
+				up = true
+				downTime = downTime + time.Duration(data[i].MinTime.Sub(temp).Seconds())*time.Second
+			}
+			continue
+		}
+		current = data[i].Success
 		if data[i].Success {
 			success += 1
 		}
@@ -3639,6 +4395,10 @@ func (hc *httpControllers) ReportPingQuickStats(ctx echo.Context) error {
 	if !up {
 		downTime = downTime + time.Duration(data[0].MaxTime.Sub(temp).Seconds())*time.Second
 	}
+	upTimePercent := 0.0
+	if total != 0 {
+		upTimePercent = float64(success) * 100 / float64(total)
+	}
 
 	var pipelineStatsResponse []struct {
 		PipelineName string  `json:"pipeline_name"`
@@ -3659,8 +4419,8 @@ func (hc *httpControllers) ReportPingQuickStats(ctx echo.Context) error {
 		Status:  200,
 		Data: ReportPingQuickStatsResponse{
 			DownTime:          downTime.Minutes(),
-			UpTimePercent:     float64(success) * 100 / float64(total),
-			CurrentStatus:     up,
+			UpTimePercent:     upTimePercent,
+			CurrentStatus:     current,
 			StatusPerPipeline: pipelineStatsResponse,
 		}})
 }
@@ -3683,6 +4443,9 @@ func (hc *httpControllers) ReportTraceRouteQuickStats(ctx echo.Context) error {
 
 	filter := repos.Filters{
 		repos.Filter{Field: "time", Op: repos.FilterOpGt, Value: time.Now().Add(-time.Duration(timeframe) * time.Minute)},
+		// checks the datacenter agent could not run are not counted against uptime,
+		// maintenance sessions are read to cut down time at the windows below
+		repos.Filter{Field: repos.StatsColumnIsAgentError, Op: repos.FilterOpEq, Value: false},
 	}
 
 	if datacenterId := ctx.QueryParam("datacenter_id"); datacenterId != "" {
@@ -3724,12 +4487,23 @@ func (hc *httpControllers) ReportTraceRouteQuickStats(ctx echo.Context) error {
 	success := 0
 	total := 0
 	up := true
+	current := true
 	var downTime = 0 * time.Second
 	temp := time.Time{}
 	pipelineStats := map[int]float64{}
 	pipelineTotals := map[int]float64{}
 	pipelineNames := map[int]string{}
 	for i := len(data) - 1; i >= 0; i-- {
+		if data[i].IsMaintenance {
+			// planned downtime is not counted against uptime, a down streak ends where the window starts
+			// and a new one starts with the first failure after it
+			if !up {
+				up = true
+				downTime = downTime + time.Duration(data[i].MinTime.Sub(temp).Seconds())*time.Second
+			}
+			continue
+		}
+		current = data[i].Success
 		if data[i].Success {
 			success += 1
 		}
@@ -3775,6 +4549,10 @@ func (hc *httpControllers) ReportTraceRouteQuickStats(ctx echo.Context) error {
 	if !up {
 		downTime = downTime + time.Duration(data[0].MaxTime.Sub(temp).Seconds())*time.Second
 	}
+	upTimePercent := 0.0
+	if total != 0 {
+		upTimePercent = float64(success) * 100 / float64(total)
+	}
 
 	var pipelineStatsResponse []struct {
 		PipelineName string  `json:"pipeline_name"`
@@ -3795,8 +4573,8 @@ func (hc *httpControllers) ReportTraceRouteQuickStats(ctx echo.Context) error {
 		Status:  200,
 		Data: ReportTraceRouteQuickStatsResponse{
 			DownTime:          downTime.Minutes(),
-			UpTimePercent:     float64(success) * 100 / float64(total),
-			CurrentStatus:     up,
+			UpTimePercent:     upTimePercent,
+			CurrentStatus:     current,
 			StatusPerPipeline: pipelineStatsResponse,
 		}})
 }
@@ -4726,3 +5504,1938 @@ func (hc *httpControllers) UpdateTicket(ctx echo.Context) error {
 		Data:    nil,
 	})
 }
+
+func (hc *httpControllers) GetIncidents(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	page := 1
+	perPage := 10
+	if p, err := strconv.Atoi(ctx.QueryParam("page")); err == nil {
+		page = p
+	}
+	if pp, err := strconv.Atoi(ctx.QueryParam("per_page")); err == nil {
+		perPage = pp
+	}
+
+	filter := repos.Filters{
+		repos.Filter{Field: "project_id", Op: repos.FilterOpEq, Value: projectId},
+	}
+	if pipelineType := ctx.QueryParam("pipeline_type"); pipelineType != "" {
+		filter = append(filter, repos.Filter{Field: "pipeline_type", Op: repos.FilterOpEq, Value: pipelineType})
+	}
+	if pipelineId := ctx.QueryParam("pipeline_id"); pipelineId != "" {
+		filter = append(filter, repos.Filter{Field: "pipeline_id", Op: repos.FilterOpIn, Value: strings.Split(pipelineId, ",")})
+	}
+	if status := ctx.QueryParam("status"); status != "" {
+		filter = append(filter, repos.Filter{Field: "status", Op: repos.FilterOpEq, Value: status})
+	}
+	if from, err := time.Parse("2006-01-02 15:04:05", ctx.QueryParam("from")); err == nil {
+		filter = append(filter, repos.Filter{Field: "started_at", Op: repos.FilterOpGte, Value: from})
+	}
+	if to, err := time.Parse("2006-01-02 15:04:05", ctx.QueryParam("to")); err == nil {
+		filter = append(filter, repos.Filter{Field: "started_at", Op: repos.FilterOpLte, Value: to})
+	}
+
+	incidents, err := hc.incidentsRepository.GetIncidents(ctx.Request().Context(), filter, perPage,
+		int(utils.OffsetFromPage(int64(page), int64(perPage))))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    incidents,
+	})
+}
+
+func (hc *httpControllers) GetIncident(ctx echo.Context) error {
+	incident, errResponse := hc.bindProjectIncident(ctx)
+	if errResponse != nil {
+		return ctx.JSON(errResponse.Status, errResponse)
+	}
+	var err error
+	incident.Events, err = hc.incidentsRepository.GetIncidentEvents(ctx.Request().Context(), incident.ID)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    incident,
+	})
+}
+
+func (hc *httpControllers) AcknowledgeIncident(ctx echo.Context) error {
+	incident, errResponse := hc.bindProjectIncident(ctx)
+	if errResponse != nil {
+		return ctx.JSON(errResponse.Status, errResponse)
+	}
+
+	err := hc.incidentsRepository.AcknowledgeIncident(ctx.Request().Context(), incident.ID, IdentityStruct.Id)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	event := usecase_models.IncidentEvent{
+		IncidentId: incident.ID,
+		EventType:  usecase_models.IncidentEventAcknowledged,
+		AccountId:  null.IntFrom(IdentityStruct.Id),
+	}
+	event.ID, err = hc.incidentsRepository.SaveIncidentEvent(ctx.Request().Context(), event)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	hc.dispatchIncidentUpdated(ctx.Request().Context(), incident.ID, event)
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) AddIncidentNote(ctx echo.Context) error {
+	req := new(usecase_models.IncidentNoteRequest)
+	if err := ctx.Bind(req); err != nil || strings.TrimSpace(req.Note) == "" {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Note"),
+			Status:  400,
+			Data:    nil,
+		})
+	}
+	incident, errResponse := hc.bindProjectIncident(ctx)
+	if errResponse != nil {
+		return ctx.JSON(errResponse.Status, errResponse)
+	}
+
+	event := usecase_models.IncidentEvent{
+		IncidentId: incident.ID,
+		EventType:  usecase_models.IncidentEventNote,
+		AccountId:  null.IntFrom(IdentityStruct.Id),
+		Note:       null.StringFrom(req.Note),
+	}
+	var err error
+	event.ID, err = hc.incidentsRepository.SaveIncidentEvent(ctx.Request().Context(), event)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	hc.dispatchIncidentUpdated(ctx.Request().Context(), incident.ID, event)
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    event.ID,
+	})
+}
+
+func (hc *httpControllers) ResolveIncident(ctx echo.Context) error {
+	req := new(usecase_models.IncidentNoteRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	incident, errResponse := hc.bindProjectIncident(ctx)
+	if errResponse != nil {
+		return ctx.JSON(errResponse.Status, errResponse)
+	}
+
+	resolved, err := hc.incidentsRepository.ResolveIncident(ctx.Request().Context(), incident.ID, time.Now(), null.IntFrom(IdentityStruct.Id))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    "incident is already resolved",
+		})
+	}
+	event := usecase_models.IncidentEvent{
+		IncidentId: incident.ID,
+		EventType:  usecase_models.IncidentEventResolved,
+		AccountId:  null.IntFrom(IdentityStruct.Id),
+		Note:       null.NewString(req.Note, req.Note != ""),
+	}
+	event.ID, err = hc.incidentsRepository.SaveIncidentEvent(ctx.Request().Context(), event)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	hc.dispatchIncidentUpdated(ctx.Request().Context(), incident.ID, event)
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    resolved,
+	})
+}
+
+// dispatchIncidentUpdated sends incident.updated webhooks with the incident as it is after the change
+func (hc *httpControllers) dispatchIncidentUpdated(ctx context.Context, incidentId int, event usecase_models.IncidentEvent) {
+	incident, err := hc.incidentsRepository.GetIncident(ctx, incidentId)
+	if err != nil {
+		log.Error("error on getting incident for webhooks: ", err)
+		return
+	}
+	event.CreatedAt = time.Now()
+	err = hc.webhookDispatcher.Dispatch(ctx, incident.ProjectId, usecase_models.WebhookEventIncidentUpdated, usecase_models.IncidentEventData{
+		Incident: incident,
+		Event:    event,
+	})
+	if err != nil {
+		log.Error("error on dispatching incident webhook: ", err)
+	}
+}
+
+// bindProjectIncident reads project_id and incident_id params and makes sure the caller can access the incident
+func (hc *httpControllers) bindProjectIncident(ctx echo.Context) (usecase_models.Incident, *utils.StandardHttpResponse) {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return usecase_models.Incident{}, &utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		}
+	}
+	incidentId, err := strconv.Atoi(ctx.Param("incident_id"))
+	if err != nil {
+		return usecase_models.Incident{}, &utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Incident ID"),
+			Status:  400,
+			Data:    err.Error(),
+		}
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return usecase_models.Incident{}, &utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		}
+	}
+	incident, err := hc.incidentsRepository.GetIncident(ctx.Request().Context(), incidentId)
+	if err != nil || incident.ProjectId != projectId {
+		return usecase_models.Incident{}, &utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		}
+	}
+	return incident, nil
+}
+
+// hasDatacenterAccess is true when the account can read the datacenter, every account reads the datacenters of the
+// platform and the projects of the account read their private datacenters. changes are guarded by canManageDatacenter
+func (hc *httpControllers) hasDatacenterAccess(ctx context.Context, datacenterId int) bool {
+	owners, err := hc.datacenterRepo.GetDataCenterProjects(ctx)
+	if err != nil {
+		return false
+	}
+	projectId, private := owners[datacenterId]
+	return !private || hc.hasProjectAccess(ctx, projectId)
+}
+
+// canManageDatacenter is true for the operators of the platform on its datacenters and for the projects of
+// the account on their private datacenters
+func (hc *httpControllers) canManageDatacenter(ctx context.Context, datacenterId int) bool {
+	owners, err := hc.datacenterRepo.GetDataCenterProjects(ctx)
+	if err != nil {
+		return false
+	}
+	if projectId, private := owners[datacenterId]; private {
+		return hc.hasProjectAccess(ctx, projectId)
+	}
+	return hc.isOperator()
+}
+
+// isOperator is true for the accounts in the operator_account_ids of the platform config
+func (hc *httpControllers) isOperator() bool {
+	if IdentityStruct == nil {
+		return false
+	}
+	for _, id := range hc.operatorAccountIds {
+		if id == IdentityStruct.Id {
+			return true
+		}
+	}
+	return false
+}
+
+func (hc *httpControllers) hasProjectAccess(ctx context.Context, projectId int) bool {
+	projects, err := hc.projectRepo.GetProjects(ctx, IdentityStruct.Id)
+	if err != nil {
+		return false
+	}
+	for _, value := range projects {
+		if value.ID == projectId {
+			return true
+		}
+	}
+	return false
+}
+
+func (hc *httpControllers) CreateMaintenanceWindow(ctx echo.Context) error {
+	req := new(usecase_models.MaintenanceWindowRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	window, err := maintenanceWindowFromRequest(*req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), window.ProjectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	windowId, err := hc.maintenanceRepository.SaveMaintenanceWindow(ctx.Request().Context(), window)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data: usecase_models.CreateMaintenanceWindowResponse{
+			MaintenanceWindowId: windowId,
+		}})
+}
+
+func (hc *httpControllers) GetMaintenanceWindows(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	windows, err := hc.maintenanceRepository.GetMaintenanceWindows(ctx.Request().Context(), projectId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    windows,
+	})
+}
+
+func (hc *httpControllers) UpdateMaintenanceWindow(ctx echo.Context) error {
+	req := new(usecase_models.MaintenanceWindowRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	windowId, err := strconv.Atoi(ctx.Param("maintenance_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Maintenance ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.maintenanceRepository.GetMaintenanceWindow(ctx.Request().Context(), windowId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.ProjectId = old.ProjectId
+	window, err := maintenanceWindowFromRequest(*req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	window.ID = windowId
+
+	err = hc.maintenanceRepository.UpdateMaintenanceWindow(ctx.Request().Context(), window)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) DeleteMaintenanceWindow(ctx echo.Context) error {
+	windowId, err := strconv.Atoi(ctx.Param("maintenance_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Maintenance ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	window, err := hc.maintenanceRepository.GetMaintenanceWindow(ctx.Request().Context(), windowId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), window.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err = hc.maintenanceRepository.DeleteMaintenanceWindow(ctx.Request().Context(), windowId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) CreateJsonSchema(ctx echo.Context) error {
+	req := new(usecase_models.JsonSchemaRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	schema, err := jsonSchemaFromRequest(*req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), schema.ProjectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	schemaId, err := hc.jsonSchemasRepository.SaveJsonSchema(ctx.Request().Context(), schema)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data: usecase_models.CreateJsonSchemaResponse{
+			JsonSchemaId: schemaId,
+		}})
+}
+
+func (hc *httpControllers) GetJsonSchemas(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	schemas, err := hc.jsonSchemasRepository.GetJsonSchemas(ctx.Request().Context(), projectId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    schemas,
+	})
+}
+
+func (hc *httpControllers) UpdateJsonSchema(ctx echo.Context) error {
+	req := new(usecase_models.JsonSchemaRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	schemaId, err := strconv.Atoi(ctx.Param("json_schema_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Json Schema ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.jsonSchemasRepository.GetJsonSchema(ctx.Request().Context(), schemaId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.ProjectId = old.ProjectId
+	schema, err := jsonSchemaFromRequest(*req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	schema.ID = schemaId
+
+	err = hc.jsonSchemasRepository.UpdateJsonSchema(ctx.Request().Context(), schema)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) DeleteJsonSchema(ctx echo.Context) error {
+	schemaId, err := strconv.Atoi(ctx.Param("json_schema_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Json Schema ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	schema, err := hc.jsonSchemasRepository.GetJsonSchema(ctx.Request().Context(), schemaId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), schema.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err = hc.jsonSchemasRepository.DeleteJsonSchema(ctx.Request().Context(), schemaId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) CreateCronMonitor(ctx echo.Context) error {
+	req := new(usecase_models.CronMonitorRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	monitor, err := cronMonitorFromRequest(*req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), monitor.ProjectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	monitor.Token, err = utils.GenerateToken(20)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	monitorId, err := hc.cronMonitorsRepository.SaveCronMonitor(ctx.Request().Context(), monitor)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data: usecase_models.CreateCronMonitorResponse{
+			CronMonitorId: monitorId,
+			Token:         monitor.Token,
+		}})
+}
+
+func (hc *httpControllers) GetCronMonitors(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	monitors, err := hc.cronMonitorsRepository.GetCronMonitors(ctx.Request().Context(), projectId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    monitors,
+	})
+}
+
+func (hc *httpControllers) UpdateCronMonitor(ctx echo.Context) error {
+	req := new(usecase_models.CronMonitorRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	monitorId, err := strconv.Atoi(ctx.Param("cron_monitor_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Cron Monitor ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.cronMonitorsRepository.GetCronMonitor(ctx.Request().Context(), monitorId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.ProjectId = old.ProjectId
+	monitor, err := cronMonitorFromRequest(*req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	monitor.ID = monitorId
+
+	err = hc.cronMonitorsRepository.UpdateCronMonitor(ctx.Request().Context(), monitor)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) DeleteCronMonitor(ctx echo.Context) error {
+	monitorId, err := strconv.Atoi(ctx.Param("cron_monitor_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Cron Monitor ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	monitor, err := hc.cronMonitorsRepository.GetCronMonitor(ctx.Request().Context(), monitorId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), monitor.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err = hc.cronMonitorsRepository.DeleteCronMonitor(ctx.Request().Context(), monitorId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) GetCronMonitorPings(ctx echo.Context) error {
+	monitorId, err := strconv.Atoi(ctx.Param("cron_monitor_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Cron Monitor ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	monitor, err := hc.cronMonitorsRepository.GetCronMonitor(ctx.Request().Context(), monitorId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), monitor.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	pings, err := hc.cronMonitorsRepository.GetPings(ctx.Request().Context(), monitorId, CronMonitorPingsLimit)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    pings,
+	})
+}
+
+// PingCronMonitor is called by the watched jobs, the token in the url is the only credential
+func (hc *httpControllers) PingCronMonitor(ctx echo.Context) error {
+	kind := ctx.Param("kind")
+	if kind == "" {
+		kind = usecase_models.CronPingKindPing
+	}
+	switch kind {
+	case usecase_models.CronPingKindStart, usecase_models.CronPingKindSuccess, usecase_models.CronPingKindFail, usecase_models.CronPingKindPing:
+	default:
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err := hc.cronMonitorsRepository.RecordPing(ctx.Request().Context(), ctx.Param("token"), kind, ctx.RealIP())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) RegisterTLSRules(ctx echo.Context) error {
+	req := new(usecase_models.TLSMonitors)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	err := hc.rulesHandler.RegisterRules(ctx.Request().Context(), usecase_models.RulesRequest{TLSMonitors: *req})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+func (hc *httpControllers) ManualRunTLSRules(ctx echo.Context) error {
+	req := new(usecase_models.TLSMonitors)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if err := validateTLSRules(*req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	err := hc.tlsHandler.ExecuteTLSRule(ctx.Request().Context(), *req)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+func (hc *httpControllers) GetTLSRules(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if id == 0 {
+		projectId, err := strconv.Atoi(ctx.Param("project_id"))
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+				Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+				Status:  400,
+				Data:    err.Error(),
+			})
+		}
+		if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+			return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+				Message: utils.NoAccess,
+				Status:  403,
+				Data:    "you dont have access to this project",
+			})
+		}
+		tlsMonitors, err := hc.tlsMonitorsRepository.GetTLSMonitors(ctx.Request().Context(), projectId)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+				Message: utils.ProblemInGettingData,
+				Status:  500,
+				Data:    err.Error(),
+			})
+		}
+		return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+			Message: utils.Ok,
+			Status:  200,
+			Data:    tlsMonitors,
+		})
+	}
+	tlsMonitor, err := hc.tlsMonitorsRepository.GetTLSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), tlsMonitor.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    tlsMonitor,
+	})
+}
+
+func (hc *httpControllers) UpdateTLSRules(ctx echo.Context) error {
+	req := new(usecase_models.TLSMonitors)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if err := validateScheduling(req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "scheduling"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if err := validateTLSRules(*req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.tlsMonitorsRepository.GetTLSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.Scheduling.ProjectId = old.Scheduling.ProjectId
+	req.Scheduling.EndAt = old.Scheduling.EndAt
+	if err := validateSchedulingDataCenters(ctx.Request().Context(), hc.datacenterRepo, old.Scheduling.ProjectId, req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "data_centers"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	err = hc.tlsMonitorsRepository.UpdateTLSMonitor(ctx.Request().Context(), id, *req)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusCreated, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+func (hc *httpControllers) DeleteTLSRules(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	tlsMonitor, err := hc.tlsMonitorsRepository.GetTLSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), tlsMonitor.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err = hc.tlsMonitorsRepository.DeleteTLSMonitor(ctx.Request().Context(), id)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+// ReportTLSCertificates lists the chain, issuer, SANs and days until expiry each datacenter saw on the last session
+func (hc *httpControllers) ReportTLSCertificates(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.QueryParam("tls_monitor_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "TLS Monitor ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	tlsMonitor, err := hc.tlsMonitorsRepository.GetTLSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), tlsMonitor.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	certificates, err := hc.tlsStatsRepository.GetLastSession(ctx.Request().Context(), id)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    certificates,
+	})
+}
+
+func (hc *httpControllers) RegisterDNSRules(ctx echo.Context) error {
+	req := new(usecase_models.DNSMonitors)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	err := hc.rulesHandler.RegisterRules(ctx.Request().Context(), usecase_models.RulesRequest{DNSMonitors: *req})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+func (hc *httpControllers) ManualRunDNSRules(ctx echo.Context) error {
+	req := new(usecase_models.DNSMonitors)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if err := validateDNSRules(*req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	err := hc.dnsHandler.ExecuteDNSRule(ctx.Request().Context(), *req)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+func (hc *httpControllers) GetDNSRules(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if id == 0 {
+		projectId, err := strconv.Atoi(ctx.Param("project_id"))
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+				Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+				Status:  400,
+				Data:    err.Error(),
+			})
+		}
+		if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+			return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+				Message: utils.NoAccess,
+				Status:  403,
+				Data:    "you dont have access to this project",
+			})
+		}
+		dnsMonitors, err := hc.dnsMonitorsRepository.GetDNSMonitors(ctx.Request().Context(), projectId)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+				Message: utils.ProblemInGettingData,
+				Status:  500,
+				Data:    err.Error(),
+			})
+		}
+		return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+			Message: utils.Ok,
+			Status:  200,
+			Data:    dnsMonitors,
+		})
+	}
+	dnsMonitor, err := hc.dnsMonitorsRepository.GetDNSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), dnsMonitor.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    dnsMonitor,
+	})
+}
+
+func (hc *httpControllers) UpdateDNSRules(ctx echo.Context) error {
+	req := new(usecase_models.DNSMonitors)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if err := validateScheduling(req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "scheduling"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if err := validateDNSRules(*req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.dnsMonitorsRepository.GetDNSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.Scheduling.ProjectId = old.Scheduling.ProjectId
+	req.Scheduling.EndAt = old.Scheduling.EndAt
+	if err := validateSchedulingDataCenters(ctx.Request().Context(), hc.datacenterRepo, old.Scheduling.ProjectId, req.Scheduling); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "data_centers"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	err = hc.dnsMonitorsRepository.UpdateDNSMonitor(ctx.Request().Context(), id, *req)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusCreated, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+func (hc *httpControllers) DeleteDNSRules(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	dnsMonitor, err := hc.dnsMonitorsRepository.GetDNSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), dnsMonitor.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err = hc.dnsMonitorsRepository.DeleteDNSMonitor(ctx.Request().Context(), id)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    "ok",
+	})
+}
+
+// ReportDNSAnswers lists the answers and resolution time of every record each datacenter saw on the last session
+func (hc *httpControllers) ReportDNSAnswers(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.QueryParam("dns_monitor_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "DNS Monitor ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	dnsMonitor, err := hc.dnsMonitorsRepository.GetDNSMonitor(ctx.Request().Context(), id)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), dnsMonitor.Scheduling.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	answers, err := hc.dnsStatsRepository.GetLastSession(ctx.Request().Context(), id)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInGettingData,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    answers,
+	})
+}
+
+func maintenanceWindowFromRequest(req usecase_models.MaintenanceWindowRequest) (usecase_models.MaintenanceWindow, error) {
+	startsAt, err := time.Parse("2006-01-02 15:04:05", req.StartsAt)
+	if err != nil {
+		return usecase_models.MaintenanceWindow{}, errors.New("starts_at must be in 2006-01-02 15:04:05 format")
+	}
+	endsAt, err := time.Parse("2006-01-02 15:04:05", req.EndsAt)
+	if err != nil {
+		return usecase_models.MaintenanceWindow{}, errors.New("ends_at must be in 2006-01-02 15:04:05 format")
+	}
+	if !endsAt.After(startsAt) {
+		return usecase_models.MaintenanceWindow{}, errors.New("ends_at must be after starts_at")
+	}
+	switch req.Recurrence {
+	case usecase_models.MaintenanceRecurrenceNone, usecase_models.MaintenanceRecurrenceDaily,
+		usecase_models.MaintenanceRecurrenceWeekly, usecase_models.MaintenanceRecurrenceMonthly:
+	default:
+		return usecase_models.MaintenanceWindow{}, errors.New("recurrence must be one of daily, weekly or monthly")
+	}
+	if (req.PipelineType == "") != (req.PipelineId == 0) {
+		return usecase_models.MaintenanceWindow{}, errors.New("pipeline_type and pipeline_id must be set together")
+	}
+
+	window := usecase_models.MaintenanceWindow{
+		ProjectId:    req.ProjectId,
+		PipelineType: null.NewString(req.PipelineType, req.PipelineType != ""),
+		PipelineId:   null.NewInt(req.PipelineId, req.PipelineId != 0),
+		Title:        req.Title,
+		StartsAt:     startsAt,
+		EndsAt:       endsAt,
+		Recurrence:   req.Recurrence,
+	}
+	if req.RepeatUntil != "" {
+		repeatUntil, err := time.Parse("2006-01-02 15:04:05", req.RepeatUntil)
+		if err != nil {
+			return usecase_models.MaintenanceWindow{}, errors.New("repeat_until must be in 2006-01-02 15:04:05 format")
+		}
+		window.RepeatUntil = null.TimeFrom(repeatUntil)
+	}
+	return window, nil
+}
+
+func (hc *httpControllers) CreateStatusPage(ctx echo.Context) error {
+	req := new(usecase_models.StatusPageRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), req.ProjectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+	page, err := hc.statusPageFromRequest(ctx.Request().Context(), *req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+
+	pageId, err := hc.statusPagesRepository.SaveStatusPage(ctx.Request().Context(), page)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data: usecase_models.CreateStatusPageResponse{
+			StatusPageId: pageId,
+		}})
+}
+
+func (hc *httpControllers) GetStatusPages(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	pages, err := hc.statusPagesRepository.GetStatusPages(ctx.Request().Context(), projectId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    pages,
+	})
+}
+
+func (hc *httpControllers) UpdateStatusPage(ctx echo.Context) error {
+	req := new(usecase_models.StatusPageRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	pageId, err := strconv.Atoi(ctx.Param("status_page_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Status Page ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	old, err := hc.statusPagesRepository.GetStatusPage(ctx.Request().Context(), pageId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), old.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+	req.ProjectId = old.ProjectId
+	page, err := hc.statusPageFromRequest(ctx.Request().Context(), *req)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	page.ID = pageId
+
+	err = hc.statusPagesRepository.UpdateStatusPage(ctx.Request().Context(), page)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+func (hc *httpControllers) DeleteStatusPage(ctx echo.Context) error {
+	pageId, err := strconv.Atoi(ctx.Param("status_page_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Status Page ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	page, err := hc.statusPagesRepository.GetStatusPage(ctx.Request().Context(), pageId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), page.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err = hc.statusPagesRepository.DeleteStatusPage(ctx.Request().Context(), pageId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+// GetPublicStatusPage is not authenticated, anyone with the slug can see the page
+func (hc *httpControllers) GetPublicStatusPage(ctx echo.Context) error {
+	page, err := hc.getPublicStatusPage(ctx.Request().Context(), ctx.Param("slug"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+				Message: utils.NotFound,
+				Status:  404,
+				Data:    nil,
+			})
+		}
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    nil,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    page,
+	})
+}
+
+func (hc *httpControllers) GetPublicStatusPageHtml(ctx echo.Context) error {
+	page, err := hc.getPublicStatusPage(ctx.Request().Context(), ctx.Param("slug"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx.String(http.StatusNotFound, utils.NotFound)
+		}
+		return ctx.String(http.StatusInternalServerError, utils.ProblemInSystem)
+	}
+
+	var body strings.Builder
+	if err = statusPageTemplate.Execute(&body, page); err != nil {
+		return ctx.String(http.StatusInternalServerError, utils.ProblemInSystem)
+	}
+	return ctx.HTML(http.StatusOK, body.String())
+}
+
+func (hc *httpControllers) statusPageFromRequest(ctx context.Context, req usecase_models.StatusPageRequest) (usecase_models.StatusPage, error) {
+	if req.Slug == "" || req.Title == "" {
+		return usecase_models.StatusPage{}, errors.New("slug and title are required")
+	}
+	for _, c := range req.Slug {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return usecase_models.StatusPage{}, errors.New("slug may only contain lowercase letters, digits and dashes")
+		}
+	}
+
+	page := usecase_models.StatusPage{
+		ProjectId:   req.ProjectId,
+		Slug:        req.Slug,
+		Title:       req.Title,
+		Description: null.NewString(req.Description, req.Description != ""),
+	}
+	for _, component := range req.Components {
+		scheduling, err := hc.getPipelineScheduling(ctx, component.PipelineType, component.PipelineId)
+		if err != nil || scheduling.ProjectId != req.ProjectId {
+			return usecase_models.StatusPage{}, fmt.Errorf("%s %d is not a pipeline of this project", component.PipelineType, component.PipelineId)
+		}
+		name := component.Name
+		if name == "" {
+			name = scheduling.PipelineName
+		}
+		page.Components = append(page.Components, usecase_models.StatusPageComponent{
+			Name:         name,
+			PipelineType: component.PipelineType,
+			PipelineId:   component.PipelineId,
+		})
+	}
+	return page, nil
+}
+
+func (hc *httpControllers) EnableBadge(ctx echo.Context) error {
+	req := new(usecase_models.BadgeRequest)
+	if err := ctx.Bind(req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: utils.NotValidData,
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), req.ProjectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+	scheduling, err := hc.getPipelineScheduling(ctx.Request().Context(), req.PipelineType, req.PipelineId)
+	if err != nil || scheduling.ProjectId != req.ProjectId {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	token, err := utils.GenerateToken(20)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+	badgeId, err := hc.badgesRepository.EnableBadge(ctx.Request().Context(), usecase_models.Badge{
+		ProjectId:    req.ProjectId,
+		PipelineType: req.PipelineType,
+		PipelineId:   req.PipelineId,
+		Token:        token,
+	})
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data: usecase_models.EnableBadgeResponse{
+			BadgeId:   badgeId,
+			Token:     token,
+			UptimeUrl: fmt.Sprintf("/badge/%d/uptime?token=%s", req.PipelineId, token),
+			StatusUrl: fmt.Sprintf("/badge/%d/status?token=%s", req.PipelineId, token),
+		}})
+}
+
+func (hc *httpControllers) GetBadges(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	badges, err := hc.badgesRepository.GetBadges(ctx.Request().Context(), projectId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    badges,
+	})
+}
+
+func (hc *httpControllers) DeleteBadge(ctx echo.Context) error {
+	badgeId, err := strconv.Atoi(ctx.Param("badge_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Badge ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	badge, err := hc.badgesRepository.GetBadge(ctx.Request().Context(), badgeId)
+	if err != nil || !hc.hasProjectAccess(ctx.Request().Context(), badge.ProjectId) {
+		return ctx.JSON(http.StatusNotFound, utils.StandardHttpResponse{
+			Message: utils.NotFound,
+			Status:  404,
+			Data:    nil,
+		})
+	}
+
+	err = hc.badgesRepository.DeleteBadge(ctx.Request().Context(), badgeId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    nil,
+	})
+}
+
+// GetUptimeBadge is not authenticated, the badge token of the pipeline is required instead
+func (hc *httpControllers) GetUptimeBadge(ctx echo.Context) error {
+	badge, ok := hc.bindBadge(ctx)
+	if !ok {
+		return ctx.String(http.StatusNotFound, utils.NotFound)
+	}
+	window, err := parseBadgeWindow(ctx.QueryParam("window"))
+	if err != nil {
+		return ctx.String(http.StatusBadRequest, fmt.Sprintf(utils.NotValidField, "window"))
+	}
+
+	successes, err := hc.getSessionSuccesses(ctx.Request().Context(), badge.PipelineType, badge.PipelineId, time.Now().Add(-window), false)
+	if err != nil {
+		return ctx.String(http.StatusInternalServerError, utils.ProblemInSystem)
+	}
+
+	message, color := "no data", badgeColorGrey
+	if len(successes) > 0 {
+		success := 0
+		for _, value := range successes {
+			if value {
+				success++
+			}
+		}
+		uptime := float64(success) * 100 / float64(len(successes))
+		message, color = fmt.Sprintf("%.2f%%", uptime), uptimeBadgeColor(uptime)
+	}
+	label := "uptime 30d"
+	if ctx.QueryParam("window") != "" {
+		label = "uptime " + ctx.QueryParam("window")
+	}
+	return hc.writeBadge(ctx, label, message, color)
+}
+
+// GetStatusBadge is not authenticated, the badge token of the pipeline is required instead
+func (hc *httpControllers) GetStatusBadge(ctx echo.Context) error {
+	badge, ok := hc.bindBadge(ctx)
+	if !ok {
+		return ctx.String(http.StatusNotFound, utils.NotFound)
+	}
+
+	successes, err := hc.getSessionSuccesses(ctx.Request().Context(), badge.PipelineType, badge.PipelineId, time.Now().Add(-BadgeStatusWindow), true)
+	if err != nil {
+		return ctx.String(http.StatusInternalServerError, utils.ProblemInSystem)
+	}
+
+	message, color := "unknown", badgeColorGrey
+	if len(successes) > 0 {
+		if successes[0] {
+			message, color = "up", badgeColorGreen
+		} else {
+			message, color = "down", badgeColorRed
+		}
+	}
+	return hc.writeBadge(ctx, "status", message, color)
+}
+
+// bindBadge finds the badge of the token and checks it belongs to the pipeline of the url
+func (hc *httpControllers) bindBadge(ctx echo.Context) (usecase_models.Badge, bool) {
+	pipelineId, err := strconv.Atoi(ctx.Param("pipeline_id"))
+	if err != nil || ctx.QueryParam("token") == "" {
+		return usecase_models.Badge{}, false
+	}
+	badge, err := hc.badgesRepository.GetBadgeByToken(ctx.Request().Context(), ctx.QueryParam("token"))
+	if err != nil || badge.PipelineId != pipelineId {
+		return usecase_models.Badge{}, false
+	}
+	return badge, true
+}
+
+func (hc *httpControllers) writeBadge(ctx echo.Context, label string, message string, color string) error {
+	svg, err := renderBadge(label, message, color)
+	if err != nil {
+		return ctx.String(http.StatusInternalServerError, utils.ProblemInSystem)
+	}
+	ctx.Response().Header().Set(echo.HeaderCacheControl, BadgeCacheControl)
+	return ctx.Blob(http.StatusOK, "image/svg+xml", []byte(svg))
+}
+
+func validNotificationTargets(notifications usecase_models.Notifications) bool {
+	for _, webhook := range notifications.Webhooks {
+		if !utils.IsPublicUrl(webhook.Url) || webhook.Secret == "" {
+			return false
+		}
+	}
+	for _, target := range notifications.Targets {
+		if target.Target == "" {
+			return false
+		}
+		switch target.Driver {
+		case notifier.DriverSlack, notifier.DriverWebhook:
+			// the manager posts to these urls itself
+			if !utils.IsPublicUrl(target.Target) {
+				return false
+			}
+		case notifier.DriverTelegram, notifier.DriverSmtp, notifier.DriverPagerDuty:
+		case notifier.DriverAlertService:
+			switch target.Channel {
+			case usecase_models.NotificationChannelSlack, usecase_models.NotificationChannelTelegram, usecase_models.NotificationChannelEmail:
+			default:
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (hc *httpControllers) GetWebhookDeliveries(ctx echo.Context) error {
+	projectId, err := strconv.Atoi(ctx.Param("project_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, utils.StandardHttpResponse{
+			Message: fmt.Sprintf(utils.NotValidField, "Project ID"),
+			Status:  400,
+			Data:    err.Error(),
+		})
+	}
+	if !hc.hasProjectAccess(ctx.Request().Context(), projectId) {
+		return ctx.JSON(http.StatusForbidden, utils.StandardHttpResponse{
+			Message: utils.NoAccess,
+			Status:  403,
+			Data:    "you dont have access to this project",
+		})
+	}
+
+	page := 1
+	perPage := 10
+	if p, err := strconv.Atoi(ctx.QueryParam("page")); err == nil {
+		page = p
+	}
+	if pp, err := strconv.Atoi(ctx.QueryParam("per_page")); err == nil {
+		perPage = pp
+	}
+
+	filter := repos.Filters{
+		repos.Filter{Field: "project_id", Op: repos.FilterOpEq, Value: projectId},
+	}
+	if eventType := ctx.QueryParam("event_type"); eventType != "" {
+		filter = append(filter, repos.Filter{Field: "event_type", Op: repos.FilterOpEq, Value: eventType})
+	}
+	if eventId := ctx.QueryParam("event_id"); eventId != "" {
+		filter = append(filter, repos.Filter{Field: "event_id", Op: repos.FilterOpEq, Value: eventId})
+	}
+	if status := ctx.QueryParam("status"); status != "" {
+		filter = append(filter, repos.Filter{Field: "status", Op: repos.FilterOpEq, Value: status})
+	}
+	if url := ctx.QueryParam("url"); url != "" {
+		filter = append(filter, repos.Filter{Field: "url", Op: repos.FilterOpEq, Value: url})
+	}
+	if from, err := time.Parse("2006-01-02 15:04:05", ctx.QueryParam("from")); err == nil {
+		filter = append(filter, repos.Filter{Field: "created_at", Op: repos.FilterOpGte, Value: from})
+	}
+	if to, err := time.Parse("2006-01-02 15:04:05", ctx.QueryParam("to")); err == nil {
+		filter = append(filter, repos.Filter{Field: "created_at", Op: repos.FilterOpLte, Value: to})
+	}
+
+	deliveries, err := hc.webhookDeliveriesRepo.GetDeliveries(ctx.Request().Context(), filter, perPage,
+		int(utils.OffsetFromPage(int64(page), int64(perPage))))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, utils.StandardHttpResponse{
+			Message: utils.ProblemInSystem,
+			Status:  500,
+			Data:    err.Error(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, utils.StandardHttpResponse{
+		Message: utils.Ok,
+		Status:  200,
+		Data:    deliveries,
+	})
+}
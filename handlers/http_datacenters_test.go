@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"test-manager/repos"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"testing"
+)
+
+type fakeDatacentersRepo struct {
+	repos.DataCentersRepository
+	owners map[int]int
+}
+
+func (f *fakeDatacentersRepo) GetDataCenterProjects(ctx context.Context) (map[int]int, error) {
+	return f.owners, nil
+}
+
+func (f *fakeDatacentersRepo) GetDataCenter(ctx context.Context, id int) (models.Datacenter, error) {
+	return models.Datacenter{ID: id}, nil
+}
+
+type fakeProjectsRepo struct {
+	repos.ProjectsRepository
+	projects map[int][]int
+}
+
+func (f *fakeProjectsRepo) GetProjects(ctx context.Context, accountId int) ([]*models.Project, error) {
+	var projects []*models.Project
+	for _, id := range f.projects[accountId] {
+		projects = append(projects, &models.Project{ID: id})
+	}
+	return projects, nil
+}
+
+type fakeDatacenterKeysRepo struct {
+	repos.DatacenterKeysRepository
+	keys             []usecase_models.DatacenterKey
+	created, revoked int
+}
+
+func (f *fakeDatacenterKeysRepo) CreateKey(ctx context.Context, datacenterId int) (usecase_models.DatacenterKey, error) {
+	f.created++
+	return usecase_models.DatacenterKey{KeyId: "key", Secret: "secret"}, nil
+}
+
+func (f *fakeDatacenterKeysRepo) GetKeys(ctx context.Context, datacenterId int) ([]usecase_models.DatacenterKey, error) {
+	return f.keys, nil
+}
+
+func (f *fakeDatacenterKeysRepo) GetActiveKeys(ctx context.Context, datacenterId int) ([]usecase_models.DatacenterKey, error) {
+	var keys []usecase_models.DatacenterKey
+	for _, key := range f.keys {
+		if !key.RevokedAt.Valid {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeDatacenterKeysRepo) RevokeKey(ctx context.Context, datacenterId int, keyId string) error {
+	f.revoked++
+	return nil
+}
+
+func TestDatacenterKeysAccess(t *testing.T) {
+	const (
+		operator = 1
+		owner    = 2
+		foreign  = 3
+
+		platformDatacenter = 1
+		privateDatacenter  = 5
+	)
+	keys := &fakeDatacenterKeysRepo{}
+	hc := &httpControllers{
+		datacenterRepo:           &fakeDatacentersRepo{owners: map[int]int{privateDatacenter: 10}},
+		projectRepo:              &fakeProjectsRepo{projects: map[int][]int{owner: {10}, foreign: {20}}},
+		datacenterKeysRepository: keys,
+		operatorAccountIds:       []int{operator},
+	}
+	defer func(identity *Identity) { IdentityStruct = identity }(IdentityStruct)
+
+	endpoints := []struct {
+		name    string
+		method  string
+		handler echo.HandlerFunc
+	}{
+		{"create", http.MethodPost, hc.CreateDatacenterKey},
+		{"list", http.MethodGet, hc.GetDatacenterKeys},
+		{"revoke", http.MethodDelete, hc.RevokeDatacenterKey},
+	}
+	tests := []struct {
+		name       string
+		accountId  int
+		datacenter int
+		status     int
+	}{
+		{"foreign account on platform datacenter", foreign, platformDatacenter, http.StatusForbidden},
+		{"foreign account on private datacenter", foreign, privateDatacenter, http.StatusForbidden},
+		{"project owner on platform datacenter", owner, platformDatacenter, http.StatusForbidden},
+		{"project owner on private datacenter", owner, privateDatacenter, http.StatusOK},
+		{"operator on platform datacenter", operator, platformDatacenter, http.StatusOK},
+		{"operator on private datacenter", operator, privateDatacenter, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		for _, endpoint := range endpoints {
+			t.Run(tt.name+"/"+endpoint.name, func(t *testing.T) {
+				keys.created, keys.revoked = 0, 0
+				IdentityStruct = &Identity{Id: tt.accountId}
+
+				rec := httptest.NewRecorder()
+				ctx := echo.New().NewContext(httptest.NewRequest(endpoint.method, "/", nil), rec)
+				ctx.SetParamNames("datacenter_id", "key_id")
+				ctx.SetParamValues(strconv.Itoa(tt.datacenter), "key")
+				if err := endpoint.handler(ctx); err != nil {
+					t.Fatal(err)
+				}
+				if rec.Code != tt.status {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+				}
+				if tt.status == http.StatusForbidden && keys.created+keys.revoked != 0 {
+					t.Fatal("keys were changed without access")
+				}
+			})
+		}
+	}
+}
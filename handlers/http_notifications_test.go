@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"test-manager/services/notifier"
+	"test-manager/usecase_models"
+	"testing"
+)
+
+func TestValidNotificationTargets(t *testing.T) {
+	target := func(driver, value string) usecase_models.Notifications {
+		return usecase_models.Notifications{Targets: []usecase_models.NotificationTarget{{Driver: driver, Target: value}}}
+	}
+	webhook := func(url string) usecase_models.Notifications {
+		return usecase_models.Notifications{Webhooks: []usecase_models.WebhookSubscription{{Url: url, Secret: "s3cret"}}}
+	}
+	tests := []struct {
+		name          string
+		notifications usecase_models.Notifications
+		valid         bool
+	}{
+		{"slack webhook", target(notifier.DriverSlack, "https://hooks.slack.com/services/T0/B0/x"), true},
+		{"webhook on a public ip", target(notifier.DriverWebhook, "http://93.184.216.34/alerts"), true},
+		{"webhook without a scheme", target(notifier.DriverWebhook, "example.com/alerts"), false},
+		{"webhook with another scheme", target(notifier.DriverWebhook, "gopher://example.com/alerts"), false},
+		{"webhook to localhost", target(notifier.DriverWebhook, "http://localhost:8080/alerts"), false},
+		{"slack to loopback", target(notifier.DriverSlack, "http://127.0.0.1:6379/"), false},
+		{"webhook to a private network", target(notifier.DriverWebhook, "http://10.0.0.5/alerts"), false},
+		{"webhook to cloud metadata", target(notifier.DriverWebhook, "http://169.254.169.254/latest/meta-data"), false},
+		{"webhook to ipv6 loopback", target(notifier.DriverWebhook, "http://[::1]/alerts"), false},
+		{"telegram chat id", target(notifier.DriverTelegram, "-100123"), true},
+		{"empty target", target(notifier.DriverSmtp, ""), false},
+		{"webhook subscription", webhook("https://example.com/events"), true},
+		{"webhook subscription to a private network", webhook("https://192.168.1.10/events"), false},
+		{"webhook subscription to loopback", webhook("http://127.0.0.1:9000/events"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := validNotificationTargets(tt.notifications); valid != tt.valid {
+				t.Fatalf("valid = %v, want %v", valid, tt.valid)
+			}
+		})
+	}
+}
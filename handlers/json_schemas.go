@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xeipuuv/gojsonschema"
+	"strings"
+	"test-manager/usecase_models"
+)
+
+// MaxJsonSchemaViolations caps the violations stored with a session, a broken contract can report one per item
+const MaxJsonSchemaViolations = 20
+
+// responseSchema is the compiled schema of a step, err is reported as a violation when the schema could not be loaded
+type responseSchema struct {
+	schema *gojsonschema.Schema
+	err    error
+}
+
+func compileJsonSchema(schema []byte) (*gojsonschema.Schema, error) {
+	return gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+}
+
+// loadResponseSchemas compiles the schema of every step once per run, steps without a schema are nil
+func (e *endpointHandler) loadResponseSchemas(ctx context.Context, endpointRules usecase_models.Endpoints) []*responseSchema {
+	schemas := make([]*responseSchema, len(endpointRules.Endpoints))
+	for i, rule := range endpointRules.Endpoints {
+		switch {
+		case len(rule.ResponseSchema) != 0:
+			schema, err := compileJsonSchema(rule.ResponseSchema)
+			schemas[i] = &responseSchema{schema: schema, err: err}
+		case rule.ResponseSchemaId != 0:
+			stored, err := e.jsonSchemasRepo.GetJsonSchema(ctx, rule.ResponseSchemaId)
+			if err != nil || stored.ProjectId != endpointRules.Scheduling.ProjectId {
+				schemas[i] = &responseSchema{err: fmt.Errorf("json schema %d not found", rule.ResponseSchemaId)}
+				continue
+			}
+			schema, err := compileJsonSchema(stored.Schema.JSON)
+			schemas[i] = &responseSchema{schema: schema, err: err}
+		}
+	}
+	return schemas
+}
+
+// violations validates the body and returns each schema violation as a failed assertion
+func (r *responseSchema) violations(body string) []usecase_models.AssertionFailure {
+	if r == nil {
+		return nil
+	}
+	if r.err != nil {
+		return []usecase_models.AssertionFailure{{
+			Key:      "schema",
+			Operator: usecase_models.AssertionOpSchema,
+			Message:  fmt.Sprintf("json schema could not be loaded: %s", r.err.Error()),
+		}}
+	}
+
+	result, err := r.schema.Validate(gojsonschema.NewStringLoader(body))
+	if err != nil {
+		return []usecase_models.AssertionFailure{{
+			Key:      "schema",
+			Operator: usecase_models.AssertionOpSchema,
+			Message:  fmt.Sprintf("response is not valid json: %s", err.Error()),
+		}}
+	}
+	var failures []usecase_models.AssertionFailure
+	for _, violation := range result.Errors() {
+		if len(failures) == MaxJsonSchemaViolations {
+			break
+		}
+		failures = append(failures, usecase_models.AssertionFailure{
+			Key:      violation.Field(),
+			Operator: usecase_models.AssertionOpSchema,
+			Expected: violation.Type(),
+			Actual:   fmt.Sprint(violation.Value()),
+			Message:  violation.String(),
+		})
+	}
+	return failures
+}
+
+// validateResponseSchema checks an inline schema of a step before it is saved
+func validateResponseSchema(rule usecase_models.EndpointRules) error {
+	if len(rule.ResponseSchema) != 0 && rule.ResponseSchemaId != 0 {
+		return errors.New("either response_schema or response_schema_id can be set")
+	}
+	if len(rule.ResponseSchema) != 0 {
+		if _, err := compileJsonSchema(rule.ResponseSchema); err != nil {
+			return fmt.Errorf("not valid json schema: %w", err)
+		}
+	}
+	return nil
+}
+
+func jsonSchemaFromRequest(req usecase_models.JsonSchemaRequest) (usecase_models.JsonSchema, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return usecase_models.JsonSchema{}, errors.New("name is required")
+	}
+	if _, err := compileJsonSchema(req.Schema); err != nil {
+		return usecase_models.JsonSchema{}, fmt.Errorf("not valid json schema: %w", err)
+	}
+	schema := usecase_models.JsonSchema{
+		ProjectId: req.ProjectId,
+		Name:      req.Name,
+	}
+	schema.Schema.SetValid(req.Schema)
+	return schema, nil
+}
@@ -2,17 +2,16 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/labstack/gommon/log"
-	"math/rand"
 	"strings"
 	"sync"
+	"test-manager/cache"
 	"test-manager/repos"
 	"test-manager/services/alert_system"
 	"test-manager/tasks/push"
-	"test-manager/tasks/task_models"
 	"test-manager/usecase_models"
-	models "test-manager/usecase_models/boiler"
 	"time"
 )
 
@@ -26,310 +25,115 @@ type pageSpeedHandler struct {
 	dataCentersRepo    repos.DataCentersRepository
 	projectRepo        repos.ProjectsRepository
 	pageSpeedStatsRepo repos.PageSpeedStatsRepository
+	cacheRepo          cache.Cache
 	taskPusher         push.TaskPusher
 	agentHandler       AgentHandler
+	sessionNotifier    *sessionNotifier
+	dataCenterSelector *dataCenterSelector
 }
 
-func NewPageSpeedHandler(alertSystem alert_system.AlertHandler, pageSpeedRepo repos.PageSpeedRepository, dataCentersRepo repos.DataCentersRepository, projectRepo repos.ProjectsRepository, pageSpeedStatsRepo repos.PageSpeedStatsRepository, taskPusher push.TaskPusher, agentHandler AgentHandler) PageSpeedHandler {
+func NewPageSpeedHandler(
+	alertSystem alert_system.AlertHandler,
+	pageSpeedRepo repos.PageSpeedRepository,
+	dataCentersRepo repos.DataCentersRepository,
+	projectRepo repos.ProjectsRepository,
+	pageSpeedStatsRepo repos.PageSpeedStatsRepository,
+	cacheRepo cache.Cache,
+	maintenanceRepo repos.MaintenanceWindowsRepository,
+	monitorStatesRepo repos.MonitorStatesRepository,
+	taskPusher push.TaskPusher,
+	agentHandler AgentHandler,
+) PageSpeedHandler {
 	return &pageSpeedHandler{
 		alertSystem:        alertSystem,
 		pageSpeedRepo:      pageSpeedRepo,
 		dataCentersRepo:    dataCentersRepo,
 		projectRepo:        projectRepo,
 		pageSpeedStatsRepo: pageSpeedStatsRepo,
+		cacheRepo:          cacheRepo,
 		taskPusher:         taskPusher,
 		agentHandler:       agentHandler,
+		sessionNotifier:    newSessionNotifier(projectRepo, dataCentersRepo, maintenanceRepo, monitorStatesRepo, cacheRepo, taskPusher),
+		dataCenterSelector: newDataCenterSelector(dataCentersRepo, cacheRepo),
 	}
 }
 
 func (e *pageSpeedHandler) ExecutePageSpeedRule(ctx context.Context, pageSpeedRules usecase_models.PageSpeeds) error {
-	if len(pageSpeedRules.Scheduling.DataCentersIds) == 1 && pageSpeedRules.Scheduling.DataCentersIds[0] == 0 {
-		datacenters, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err == nil {
-			// random number between one and the number of datacenters
-			pageSpeedRules.Scheduling.DataCentersIds = []int{}
-			pageSpeedRules.Scheduling.DataCentersIds = append(pageSpeedRules.Scheduling.DataCentersIds, datacenters[rand.Intn(len(datacenters))].ID)
-		}
-	} else if len(pageSpeedRules.Scheduling.DataCentersIds) == 0 {
-		datacenters, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err == nil {
-			// random number between one and the number of datacenters
-			pageSpeedRules.Scheduling.DataCentersIds = []int{}
-			for _, value := range datacenters {
-				pageSpeedRules.Scheduling.DataCentersIds = append(pageSpeedRules.Scheduling.DataCentersIds, value.ID)
-			}
-		}
+	dataCentersIds, err := e.dataCenterSelector.selectDataCenters(ctx, "pagespeed", pageSpeedRules.Scheduling)
+	if err != nil {
+		return err
 	}
-
-	isHeart := pageSpeedRules.Scheduling.IsHeartBeat
-	currentTime := time.Now()
-	for {
-		waitGroup := sync.WaitGroup{}
-		waitGroup.Add(len(pageSpeedRules.Scheduling.DataCentersIds))
-		for _, dataC := range pageSpeedRules.Scheduling.DataCentersIds {
-			go func(dataCenter int) {
-				var urlsCalled []string
-				for _, rule := range pageSpeedRules.PageSpeed {
-					dataCenter, err := e.dataCentersRepo.GetDataCenter(ctx, dataCenter)
-					if err != nil {
-						log.Info("error on getting data center in executing page speed rule: ", err)
-						waitGroup.Done()
-						return
-					}
-
-					response, err := e.agentHandler.SendPageSpeed(ctx, dataCenter.Baseurl, usecase_models.AgentPageSpeedRequest{
-						Url: rule.Url,
-					})
-					if err != nil {
-						log.Info("error on sending page speed in executing rule: ", err)
-						waitGroup.Done()
-						return
-					}
-
-					if response.Status == 0 {
-						urlsCalled = append(urlsCalled, rule.Url)
-						err = e.pageSpeedStatsRepo.Write(ctx, time.Now(), repos.WritePageSpeedStatsOptions{
-							ProjectId:    pageSpeedRules.Scheduling.ProjectId,
-							PageSpeedId:  pageSpeedRules.Scheduling.PipelineId,
-							IsHeartBeat:  isHeart,
-							Url:          strings.Join(urlsCalled, ","),
-							DatacenterId: dataCenter.ID,
-							Success:      0,
-						})
-						if err != nil {
-							log.Info("error on writing curl report in executing rule: ", err)
-						}
-
-						waitGroup.Done()
-						return
-					}
-					urlsCalled = append(urlsCalled, rule.Url)
-				}
-				err := e.pageSpeedStatsRepo.Write(ctx, time.Now(), repos.WritePageSpeedStatsOptions{
-					ProjectId:    pageSpeedRules.Scheduling.ProjectId,
-					PageSpeedId:  pageSpeedRules.Scheduling.PipelineId,
-					IsHeartBeat:  isHeart,
-					Url:          strings.Join(urlsCalled, ","),
-					DatacenterId: dataCenter,
-					Success:      1,
-				})
-				if err != nil {
-					log.Info("error on writing curl report in executing rule: ", err)
-				}
-				waitGroup.Done()
-			}(dataC)
-		}
-
-		waitGroup.Wait()
-
-		sessionIds, err := e.pageSpeedStatsRepo.GetLastNSessionsByPageSpeedId(ctx, 2, pageSpeedRules.Scheduling.PipelineId)
-		if err != nil {
-			log.Info("problem in fetching session ids: ", err.Error())
-			break
-		}
-
-		if len(sessionIds) != 2 {
-			break
-		}
-		newSession, err := e.pageSpeedStatsRepo.Read(ctx,
-			[]string{"time", "success", "datacenter_id", "url"},
-			repos.Filters{repos.Filter{Field: "session_id", Op: repos.FilterOpEq, Value: sessionIds[0]}},
-			[]string{models.PageSpeedsStatRels.Datacenter},
-		)
-		oldSession, err := e.pageSpeedStatsRepo.Read(ctx,
-			[]string{"time", "success", "datacenter_id", "url"},
-			repos.Filters{repos.Filter{Field: "session_id", Op: repos.FilterOpEq, Value: sessionIds[1]}},
-			[]string{models.PageSpeedsStatRels.Datacenter},
-		)
-
-		project, err := e.projectRepo.GetProjectWithLoads(ctx, pageSpeedRules.Scheduling.ProjectId)
-		if err != nil {
-			log.Info("error on getting project in executing rule: ", err)
-			break
-		}
-		var notifications usecase_models.Notifications
-		err = json.Unmarshal(project.Notifications.JSON, &notifications)
-		if err != nil {
-			log.Info("can not unmarshal notification")
-			break
-		}
-
-		resolved, failed, newSuccess, oldSuccess := calculatePageSpeedStatState(newSession, oldSession)
-		if oldSuccess && !newSuccess {
-			// send fail
-			address := ""
-			rootCause := "working on it"
-			for _, value := range oldSession {
-				if value.Success == 0 {
-					t := strings.Split(value.URL.String, ",")
-					address = t[len(t)-1]
-					break
-				}
-			}
-			dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
+	pageSpeedRules.Scheduling.DataCentersIds = dataCentersIds
+
+	maintenance := e.sessionNotifier.underMaintenance(ctx, "pagespeed", pageSpeedRules.Scheduling)
+	session, _ := uuid.NewUUID()
+	sessionResults := make([]monitorSession, len(pageSpeedRules.Scheduling.DataCentersIds))
+	sessionIsValid := make([]bool, len(pageSpeedRules.Scheduling.DataCentersIds))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(pageSpeedRules.Scheduling.DataCentersIds))
+	for i, dataC := range pageSpeedRules.Scheduling.DataCentersIds {
+		go func(i int, dataCenterId int) {
+			defer waitGroup.Done()
+			dataCenter, err := e.dataCentersRepo.GetDataCenterWithCache(ctx, dataCenterId)
 			if err != nil {
-				log.Info("problem in getting datacenters in sending alerts: ", err.Error())
-			}
-			var dcTitles []string
-			for _, value := range failed {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						dcTitles = append(dcTitles, dc.Title)
-					}
-				}
+				log.Error("error on getting data center in executing page speed rule: ", err)
+				return
 			}
-			_, err = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-				Type:                "pagespeed",
-				State:               "down",
-				ProjectId:           pageSpeedRules.Scheduling.ProjectId,
-				Username:            project.R.Account.Username.String,
-				PipelineName:        pageSpeedRules.Scheduling.PipelineName,
-				Time:                time.Now().String(),
-				Address:             address,
-				Datacenters:         strings.Join(dcTitles, ","),
-				RootCause:           rootCause,
-				ResolvedDatacenters: "",
-				FailedDatacenters:   "",
-			})
-			if err != nil {
-				log.Info(err.Error())
+			sessionIsValid[i] = true
+
+			sessionR := repos.WritePageSpeedStatsOptions{
+				SessionId:     session.String(),
+				ProjectId:     pageSpeedRules.Scheduling.ProjectId,
+				PageSpeedId:   pageSpeedRules.Scheduling.PipelineId,
+				IsHeartBeat:   pageSpeedRules.Scheduling.IsHeartBeat,
+				IsMaintenance: maintenance,
+				DatacenterId:  dataCenter.ID,
+				Success:       1,
 			}
-		}
-		if !oldSuccess && newSuccess {
-			// send success
-			address := ""
-			rootCause := "working on it"
-			for _, value := range oldSession {
-				if value.Success == 1 {
-					t := strings.Split(value.URL.String, ",")
-					address = t[len(t)-1]
+			rootCause := ""
+			var addressesCalled []string
+			for _, rule := range pageSpeedRules.PageSpeed {
+				addressesCalled = append(addressesCalled, rule.Url)
+				response, err := e.agentHandler.SendPageSpeed(ctx, dataCenter, usecase_models.AgentPageSpeedRequest{
+					Url: rule.Url,
+				})
+				if err != nil {
+					log.Info("error on sending page speed in executing rule: ", err)
+					sessionR.Success = 0
+					sessionR.IsAgentError = true
+					rootCause = fmt.Sprintf("error on sending page speed in executing rule: %s", err.Error())
 					break
 				}
-			}
-			dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-			if err != nil {
-				log.Info("problem in getting datacenters in sending alerts: ", err.Error())
-			}
-			var dcTitles []string
-			for _, value := range resolved {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						dcTitles = append(dcTitles, dc.Title)
-					}
-				}
-			}
-			_, err = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-				Type:                "pagespeed",
-				State:               "up",
-				ProjectId:           pageSpeedRules.Scheduling.ProjectId,
-				Username:            project.R.Account.Username.String,
-				PipelineName:        pageSpeedRules.Scheduling.PipelineName,
-				Time:                time.Now().String(),
-				Address:             address,
-				Datacenters:         strings.Join(dcTitles, ","),
-				RootCause:           rootCause,
-				ResolvedDatacenters: "",
-				FailedDatacenters:   "",
-			})
-			if err != nil {
-				log.Info(err.Error())
-			}
-		}
-		if !oldSuccess && !newSuccess && (len(resolved) > 0 || len(failed) > 0) {
-			// send diff
-			address := ""
-			rootCause := "working on it"
-			for _, value := range oldSession {
-				if value.Success == 1 {
-					t := strings.Split(value.URL.String, ",")
-					address = t[len(t)-1]
+				if response.Status == 0 {
+					sessionR.Success = 0
+					rootCause = "page speed check failed"
 					break
 				}
 			}
-			dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-			if err != nil {
-				log.Info("problem in getting datacenters in sending alerts: ", err.Error())
-			}
-			var rTitles []string
-			var fTitles []string
-			for _, value := range resolved {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						rTitles = append(rTitles, dc.Title)
-					}
-				}
-			}
-			for _, value := range failed {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						fTitles = append(fTitles, dc.Title)
-					}
-				}
-			}
-			_, err = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-				Type:                "pagespeed",
-				State:               "diff",
-				ProjectId:           pageSpeedRules.Scheduling.ProjectId,
-				Username:            project.R.Account.Username.String,
-				PipelineName:        pageSpeedRules.Scheduling.PipelineName,
-				Time:                time.Now().String(),
-				Address:             address,
-				Datacenters:         "",
-				RootCause:           rootCause,
-				ResolvedDatacenters: strings.Join(rTitles, ","),
-				FailedDatacenters:   strings.Join(fTitles, ","),
-			})
+			sessionR.Time = time.Now()
+			sessionR.Url = strings.Join(addressesCalled, ",")
+			_, err = e.taskPusher.PushPageSpeedStore(ctx, sessionR)
 			if err != nil {
-				log.Info(err.Error())
+				log.Info("error on pushing page speed report in executing rule: ", err)
 			}
-		}
 
-		if isHeart {
-			if time.Now().Sub(currentTime) >= 59*time.Second {
-				break
+			sessionResults[i] = monitorSession{
+				DatacenterId: sessionR.DatacenterId,
+				Success:      sessionR.Success,
+				Url:          sessionR.Url,
+				RootCause:    rootCause,
+				AgentError:   sessionR.IsAgentError,
 			}
-			time.Sleep(5 * time.Second)
-		} else {
-			break
-		}
+		}(i, dataC)
 	}
-	return nil
-}
+	waitGroup.Wait()
 
-func calculatePageSpeedStatState(newSession, oldSession models.PageSpeedsStatSlice) ([]int, []int, bool, bool) {
-	var mapNDcSuccess map[int]bool
-	var mapODcSuccess map[int]bool
-	var newSuccess = true
-	var oldSuccess = true
-	for _, value := range newSession {
-		mapNDcSuccess[value.DatacenterID] = value.Success != 0
-	}
-	for _, value := range oldSession {
-		if _, ok := mapNDcSuccess[value.DatacenterID]; !ok {
-			mapNDcSuccess[value.DatacenterID] = true
+	for _, valid := range sessionIsValid {
+		if !valid {
+			log.Warn("this session was invalid: ", session.String())
+			return nil
 		}
-		mapODcSuccess[value.DatacenterID] = value.Success != 0
 	}
 
-	var resolved []int
-	var failed []int
-	for key, newValue := range mapNDcSuccess {
-		oldValue, ok := mapODcSuccess[key]
-		if !ok {
-			oldValue = true
-		}
-		newSuccess = newSuccess && newValue
-		oldSuccess = oldSuccess && oldValue
-
-		if newValue && !oldValue {
-			// resolved
-			resolved = append(resolved, key)
-		} else if !newValue && oldValue {
-			// error detected
-			failed = append(failed, key)
-		} else {
-			// same shit
-		}
-	}
-	return resolved, failed, newSuccess, oldSuccess
+	return e.sessionNotifier.notifyTransitions(ctx, "pagespeed", repos.PageSpeedSessionCachePrefix, pageSpeedRules.Scheduling, session.String(), sessionResults, maintenance)
 }
@@ -2,17 +2,16 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/labstack/gommon/log"
-	"math/rand"
 	"strings"
 	"sync"
+	"test-manager/cache"
 	"test-manager/repos"
 	"test-manager/services/alert_system"
 	"test-manager/tasks/push"
-	"test-manager/tasks/task_models"
 	"test-manager/usecase_models"
-	models "test-manager/usecase_models/boiler"
 	"time"
 )
 
@@ -21,317 +20,124 @@ type PingHandler interface {
 }
 
 type pingHandler struct {
-	alertSystem     alert_system.AlertHandler
-	pingRepo        repos.PingRepository
-	dataCentersRepo repos.DataCentersRepository
-	projectRepo     repos.ProjectsRepository
-	pingStatsRepo   repos.PingStatsRepository
-	taskPusher      push.TaskPusher
-	agentHandler    AgentHandler
+	alertSystem        alert_system.AlertHandler
+	pingRepo           repos.PingRepository
+	dataCentersRepo    repos.DataCentersRepository
+	projectRepo        repos.ProjectsRepository
+	pingStatsRepo      repos.PingStatsRepository
+	cacheRepo          cache.Cache
+	maintenanceRepo    repos.MaintenanceWindowsRepository
+	taskPusher         push.TaskPusher
+	agentHandler       AgentHandler
+	sessionNotifier    *sessionNotifier
+	dataCenterSelector *dataCenterSelector
 }
 
-func NewPingHandler(alertSystem alert_system.AlertHandler, pingRepo repos.PingRepository, dataCentersRepo repos.DataCentersRepository, projectRepo repos.ProjectsRepository, pingStatsRepo repos.PingStatsRepository, taskPusher push.TaskPusher, agentHandler AgentHandler) PingHandler {
+func NewPingHandler(
+	alertSystem alert_system.AlertHandler,
+	pingRepo repos.PingRepository,
+	dataCentersRepo repos.DataCentersRepository,
+	projectRepo repos.ProjectsRepository,
+	pingStatsRepo repos.PingStatsRepository,
+	cacheRepo cache.Cache,
+	maintenanceRepo repos.MaintenanceWindowsRepository,
+	monitorStatesRepo repos.MonitorStatesRepository,
+	taskPusher push.TaskPusher,
+	agentHandler AgentHandler,
+) PingHandler {
 	return &pingHandler{
-		alertSystem:     alertSystem,
-		pingRepo:        pingRepo,
-		dataCentersRepo: dataCentersRepo,
-		projectRepo:     projectRepo,
-		pingStatsRepo:   pingStatsRepo,
-		taskPusher:      taskPusher,
-		agentHandler:    agentHandler,
+		alertSystem:        alertSystem,
+		pingRepo:           pingRepo,
+		dataCentersRepo:    dataCentersRepo,
+		projectRepo:        projectRepo,
+		pingStatsRepo:      pingStatsRepo,
+		cacheRepo:          cacheRepo,
+		maintenanceRepo:    maintenanceRepo,
+		taskPusher:         taskPusher,
+		agentHandler:       agentHandler,
+		sessionNotifier:    newSessionNotifier(projectRepo, dataCentersRepo, maintenanceRepo, monitorStatesRepo, cacheRepo, taskPusher),
+		dataCenterSelector: newDataCenterSelector(dataCentersRepo, cacheRepo),
 	}
 }
 
 func (e *pingHandler) ExecutePingRule(ctx context.Context, pingRules usecase_models.Pings) error {
-	if len(pingRules.Scheduling.DataCentersIds) == 1 && pingRules.Scheduling.DataCentersIds[0] == 0 {
-		datacenters, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err == nil {
-			// random number between one and the number of datacenters
-			pingRules.Scheduling.DataCentersIds = []int{}
-			pingRules.Scheduling.DataCentersIds = append(pingRules.Scheduling.DataCentersIds, datacenters[rand.Intn(len(datacenters))].ID)
-		}
-	} else if len(pingRules.Scheduling.DataCentersIds) == 0 {
-		datacenters, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-		if err == nil {
-			// random number between one and the number of datacenters
-			pingRules.Scheduling.DataCentersIds = []int{}
-			for _, value := range datacenters {
-				pingRules.Scheduling.DataCentersIds = append(pingRules.Scheduling.DataCentersIds, value.ID)
-			}
-		}
+	dataCentersIds, err := e.dataCenterSelector.selectDataCenters(ctx, "ping", pingRules.Scheduling)
+	if err != nil {
+		return err
 	}
-
-	isHeart := pingRules.Scheduling.IsHeartBeat
-	currentTime := time.Now()
-	for {
-		waitGroup := sync.WaitGroup{}
-		waitGroup.Add(len(pingRules.Scheduling.DataCentersIds))
-		for _, dataC := range pingRules.Scheduling.DataCentersIds {
-			go func(dataCenter int) {
-				var urlsCalled []string
-				for _, rule := range pingRules.Pings {
-					dataCenter, err := e.dataCentersRepo.GetDataCenter(ctx, dataCenter)
-					if err != nil {
-						log.Info("error on getting data center in executing ping rule: ", err)
-						waitGroup.Done()
-						return
-					}
-
-					response, err := e.agentHandler.SendPing(ctx, dataCenter.Baseurl, usecase_models.AgentPingRequest{
-						Address: rule.Address,
-						Count:   rule.Count,
-						TimeOut: rule.TimeOut,
-					})
-					if err != nil {
-						log.Info("error on sending ping in executing rule: ", err)
-						waitGroup.Done()
-						return
-					}
-
-					if response.Status == 0 {
-						urlsCalled = append(urlsCalled, rule.Address)
-						err = e.pingStatsRepo.Write(ctx, time.Now(), repos.WritePingStatsOptions{
-							ProjectId:    pingRules.Scheduling.ProjectId,
-							PingId:       pingRules.Scheduling.PipelineId,
-							IsHeartBeat:  isHeart,
-							Url:          strings.Join(urlsCalled, ","),
-							DatacenterId: dataCenter.ID,
-							Success:      0,
-						})
-						if err != nil {
-							log.Info("error on writing ping report in executing rule: ", err)
-						}
-
-						waitGroup.Done()
-						return
-					}
-					urlsCalled = append(urlsCalled, rule.Address)
-				}
-				err := e.pingStatsRepo.Write(ctx, time.Now(), repos.WritePingStatsOptions{
-					ProjectId:    pingRules.Scheduling.ProjectId,
-					PingId:       pingRules.Scheduling.PipelineId,
-					IsHeartBeat:  isHeart,
-					Url:          strings.Join(urlsCalled, ","),
-					DatacenterId: dataCenter,
-					Success:      1,
-				})
-				if err != nil {
-					log.Info("error on writing ping report in executing rule: ", err)
-				}
-				waitGroup.Done()
-			}(dataC)
-		}
-
-		waitGroup.Wait()
-
-		sessionIds, err := e.pingStatsRepo.GetLastNSessionsByPingId(ctx, 2, pingRules.Scheduling.PipelineId)
-		if err != nil {
-			log.Info("problem in fetching session ids: ", err.Error())
-			break
-		}
-
-		if len(sessionIds) != 2 {
-			break
-		}
-		newSession, err := e.pingStatsRepo.Read(ctx,
-			[]string{"time", "success", "datacenter_id", "url"},
-			repos.Filters{repos.Filter{Field: "session_id", Op: repos.FilterOpEq, Value: sessionIds[0]}},
-			[]string{models.PingsStatRels.Datacenter},
-		)
-		oldSession, err := e.pingStatsRepo.Read(ctx,
-			[]string{"time", "success", "datacenter_id", "url"},
-			repos.Filters{repos.Filter{Field: "session_id", Op: repos.FilterOpEq, Value: sessionIds[1]}},
-			[]string{models.PingsStatRels.Datacenter},
-		)
-
-		project, err := e.projectRepo.GetProjectWithLoads(ctx, pingRules.Scheduling.ProjectId)
-		if err != nil {
-			log.Info("error on getting project in executing rule: ", err)
-			break
-		}
-		var notifications usecase_models.Notifications
-		err = json.Unmarshal(project.Notifications.JSON, &notifications)
-		if err != nil {
-			log.Info("can not unmarshal notification")
-			break
-		}
-
-		resolved, failed, newSuccess, oldSuccess := calculatePingStatState(newSession, oldSession)
-		if oldSuccess && !newSuccess {
-			// send fail
-			address := ""
-			rootCause := "working on it"
-			for _, value := range oldSession {
-				if value.Success == 0 {
-					t := strings.Split(value.URL.String, ",")
-					address = t[len(t)-1]
-					break
-				}
-			}
-			dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
+	pingRules.Scheduling.DataCentersIds = dataCentersIds
+
+	maintenance := e.sessionNotifier.underMaintenance(ctx, "ping", pingRules.Scheduling)
+	session, _ := uuid.NewUUID()
+	sessionResults := make([]monitorSession, len(pingRules.Scheduling.DataCentersIds))
+	sessionIsValid := make([]bool, len(pingRules.Scheduling.DataCentersIds))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(pingRules.Scheduling.DataCentersIds))
+	for i, dataC := range pingRules.Scheduling.DataCentersIds {
+		go func(i int, dataCenterId int) {
+			defer waitGroup.Done()
+			dataCenter, err := e.dataCentersRepo.GetDataCenterWithCache(ctx, dataCenterId)
 			if err != nil {
-				log.Info("problem in getting datacenters in sending alerts: ", err.Error())
-			}
-			var dcTitles []string
-			for _, value := range failed {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						dcTitles = append(dcTitles, dc.Title)
-					}
-				}
+				log.Error("error on getting data center in executing ping rule: ", err)
+				return
 			}
-			_, err = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-				Type:                "ping",
-				State:               "down",
-				ProjectId:           pingRules.Scheduling.ProjectId,
-				Username:            project.R.Account.Username.String,
-				PipelineName:        pingRules.Scheduling.PipelineName,
-				Time:                time.Now().String(),
-				Address:             address,
-				Datacenters:         strings.Join(dcTitles, ","),
-				RootCause:           rootCause,
-				ResolvedDatacenters: "",
-				FailedDatacenters:   "",
-			})
-			if err != nil {
-				log.Info(err.Error())
+			sessionIsValid[i] = true
+
+			sessionR := repos.WritePingStatsOptions{
+				SessionId:     session.String(),
+				ProjectId:     pingRules.Scheduling.ProjectId,
+				PingId:        pingRules.Scheduling.PipelineId,
+				IsHeartBeat:   pingRules.Scheduling.IsHeartBeat,
+				IsMaintenance: maintenance,
+				DatacenterId:  dataCenter.ID,
+				Success:       1,
 			}
-		}
-		if !oldSuccess && newSuccess {
-			// send success
-			address := ""
-			rootCause := "working on it"
-			for _, value := range oldSession {
-				if value.Success == 1 {
-					t := strings.Split(value.URL.String, ",")
-					address = t[len(t)-1]
+			rootCause := ""
+			var addressesCalled []string
+			for _, rule := range pingRules.Pings {
+				addressesCalled = append(addressesCalled, rule.Address)
+				response, err := e.agentHandler.SendPing(ctx, dataCenter, usecase_models.AgentPingRequest{
+					Address: rule.Address,
+					Count:   rule.Count,
+					TimeOut: rule.TimeOut,
+				})
+				if err != nil {
+					log.Info("error on sending ping in executing rule: ", err)
+					sessionR.Success = 0
+					sessionR.IsAgentError = true
+					rootCause = fmt.Sprintf("error on sending ping in executing rule: %s", err.Error())
 					break
 				}
-			}
-			dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-			if err != nil {
-				log.Info("problem in getting datacenters in sending alerts: ", err.Error())
-			}
-			var dcTitles []string
-			for _, value := range resolved {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						dcTitles = append(dcTitles, dc.Title)
-					}
-				}
-			}
-			_, err = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-				Type:                "ping",
-				State:               "up",
-				ProjectId:           pingRules.Scheduling.ProjectId,
-				Username:            project.R.Account.Username.String,
-				PipelineName:        pingRules.Scheduling.PipelineName,
-				Time:                time.Now().String(),
-				Address:             address,
-				Datacenters:         strings.Join(dcTitles, ","),
-				RootCause:           rootCause,
-				ResolvedDatacenters: "",
-				FailedDatacenters:   "",
-			})
-			if err != nil {
-				log.Info(err.Error())
-			}
-		}
-		if !oldSuccess && !newSuccess && (len(resolved) > 0 || len(failed) > 0) {
-			// send diff
-			address := ""
-			rootCause := "working on it"
-			for _, value := range oldSession {
-				if value.Success == 1 {
-					t := strings.Split(value.URL.String, ",")
-					address = t[len(t)-1]
+				if response.Status == 0 {
+					sessionR.Success = 0
+					rootCause = response.Message
 					break
 				}
 			}
-			dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
-			if err != nil {
-				log.Info("problem in getting datacenters in sending alerts: ", err.Error())
-			}
-			var rTitles []string
-			var fTitles []string
-			for _, value := range resolved {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						rTitles = append(rTitles, dc.Title)
-					}
-				}
-			}
-			for _, value := range failed {
-				for _, dc := range dcs {
-					if dc.ID == value {
-						fTitles = append(fTitles, dc.Title)
-					}
-				}
-			}
-			_, err = e.taskPusher.PushNotifications(ctx, task_models.NotificationsPayload{
-				Type:                "ping",
-				State:               "diff",
-				ProjectId:           pingRules.Scheduling.ProjectId,
-				Username:            project.R.Account.Username.String,
-				PipelineName:        pingRules.Scheduling.PipelineName,
-				Time:                time.Now().String(),
-				Address:             address,
-				Datacenters:         "",
-				RootCause:           rootCause,
-				ResolvedDatacenters: strings.Join(rTitles, ","),
-				FailedDatacenters:   strings.Join(fTitles, ","),
-			})
+			sessionR.Time = time.Now()
+			sessionR.Url = strings.Join(addressesCalled, ",")
+			_, err = e.taskPusher.PushPingStore(ctx, sessionR)
 			if err != nil {
-				log.Info(err.Error())
+				log.Info("error on pushing ping report in executing rule: ", err)
 			}
-		}
 
-		if isHeart {
-			if time.Now().Sub(currentTime) >= 59*time.Second {
-				break
+			sessionResults[i] = monitorSession{
+				DatacenterId: sessionR.DatacenterId,
+				Success:      sessionR.Success,
+				Url:          sessionR.Url,
+				RootCause:    rootCause,
+				AgentError:   sessionR.IsAgentError,
 			}
-			time.Sleep(5 * time.Second)
-		} else {
-			break
-		}
+		}(i, dataC)
 	}
-	return nil
-}
+	waitGroup.Wait()
 
-func calculatePingStatState(newSession, oldSession models.PingsStatSlice) ([]int, []int, bool, bool) {
-	var mapNDcSuccess map[int]bool
-	var mapODcSuccess map[int]bool
-	var newSuccess = true
-	var oldSuccess = true
-	for _, value := range newSession {
-		mapNDcSuccess[value.DatacenterID] = value.Success != 0
-	}
-	for _, value := range oldSession {
-		if _, ok := mapNDcSuccess[value.DatacenterID]; !ok {
-			mapNDcSuccess[value.DatacenterID] = true
+	for _, valid := range sessionIsValid {
+		if !valid {
+			log.Warn("this session was invalid: ", session.String())
+			return nil
 		}
-		mapODcSuccess[value.DatacenterID] = value.Success != 0
 	}
 
-	var resolved []int
-	var failed []int
-	for key, newValue := range mapNDcSuccess {
-		oldValue, ok := mapODcSuccess[key]
-		if !ok {
-			oldValue = true
-		}
-		newSuccess = newSuccess && newValue
-		oldSuccess = oldSuccess && oldValue
-
-		if newValue && !oldValue {
-			// resolved
-			resolved = append(resolved, key)
-		} else if !newValue && oldValue {
-			// error detected
-			failed = append(failed, key)
-		} else {
-			// same shit
-		}
-	}
-	return resolved, failed, newSuccess, oldSuccess
+	return e.sessionNotifier.notifyTransitions(ctx, "ping", repos.PingSessionCachePrefix, pingRules.Scheduling, session.String(), sessionResults, maintenance)
 }
@@ -23,6 +23,8 @@ type rulesHandler struct {
 	pageSpeedRepo   repos.PageSpeedRepository
 	pingRepo        repos.PingRepository
 	traceRouteRepo  repos.TraceRouteRepository
+	tlsMonitorsRepo repos.TLSMonitorsRepository
+	dnsMonitorsRepo repos.DNSMonitorsRepository
 	dataCentersRepo repos.DataCentersRepository
 	taskPusher      push.TaskPusher
 	agentHandler    AgentHandler
@@ -35,6 +37,8 @@ func NewRulesHandler(
 	pageSpeedRepo repos.PageSpeedRepository,
 	pingRepo repos.PingRepository,
 	traceRouteRepo repos.TraceRouteRepository,
+	tlsMonitorsRepo repos.TLSMonitorsRepository,
+	dnsMonitorsRepo repos.DNSMonitorsRepository,
 	dataCentersRepo repos.DataCentersRepository,
 	taskPusher push.TaskPusher,
 	agentHandler AgentHandler,
@@ -46,6 +50,8 @@ func NewRulesHandler(
 		pageSpeedRepo:   pageSpeedRepo,
 		pingRepo:        pingRepo,
 		traceRouteRepo:  traceRouteRepo,
+		tlsMonitorsRepo: tlsMonitorsRepo,
+		dnsMonitorsRepo: dnsMonitorsRepo,
 		dataCentersRepo: dataCentersRepo,
 		taskPusher:      taskPusher,
 		agentHandler:    agentHandler,
@@ -85,6 +91,12 @@ func (r *rulesHandler) RegisterRules(ctx context.Context, rules usecase_models.R
 	if len(rules.PageSpeed.PageSpeed) != 0 && !contains(projectIds, rules.PageSpeed.Scheduling.ProjectId) {
 		return errors.New(fmt.Sprintf("project id : %d is not your project", rules.PageSpeed.Scheduling.ProjectId))
 	}
+	if len(rules.TLSMonitors.TLSMonitors) != 0 && !contains(projectIds, rules.TLSMonitors.Scheduling.ProjectId) {
+		return errors.New(fmt.Sprintf("project id : %d is not your project", rules.TLSMonitors.Scheduling.ProjectId))
+	}
+	if len(rules.DNSMonitors.DNSMonitors) != 0 && !contains(projectIds, rules.DNSMonitors.Scheduling.ProjectId) {
+		return errors.New(fmt.Sprintf("project id : %d is not your project", rules.DNSMonitors.Scheduling.ProjectId))
+	}
 
 	if rules.Endpoints.Scheduling.IsHeartBeat && len(rules.Endpoints.Endpoints) > 1 {
 		return errors.New("more that one endpoint can not be registered if heartbeat is active")
@@ -101,6 +113,40 @@ func (r *rulesHandler) RegisterRules(ctx context.Context, rules usecase_models.R
 	if rules.PageSpeed.Scheduling.IsHeartBeat && len(rules.PageSpeed.PageSpeed) > 1 {
 		return errors.New("more that one page speed can not be registered if heartbeat is active")
 	}
+	if rules.TLSMonitors.Scheduling.IsHeartBeat && len(rules.TLSMonitors.TLSMonitors) > 1 {
+		return errors.New("more that one tls monitor can not be registered if heartbeat is active")
+	}
+	if rules.DNSMonitors.Scheduling.IsHeartBeat && len(rules.DNSMonitors.DNSMonitors) > 1 {
+		return errors.New("more that one dns monitor can not be registered if heartbeat is active")
+	}
+	for _, scheduling := range []usecase_models.Scheduling{rules.Endpoints.Scheduling, rules.NetCats.Scheduling, rules.Pings.Scheduling,
+		rules.TraceRoutes.Scheduling, rules.PageSpeed.Scheduling, rules.TLSMonitors.Scheduling, rules.DNSMonitors.Scheduling} {
+		if err := validateScheduling(scheduling); err != nil {
+			return err
+		}
+		// rule sets left empty skip the project check above, they may name no private datacenter
+		projectId := 0
+		if contains(projectIds, scheduling.ProjectId) {
+			projectId = scheduling.ProjectId
+		}
+		if err := validateSchedulingDataCenters(ctx, r.dataCentersRepo, projectId, scheduling); err != nil {
+			return err
+		}
+	}
+	if err := validateTLSRules(rules.TLSMonitors); err != nil {
+		return err
+	}
+	if err := validateDNSRules(rules.DNSMonitors); err != nil {
+		return err
+	}
+	for _, endpoint := range rules.Endpoints.Endpoints {
+		if err := validateAssertions(endpoint.AcceptanceModel); err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.EndpointName, err)
+		}
+		if err := validateResponseSchema(endpoint); err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.EndpointName, err)
+		}
+	}
 
 	if len(rules.Endpoints.Endpoints) != 0 {
 		var project models.Project
@@ -192,6 +238,35 @@ func (r *rulesHandler) RegisterRules(ctx context.Context, rules usecase_models.R
 		}
 		rules.TraceRoutes.Scheduling.PipelineId = tracerouteId
 	}
+	if len(rules.TLSMonitors.TLSMonitors) != 0 {
+		var project models.Project
+		for _, value := range projects {
+			if value.ID == rules.TLSMonitors.Scheduling.ProjectId {
+				project = *value
+			}
+		}
+		rules.TLSMonitors.Scheduling.EndAt = project.ExpireAt.Time.String()
+		tlsMonitorId, err := r.tlsMonitorsRepo.SaveTLSMonitor(ctx, rules.TLSMonitors.Scheduling.ProjectId, rules.TLSMonitors)
+		if err != nil {
+			return err
+		}
+		rules.TLSMonitors.Scheduling.PipelineId = tlsMonitorId
+	}
+
+	if len(rules.DNSMonitors.DNSMonitors) != 0 {
+		var project models.Project
+		for _, value := range projects {
+			if value.ID == rules.DNSMonitors.Scheduling.ProjectId {
+				project = *value
+			}
+		}
+		rules.DNSMonitors.Scheduling.EndAt = project.ExpireAt.Time.String()
+		dnsMonitorId, err := r.dnsMonitorsRepo.SaveDNSMonitor(ctx, rules.DNSMonitors.Scheduling.ProjectId, rules.DNSMonitors)
+		if err != nil {
+			return err
+		}
+		rules.DNSMonitors.Scheduling.PipelineId = dnsMonitorId
+	}
 
 	//_, err = r.taskPusher.PushRules(ctx, rules)
 	//if err != nil {
@@ -3,9 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/hibiken/asynq"
 	"github.com/labstack/gommon/log"
+	"github.com/robfig/cron/v3"
 	"strconv"
+	"strings"
 	"test-manager/monitoring"
 	"test-manager/repos"
 	"test-manager/tasks/push"
@@ -20,6 +24,8 @@ type schedulerProvider struct {
 	pageSpeedRepository  repos.PageSpeedRepository
 	traceRouteRepository repos.TraceRouteRepository
 	pingRepository       repos.PingRepository
+	tlsMonitorRepository repos.TLSMonitorsRepository
+	dnsMonitorRepository repos.DNSMonitorsRepository
 }
 
 func NewProvider(
@@ -28,6 +34,8 @@ func NewProvider(
 	pageSpeedRepository repos.PageSpeedRepository,
 	traceRouteRepository repos.TraceRouteRepository,
 	pingRepository repos.PingRepository,
+	tlsMonitorRepository repos.TLSMonitorsRepository,
+	dnsMonitorRepository repos.DNSMonitorsRepository,
 ) asynq.PeriodicTaskConfigProvider {
 	return &schedulerProvider{
 		endpointRepository:   endpointRepository,
@@ -35,6 +43,8 @@ func NewProvider(
 		pageSpeedRepository:  pageSpeedRepository,
 		traceRouteRepository: traceRouteRepository,
 		pingRepository:       pingRepository,
+		tlsMonitorRepository: tlsMonitorRepository,
+		dnsMonitorRepository: dnsMonitorRepository,
 	}
 }
 
@@ -43,22 +53,30 @@ func (c *schedulerProvider) GetConfigs() ([]*asynq.PeriodicTaskConfig, error) {
 	if err != nil {
 		panic(err)
 	}
-	//netcats, err := c.netCatRepository.GetActiveNetCats(context.TODO())
-	//if err != nil {
-	//	panic(err)
-	//}
-	//pagespeeds, err := c.pageSpeedRepository.GetActivePageSpeeds(context.TODO())
-	//if err != nil {
-	//	panic(err)
-	//}
-	//pings, err := c.pingRepository.GetActivePings(context.TODO())
-	//if err != nil {
-	//	panic(err)
-	//}
-	//traceRoutes, err := c.traceRouteRepository.GetActiveTraceRoutes(context.TODO())
-	//if err != nil {
-	//	panic(err)
-	//}
+	netcats, err := c.netCatRepository.GetActiveNetCats(context.TODO())
+	if err != nil {
+		panic(err)
+	}
+	pagespeeds, err := c.pageSpeedRepository.GetActivePageSpeeds(context.TODO())
+	if err != nil {
+		panic(err)
+	}
+	pings, err := c.pingRepository.GetActivePings(context.TODO())
+	if err != nil {
+		panic(err)
+	}
+	traceRoutes, err := c.traceRouteRepository.GetActiveTraceRoutes(context.TODO())
+	if err != nil {
+		panic(err)
+	}
+	tlsMonitors, err := c.tlsMonitorRepository.GetActiveTLSMonitors(context.TODO())
+	if err != nil {
+		panic(err)
+	}
+	dnsMonitors, err := c.dnsMonitorRepository.GetActiveDNSMonitors(context.TODO())
+	if err != nil {
+		panic(err)
+	}
 
 	var taskConfigs []*asynq.PeriodicTaskConfig
 	for _, endpoint := range endpoints {
@@ -68,12 +86,9 @@ func (c *schedulerProvider) GetConfigs() ([]*asynq.PeriodicTaskConfig, error) {
 		}
 		newTask := asynq.NewTask(task_models.TypeEndpoint, payloadBytes)
 
-		if endpoint.Scheduling.IsHeartBeat {
-			endpoint.Scheduling.Duration = 1
-		}
 		// create periodic task config
 		taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
-			Cronspec: calcCronSpec(endpoint.Scheduling.Duration),
+			Cronspec: calcCronSpec(endpoint.Scheduling),
 			Task:     newTask,
 			Opts: []asynq.Option{
 				asynq.Unique(24 * time.Hour),
@@ -81,211 +96,427 @@ func (c *schedulerProvider) GetConfigs() ([]*asynq.PeriodicTaskConfig, error) {
 			},
 		})
 	}
-	//for _, netcat := range netcats {
-	//	payloadBytes, err := json.Marshal(netcat)
-	//	if err != nil {
-	//		panic(err)
-	//	}
-	//	newTask := asynq.NewTask(task_models.TypeNetCats, payloadBytes)
-	//	if netcat.Scheduling.IsHeartBeat {
-	//		netcat.Scheduling.Duration = 1
-	//	}
-	//	// create periodic task config
-	//	taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
-	//		Cronspec: calcCronSpec(netcat.Scheduling.Duration),
-	//		Task:     newTask,
-	//		Opts: []asynq.Option{
-	//			asynq.Unique(24 * time.Hour),
-	//			asynq.Queue(task_models.QueueNetCats),
-	//		},
-	//	})
-	//}
-	//for _, pagespeed := range pagespeeds {
-	//	payloadBytes, err := json.Marshal(pagespeed)
-	//	if err != nil {
-	//		panic(err)
-	//	}
-	//	newTask := asynq.NewTask(task_models.TypePageSpeeds, payloadBytes)
-	//	if pagespeed.Scheduling.IsHeartBeat {
-	//		pagespeed.Scheduling.Duration = 1
-	//	}
-	//	// create periodic task config
-	//	taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
-	//		Cronspec: calcCronSpec(pagespeed.Scheduling.Duration),
-	//		Task:     newTask,
-	//		Opts: []asynq.Option{
-	//			asynq.Unique(24 * time.Hour),
-	//			asynq.Queue(task_models.QueuePageSpeeds),
-	//		},
-	//	})
-	//}
-	//for _, ping := range pings {
-	//	payloadBytes, err := json.Marshal(ping)
-	//	if err != nil {
-	//		panic(err)
-	//	}
-	//	newTask := asynq.NewTask(task_models.TypePings, payloadBytes)
-	//	if ping.Scheduling.IsHeartBeat {
-	//		ping.Scheduling.Duration = 1
-	//	}
-	//	// create periodic task config
-	//	taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
-	//		Cronspec: calcCronSpec(ping.Scheduling.Duration),
-	//		Task:     newTask,
-	//		Opts: []asynq.Option{
-	//			asynq.Unique(24 * time.Hour),
-	//			asynq.Queue(task_models.QueuePings),
-	//		},
-	//	})
-	//}
-	//for _, traceRoute := range traceRoutes {
-	//	payloadBytes, err := json.Marshal(traceRoute)
-	//	if err != nil {
-	//		panic(err)
-	//	}
-	//	newTask := asynq.NewTask(task_models.TypeTraceRoutes, payloadBytes)
-	//	if traceRoute.Scheduling.IsHeartBeat {
-	//		traceRoute.Scheduling.Duration = 1
-	//	}
-	//	// create periodic task config
-	//	taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
-	//		Cronspec: calcCronSpec(traceRoute.Scheduling.Duration),
-	//		Task:     newTask,
-	//		Opts: []asynq.Option{
-	//			asynq.Unique(24 * time.Hour),
-	//			asynq.Queue(task_models.QueueTraceRoutes),
-	//		},
-	//	})
-	//}
+	for _, netcat := range netcats {
+		payloadBytes, err := json.Marshal(netcat)
+		if err != nil {
+			panic(err)
+		}
+		newTask := asynq.NewTask(task_models.TypeNetCats, payloadBytes)
+		// create periodic task config
+		taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
+			Cronspec: calcCronSpec(netcat.Scheduling),
+			Task:     newTask,
+			Opts: []asynq.Option{
+				asynq.Unique(24 * time.Hour),
+				asynq.Queue(task_models.QueueNetCats),
+			},
+		})
+	}
+	for _, pagespeed := range pagespeeds {
+		payloadBytes, err := json.Marshal(pagespeed)
+		if err != nil {
+			panic(err)
+		}
+		newTask := asynq.NewTask(task_models.TypePageSpeeds, payloadBytes)
+		// create periodic task config
+		taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
+			Cronspec: calcCronSpec(pagespeed.Scheduling),
+			Task:     newTask,
+			Opts: []asynq.Option{
+				asynq.Unique(24 * time.Hour),
+				asynq.Queue(task_models.QueuePageSpeeds),
+			},
+		})
+	}
+	for _, ping := range pings {
+		payloadBytes, err := json.Marshal(ping)
+		if err != nil {
+			panic(err)
+		}
+		newTask := asynq.NewTask(task_models.TypePings, payloadBytes)
+		// create periodic task config
+		taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
+			Cronspec: calcCronSpec(ping.Scheduling),
+			Task:     newTask,
+			Opts: []asynq.Option{
+				asynq.Unique(24 * time.Hour),
+				asynq.Queue(task_models.QueuePings),
+			},
+		})
+	}
+	for _, traceRoute := range traceRoutes {
+		payloadBytes, err := json.Marshal(traceRoute)
+		if err != nil {
+			panic(err)
+		}
+		newTask := asynq.NewTask(task_models.TypeTraceRoutes, payloadBytes)
+		// create periodic task config
+		taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
+			Cronspec: calcCronSpec(traceRoute.Scheduling),
+			Task:     newTask,
+			Opts: []asynq.Option{
+				asynq.Unique(24 * time.Hour),
+				asynq.Queue(task_models.QueueTraceRoutes),
+			},
+		})
+	}
+	for _, tlsMonitor := range tlsMonitors {
+		payloadBytes, err := json.Marshal(tlsMonitor)
+		if err != nil {
+			panic(err)
+		}
+		newTask := asynq.NewTask(task_models.TypeTLS, payloadBytes)
+		// create periodic task config
+		taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
+			Cronspec: calcCronSpec(tlsMonitor.Scheduling),
+			Task:     newTask,
+			Opts: []asynq.Option{
+				asynq.Unique(24 * time.Hour),
+				asynq.Queue(task_models.QueueTLS),
+			},
+		})
+	}
+
+	for _, dnsMonitor := range dnsMonitors {
+		payloadBytes, err := json.Marshal(dnsMonitor)
+		if err != nil {
+			panic(err)
+		}
+		newTask := asynq.NewTask(task_models.TypeDNS, payloadBytes)
+		// create periodic task config
+		taskConfigs = append(taskConfigs, &asynq.PeriodicTaskConfig{
+			Cronspec: calcCronSpec(dnsMonitor.Scheduling),
+			Task:     newTask,
+			Opts: []asynq.Option{
+				asynq.Unique(24 * time.Hour),
+				asynq.Queue(task_models.QueueDNS),
+			},
+		})
+	}
 
 	monitoring.ActiveSchedulerTasksGauge.Set(float64(len(taskConfigs)))
 	return taskConfigs, nil
 }
 
-func calcCronSpec(duration int) string {
-	// duration is in minutes
-	if duration > 59 {
-		minute := duration % 60
-		hour := duration / 60
-		return "*/" + strconv.Itoa(minute) + " */" + strconv.Itoa(hour) + " * * *"
-	}
-	return "*/" + strconv.Itoa(duration) + " * * * *"
+// calcCronSpec returns the asynq cron spec of a rule. a cron expression wins over interval seconds, which wins over
+// duration minutes. heart beat rules without an interval of their own run every heartBeatInterval
+func calcCronSpec(scheduling usecase_models.Scheduling) string {
+	switch {
+	case scheduling.Cron != "" && scheduling.Timezone != "":
+		return "CRON_TZ=" + scheduling.Timezone + " " + scheduling.Cron
+	case scheduling.Cron != "":
+		return "CRON_TZ=UTC " + scheduling.Cron
+	case scheduling.IntervalSeconds > 0:
+		return "@every " + strconv.Itoa(scheduling.IntervalSeconds) + "s"
+	case scheduling.IsHeartBeat:
+		return "@every " + heartBeatInterval.String()
+	case scheduling.Duration > 0:
+		return "@every " + strconv.Itoa(scheduling.Duration) + "m"
+	}
+	return "@every 1m"
+}
+
+// scheduleOf parses the cron spec of a rule so the heart beat scheduler runs it at the same times asynq would
+func scheduleOf(scheduling usecase_models.Scheduling) (cron.Schedule, error) {
+	return cronParser.Parse(calcCronSpec(scheduling))
+}
+
+// validateScheduling rejects schedules that can not be parsed or that run more often than minIntervalSeconds,
+// and datacenter selections the selector can not follow
+func validateScheduling(scheduling usecase_models.Scheduling) error {
+	if scheduling.Cron != "" && scheduling.IntervalSeconds != 0 {
+		return errors.New("only one of cron and interval_seconds can be set")
+	}
+	if scheduling.Timezone != "" && scheduling.Cron == "" {
+		return errors.New("timezone can only be set with cron")
+	}
+	if strings.Contains(scheduling.Cron, "TZ=") {
+		return errors.New("cron must not contain a timezone, use the timezone field")
+	}
+	if scheduling.IntervalSeconds < 0 || (scheduling.IntervalSeconds > 0 && scheduling.IntervalSeconds < minIntervalSeconds) {
+		return fmt.Errorf("interval_seconds must be at least %d", minIntervalSeconds)
+	}
+	if scheduling.Duration < 0 {
+		return errors.New("duration can not be negative")
+	}
+	if scheduling.Timezone != "" {
+		if _, err := time.LoadLocation(scheduling.Timezone); err != nil {
+			return fmt.Errorf("timezone %s is not valid", scheduling.Timezone)
+		}
+	}
+	if _, err := scheduleOf(scheduling); err != nil {
+		return fmt.Errorf("cron %s is not valid: %w", scheduling.Cron, err)
+	}
+	selection := scheduling.DataCenterSelection
+	switch selection.Strategy {
+	case "", usecase_models.DataCenterStrategyAll, usecase_models.DataCenterStrategyRandomN,
+		usecase_models.DataCenterStrategyRoundRobin, usecase_models.DataCenterStrategyWeighted:
+	case usecase_models.DataCenterStrategyRegion:
+		if selection.CountryName == "" && selection.LocationName == "" {
+			return errors.New("region strategy needs a country_name or a location_name")
+		}
+	default:
+		return fmt.Errorf("data center strategy %s is not valid", selection.Strategy)
+	}
+	if selection.Count < 0 {
+		return errors.New("data center count can not be negative")
+	}
+	return nil
+}
+
+// validateSchedulingDataCenters rejects datacenters that are private to another project than projectId, which is
+// the project the handler authorized the caller for and not the one in the body of the request
+func validateSchedulingDataCenters(ctx context.Context, dataCentersRepo repos.DataCentersRepository, projectId int, scheduling usecase_models.Scheduling) error {
+	owners, err := dataCentersRepo.GetDataCenterProjects(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range scheduling.DataCentersIds {
+		if owner, private := owners[id]; private && owner != projectId {
+			return fmt.Errorf("datacenter %d is not available to project %d", id, projectId)
+		}
+	}
+	return nil
+}
+
+const (
+	// heartBeatInterval is how often heart beat rules run when they have no interval of their own
+	heartBeatInterval = 15 * time.Second
+	// minIntervalSeconds is the shortest interval a rule can ask for
+	minIntervalSeconds = 5
+)
+
+// cronParser accepts the same specs as the asynq scheduler, five fields plus descriptors and CRON_TZ
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// scheduledTask is one active rule of any monitor type as seen by the heart beat scheduler
+type scheduledTask struct {
+	key      string
+	queue    string
+	spec     string
+	schedule cron.Schedule
+	push     func(ctx context.Context, taskPusher push.TaskPusher) (string, error)
 }
 
 type HeartBeatScheduler struct {
-	endpointRepository repos.EndpointRepository
-	taskPusher         push.TaskPusher
-	inspector          *asynq.Inspector
+	endpointRepository   repos.EndpointRepository
+	netCatRepository     repos.NetCatRepository
+	pageSpeedRepository  repos.PageSpeedRepository
+	traceRouteRepository repos.TraceRouteRepository
+	pingRepository       repos.PingRepository
+	tlsMonitorRepository repos.TLSMonitorsRepository
+	dnsMonitorRepository repos.DNSMonitorsRepository
+	taskPusher           push.TaskPusher
+	inspector            *asynq.Inspector
 }
 
 func NewHeartBeatScheduler(
 	endpointRepository repos.EndpointRepository,
+	netCatRepository repos.NetCatRepository,
+	pageSpeedRepository repos.PageSpeedRepository,
+	traceRouteRepository repos.TraceRouteRepository,
+	pingRepository repos.PingRepository,
+	tlsMonitorRepository repos.TLSMonitorsRepository,
+	dnsMonitorRepository repos.DNSMonitorsRepository,
 	taskPusher push.TaskPusher,
 	inspector *asynq.Inspector,
 ) *HeartBeatScheduler {
 	return &HeartBeatScheduler{
-		endpointRepository: endpointRepository,
-		taskPusher:         taskPusher,
-		inspector:          inspector,
+		endpointRepository:   endpointRepository,
+		netCatRepository:     netCatRepository,
+		pageSpeedRepository:  pageSpeedRepository,
+		traceRouteRepository: traceRouteRepository,
+		pingRepository:       pingRepository,
+		tlsMonitorRepository: tlsMonitorRepository,
+		dnsMonitorRepository: dnsMonitorRepository,
+		taskPusher:           taskPusher,
+		inspector:            inspector,
 	}
 }
 
+// getActiveTasks collects active rules of every monitor type, keyed by type and pipeline id
+func (c *HeartBeatScheduler) getActiveTasks(ctx context.Context) (map[string]scheduledTask, error) {
+	var activeTasks = make(map[string]scheduledTask)
+
+	endpoints, err := c.endpointRepository.GetActiveEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range endpoints {
+		endpoint := *value
+		addScheduledTask(activeTasks, task_models.TypeEndpoint, task_models.QueueEndpoint, endpoint.Scheduling,
+			func(ctx context.Context, taskPusher push.TaskPusher) (string, error) {
+				return taskPusher.PushEndpoint(ctx, endpoint)
+			})
+	}
+
+	netCats, err := c.netCatRepository.GetActiveNetCats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range netCats {
+		netCat := *value
+		addScheduledTask(activeTasks, task_models.TypeNetCats, task_models.QueueNetCats, netCat.Scheduling,
+			func(ctx context.Context, taskPusher push.TaskPusher) (string, error) {
+				return taskPusher.PushNetCat(ctx, netCat)
+			})
+	}
+
+	pageSpeeds, err := c.pageSpeedRepository.GetActivePageSpeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range pageSpeeds {
+		pageSpeed := *value
+		addScheduledTask(activeTasks, task_models.TypePageSpeeds, task_models.QueuePageSpeeds, pageSpeed.Scheduling,
+			func(ctx context.Context, taskPusher push.TaskPusher) (string, error) {
+				return taskPusher.PushPageSpeed(ctx, pageSpeed)
+			})
+	}
+
+	pings, err := c.pingRepository.GetActivePings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range pings {
+		ping := *value
+		addScheduledTask(activeTasks, task_models.TypePings, task_models.QueuePings, ping.Scheduling,
+			func(ctx context.Context, taskPusher push.TaskPusher) (string, error) {
+				return taskPusher.PushPing(ctx, ping)
+			})
+	}
+
+	traceRoutes, err := c.traceRouteRepository.GetActiveTraceRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range traceRoutes {
+		traceRoute := *value
+		addScheduledTask(activeTasks, task_models.TypeTraceRoutes, task_models.QueueTraceRoutes, traceRoute.Scheduling,
+			func(ctx context.Context, taskPusher push.TaskPusher) (string, error) {
+				return taskPusher.PushTraceRoute(ctx, traceRoute)
+			})
+	}
+
+	tlsMonitors, err := c.tlsMonitorRepository.GetActiveTLSMonitors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range tlsMonitors {
+		tlsMonitor := *value
+		addScheduledTask(activeTasks, task_models.TypeTLS, task_models.QueueTLS, tlsMonitor.Scheduling,
+			func(ctx context.Context, taskPusher push.TaskPusher) (string, error) {
+				return taskPusher.PushTLS(ctx, tlsMonitor)
+			})
+	}
+
+	dnsMonitors, err := c.dnsMonitorRepository.GetActiveDNSMonitors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range dnsMonitors {
+		dnsMonitor := *value
+		addScheduledTask(activeTasks, task_models.TypeDNS, task_models.QueueDNS, dnsMonitor.Scheduling,
+			func(ctx context.Context, taskPusher push.TaskPusher) (string, error) {
+				return taskPusher.PushDNS(ctx, dnsMonitor)
+			})
+	}
+	return activeTasks, nil
+}
+
+// addScheduledTask keys the rule by type and pipeline id, rules with a broken schedule are skipped
+func addScheduledTask(activeTasks map[string]scheduledTask, taskType string, queue string, scheduling usecase_models.Scheduling,
+	push func(ctx context.Context, taskPusher push.TaskPusher) (string, error)) {
+	key := taskType + ":" + strconv.Itoa(scheduling.PipelineId)
+	schedule, err := scheduleOf(scheduling)
+	if err != nil {
+		log.Warnf("skipping %s, schedule is not valid: %v", key, err)
+		return
+	}
+	activeTasks[key] = scheduledTask{key: key, queue: queue, spec: calcCronSpec(scheduling), schedule: schedule, push: push}
+}
+
 func (c *HeartBeatScheduler) HeartBeatScheduling(ctx context.Context) {
 	go func() {
-		var contextMap = make(map[int]context.CancelFunc)
-		var oldActiveEndpoints = new([]*usecase_models.Endpoints)
-		var newActiveEndpoints *[]*usecase_models.Endpoints
+		var contextMap = make(map[string]context.CancelFunc)
+		var oldActiveTasks = make(map[string]scheduledTask)
 		for {
-			e, err := c.endpointRepository.GetActiveEndpoints(ctx)
+			newActiveTasks, err := c.getActiveTasks(ctx)
 			if err != nil {
 				log.Error(err)
 				time.Sleep(5 * time.Second)
 				continue
 			}
-			newActiveEndpoints = &e
 
-			var activeEndpoints = new([]*usecase_models.Endpoints)
-			for _, newActive := range *newActiveEndpoints {
-				flag := false
-				for _, oldActive := range *oldActiveEndpoints {
-					if newActive.Scheduling.PipelineId == oldActive.Scheduling.PipelineId {
-						flag = true
-						break
-					}
-				}
-				if !flag {
-					*activeEndpoints = append(*activeEndpoints, newActive)
-				}
-			}
-			var deActiveEndpoints = new([]*usecase_models.Endpoints)
-			for _, oldActive := range *oldActiveEndpoints {
-				flag := false
-				for _, newActive := range *newActiveEndpoints {
-					if oldActive.Scheduling.PipelineId == newActive.Scheduling.PipelineId {
-						flag = true
-						break
+			var activated int
+			for key, task := range newActiveTasks {
+				if old, ok := oldActiveTasks[key]; ok {
+					if old.spec == task.spec {
+						continue
 					}
+					// schedule was edited, restart the rule with the new one
+					contextMap[key]()
 				}
-				if !flag {
-					*deActiveEndpoints = append(*deActiveEndpoints, oldActive)
-				}
-			}
-
-			for _, endpoint := range *activeEndpoints {
 				hCtx, hCancel := context.WithCancel(context.Background())
-				contextMap[endpoint.Scheduling.PipelineId] = hCancel
-				go heartBeatHandler(hCtx, endpoint, c.taskPusher, c.inspector)
+				contextMap[key] = hCancel
+				go heartBeatHandler(hCtx, task, c.taskPusher, c.inspector)
+				activated++
 			}
-			if len(*activeEndpoints) > 0 {
-				log.Infof("successfully registered %d new heart beat scheduler", len(*activeEndpoints))
+			if activated > 0 {
+				log.Infof("successfully registered %d new heart beat scheduler", activated)
 			}
-			for _, endpoint := range *deActiveEndpoints {
+			var deActivated int
+			for key := range oldActiveTasks {
+				if _, ok := newActiveTasks[key]; ok {
+					continue
+				}
 				// cancel func
-				contextMap[endpoint.Scheduling.PipelineId]()
-				delete(contextMap, endpoint.Scheduling.PipelineId)
+				contextMap[key]()
+				delete(contextMap, key)
+				deActivated++
 			}
-			if len(*deActiveEndpoints) > 0 {
-				log.Infof("successfully deleted %d heart beat scheduler", len(*deActiveEndpoints))
+			if deActivated > 0 {
+				log.Infof("successfully deleted %d heart beat scheduler", deActivated)
 			}
-			oldActiveEndpoints = newActiveEndpoints
-			monitoring.ActiveSchedulerTasksGauge.Set(float64(len(*newActiveEndpoints)))
+			oldActiveTasks = newActiveTasks
+			monitoring.ActiveSchedulerTasksGauge.Set(float64(len(newActiveTasks)))
 			time.Sleep(10 * time.Second)
 		}
 	}()
 }
 
-func heartBeatHandler(ctx context.Context, endpoint *usecase_models.Endpoints,
+func heartBeatHandler(ctx context.Context, task scheduledTask,
 	taskPusher push.TaskPusher, inspector *asynq.Inspector) {
 	for {
+		wait := time.Until(task.schedule.Next(time.Now()))
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			handleTaskExecuting(ctx, endpoint, taskPusher, inspector)
-			time.Sleep(15 * time.Second)
+		case <-time.After(wait):
+			handleTaskExecuting(ctx, task, taskPusher, inspector)
 		}
 	}
 }
 
-func handleTaskExecuting(ctx context.Context, endpoint *usecase_models.Endpoints,
+func handleTaskExecuting(ctx context.Context, task scheduledTask,
 	taskPusher push.TaskPusher, inspector *asynq.Inspector) {
 	taskContext, cf := context.WithTimeout(ctx, 60*time.Second)
 	defer cf()
-	taskId, err := taskPusher.PushEndpoint(ctx, *endpoint)
+	taskId, err := task.push(ctx, taskPusher)
 	if err != nil {
 		log.Error(err)
 	}
 	for {
 		select {
 		case <-taskContext.Done():
-			err = inspector.DeleteTask(task_models.QueueEndpoint, taskId)
+			err = inspector.DeleteTask(task.queue, taskId)
 			if err != nil {
 				log.Error(err)
 			}
 			return
 		default:
 			var err error
-			taskInfo, err := inspector.GetTaskInfo(task_models.QueueEndpoint, taskId)
+			taskInfo, err := inspector.GetTaskInfo(task.queue, taskId)
 			if err != nil {
 				log.Error(err)
 				return
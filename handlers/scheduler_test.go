@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"strings"
+	"test-manager/usecase_models"
+	"testing"
+	"time"
+)
+
+func TestCalcCronSpec(t *testing.T) {
+	tests := []struct {
+		name       string
+		scheduling usecase_models.Scheduling
+		spec       string
+		every      time.Duration
+	}{
+		{
+			name:       "nothing set runs every minute",
+			scheduling: usecase_models.Scheduling{},
+			spec:       "@every 1m",
+			every:      time.Minute,
+		},
+		{
+			name:       "duration in minutes",
+			scheduling: usecase_models.Scheduling{Duration: 10},
+			spec:       "@every 10m",
+			every:      10 * time.Minute,
+		},
+		{
+			name:       "heart beat without interval",
+			scheduling: usecase_models.Scheduling{IsHeartBeat: true, Duration: 10},
+			spec:       "@every 15s",
+			every:      heartBeatInterval,
+		},
+		{
+			name:       "interval seconds wins over heart beat and duration",
+			scheduling: usecase_models.Scheduling{IntervalSeconds: 30, IsHeartBeat: true, Duration: 10},
+			spec:       "@every 30s",
+			every:      30 * time.Second,
+		},
+		{
+			name:       "cron is evaluated in utc",
+			scheduling: usecase_models.Scheduling{Cron: "*/5 * * * *", Duration: 10},
+			spec:       "CRON_TZ=UTC */5 * * * *",
+			every:      5 * time.Minute,
+		},
+		{
+			name:       "cron with timezone",
+			scheduling: usecase_models.Scheduling{Cron: "0 9 * * *", Timezone: "Asia/Tehran"},
+			spec:       "CRON_TZ=Asia/Tehran 0 9 * * *",
+			every:      24 * time.Hour,
+		},
+		{
+			name:       "cron descriptor",
+			scheduling: usecase_models.Scheduling{Cron: "@hourly"},
+			spec:       "CRON_TZ=UTC @hourly",
+			every:      time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if spec := calcCronSpec(tt.scheduling); spec != tt.spec {
+				t.Fatalf("spec = %q, want %q", spec, tt.spec)
+			}
+			schedule, err := scheduleOf(tt.scheduling)
+			if err != nil {
+				t.Fatalf("spec does not parse: %v", err)
+			}
+			next := schedule.Next(time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC))
+			if every := schedule.Next(next).Sub(next); every != tt.every {
+				t.Fatalf("runs every %s, want %s", every, tt.every)
+			}
+		})
+	}
+}
+
+func TestValidateScheduling(t *testing.T) {
+	tests := []struct {
+		name       string
+		scheduling usecase_models.Scheduling
+		err        string
+	}{
+		{name: "duration", scheduling: usecase_models.Scheduling{Duration: 5}},
+		{name: "heart beat", scheduling: usecase_models.Scheduling{IsHeartBeat: true}},
+		{name: "shortest interval", scheduling: usecase_models.Scheduling{IntervalSeconds: minIntervalSeconds}},
+		{name: "cron", scheduling: usecase_models.Scheduling{Cron: "0 */2 * * 1-5"}},
+		{name: "cron with timezone", scheduling: usecase_models.Scheduling{Cron: "0 9 * * *", Timezone: "Europe/Berlin"}},
+		{
+			name:       "cron and interval together",
+			scheduling: usecase_models.Scheduling{Cron: "* * * * *", IntervalSeconds: 30},
+			err:        "only one of cron and interval_seconds",
+		},
+		{
+			name:       "timezone without cron",
+			scheduling: usecase_models.Scheduling{IntervalSeconds: 30, Timezone: "UTC"},
+			err:        "timezone can only be set with cron",
+		},
+		{
+			name:       "timezone inside cron",
+			scheduling: usecase_models.Scheduling{Cron: "CRON_TZ=UTC * * * * *"},
+			err:        "must not contain a timezone",
+		},
+		{
+			name:       "interval below the minimum",
+			scheduling: usecase_models.Scheduling{IntervalSeconds: minIntervalSeconds - 1},
+			err:        "interval_seconds must be at least",
+		},
+		{
+			name:       "negative interval",
+			scheduling: usecase_models.Scheduling{IntervalSeconds: -10},
+			err:        "interval_seconds must be at least",
+		},
+		{
+			name:       "negative duration",
+			scheduling: usecase_models.Scheduling{Duration: -1},
+			err:        "duration can not be negative",
+		},
+		{
+			name:       "unknown timezone",
+			scheduling: usecase_models.Scheduling{Cron: "* * * * *", Timezone: "Mars/Olympus"},
+			err:        "timezone Mars/Olympus is not valid",
+		},
+		{
+			name:       "broken cron",
+			scheduling: usecase_models.Scheduling{Cron: "61 * * * *"},
+			err:        "cron 61 * * * * is not valid",
+		},
+		{
+			name:       "cron with six fields",
+			scheduling: usecase_models.Scheduling{Cron: "0 * * * * *"},
+			err:        "is not valid",
+		},
+		{
+			name: "region strategy with a country",
+			scheduling: usecase_models.Scheduling{DataCenterSelection: usecase_models.DataCenterSelection{
+				Strategy: usecase_models.DataCenterStrategyRegion, CountryName: "Germany"}},
+		},
+		{
+			name: "region strategy without a region",
+			scheduling: usecase_models.Scheduling{DataCenterSelection: usecase_models.DataCenterSelection{
+				Strategy: usecase_models.DataCenterStrategyRegion}},
+			err: "region strategy needs",
+		},
+		{
+			name: "unknown strategy",
+			scheduling: usecase_models.Scheduling{DataCenterSelection: usecase_models.DataCenterSelection{
+				Strategy: "closest"}},
+			err: "data center strategy closest is not valid",
+		},
+		{
+			name: "negative count",
+			scheduling: usecase_models.Scheduling{DataCenterSelection: usecase_models.DataCenterSelection{
+				Strategy: usecase_models.DataCenterStrategyRandomN, Count: -1}},
+			err: "count can not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateScheduling(tt.scheduling)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
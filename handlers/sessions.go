@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/volatiletech/null/v8"
+	"strconv"
+	"strings"
+	"test-manager/cache"
+	"test-manager/monitoring"
+	"test-manager/repos"
+	"test-manager/tasks/push"
+	"test-manager/tasks/task_models"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+// monitorSession is the result of one execution cycle on a single datacenter.
+// every monitor type caches its last session in this shape, so up, down and diff
+// transitions are evaluated the same way regardless of what was checked.
+// AgentError marks a cycle the datacenter agent could not run, its result is unknown.
+type monitorSession struct {
+	DatacenterId int    `json:"datacenter_id"`
+	Success      int    `json:"success"`
+	Url          string `json:"url"`
+	RootCause    string `json:"root_cause"`
+	AgentError   bool   `json:"agent_error"`
+}
+
+type sessionNotifier struct {
+	projectRepo            repos.ProjectsRepository
+	dataCentersRepo        repos.DataCentersRepository
+	maintenanceWindowsRepo repos.MaintenanceWindowsRepository
+	monitorStatesRepo      repos.MonitorStatesRepository
+	cacheRepo              cache.Cache
+	taskPusher             push.TaskPusher
+}
+
+func newSessionNotifier(
+	projectRepo repos.ProjectsRepository,
+	dataCentersRepo repos.DataCentersRepository,
+	maintenanceWindowsRepo repos.MaintenanceWindowsRepository,
+	monitorStatesRepo repos.MonitorStatesRepository,
+	cacheRepo cache.Cache,
+	taskPusher push.TaskPusher,
+) *sessionNotifier {
+	return &sessionNotifier{
+		projectRepo:            projectRepo,
+		dataCentersRepo:        dataCentersRepo,
+		maintenanceWindowsRepo: maintenanceWindowsRepo,
+		monitorStatesRepo:      monitorStatesRepo,
+		cacheRepo:              cacheRepo,
+		taskPusher:             taskPusher,
+	}
+}
+
+// underMaintenance tells if the pipeline is inside one of its project maintenance windows right now
+func (s *sessionNotifier) underMaintenance(ctx context.Context, monitorType string, scheduling usecase_models.Scheduling) bool {
+	maintenance, err := s.maintenanceWindowsRepo.IsUnderMaintenance(ctx, scheduling.ProjectId, monitorType, scheduling.PipelineId, time.Now())
+	if err != nil {
+		log.Warn("problem on checking maintenance windows: ", err)
+		return false
+	}
+	return maintenance
+}
+
+// cachedSession is what is kept between two cycles of a pipeline. Session holds the
+// confirmed state of each datacenter and Failures counts consecutive failed sessions of it.
+// Checked lists the datacenters run on the cycle, the others were left out by the selection
+// strategy and are carried over from earlier cycles.
+type cachedSession struct {
+	Session  []monitorSession `json:"session"`
+	Failures map[int]int      `json:"failures"`
+	Checked  []int            `json:"checked"`
+}
+
+// notifyTransitions swaps the last session of the pipeline with newSession and
+// pushes a down, up or diff notification if the confirmed state changed between them.
+// the last session is read from cache and falls back to the states kept in postgres, so
+// a cache flush or a long pause does not lose transitions.
+// during maintenance the last session is only kept alive, so the state before the window
+// is compared with the first session after it.
+func (s *sessionNotifier) notifyTransitions(ctx context.Context, monitorType string, cachePrefix string,
+	scheduling usecase_models.Scheduling, sessionId string, newSession []monitorSession, maintenance bool) error {
+	cacheKey := cachePrefix + strconv.Itoa(scheduling.PipelineId)
+	reportAgentErrors(monitorType, scheduling, newSession)
+
+	old, found := s.lastSession(ctx, cacheKey, monitorType, scheduling.PipelineId)
+	if found {
+		newSession = keepOfflineDatacenters(s.dataCentersRepo.GetOfflineDataCenterIds(ctx), old.Session, newSession)
+	}
+
+	if maintenance {
+		if found {
+			temp, _ := json.Marshal(old)
+			err := s.cacheRepo.Set(ctx, cacheKey, temp, sessionTTL(scheduling))
+			if err != nil {
+				log.Warn("problem on keeping session on cache: ", err)
+			}
+		}
+		return nil
+	}
+
+	current := confirmSession(scheduling.ConfirmationPolicy, old, newSession)
+
+	temp, _ := json.Marshal(current)
+	err := s.cacheRepo.Set(ctx, cacheKey, temp, sessionTTL(scheduling))
+	if err != nil {
+		log.Warn("problem on setting new session on cache: ", err)
+	}
+	s.saveStates(ctx, monitorType, scheduling, sessionId, current)
+
+	if !found {
+		return errors.New(fmt.Sprintf("first cycle of session started on %s id: %d", monitorType, scheduling.PipelineId))
+	}
+	oldSession := checkedSession(old)
+	newSession = checkedSession(current)
+
+	resolved, failed, newSuccess, oldSuccess := calculateSessionState(scheduling.ConfirmationPolicy, current, old)
+
+	payload := task_models.NotificationsPayload{
+		Type:         monitorType,
+		ProjectId:    scheduling.ProjectId,
+		PipelineId:   scheduling.PipelineId,
+		PipelineName: scheduling.PipelineName,
+		Time:         time.Now().String(),
+		RootCause:    "working on it",
+	}
+	switch {
+	case oldSuccess && !newSuccess:
+		payload.State = "down"
+		for _, value := range oldSession {
+			if value.Success == 1 {
+				payload.Address = lastAddress(value.Url)
+				break
+			}
+		}
+		for _, value := range newSession {
+			if value.Success == 0 {
+				payload.RootCause = value.RootCause
+			}
+		}
+		// every datacenter that confirmed the outage, not only those failed on this cycle
+		failed = failedDatacenters(newSession)
+	case !oldSuccess && newSuccess:
+		payload.State = "up"
+		for _, value := range oldSession {
+			if value.Success == 0 {
+				payload.RootCause = value.RootCause
+				payload.Address = lastAddress(value.Url)
+				break
+			}
+		}
+	case !oldSuccess && !newSuccess && (len(resolved) > 0 || len(failed) > 0):
+		payload.State = "diff"
+		for _, value := range oldSession {
+			payload.RootCause = value.RootCause
+			payload.Address = lastAddress(value.Url)
+			break
+		}
+	default:
+		return nil
+	}
+
+	project, err := s.projectRepo.GetProjectWithLoads(ctx, scheduling.ProjectId)
+	if err != nil {
+		log.Info("error on getting project in executing rule: ", err)
+		return err
+	}
+	var notifications usecase_models.Notifications
+	err = json.Unmarshal(project.Notifications.JSON, &notifications)
+	if err != nil {
+		log.Info("can not unmarshal notification")
+		return err
+	}
+	payload.Username = project.R.Account.Username.String
+
+	dcs, err := s.dataCentersRepo.GetDataCentersWithCache(ctx)
+	if err != nil {
+		log.Info("problem in getting datacenters in sending alerts: ", err.Error())
+	}
+	switch payload.State {
+	case "down":
+		payload.Datacenters = strings.Join(datacenterTitles(dcs, failed), ",")
+	case "up":
+		payload.Datacenters = strings.Join(datacenterTitles(dcs, resolved), ",")
+	case "diff":
+		payload.ResolvedDatacenters = strings.Join(datacenterTitles(dcs, resolved), ",")
+		payload.FailedDatacenters = strings.Join(datacenterTitles(dcs, failed), ",")
+	}
+
+	_, err = s.taskPusher.PushNotifications(ctx, payload)
+	if err != nil {
+		log.Info(err.Error())
+	}
+	return nil
+}
+
+// lastSession returns the last confirmed session of the pipeline from cache, or rebuilds it from the
+// states kept in postgres when the cache is empty or unreadable. found is false only on the very first cycle
+func (s *sessionNotifier) lastSession(ctx context.Context, cacheKey string, monitorType string, pipelineId int) (cachedSession, bool) {
+	var old cachedSession
+	// rosin means nothing
+	rosin, err := s.cacheRepo.Get(ctx, cacheKey)
+	if err != nil {
+		log.Warn("could not find old session on cache: ", err)
+	}
+	if rosinStr, ok := rosin.(string); ok && rosinStr != "" {
+		err = json.Unmarshal([]byte(rosinStr), &old)
+		if err == nil {
+			return old, true
+		}
+		// a broken cache entry is rebuilt from the states below instead of reading as an up monitor
+		log.Error("problem on unmarshalling rosinByte: ", err)
+		old = cachedSession{}
+	}
+
+	states, err := s.monitorStatesRepo.GetStates(ctx, monitorType, pipelineId)
+	if err != nil {
+		log.Warn("problem on getting monitor states: ", err)
+		return old, false
+	}
+	if len(states) == 0 {
+		return old, false
+	}
+	// the datacenters of the last cycle are those written by its session
+	latest := states[0]
+	for _, state := range states {
+		if state.UpdatedAt.After(latest.UpdatedAt) {
+			latest = state
+		}
+	}
+	old.Failures = make(map[int]int)
+	for _, state := range states {
+		success := 0
+		if state.Status != usecase_models.MonitorStatusDown {
+			success = 1
+		}
+		old.Session = append(old.Session, monitorSession{
+			DatacenterId: state.DatacenterId,
+			Success:      success,
+			Url:          state.Address.String,
+			RootCause:    state.RootCause.String,
+			AgentError:   state.Status == usecase_models.MonitorStatusUnknown,
+		})
+		old.Failures[state.DatacenterId] = state.ConsecutiveFailures
+		if state.LastSessionId == latest.LastSessionId {
+			old.Checked = append(old.Checked, state.DatacenterId)
+		}
+	}
+	return old, true
+}
+
+// saveStates keeps the confirmed state of the datacenters checked on the cycle in postgres,
+// errors are only logged since the cache still holds it
+func (s *sessionNotifier) saveStates(ctx context.Context, monitorType string, scheduling usecase_models.Scheduling, sessionId string, current cachedSession) {
+	if len(current.Checked) == 0 {
+		return
+	}
+	now := time.Now()
+	var states []usecase_models.MonitorState
+	for _, value := range current.Session {
+		if !contains(current.Checked, value.DatacenterId) {
+			continue
+		}
+		status := usecase_models.MonitorStatusUp
+		if value.AgentError {
+			status = usecase_models.MonitorStatusUnknown
+		} else if value.Success == 0 {
+			status = usecase_models.MonitorStatusDown
+		}
+		states = append(states, usecase_models.MonitorState{
+			DatacenterId:        value.DatacenterId,
+			ProjectId:           scheduling.ProjectId,
+			Status:              status,
+			Since:               now,
+			LastSessionId:       sessionId,
+			ConsecutiveFailures: current.Failures[value.DatacenterId],
+			Address:             null.NewString(value.Url, value.Url != ""),
+			RootCause:           null.NewString(value.RootCause, value.RootCause != ""),
+		})
+	}
+	err := s.monitorStatesRepo.SaveStates(ctx, monitorType, scheduling.PipelineId, states)
+	if err != nil {
+		log.Warn("problem on saving monitor states: ", err)
+	}
+}
+
+// sessionTTL keeps the cached session for two cycles of the pipeline
+func sessionTTL(scheduling usecase_models.Scheduling) time.Duration {
+	schedule, err := scheduleOf(scheduling)
+	if err != nil {
+		return time.Duration(2*scheduling.Duration) * time.Minute
+	}
+	next := schedule.Next(time.Now())
+	return 2 * schedule.Next(next).Sub(next)
+}
+
+// confirmSession counts consecutive failures per datacenter and only lets a failure
+// into the confirmed session once it happened policy.ConsecutiveSessions times in a row.
+// until then the datacenter keeps its previously confirmed result, recoveries apply at once.
+// agent errors keep the previously confirmed result as is, a datacenter without one is
+// confirmed as unknown, kept with AgentError set, which calculateSessionState counts as
+// neither up nor down, so agent errors never notify.
+// datacenters of old that are not in newSession keep their confirmed result and streak.
+func confirmSession(policy usecase_models.ConfirmationPolicy, old cachedSession, newSession []monitorSession) cachedSession {
+	current := cachedSession{Failures: make(map[int]int)}
+	var oldConfirmed = make(map[int]monitorSession)
+	for _, value := range old.Session {
+		oldConfirmed[value.DatacenterId] = value
+	}
+	for _, value := range newSession {
+		current.Checked = append(current.Checked, value.DatacenterId)
+		if value.AgentError {
+			current.Failures[value.DatacenterId] = old.Failures[value.DatacenterId]
+			confirmed, ok := oldConfirmed[value.DatacenterId]
+			if !ok {
+				confirmed = value
+				confirmed.Success = 1
+			}
+			current.Session = append(current.Session, confirmed)
+			continue
+		}
+		if value.Success != 0 {
+			current.Failures[value.DatacenterId] = 0
+			current.Session = append(current.Session, value)
+			continue
+		}
+		current.Failures[value.DatacenterId] = old.Failures[value.DatacenterId] + 1
+		if current.Failures[value.DatacenterId] >= policy.ConsecutiveSessions {
+			current.Session = append(current.Session, value)
+			continue
+		}
+		confirmed, ok := oldConfirmed[value.DatacenterId]
+		if !ok {
+			confirmed = value
+			confirmed.Success = 1
+			confirmed.RootCause = ""
+		}
+		current.Session = append(current.Session, confirmed)
+	}
+	for _, value := range old.Session {
+		if contains(current.Checked, value.DatacenterId) {
+			continue
+		}
+		current.Failures[value.DatacenterId] = old.Failures[value.DatacenterId]
+		current.Session = append(current.Session, value)
+	}
+	return current
+}
+
+// calculateSessionState returns resolved and failed datacenters between two sessions and whether
+// each session is up. a session is down once policy.MinFailedDatacenters of the datacenters checked
+// on its cycle failed, datacenters left out by the selection strategy and unknown ones do not count.
+func calculateSessionState(policy usecase_models.ConfirmationPolicy, current, old cachedSession) ([]int, []int, bool, bool) {
+	var mapODcSuccess = make(map[int]bool)
+	for _, value := range old.Session {
+		if !value.AgentError {
+			mapODcSuccess[value.DatacenterId] = value.Success != 0
+		}
+	}
+
+	var resolved []int
+	var failed []int
+	for _, value := range checkedSession(current) {
+		if value.AgentError {
+			continue
+		}
+		newValue := value.Success != 0
+		oldValue, ok := mapODcSuccess[value.DatacenterId]
+		if !ok {
+			oldValue = true
+		}
+
+		if newValue && !oldValue {
+			// resolved
+			resolved = append(resolved, value.DatacenterId)
+		} else if !newValue && oldValue {
+			// error detected
+			failed = append(failed, value.DatacenterId)
+		}
+	}
+	return resolved, failed, sessionUp(policy, checkedSession(current)), sessionUp(policy, checkedSession(old))
+}
+
+// checkedSession returns the confirmed state of the datacenters run on the cycle of the session.
+// sessions cached without Checked count all of their datacenters
+func checkedSession(session cachedSession) []monitorSession {
+	if session.Checked == nil {
+		return session.Session
+	}
+	var checked []monitorSession
+	for _, value := range session.Session {
+		if contains(session.Checked, value.DatacenterId) {
+			checked = append(checked, value)
+		}
+	}
+	return checked
+}
+
+// sessionUp tells if fewer than policy.MinFailedDatacenters of the known datacenters of the session failed
+func sessionUp(policy usecase_models.ConfirmationPolicy, session []monitorSession) bool {
+	var known int
+	var failures int
+	for _, value := range session {
+		if value.AgentError {
+			continue
+		}
+		known++
+		if value.Success == 0 {
+			failures++
+		}
+	}
+
+	quorum := policy.MinFailedDatacenters
+	if quorum > known {
+		quorum = known
+	}
+	if quorum < 1 {
+		quorum = 1
+	}
+	return failures < quorum
+}
+
+// reportAgentErrors counts the datacenters whose agent could not run the checks, these are operator problems
+func reportAgentErrors(monitorType string, scheduling usecase_models.Scheduling, session []monitorSession) {
+	for _, value := range session {
+		if !value.AgentError {
+			continue
+		}
+		monitoring.AgentErrorsCounter.WithLabelValues(strconv.Itoa(value.DatacenterId), monitorType).Inc()
+		log.Errorf("agent of datacenter %d could not run %s id: %d: %s", value.DatacenterId, monitorType, scheduling.PipelineId, value.RootCause)
+	}
+}
+
+// keepOfflineDatacenters adds the datacenters skipped for being offline back to the session as
+// agent errors, so they keep their confirmed result instead of looking resolved or removed
+func keepOfflineDatacenters(offlineIds []int, oldSession, newSession []monitorSession) []monitorSession {
+	if len(offlineIds) == 0 {
+		return newSession
+	}
+	var checked = make(map[int]bool)
+	for _, value := range newSession {
+		checked[value.DatacenterId] = true
+	}
+	for _, value := range oldSession {
+		if checked[value.DatacenterId] || !contains(offlineIds, value.DatacenterId) {
+			continue
+		}
+		newSession = append(newSession, monitorSession{
+			DatacenterId: value.DatacenterId,
+			Success:      value.Success,
+			Url:          value.Url,
+			RootCause:    "datacenter is offline",
+			AgentError:   true,
+		})
+	}
+	return newSession
+}
+
+func failedDatacenters(session []monitorSession) []int {
+	var ids []int
+	for _, value := range session {
+		if value.Success == 0 {
+			ids = append(ids, value.DatacenterId)
+		}
+	}
+	return ids
+}
+
+func datacenterTitles(dcs []*models.Datacenter, ids []int) []string {
+	var titles []string
+	for _, value := range ids {
+		for _, dc := range dcs {
+			if dc.ID == value {
+				titles = append(titles, dc.Title)
+			}
+		}
+	}
+	return titles
+}
+
+func lastAddress(urls string) string {
+	t := strings.Split(urls, ",")
+	return t[len(t)-1]
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"test-manager/usecase_models"
+	"testing"
+)
+
+func up(datacenterId int) monitorSession {
+	return monitorSession{DatacenterId: datacenterId, Success: 1, Url: "https://example.com"}
+}
+
+func down(datacenterId int) monitorSession {
+	return monitorSession{DatacenterId: datacenterId, Success: 0, Url: "https://example.com", RootCause: "status 500"}
+}
+
+func agentError(datacenterId int) monitorSession {
+	return monitorSession{DatacenterId: datacenterId, Success: 0, RootCause: "agent is not reachable", AgentError: true}
+}
+
+func TestConfirmSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   usecase_models.ConfirmationPolicy
+		old      cachedSession
+		new      []monitorSession
+		session  []monitorSession
+		failures map[int]int
+	}{
+		{
+			name:     "zero policy confirms a failure at once",
+			old:      cachedSession{Session: []monitorSession{up(1)}},
+			new:      []monitorSession{down(1)},
+			session:  []monitorSession{down(1)},
+			failures: map[int]int{1: 1},
+		},
+		{
+			name:     "failure below the streak keeps the confirmed result",
+			policy:   usecase_models.ConfirmationPolicy{ConsecutiveSessions: 3},
+			old:      cachedSession{Session: []monitorSession{up(1)}, Failures: map[int]int{1: 1}},
+			new:      []monitorSession{down(1)},
+			session:  []monitorSession{up(1)},
+			failures: map[int]int{1: 2},
+		},
+		{
+			name:     "failure completing the streak is confirmed",
+			policy:   usecase_models.ConfirmationPolicy{ConsecutiveSessions: 3},
+			old:      cachedSession{Session: []monitorSession{up(1)}, Failures: map[int]int{1: 2}},
+			new:      []monitorSession{down(1)},
+			session:  []monitorSession{down(1)},
+			failures: map[int]int{1: 3},
+		},
+		{
+			name:     "recovery applies at once and resets the streak",
+			policy:   usecase_models.ConfirmationPolicy{ConsecutiveSessions: 3},
+			old:      cachedSession{Session: []monitorSession{down(1)}, Failures: map[int]int{1: 5}},
+			new:      []monitorSession{up(1)},
+			session:  []monitorSession{up(1)},
+			failures: map[int]int{1: 0},
+		},
+		{
+			name:     "new datacenter failing below the streak is confirmed up without root cause",
+			policy:   usecase_models.ConfirmationPolicy{ConsecutiveSessions: 2},
+			new:      []monitorSession{down(4)},
+			session:  []monitorSession{{DatacenterId: 4, Success: 1, Url: "https://example.com"}},
+			failures: map[int]int{4: 1},
+		},
+		{
+			name:     "agent error keeps the confirmed result and the streak",
+			policy:   usecase_models.ConfirmationPolicy{ConsecutiveSessions: 3},
+			old:      cachedSession{Session: []monitorSession{down(1)}, Failures: map[int]int{1: 4}},
+			new:      []monitorSession{agentError(1)},
+			session:  []monitorSession{down(1)},
+			failures: map[int]int{1: 4},
+		},
+		{
+			name:     "datacenters left out of the cycle keep their result and streak",
+			policy:   usecase_models.ConfirmationPolicy{ConsecutiveSessions: 3},
+			old:      cachedSession{Session: []monitorSession{down(1), up(2), up(3)}, Failures: map[int]int{1: 4, 2: 0, 3: 2}},
+			new:      []monitorSession{up(2)},
+			session:  []monitorSession{up(2), down(1), up(3)},
+			failures: map[int]int{1: 4, 2: 0, 3: 2},
+		},
+		{
+			name:     "agent error without a confirmed result is not a failure",
+			new:      []monitorSession{agentError(2)},
+			session:  []monitorSession{{DatacenterId: 2, Success: 1, RootCause: "agent is not reachable", AgentError: true}},
+			failures: map[int]int{2: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := confirmSession(tt.policy, tt.old, tt.new)
+			if !reflect.DeepEqual(current.Session, tt.session) {
+				t.Fatalf("session = %+v, want %+v", current.Session, tt.session)
+			}
+			if !reflect.DeepEqual(current.Failures, tt.failures) {
+				t.Fatalf("failures = %v, want %v", current.Failures, tt.failures)
+			}
+		})
+	}
+}
+
+func TestConfirmSessionStreakResetOnRecovery(t *testing.T) {
+	policy := usecase_models.ConfirmationPolicy{ConsecutiveSessions: 3}
+	current := cachedSession{Session: []monitorSession{up(1)}}
+	// two failures, a recovery, then two failures again never make three in a row
+	for i, session := range [][]monitorSession{{down(1)}, {down(1)}, {up(1)}, {down(1)}, {down(1)}} {
+		current = confirmSession(policy, current, session)
+		if current.Session[0].Success != 1 {
+			t.Fatalf("cycle %d confirmed the datacenter down with failures %v", i, current.Failures)
+		}
+	}
+	current = confirmSession(policy, current, []monitorSession{down(1)})
+	if current.Session[0].Success != 0 || current.Failures[1] != 3 {
+		t.Fatalf("third failure in a row was not confirmed: %+v", current)
+	}
+}
+
+func TestCalculateSessionState(t *testing.T) {
+	unknown := monitorSession{DatacenterId: 2, Success: 1, RootCause: "agent is not reachable", AgentError: true}
+	tests := []struct {
+		name       string
+		policy     usecase_models.ConfirmationPolicy
+		old        cachedSession
+		new        cachedSession
+		resolved   []int
+		failed     []int
+		newSuccess bool
+		oldSuccess bool
+	}{
+		{
+			name:       "zero quorum means one failed datacenter is down",
+			old:        cachedSession{Session: []monitorSession{up(1), up(2), up(3)}},
+			new:        cachedSession{Session: []monitorSession{up(1), down(2), up(3)}},
+			failed:     []int{2},
+			newSuccess: false,
+			oldSuccess: true,
+		},
+		{
+			name:       "below the quorum is still up",
+			policy:     usecase_models.ConfirmationPolicy{MinFailedDatacenters: 2},
+			old:        cachedSession{Session: []monitorSession{up(1), up(2), up(3)}},
+			new:        cachedSession{Session: []monitorSession{up(1), down(2), up(3)}},
+			failed:     []int{2},
+			newSuccess: true,
+			oldSuccess: true,
+		},
+		{
+			name:       "reaching the quorum is down",
+			policy:     usecase_models.ConfirmationPolicy{MinFailedDatacenters: 2},
+			old:        cachedSession{Session: []monitorSession{up(1), down(2), up(3)}},
+			new:        cachedSession{Session: []monitorSession{down(1), down(2), up(3)}},
+			failed:     []int{1},
+			newSuccess: false,
+			oldSuccess: true,
+		},
+		{
+			name:       "quorum above the datacenter count is capped to all of them",
+			policy:     usecase_models.ConfirmationPolicy{MinFailedDatacenters: 5},
+			old:        cachedSession{Session: []monitorSession{up(1), up(2)}},
+			new:        cachedSession{Session: []monitorSession{down(1), down(2)}},
+			failed:     []int{1, 2},
+			newSuccess: false,
+			oldSuccess: true,
+		},
+		{
+			name:       "recovery below the quorum is up again",
+			policy:     usecase_models.ConfirmationPolicy{MinFailedDatacenters: 2},
+			old:        cachedSession{Session: []monitorSession{down(1), down(2), up(3)}},
+			new:        cachedSession{Session: []monitorSession{up(1), down(2), up(3)}},
+			resolved:   []int{1},
+			newSuccess: true,
+			oldSuccess: false,
+		},
+		{
+			name:       "datacenter left out of the cycle is neither resolved nor counted",
+			policy:     usecase_models.ConfirmationPolicy{MinFailedDatacenters: 2},
+			old:        cachedSession{Session: []monitorSession{down(1), down(2), up(3)}, Checked: []int{1, 2}},
+			new:        cachedSession{Session: []monitorSession{down(1), up(3), down(2)}, Checked: []int{1, 3}},
+			newSuccess: true,
+			oldSuccess: false,
+		},
+		{
+			name:       "rotation over up datacenters stays up",
+			old:        cachedSession{Session: []monitorSession{up(1), up(2), down(3)}, Checked: []int{1, 2}},
+			new:        cachedSession{Session: []monitorSession{up(3), up(4), up(1), up(2)}, Checked: []int{3, 4}},
+			resolved:   []int{3},
+			newSuccess: true,
+			oldSuccess: true,
+		},
+		{
+			name:       "rotation onto another failing datacenter is a diff",
+			old:        cachedSession{Session: []monitorSession{down(1), up(2)}, Checked: []int{1}},
+			new:        cachedSession{Session: []monitorSession{down(2), down(1)}, Checked: []int{2}},
+			failed:     []int{2},
+			newSuccess: false,
+			oldSuccess: false,
+		},
+		{
+			name:       "unknown datacenter counts as neither up nor down",
+			policy:     usecase_models.ConfirmationPolicy{MinFailedDatacenters: 2},
+			old:        cachedSession{Session: []monitorSession{up(1)}},
+			new:        cachedSession{Session: []monitorSession{down(1), unknown}},
+			failed:     []int{1},
+			newSuccess: false,
+			oldSuccess: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, failed, newSuccess, oldSuccess := calculateSessionState(tt.policy, tt.new, tt.old)
+			sort.Ints(resolved)
+			sort.Ints(failed)
+			if !reflect.DeepEqual(resolved, tt.resolved) || !reflect.DeepEqual(failed, tt.failed) {
+				t.Fatalf("resolved, failed = %v, %v, want %v, %v", resolved, failed, tt.resolved, tt.failed)
+			}
+			if newSuccess != tt.newSuccess || oldSuccess != tt.oldSuccess {
+				t.Fatalf("newSuccess, oldSuccess = %v, %v, want %v, %v", newSuccess, oldSuccess, tt.newSuccess, tt.oldSuccess)
+			}
+		})
+	}
+}
+
+func (f *fakeDatacentersRepo) GetOfflineDataCenterIds(ctx context.Context) []int {
+	return nil
+}
+
+func TestNotifyTransitionsUnderMaintenance(t *testing.T) {
+	ctx := context.Background()
+	cacheRepo := newMemoryCache()
+	notifier := &sessionNotifier{dataCentersRepo: &fakeDatacentersRepo{}, cacheRepo: cacheRepo}
+	scheduling := usecase_models.Scheduling{PipelineId: 9, ProjectId: 1}
+	before := cachedSession{Session: []monitorSession{down(1), up(2)}, Failures: map[int]int{1: 3, 2: 0}}
+	beforeB, _ := json.Marshal(before)
+	_ = cacheRepo.Set(ctx, "session:9", beforeB, 0)
+
+	// sessions inside the window neither notify nor replace the session from before it
+	for _, session := range [][]monitorSession{{up(1), up(2)}, {down(1), down(2)}} {
+		if err := notifier.notifyTransitions(ctx, "endpoint", "session:", scheduling, "session", session, true); err != nil {
+			t.Fatalf("maintenance cycle returned %v", err)
+		}
+	}
+	kept, found := notifier.lastSession(ctx, "session:9", "endpoint", 9)
+	if !found || !reflect.DeepEqual(kept, before) {
+		t.Fatalf("session after maintenance = %+v, want the one from before the window %+v", kept, before)
+	}
+
+	// the first session after the window is compared with the one before it
+	current := confirmSession(scheduling.ConfirmationPolicy, kept, []monitorSession{up(1), up(2)})
+	resolved, failed, newSuccess, oldSuccess := calculateSessionState(scheduling.ConfirmationPolicy, current, kept)
+	if !reflect.DeepEqual(resolved, []int{1}) || len(failed) != 0 || !newSuccess || oldSuccess {
+		t.Fatalf("after maintenance got resolved %v failed %v up %v was up %v", resolved, failed, newSuccess, oldSuccess)
+	}
+}
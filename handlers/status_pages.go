@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"test-manager/repos"
+	"test-manager/usecase_models"
+	"time"
+)
+
+const StatusPageCacheTTL = time.Minute
+
+var statusPageTemplate = template.Must(template.New("status_page").Funcs(template.FuncMap{
+	"percent": func(uptime usecase_models.DailyUptime) string {
+		if !uptime.Uptime.Valid {
+			return "no data"
+		}
+		return fmt.Sprintf("%.2f%%", uptime.Uptime.Float64)
+	},
+	"bar": func(uptime usecase_models.DailyUptime) string {
+		switch {
+		case !uptime.Uptime.Valid:
+			return "#d0d4d9"
+		case uptime.Uptime.Float64 >= 99:
+			return "#3bd671"
+		case uptime.Uptime.Float64 >= 95:
+			return "#f29030"
+		default:
+			return "#df484a"
+		}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="60">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, sans-serif; max-width: 860px; margin: 40px auto; padding: 0 16px; color: #2b2f33; }
+.status { padding: 16px; border-radius: 6px; color: #fff; font-weight: bold; margin-bottom: 24px; }
+.operational { background: #3bd671; } .degraded, .maintenance { background: #f29030; } .down { background: #df484a; } .no_data { background: #8a9199; }
+.component { border: 1px solid #e3e6e8; border-radius: 6px; padding: 12px 16px; margin-bottom: 12px; }
+.component-head { display: flex; justify-content: space-between; margin-bottom: 8px; }
+.bars { display: flex; gap: 2px; } .bars span { flex: 1; height: 28px; border-radius: 2px; }
+.incident { border-left: 4px solid #df484a; padding: 8px 12px; margin-bottom: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+<div class="status {{.Status}}">{{.Status}}</div>
+{{if .Incidents}}<h2>Active incidents</h2>{{end}}
+{{range .Incidents}}<div class="incident"><strong>{{.Component}}</strong> since {{.StartedAt.Format "2006-01-02 15:04 MST"}}{{if .Acknowledged}}, investigating{{end}}</div>
+{{end}}
+<h2>Components</h2>
+{{range .Components}}<div class="component">
+<div class="component-head"><span>{{.Name}}</span><span>{{if .Uptime.Valid}}{{printf "%.2f" .Uptime.Float64}}% uptime · {{end}}{{.Status}}</span></div>
+<div class="bars">{{range .Days}}<span style="background: {{bar .}}" title="{{.Day.Format "2006-01-02"}}: {{percent .}}"></span>{{end}}</div>
+</div>
+{{end}}
+<p><small>Last updated {{.UpdatedAt.Format "2006-01-02 15:04:05 MST"}}</small></p>
+</body>
+</html>
+`))
+
+// getPipelineScheduling finds the scheduling of a pipeline by its monitor type
+func (hc *httpControllers) getPipelineScheduling(ctx context.Context, pipelineType string, pipelineId int) (usecase_models.Scheduling, error) {
+	switch pipelineType {
+	case "endpoint":
+		endpoint, err := hc.endpointRepository.GetEndpoint(ctx, pipelineId)
+		if err != nil {
+			return usecase_models.Scheduling{}, err
+		}
+		return endpoint.Scheduling, nil
+	case "netcat":
+		netCat, err := hc.netCatRepository.GetNetCat(ctx, pipelineId)
+		if err != nil {
+			return usecase_models.Scheduling{}, err
+		}
+		return netCat.Scheduling, nil
+	case "pagespeed":
+		pageSpeed, err := hc.pageSpeedRepository.GetPageSpeed(ctx, pipelineId)
+		if err != nil {
+			return usecase_models.Scheduling{}, err
+		}
+		return pageSpeed.Scheduling, nil
+	case "ping":
+		ping, err := hc.pingRepository.GetPing(ctx, pipelineId)
+		if err != nil {
+			return usecase_models.Scheduling{}, err
+		}
+		return ping.Scheduling, nil
+	case "traceroute":
+		traceRoute, err := hc.traceRouteRepository.GetTraceRoute(ctx, pipelineId)
+		if err != nil {
+			return usecase_models.Scheduling{}, err
+		}
+		return traceRoute.Scheduling, nil
+	case usecase_models.TLSMonitorType:
+		tlsMonitor, err := hc.tlsMonitorsRepository.GetTLSMonitor(ctx, pipelineId)
+		if err != nil {
+			return usecase_models.Scheduling{}, err
+		}
+		return tlsMonitor.Scheduling, nil
+	case usecase_models.DNSMonitorType:
+		dnsMonitor, err := hc.dnsMonitorsRepository.GetDNSMonitor(ctx, pipelineId)
+		if err != nil {
+			return usecase_models.Scheduling{}, err
+		}
+		return dnsMonitor.Scheduling, nil
+	}
+	return usecase_models.Scheduling{}, fmt.Errorf("unknown pipeline type: %s", pipelineType)
+}
+
+func componentStatus(state repos.LastSessionState) string {
+	switch {
+	case state.Total == 0:
+		return usecase_models.ComponentStatusNoData
+	case state.IsMaintenance:
+		return usecase_models.ComponentStatusMaintenance
+	case state.Success == state.Total:
+		return usecase_models.ComponentStatusOperational
+	case state.Success == 0:
+		return usecase_models.ComponentStatusDown
+	}
+	return usecase_models.ComponentStatusDegraded
+}
+
+// buildPublicStatusPage computes the current state, daily uptime bars and open incidents of the page components
+func (hc *httpControllers) buildPublicStatusPage(ctx context.Context, page usecase_models.StatusPage) (usecase_models.PublicStatusPage, error) {
+	now := time.Now()
+	since := now.AddDate(0, 0, -(usecase_models.StatusPageDays - 1))
+	publicPage := usecase_models.PublicStatusPage{
+		Title:       page.Title,
+		Description: page.Description.String,
+		Status:      usecase_models.ComponentStatusOperational,
+		Components:  []usecase_models.PublicStatusComponent{},
+		Incidents:   []usecase_models.PublicIncident{},
+		UpdatedAt:   now,
+	}
+
+	componentNames := map[string]string{}
+	for _, component := range page.Components {
+		componentNames[fmt.Sprintf("%s:%d", component.PipelineType, component.PipelineId)] = component.Name
+
+		state, err := hc.uptimeRepository.GetLastSessionState(ctx, component.PipelineType, component.PipelineId)
+		if err != nil {
+			return usecase_models.PublicStatusPage{}, err
+		}
+		uptime, err := hc.uptimeRepository.GetUptime(ctx, component.PipelineType, component.PipelineId, since)
+		if err != nil {
+			return usecase_models.PublicStatusPage{}, err
+		}
+		days, err := hc.uptimeRepository.GetDailyUptime(ctx, component.PipelineType, component.PipelineId, since)
+		if err != nil {
+			return usecase_models.PublicStatusPage{}, err
+		}
+
+		status := componentStatus(state)
+		switch {
+		case status == usecase_models.ComponentStatusDown:
+			publicPage.Status = usecase_models.ComponentStatusDown
+		case status == usecase_models.ComponentStatusDegraded && publicPage.Status != usecase_models.ComponentStatusDown:
+			publicPage.Status = usecase_models.ComponentStatusDegraded
+		case status == usecase_models.ComponentStatusMaintenance && publicPage.Status == usecase_models.ComponentStatusOperational:
+			publicPage.Status = usecase_models.ComponentStatusMaintenance
+		}
+
+		publicPage.Components = append(publicPage.Components, usecase_models.PublicStatusComponent{
+			Name:   component.Name,
+			Status: status,
+			Uptime: uptime,
+			Days:   days,
+		})
+	}
+
+	incidents, err := hc.incidentsRepository.GetIncidents(ctx, repos.Filters{
+		repos.Filter{Field: "project_id", Op: repos.FilterOpEq, Value: page.ProjectId},
+		repos.Filter{Field: "status", Op: repos.FilterOpEq, Value: usecase_models.IncidentStatusOpen},
+	}, 0, 0)
+	if err != nil {
+		return usecase_models.PublicStatusPage{}, err
+	}
+	for _, incident := range incidents {
+		name, ok := componentNames[fmt.Sprintf("%s:%d", incident.PipelineType, incident.PipelineId)]
+		if !ok {
+			continue
+		}
+		publicPage.Incidents = append(publicPage.Incidents, usecase_models.PublicIncident{
+			Component:    name,
+			StartedAt:    incident.StartedAt,
+			Acknowledged: incident.AcknowledgedAt.Valid,
+		})
+	}
+	return publicPage, nil
+}
+
+// getPublicStatusPage loads the public page of a slug, pages are cached for a short time as they are unauthenticated
+func (hc *httpControllers) getPublicStatusPage(ctx context.Context, slug string) (usecase_models.PublicStatusPage, error) {
+	var publicPage usecase_models.PublicStatusPage
+	cacheKey := "status_page:" + slug
+	if cached, err := hc.redisCache.Get(ctx, cacheKey); err == nil {
+		if err = json.Unmarshal([]byte(cached.(string)), &publicPage); err == nil {
+			return publicPage, nil
+		}
+	}
+
+	page, err := hc.statusPagesRepository.GetStatusPageBySlug(ctx, slug)
+	if err != nil {
+		return usecase_models.PublicStatusPage{}, err
+	}
+	publicPage, err = hc.buildPublicStatusPage(ctx, page)
+	if err != nil {
+		return usecase_models.PublicStatusPage{}, err
+	}
+
+	if data, err := json.Marshal(publicPage); err == nil {
+		_ = hc.redisCache.Set(ctx, cacheKey, data, StatusPageCacheTTL)
+	}
+	return publicPage, nil
+}
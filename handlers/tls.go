@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"github.com/volatiletech/null/v8"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"test-manager/cache"
+	"test-manager/repos"
+	"test-manager/tasks/push"
+	"test-manager/tasks/task_models"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+type TLSHandler interface {
+	ExecuteTLSRule(ctx context.Context, tlsRules usecase_models.TLSMonitors) error
+}
+
+type tlsHandler struct {
+	tlsMonitorsRepo    repos.TLSMonitorsRepository
+	tlsStatsRepo       repos.TLSStatsRepository
+	dataCentersRepo    repos.DataCentersRepository
+	projectRepo        repos.ProjectsRepository
+	cacheRepo          cache.Cache
+	maintenanceRepo    repos.MaintenanceWindowsRepository
+	taskPusher         push.TaskPusher
+	agentHandler       AgentHandler
+	sessionNotifier    *sessionNotifier
+	dataCenterSelector *dataCenterSelector
+}
+
+func NewTLSHandler(
+	tlsMonitorsRepo repos.TLSMonitorsRepository,
+	tlsStatsRepo repos.TLSStatsRepository,
+	dataCentersRepo repos.DataCentersRepository,
+	projectRepo repos.ProjectsRepository,
+	cacheRepo cache.Cache,
+	maintenanceRepo repos.MaintenanceWindowsRepository,
+	monitorStatesRepo repos.MonitorStatesRepository,
+	taskPusher push.TaskPusher,
+	agentHandler AgentHandler,
+) TLSHandler {
+	return &tlsHandler{
+		tlsMonitorsRepo:    tlsMonitorsRepo,
+		tlsStatsRepo:       tlsStatsRepo,
+		dataCentersRepo:    dataCentersRepo,
+		projectRepo:        projectRepo,
+		cacheRepo:          cacheRepo,
+		maintenanceRepo:    maintenanceRepo,
+		taskPusher:         taskPusher,
+		agentHandler:       agentHandler,
+		sessionNotifier:    newSessionNotifier(projectRepo, dataCentersRepo, maintenanceRepo, monitorStatesRepo, cacheRepo, taskPusher),
+		dataCenterSelector: newDataCenterSelector(dataCentersRepo, cacheRepo),
+	}
+}
+
+func (e *tlsHandler) ExecuteTLSRule(ctx context.Context, tlsRules usecase_models.TLSMonitors) error {
+	dataCentersIds, err := e.dataCenterSelector.selectDataCenters(ctx, usecase_models.TLSMonitorType, tlsRules.Scheduling)
+	if err != nil {
+		return err
+	}
+	tlsRules.Scheduling.DataCentersIds = dataCentersIds
+
+	maintenance := e.sessionNotifier.underMaintenance(ctx, usecase_models.TLSMonitorType, tlsRules.Scheduling)
+	session, _ := uuid.NewUUID()
+	sessionResults := make([]monitorSession, len(tlsRules.Scheduling.DataCentersIds))
+	sessionStats := make([][]repos.WriteTLSStatsOptions, len(tlsRules.Scheduling.DataCentersIds))
+	sessionIsValid := make([]bool, len(tlsRules.Scheduling.DataCentersIds))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(tlsRules.Scheduling.DataCentersIds))
+	for i, dataC := range tlsRules.Scheduling.DataCentersIds {
+		go func(i int, dataCenterId int) {
+			defer waitGroup.Done()
+			dataCenter, err := e.dataCentersRepo.GetDataCenterWithCache(ctx, dataCenterId)
+			if err != nil {
+				log.Error("error on getting data center in executing tls rule: ", err)
+				return
+			}
+			sessionIsValid[i] = true
+
+			success := 1
+			agentError := false
+			var rootCauses []string
+			var addressesCalled []string
+			now := time.Now()
+			for _, rule := range tlsRules.TLSMonitors {
+				stat := e.checkCertificate(ctx, dataCenter, rule, now)
+				stat.Time = now
+				stat.SessionId = session.String()
+				stat.ProjectId = tlsRules.Scheduling.ProjectId
+				stat.TLSMonitorId = tlsRules.Scheduling.PipelineId
+				stat.IsHeartBeat = tlsRules.Scheduling.IsHeartBeat
+				stat.IsMaintenance = maintenance
+				stat.DatacenterId = dataCenter.ID
+				if stat.IsAgentError {
+					agentError = true
+				} else if stat.Success == 0 {
+					success = 0
+				}
+				if stat.Success == 0 {
+					rootCauses = append(rootCauses, fmt.Sprintf("%s: %s", stat.Address, stat.RootCause))
+				}
+				addressesCalled = append(addressesCalled, stat.Address)
+				sessionStats[i] = append(sessionStats[i], stat)
+			}
+
+			// the agent failing on some records says nothing about the others, it is only unknown when nothing else failed
+			agentError = agentError && success == 1
+			if agentError {
+				success = 0
+			}
+			sessionResults[i] = monitorSession{
+				DatacenterId: dataCenter.ID,
+				Success:      success,
+				Url:          strings.Join(addressesCalled, ","),
+				RootCause:    strings.Join(rootCauses, "; "),
+				AgentError:   agentError,
+			}
+		}(i, dataC)
+	}
+	waitGroup.Wait()
+
+	var stats []repos.WriteTLSStatsOptions
+	for _, value := range sessionStats {
+		stats = append(stats, value...)
+	}
+	if len(stats) != 0 {
+		err := e.tlsStatsRepo.WriteBulk(ctx, stats)
+		if err != nil {
+			log.Info("error on writing tls stats in executing rule: ", err)
+		}
+	}
+
+	for _, valid := range sessionIsValid {
+		if !valid {
+			log.Warn("this session was invalid: ", session.String())
+			return nil
+		}
+	}
+
+	if !maintenance {
+		e.notifyExpiry(ctx, tlsRules, stats)
+	}
+	return e.sessionNotifier.notifyTransitions(ctx, usecase_models.TLSMonitorType, repos.TLSSessionCachePrefix, tlsRules.Scheduling, session.String(), sessionResults, maintenance)
+}
+
+// checkCertificate asks the agent for the certificate of the rule address, an expired or not yet valid
+// certificate, a hostname mismatch and an untrusted chain all fail the check
+func (e *tlsHandler) checkCertificate(ctx context.Context, dataCenter *models.Datacenter, rule usecase_models.TLSRules, now time.Time) repos.WriteTLSStatsOptions {
+	request := rule.AgentTLSRequest
+	if request.Port == 0 {
+		request.Port = usecase_models.TLSDefaultPort
+	}
+	serverName := request.ServerName
+	if serverName == "" {
+		serverName = request.Address
+	}
+	stat := repos.WriteTLSStatsOptions{
+		Address: net.JoinHostPort(request.Address, strconv.Itoa(request.Port)),
+		Success: 1,
+	}
+
+	response, err := e.agentHandler.SendTLS(ctx, dataCenter, request)
+	if err != nil {
+		log.Info("error on sending tls in executing rule: ", err)
+		stat.Success = 0
+		stat.IsAgentError = true
+		stat.RootCause = fmt.Sprintf("error on sending tls in executing rule: %s", err.Error())
+		return stat
+	}
+	if response.Status == 0 || len(response.Statistics.Chain) == 0 {
+		stat.Success = 0
+		stat.RootCause = response.Message
+		if stat.RootCause == "" {
+			stat.RootCause = "no certificate was presented"
+		}
+		return stat
+	}
+
+	leaf := response.Statistics.Chain[0]
+	chain, _ := json.Marshal(response.Statistics.Chain)
+	stat.DaysUntilExpiry = null.IntFrom(daysUntilExpiry(leaf.NotAfter, now))
+	stat.NotAfter = null.TimeFrom(leaf.NotAfter)
+	stat.Issuer = leaf.Issuer
+	stat.SANs = strings.Join(leaf.SANs, ",")
+	stat.Chain = null.JSONFrom(chain)
+	stat.HostnameValid = null.BoolFrom(response.Statistics.HostnameValid)
+	stat.ChainTrusted = null.BoolFrom(response.Statistics.ChainTrusted)
+
+	var causes []string
+	if now.After(leaf.NotAfter) {
+		causes = append(causes, fmt.Sprintf("certificate expired on %s", leaf.NotAfter.Format(time.DateTime)))
+	} else if now.Before(leaf.NotBefore) {
+		causes = append(causes, fmt.Sprintf("certificate is not valid before %s", leaf.NotBefore.Format(time.DateTime)))
+	}
+	if !response.Statistics.HostnameValid {
+		causes = append(causes, fmt.Sprintf("certificate is not valid for %s", serverName))
+	}
+	if !response.Statistics.ChainTrusted {
+		cause := "certificate chain is not trusted"
+		if response.Statistics.VerifyError != "" {
+			cause += ": " + response.Statistics.VerifyError
+		}
+		causes = append(causes, cause)
+	}
+	if len(causes) != 0 {
+		stat.Success = 0
+		stat.RootCause = strings.Join(causes, ", ")
+	}
+	return stat
+}
+
+// notifyExpiry sends an expiring notification the first time the soonest expiry of an address crosses
+// each threshold. the alerted threshold of an address is cleared once its certificate is above all thresholds again
+func (e *tlsHandler) notifyExpiry(ctx context.Context, tlsRules usecase_models.TLSMonitors, stats []repos.WriteTLSStatsOptions) {
+	thresholds := tlsRules.ExpiryThresholds
+	if len(thresholds) == 0 {
+		thresholds = usecase_models.DefaultTLSExpiryThresholds
+	}
+
+	var soonest = make(map[string]repos.WriteTLSStatsOptions)
+	for _, stat := range stats {
+		if !stat.DaysUntilExpiry.Valid {
+			continue
+		}
+		if old, ok := soonest[stat.Address]; !ok || stat.DaysUntilExpiry.Int < old.DaysUntilExpiry.Int {
+			soonest[stat.Address] = stat
+		}
+	}
+	if len(soonest) == 0 {
+		return
+	}
+
+	alerted, err := e.tlsMonitorsRepo.GetAlertedThresholds(ctx, tlsRules.Scheduling.PipelineId)
+	if err != nil {
+		log.Warn("problem on getting alerted tls thresholds: ", err)
+		return
+	}
+	changed := false
+	for address, stat := range soonest {
+		crossed := crossedThreshold(thresholds, stat.DaysUntilExpiry.Int)
+		last, ok := alerted[address]
+		if crossed == 0 {
+			if ok {
+				delete(alerted, address)
+				changed = true
+			}
+			continue
+		}
+		if ok && last <= crossed {
+			continue
+		}
+		alerted[address] = crossed
+		changed = true
+		err = e.pushExpiring(ctx, tlsRules.Scheduling, stat)
+		if err != nil {
+			log.Info("error on pushing tls expiry notification: ", err)
+		}
+	}
+	if changed {
+		err = e.tlsMonitorsRepo.SetAlertedThresholds(ctx, tlsRules.Scheduling.PipelineId, alerted)
+		if err != nil {
+			log.Warn("problem on setting alerted tls thresholds: ", err)
+		}
+	}
+}
+
+func (e *tlsHandler) pushExpiring(ctx context.Context, scheduling usecase_models.Scheduling, stat repos.WriteTLSStatsOptions) error {
+	project, err := e.projectRepo.GetProjectWithLoads(ctx, scheduling.ProjectId)
+	if err != nil {
+		return err
+	}
+	if project.R == nil || project.R.Account == nil {
+		return errors.New("project has no account")
+	}
+
+	rootCause := fmt.Sprintf("certificate of %s expires in %d days on %s", stat.Address, stat.DaysUntilExpiry.Int, stat.NotAfter.Time.Format(time.DateTime))
+	if stat.DaysUntilExpiry.Int < 0 {
+		rootCause = fmt.Sprintf("certificate of %s expired on %s", stat.Address, stat.NotAfter.Time.Format(time.DateTime))
+	}
+	payload := task_models.NotificationsPayload{
+		Type:         usecase_models.TLSMonitorType,
+		State:        usecase_models.TLSNotificationStateExpiring,
+		ProjectId:    scheduling.ProjectId,
+		PipelineId:   scheduling.PipelineId,
+		PipelineName: scheduling.PipelineName,
+		Username:     project.R.Account.Username.String,
+		Address:      stat.Address,
+		Time:         time.Now().String(),
+		RootCause:    rootCause,
+	}
+	dcs, err := e.dataCentersRepo.GetDataCentersWithCache(ctx)
+	if err != nil {
+		log.Info("problem in getting datacenters in sending alerts: ", err.Error())
+	}
+	payload.Datacenters = strings.Join(datacenterTitles(dcs, []int{stat.DatacenterId}), ",")
+
+	_, err = e.taskPusher.PushNotifications(ctx, payload)
+	return err
+}
+
+// daysUntilExpiry rounds down, a certificate that expires later today has zero days left
+func daysUntilExpiry(notAfter time.Time, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
+
+// crossedThreshold returns the smallest threshold days is within, zero when it is above all of them
+func crossedThreshold(thresholds []int, days int) int {
+	sorted := append([]int{}, thresholds...)
+	sort.Ints(sorted)
+	for _, threshold := range sorted {
+		if days <= threshold {
+			return threshold
+		}
+	}
+	return 0
+}
+
+// validateTLSRules checks the addresses and thresholds of a tls monitor before it is saved
+func validateTLSRules(tlsRules usecase_models.TLSMonitors) error {
+	for _, rule := range tlsRules.TLSMonitors {
+		if strings.TrimSpace(rule.Address) == "" {
+			return errors.New("address of tls monitor is required")
+		}
+		if rule.Port < 0 || rule.Port > 65535 {
+			return fmt.Errorf("port of %s is not valid", rule.Address)
+		}
+	}
+	for _, threshold := range tlsRules.ExpiryThresholds {
+		if threshold <= 0 {
+			return errors.New("expiry thresholds must be positive days")
+		}
+	}
+	return nil
+}
@@ -60,6 +60,38 @@ var (
 		},
 	)
 
+	AgentErrorsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "agent_errors_total",
+			Help: "checks a datacenter agent could not run, these are datacenter problems and not target failures",
+		},
+		[]string{"datacenter_id", "monitor_type"},
+	)
+
+	DatacenterStatusGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "datacenter_status",
+			Help: "health of each datacenter agent, 2 healthy, 1 degraded and 0 offline",
+		},
+		[]string{"datacenter_id", "title"},
+	)
+
+	DatacenterAvailabilityGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "datacenter_availability_percent",
+			Help: "moving percent of successful health probes of each datacenter agent",
+		},
+		[]string{"datacenter_id", "title"},
+	)
+
+	DatacenterProbeLatencyGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "datacenter_probe_latency_seconds",
+			Help: "latency of the last successful health probe of each datacenter agent",
+		},
+		[]string{"datacenter_id", "title"},
+	)
+
 	ProcessedTasksGauge = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "processed_tasks_total",
@@ -17,6 +17,9 @@ type aggregateRepository struct {
 	pageSpeedRepo  PageSpeedRepository
 	pingRepo       PingRepository
 	traceRouteRepo TraceRouteRepository
+	tlsRepo        TLSMonitorsRepository
+	dnsRepo        DNSMonitorsRepository
+	statesRepo     MonitorStatesRepository
 }
 
 func NewAggregateRepository(db *sql.DB,
@@ -24,7 +27,10 @@ func NewAggregateRepository(db *sql.DB,
 	netCatRepo NetCatRepository,
 	pageSpeedRepo PageSpeedRepository,
 	pingRepo PingRepository,
-	traceRouteRepo TraceRouteRepository) AggregateRepository {
+	traceRouteRepo TraceRouteRepository,
+	tlsRepo TLSMonitorsRepository,
+	dnsRepo DNSMonitorsRepository,
+	statesRepo MonitorStatesRepository) AggregateRepository {
 	return &aggregateRepository{
 		db:             db,
 		endpointRepo:   endpointRepo,
@@ -32,6 +38,9 @@ func NewAggregateRepository(db *sql.DB,
 		pageSpeedRepo:  pageSpeedRepo,
 		pingRepo:       pingRepo,
 		traceRouteRepo: traceRouteRepo,
+		tlsRepo:        tlsRepo,
+		dnsRepo:        dnsRepo,
+		statesRepo:     statesRepo,
 	}
 }
 
@@ -56,6 +65,18 @@ func (a *aggregateRepository) AggregateAllRuleSubWorks(ctx context.Context, proj
 	if err != nil {
 		return usecase_models.AggregateAllRuleSubWorks{}, err
 	}
+	tlsMonitors, err := a.tlsRepo.GetTLSMonitors(ctx, projectId)
+	if err != nil {
+		return usecase_models.AggregateAllRuleSubWorks{}, err
+	}
+	dnsMonitors, err := a.dnsRepo.GetDNSMonitors(ctx, projectId)
+	if err != nil {
+		return usecase_models.AggregateAllRuleSubWorks{}, err
+	}
+	states, err := a.statesRepo.GetProjectStates(ctx, projectId)
+	if err != nil {
+		return usecase_models.AggregateAllRuleSubWorks{}, err
+	}
 
 	return usecase_models.AggregateAllRuleSubWorks{
 		Endpoints:   endpoints,
@@ -63,5 +84,8 @@ func (a *aggregateRepository) AggregateAllRuleSubWorks(ctx context.Context, proj
 		NetCats:     netcats,
 		Pings:       pings,
 		PageSpeed:   pageSpeeds,
+		TLSMonitors: tlsMonitors,
+		DNSMonitors: dnsMonitors,
+		States:      states,
 	}, nil
 }
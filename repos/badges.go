@@ -0,0 +1,77 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+)
+
+type BadgesRepository interface {
+	EnableBadge(ctx context.Context, badge usecase_models.Badge) (int, error)
+	DeleteBadge(ctx context.Context, badgeId int) error
+	GetBadge(ctx context.Context, badgeId int) (usecase_models.Badge, error)
+	GetBadgeByToken(ctx context.Context, token string) (usecase_models.Badge, error)
+	GetBadges(ctx context.Context, projectId int) ([]usecase_models.Badge, error)
+}
+
+type badgesRepository struct {
+	db *sql.DB
+}
+
+func NewBadgesRepository(db *sql.DB) BadgesRepository {
+	return &badgesRepository{db: db}
+}
+
+// EnableBadge creates the badge of a pipeline, enabling it again rotates the token
+func (r *badgesRepository) EnableBadge(ctx context.Context, badge usecase_models.Badge) (int, error) {
+	var id int
+	err := queries.Raw(`insert into badges (project_id, pipeline_type, pipeline_id, token, created_at)
+		values ($1, $2, $3, $4, now())
+		on conflict (pipeline_type, pipeline_id) where deleted_at is null do update set token = excluded.token
+		returning id;`,
+		badge.ProjectId, badge.PipelineType, badge.PipelineId, badge.Token).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *badgesRepository) DeleteBadge(ctx context.Context, badgeId int) error {
+	query := queries.Raw("update badges set deleted_at = now() where id = $1 and deleted_at is null;", badgeId)
+	_, err := query.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *badgesRepository) GetBadge(ctx context.Context, badgeId int) (usecase_models.Badge, error) {
+	var badge usecase_models.Badge
+	err := models.NewQuery(qm.From("badges"), qm.Where("id = ? and deleted_at is null", badgeId)).Bind(ctx, r.db, &badge)
+	if err != nil {
+		return usecase_models.Badge{}, err
+	}
+	return badge, nil
+}
+
+func (r *badgesRepository) GetBadgeByToken(ctx context.Context, token string) (usecase_models.Badge, error) {
+	var badge usecase_models.Badge
+	err := models.NewQuery(qm.From("badges"), qm.Where("token = ? and deleted_at is null", token)).Bind(ctx, r.db, &badge)
+	if err != nil {
+		return usecase_models.Badge{}, err
+	}
+	return badge, nil
+}
+
+func (r *badgesRepository) GetBadges(ctx context.Context, projectId int) ([]usecase_models.Badge, error) {
+	var badges []usecase_models.Badge
+	err := models.NewQuery(
+		qm.From("badges"),
+		qm.Where("project_id = ? and deleted_at is null", projectId),
+		qm.OrderBy("id"),
+	).Bind(ctx, r.db, &badges)
+	if err != nil {
+		return nil, err
+	}
+	return badges, nil
+}
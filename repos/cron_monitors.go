@@ -0,0 +1,165 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+)
+
+type CronMonitorsRepository interface {
+	SaveCronMonitor(ctx context.Context, monitor usecase_models.CronMonitor) (int, error)
+	UpdateCronMonitor(ctx context.Context, monitor usecase_models.CronMonitor) error
+	DeleteCronMonitor(ctx context.Context, monitorId int) error
+	GetCronMonitor(ctx context.Context, monitorId int) (usecase_models.CronMonitor, error)
+	GetCronMonitors(ctx context.Context, projectId int) ([]usecase_models.CronMonitor, error)
+	GetActiveCronMonitors(ctx context.Context) ([]usecase_models.CronMonitor, error)
+	RecordPing(ctx context.Context, token string, kind string, remoteAddr string) error
+	GetPings(ctx context.Context, monitorId int, limit int) ([]usecase_models.CronMonitorPing, error)
+	SetStatus(ctx context.Context, monitorId int, from string, to string, rootCause string) (bool, error)
+}
+
+type cronMonitorsRepository struct {
+	db *sql.DB
+}
+
+func NewCronMonitorsRepository(db *sql.DB) CronMonitorsRepository {
+	return &cronMonitorsRepository{db: db}
+}
+
+func (r *cronMonitorsRepository) SaveCronMonitor(ctx context.Context, monitor usecase_models.CronMonitor) (int, error) {
+	var id int
+	err := queries.Raw(`insert into cron_monitors (project_id, name, token, period, grace, is_active, status, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, now(), now()) returning id;`,
+		monitor.ProjectId, monitor.Name, monitor.Token, monitor.Period, monitor.Grace, monitor.IsActive,
+		usecase_models.CronMonitorStatusNew).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *cronMonitorsRepository) UpdateCronMonitor(ctx context.Context, monitor usecase_models.CronMonitor) error {
+	query := queries.Raw(`update cron_monitors set name = $1, period = $2, grace = $3, is_active = $4, updated_at = now()
+                           where id = $5 and deleted_at is null;`,
+		monitor.Name, monitor.Period, monitor.Grace, monitor.IsActive, monitor.ID)
+	result, err := query.ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("cron monitor not found")
+	}
+	return nil
+}
+
+func (r *cronMonitorsRepository) DeleteCronMonitor(ctx context.Context, monitorId int) error {
+	query := queries.Raw("update cron_monitors set deleted_at = now() where id = $1 and deleted_at is null;", monitorId)
+	_, err := query.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *cronMonitorsRepository) GetCronMonitor(ctx context.Context, monitorId int) (usecase_models.CronMonitor, error) {
+	var monitor usecase_models.CronMonitor
+	err := models.NewQuery(qm.From("cron_monitors"), qm.Where("id = ? and deleted_at is null", monitorId)).Bind(ctx, r.db, &monitor)
+	if err != nil {
+		return usecase_models.CronMonitor{}, err
+	}
+	return monitor, nil
+}
+
+func (r *cronMonitorsRepository) GetCronMonitors(ctx context.Context, projectId int) ([]usecase_models.CronMonitor, error) {
+	var monitors []usecase_models.CronMonitor
+	err := models.NewQuery(
+		qm.From("cron_monitors"),
+		qm.Where("project_id = ? and deleted_at is null", projectId),
+		qm.OrderBy("id"),
+	).Bind(ctx, r.db, &monitors)
+	if err != nil {
+		return nil, err
+	}
+	return monitors, nil
+}
+
+func (r *cronMonitorsRepository) GetActiveCronMonitors(ctx context.Context) ([]usecase_models.CronMonitor, error) {
+	var monitors []usecase_models.CronMonitor
+	err := models.NewQuery(
+		qm.From("cron_monitors"),
+		qm.Where("is_active = true and deleted_at is null"),
+	).Bind(ctx, r.db, &monitors)
+	if err != nil {
+		return nil, err
+	}
+	return monitors, nil
+}
+
+// RecordPing stores the time of the ping on the monitor of the token and keeps it in the ping log
+func (r *cronMonitorsRepository) RecordPing(ctx context.Context, token string, kind string, remoteAddr string) error {
+	column := "last_ping_at"
+	switch kind {
+	case usecase_models.CronPingKindPing, usecase_models.CronPingKindSuccess:
+	case usecase_models.CronPingKindStart:
+		column = "last_start_at"
+	case usecase_models.CronPingKindFail:
+		column = "last_fail_at"
+	default:
+		return fmt.Errorf("unknown ping kind: %s", kind)
+	}
+
+	txn, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	var id int
+	err = queries.Raw(fmt.Sprintf(`update cron_monitors set %s = now() where token = $1 and is_active = true and deleted_at is null
+                                     returning id;`, column), token).QueryRowContext(ctx, txn).Scan(&id)
+	if err != nil {
+		return err
+	}
+	_, err = queries.Raw(`insert into cron_monitor_pings (cron_monitor_id, kind, remote_addr, created_at) values ($1, $2, $3, now());`,
+		id, kind, null.NewString(remoteAddr, remoteAddr != "")).ExecContext(ctx, txn)
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+func (r *cronMonitorsRepository) GetPings(ctx context.Context, monitorId int, limit int) ([]usecase_models.CronMonitorPing, error) {
+	var pings []usecase_models.CronMonitorPing
+	err := models.NewQuery(
+		qm.From("cron_monitor_pings"),
+		qm.Where("cron_monitor_id = ?", monitorId),
+		qm.OrderBy("created_at desc"),
+		qm.Limit(limit),
+	).Bind(ctx, r.db, &pings)
+	if err != nil {
+		return nil, err
+	}
+	return pings, nil
+}
+
+// SetStatus moves the monitor from one status to another, false means another checker already did it
+func (r *cronMonitorsRepository) SetStatus(ctx context.Context, monitorId int, from string, to string, rootCause string) (bool, error) {
+	result, err := queries.Raw(`update cron_monitors set status = $1, root_cause = $2, updated_at = now()
+                                 where id = $3 and status = $4;`,
+		to, null.NewString(rootCause, rootCause != ""), monitorId, from).ExecContext(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected != 0, nil
+}
@@ -0,0 +1,116 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"test-manager/utils"
+	"time"
+)
+
+const (
+	DatacenterKeyIdPrefix = "dk_"
+	datacenterKeyIdBytes  = 8
+	datacenterSecretBytes = 32
+)
+
+type DatacenterKeysRepository interface {
+	CreateKey(ctx context.Context, datacenterId int) (usecase_models.DatacenterKey, error)
+	GetKeys(ctx context.Context, datacenterId int) ([]usecase_models.DatacenterKey, error)
+	GetActiveKeys(ctx context.Context, datacenterId int) ([]usecase_models.DatacenterKey, error)
+	GetActiveKey(ctx context.Context, keyId string) (usecase_models.DatacenterKey, error)
+	RevokeKey(ctx context.Context, datacenterId int, keyId string) error
+}
+
+type datacenterKeysRepository struct {
+	db *sql.DB
+}
+
+func NewDatacenterKeysRepository(db *sql.DB) DatacenterKeysRepository {
+	return &datacenterKeysRepository{db: db}
+}
+
+func (r *datacenterKeysRepository) CreateKey(ctx context.Context, datacenterId int) (usecase_models.DatacenterKey, error) {
+	keyId, err := utils.GenerateToken(datacenterKeyIdBytes)
+	if err != nil {
+		return usecase_models.DatacenterKey{}, err
+	}
+	secret, err := utils.GenerateToken(datacenterSecretBytes)
+	if err != nil {
+		return usecase_models.DatacenterKey{}, err
+	}
+	key := usecase_models.DatacenterKey{
+		KeyId:        DatacenterKeyIdPrefix + keyId,
+		DatacenterId: datacenterId,
+		Secret:       secret,
+		CreatedAt:    time.Now(),
+	}
+	_, err = queries.Raw("insert into datacenter_keys (key_id, datacenter_id, secret, created_at) values ($1, $2, $3, $4);",
+		key.KeyId, key.DatacenterId, key.Secret, key.CreatedAt).ExecContext(ctx, r.db)
+	if err != nil {
+		return usecase_models.DatacenterKey{}, err
+	}
+	return key, nil
+}
+
+// GetKeys lists the keys of a datacenter without their secrets, newest first
+func (r *datacenterKeysRepository) GetKeys(ctx context.Context, datacenterId int) ([]usecase_models.DatacenterKey, error) {
+	var keys []usecase_models.DatacenterKey
+	err := models.NewQuery(
+		qm.Select("key_id", "datacenter_id", "created_at", "revoked_at"),
+		qm.From("datacenter_keys"),
+		qm.Where("datacenter_id = ?", datacenterId),
+		qm.OrderBy("created_at desc"),
+	).Bind(ctx, r.db, &keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetActiveKeys returns the keys requests to the datacenter can be signed with, newest first
+func (r *datacenterKeysRepository) GetActiveKeys(ctx context.Context, datacenterId int) ([]usecase_models.DatacenterKey, error) {
+	var keys []usecase_models.DatacenterKey
+	err := models.NewQuery(
+		qm.From("datacenter_keys"),
+		qm.Where("datacenter_id = ? and revoked_at is null", datacenterId),
+		qm.OrderBy("created_at desc"),
+	).Bind(ctx, r.db, &keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetActiveKey finds a key that is not revoked by its id, it is how the manager checks what an agent signed
+func (r *datacenterKeysRepository) GetActiveKey(ctx context.Context, keyId string) (usecase_models.DatacenterKey, error) {
+	var key usecase_models.DatacenterKey
+	err := models.NewQuery(
+		qm.From("datacenter_keys"),
+		qm.Where("key_id = ? and revoked_at is null", keyId),
+	).Bind(ctx, r.db, &key)
+	if err != nil {
+		return usecase_models.DatacenterKey{}, err
+	}
+	return key, nil
+}
+
+func (r *datacenterKeysRepository) RevokeKey(ctx context.Context, datacenterId int, keyId string) error {
+	result, err := queries.Raw("update datacenter_keys set revoked_at = $1 where key_id = $2 and datacenter_id = $3 and revoked_at is null;",
+		time.Now(), keyId, datacenterId).ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("no active key found")
+	}
+	return nil
+}
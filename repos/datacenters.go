@@ -7,14 +7,23 @@ import (
 	"fmt"
 	"github.com/labstack/gommon/log"
 	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 	"strconv"
 	"test-manager/cache"
+	"test-manager/usecase_models"
 	models "test-manager/usecase_models/boiler"
+	"time"
 )
 
 const (
-	DatacenterPrefixCacheKey = "datacenter_id:"
-	DatacenterIdsCacheKey    = "all_datacenter_ids"
+	DatacenterPrefixCacheKey     = "datacenter_id:"
+	DatacenterIdsCacheKey        = "all_datacenter_ids"
+	DatacenterOfflineIdsCacheKey = "offline_datacenter_ids"
+	DatacenterModePrefixCacheKey = "datacenter_mode:"
+	datacenterModeCacheTTL       = time.Minute
+	DatacenterProjectsCacheKey   = "datacenter_projects"
+	datacenterProjectsCacheTTL   = time.Minute
 )
 
 type DataCentersRepository interface {
@@ -25,6 +34,20 @@ type DataCentersRepository interface {
 	GetDataCentersWithCache(ctx context.Context) ([]*models.Datacenter, error)
 	GetDataCenterByTitle(ctx context.Context, title string) (models.Datacenter, error)
 	SaveDataCenters(ctx context.Context, dataCenter models.Datacenter) (int, error)
+	GetDataCentersHealth(ctx context.Context) ([]usecase_models.DatacenterHealth, error)
+	SaveDataCenterHealth(ctx context.Context, health usecase_models.DatacenterHealth) error
+	SetOfflineDataCenterIds(ctx context.Context, ids []int, ttl time.Duration) error
+	GetOfflineDataCenterIds(ctx context.Context) []int
+	GetAvailableDataCentersWithCache(ctx context.Context) ([]*models.Datacenter, error)
+	FilterAvailableDataCenterIds(ctx context.Context, ids []int) []int
+	GetDataCenterAgents(ctx context.Context) ([]usecase_models.DatacenterAgent, error)
+	GetDataCenterMode(ctx context.Context, id int) (string, error)
+	SetDataCenterMode(ctx context.Context, id int, mode string) error
+	TouchDataCenterAgent(ctx context.Context, id int, seenAt time.Time) error
+	SaveProjectDataCenter(ctx context.Context, projectId int, dataCenter models.Datacenter) (int, error)
+	GetProjectDataCenters(ctx context.Context, projectId int) ([]*models.Datacenter, error)
+	GetDataCenterProjects(ctx context.Context) (map[int]int, error)
+	FilterProjectDataCenterIds(ctx context.Context, projectId int, ids []int) []int
 }
 
 type dataCentersRepository struct {
@@ -160,3 +183,291 @@ func (r *dataCentersRepository) GetDataCenterByTitle(ctx context.Context, title
 	}
 	return *datacenter, nil
 }
+
+func (r *dataCentersRepository) GetDataCentersHealth(ctx context.Context) ([]usecase_models.DatacenterHealth, error) {
+	var healths []usecase_models.DatacenterHealth
+	err := models.NewQuery(qm.From("datacenter_health"), qm.OrderBy("datacenter_id")).Bind(ctx, r.db, &healths)
+	if err != nil {
+		return nil, err
+	}
+	return healths, nil
+}
+
+// SaveDataCenterHealth keeps the last probe of the datacenter, its availability is also kept as the connection rate
+func (r *dataCentersRepository) SaveDataCenterHealth(ctx context.Context, health usecase_models.DatacenterHealth) error {
+	_, err := queries.Raw(`insert into datacenter_health (datacenter_id, status, availability, latency, consecutive_failures, last_error, checked_at)
+		values ($1, $2, $3, $4, $5, $6, $7)
+		on conflict (datacenter_id) do update set
+			status = excluded.status,
+			availability = excluded.availability,
+			latency = excluded.latency,
+			consecutive_failures = excluded.consecutive_failures,
+			last_error = excluded.last_error,
+			checked_at = excluded.checked_at;`,
+		health.DatacenterId, health.Status, health.Availability, health.Latency, health.ConsecutiveFailures,
+		health.LastError, health.CheckedAt).ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	_, err = queries.Raw("update datacenters set connection_rate = $1 where id = $2;",
+		int(health.Availability), health.DatacenterId).ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	// the cached datacenters carry the connection rate the weighted selection reads
+	err = r.cacheRepo.Delete(ctx, DatacenterPrefixCacheKey+strconv.Itoa(health.DatacenterId))
+	if err != nil {
+		log.Error(err)
+	}
+
+	err = r.cacheRepo.Delete(ctx, DatacenterIdsCacheKey)
+	if err != nil {
+		log.Error(err)
+	}
+	return nil
+}
+
+// SetOfflineDataCenterIds publishes the offline datacenters to every rule executor, the key expires
+// with ttl so datacenters are selected again if the prober stops
+func (r *dataCentersRepository) SetOfflineDataCenterIds(ctx context.Context, ids []int, ttl time.Duration) error {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return r.cacheRepo.Set(ctx, DatacenterOfflineIdsCacheKey, data, ttl)
+}
+
+// GetOfflineDataCenterIds returns nothing when the prober has not published yet, datacenters are available by default
+func (r *dataCentersRepository) GetOfflineDataCenterIds(ctx context.Context) []int {
+	var ids []int
+	value, err := r.cacheRepo.Get(ctx, DatacenterOfflineIdsCacheKey)
+	if err != nil {
+		return nil
+	}
+	valueStr, ok := value.(string)
+	if !ok || valueStr == "" {
+		return nil
+	}
+	err = json.Unmarshal([]byte(valueStr), &ids)
+	if err != nil {
+		log.Error("problem on unmarshalling offline datacenters: ", err)
+		return nil
+	}
+	return ids
+}
+
+// GetAvailableDataCentersWithCache returns the platform datacenters that are not offline, or all of them if none is available.
+// private datacenters of projects are never picked for a rule that did not name them
+func (r *dataCentersRepository) GetAvailableDataCentersWithCache(ctx context.Context) ([]*models.Datacenter, error) {
+	datacenters, err := r.GetDataCentersWithCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := r.GetDataCenterProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var platform []*models.Datacenter
+	for _, datacenter := range datacenters {
+		if _, private := owners[datacenter.ID]; !private {
+			platform = append(platform, datacenter)
+		}
+	}
+	offline := r.GetOfflineDataCenterIds(ctx)
+	var available []*models.Datacenter
+	for _, datacenter := range platform {
+		if !containsId(offline, datacenter.ID) {
+			available = append(available, datacenter)
+		}
+	}
+	if len(available) == 0 {
+		return platform, nil
+	}
+	return available, nil
+}
+
+// FilterAvailableDataCenterIds drops offline datacenters from ids, all of ids are kept if none is available
+// so a rule pinned to offline datacenters still records agent errors instead of silently not running
+func (r *dataCentersRepository) FilterAvailableDataCenterIds(ctx context.Context, ids []int) []int {
+	offline := r.GetOfflineDataCenterIds(ctx)
+	if len(offline) == 0 {
+		return ids
+	}
+	var available []int
+	for _, id := range ids {
+		if !containsId(offline, id) {
+			available = append(available, id)
+		}
+	}
+	if len(available) == 0 {
+		return ids
+	}
+	return available
+}
+
+// GetDataCenterAgents lists the datacenters that have a mode set, the others are push datacenters
+func (r *dataCentersRepository) GetDataCenterAgents(ctx context.Context) ([]usecase_models.DatacenterAgent, error) {
+	var agents []usecase_models.DatacenterAgent
+	err := models.NewQuery(qm.From("datacenter_agents"), qm.OrderBy("datacenter_id")).Bind(ctx, r.db, &agents)
+	if err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
+// GetDataCenterMode tells how the agent of the datacenter is reached, it is asked on every check so it is cached.
+// private datacenters are always pull, the manager never calls an address a project chose
+func (r *dataCentersRepository) GetDataCenterMode(ctx context.Context, id int) (string, error) {
+	cacheKey := DatacenterModePrefixCacheKey + strconv.Itoa(id)
+	value, err := r.cacheRepo.Get(ctx, cacheKey)
+	if err == nil {
+		if mode, ok := value.(string); ok && mode != "" {
+			return mode, nil
+		}
+	}
+
+	var agents []usecase_models.DatacenterAgent
+	err = models.NewQuery(qm.From("datacenter_agents"), qm.Where("datacenter_id = ?", id)).Bind(ctx, r.db, &agents)
+	if err != nil {
+		return "", err
+	}
+	mode := usecase_models.DatacenterModePush
+	if len(agents) != 0 {
+		mode = agents[0].Mode
+	}
+	owners, err := r.GetDataCenterProjects(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, private := owners[id]; private {
+		mode = usecase_models.DatacenterModePull
+	}
+	err = r.cacheRepo.Set(ctx, cacheKey, mode, datacenterModeCacheTTL)
+	if err != nil {
+		log.Warn("problem on caching datacenter mode: ", err)
+	}
+	return mode, nil
+}
+
+func (r *dataCentersRepository) SetDataCenterMode(ctx context.Context, id int, mode string) error {
+	_, err := queries.Raw(`insert into datacenter_agents (datacenter_id, mode) values ($1, $2)
+		on conflict (datacenter_id) do update set mode = excluded.mode;`, id, mode).ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return r.cacheRepo.Delete(ctx, DatacenterModePrefixCacheKey+strconv.Itoa(id))
+}
+
+// TouchDataCenterAgent keeps when a pull agent last polled, the health prober reads it instead of calling the agent
+func (r *dataCentersRepository) TouchDataCenterAgent(ctx context.Context, id int, seenAt time.Time) error {
+	_, err := queries.Raw("update datacenter_agents set last_seen_at = $1 where datacenter_id = $2;",
+		seenAt, id).ExecContext(ctx, r.db)
+	return err
+}
+
+// SaveProjectDataCenter adds a private datacenter of the project, it is only selectable by rules of the project
+func (r *dataCentersRepository) SaveProjectDataCenter(ctx context.Context, projectId int, dataCenter models.Datacenter) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	err = dataCenter.Insert(ctx, tx, boil.Infer())
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	_, err = queries.Raw("insert into project_datacenters (datacenter_id, project_id, created_at) values ($1, $2, $3);",
+		dataCenter.ID, projectId, time.Now()).ExecContext(ctx, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.cacheRepo.Delete(ctx, DatacenterIdsCacheKey)
+	if err != nil {
+		log.Error(err)
+	}
+	err = r.cacheRepo.Delete(ctx, DatacenterProjectsCacheKey)
+	if err != nil {
+		log.Error(err)
+	}
+	return dataCenter.ID, nil
+}
+
+func (r *dataCentersRepository) GetProjectDataCenters(ctx context.Context, projectId int) ([]*models.Datacenter, error) {
+	datacenters, err := models.Datacenters(
+		qm.Where("id in (select datacenter_id from project_datacenters where project_id = ?)", projectId),
+		qm.OrderBy("id"),
+	).All(ctx, r.db)
+	if err != nil {
+		return []*models.Datacenter{}, err
+	}
+	return datacenters, nil
+}
+
+// GetDataCenterProjects maps each private datacenter to its project, the platform datacenters are not in it.
+// it is read on every rule run so it is cached
+func (r *dataCentersRepository) GetDataCenterProjects(ctx context.Context) (map[int]int, error) {
+	owners := make(map[int]int)
+	value, err := r.cacheRepo.Get(ctx, DatacenterProjectsCacheKey)
+	if err == nil {
+		if valueStr, ok := value.(string); ok && valueStr != "" {
+			if err = json.Unmarshal([]byte(valueStr), &owners); err == nil {
+				return owners, nil
+			}
+		}
+	}
+
+	var rows []struct {
+		DatacenterId int `boil:"datacenter_id"`
+		ProjectId    int `boil:"project_id"`
+	}
+	err = models.NewQuery(qm.Select("datacenter_id", "project_id"), qm.From("project_datacenters")).Bind(ctx, r.db, &rows)
+	if err != nil {
+		return nil, err
+	}
+	owners = make(map[int]int)
+	for _, row := range rows {
+		owners[row.DatacenterId] = row.ProjectId
+	}
+	data, err := json.Marshal(owners)
+	if err == nil {
+		err = r.cacheRepo.Set(ctx, DatacenterProjectsCacheKey, data, datacenterProjectsCacheTTL)
+	}
+	if err != nil {
+		log.Warn("problem on caching datacenter projects: ", err)
+	}
+	return owners, nil
+}
+
+// FilterProjectDataCenterIds drops the private datacenters of other projects from ids. nothing is kept when
+// the owners can not be read, a private datacenter could not be told apart then
+func (r *dataCentersRepository) FilterProjectDataCenterIds(ctx context.Context, projectId int, ids []int) []int {
+	owners, err := r.GetDataCenterProjects(ctx)
+	if err != nil {
+		log.Error("problem on getting datacenter projects: ", err)
+	}
+	var allowed []int
+	for _, id := range ids {
+		owner, private := owners[id]
+		if err != nil || (private && owner != projectId) {
+			continue
+		}
+		allowed = append(allowed, id)
+	}
+	return allowed
+}
+
+func containsId(ids []int, id int) bool {
+	for _, value := range ids {
+		if value == id {
+			return true
+		}
+	}
+	return false
+}
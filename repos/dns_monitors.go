@@ -0,0 +1,153 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"log"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+const (
+	DNSSessionCachePrefix = "dns:session:dns_monitor_id:"
+)
+
+type DNSMonitorsRepository interface {
+	SaveDNSMonitor(ctx context.Context, projectId int, monitor usecase_models.DNSMonitors) (int, error)
+	UpdateDNSMonitor(ctx context.Context, monitorId int, monitor usecase_models.DNSMonitors) error
+	DeleteDNSMonitor(ctx context.Context, monitorId int) error
+	GetDNSMonitor(ctx context.Context, monitorId int) (*usecase_models.DNSMonitors, error)
+	GetDNSMonitors(ctx context.Context, projectId int) ([]*usecase_models.DNSMonitors, error)
+	GetActiveDNSMonitors(ctx context.Context) ([]*usecase_models.DNSMonitors, error)
+	GetLastAnswers(ctx context.Context, monitorId int) (map[string][]string, error)
+	SetLastAnswers(ctx context.Context, monitorId int, answers map[string][]string) error
+}
+
+type dnsMonitorsRepository struct {
+	db *sql.DB
+}
+
+func NewDNSMonitorsRepository(db *sql.DB) DNSMonitorsRepository {
+	return &dnsMonitorsRepository{db: db}
+}
+
+type dnsMonitorRow struct {
+	ID          int       `boil:"id"`
+	Data        null.JSON `boil:"data"`
+	LastAnswers null.JSON `boil:"last_answers"`
+}
+
+func (r *dnsMonitorsRepository) SaveDNSMonitor(ctx context.Context, projectId int, monitor usecase_models.DNSMonitors) (int, error) {
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = queries.Raw(`insert into dns_monitors (data, project_id, created_at, updated_at) values ($1, $2, now(), now()) returning id;`,
+		data, projectId).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *dnsMonitorsRepository) UpdateDNSMonitor(ctx context.Context, monitorId int, monitor usecase_models.DNSMonitors) error {
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		return err
+	}
+	result, err := queries.Raw(`update dns_monitors set data = $1, updated_at = now() where id = $2 and deleted_at is null;`,
+		data, monitorId).ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("dns monitor not found")
+	}
+	return nil
+}
+
+func (r *dnsMonitorsRepository) DeleteDNSMonitor(ctx context.Context, monitorId int) error {
+	_, err := queries.Raw("update dns_monitors set deleted_at = now() where id = $1 and deleted_at is null;", monitorId).ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *dnsMonitorsRepository) GetDNSMonitor(ctx context.Context, monitorId int) (*usecase_models.DNSMonitors, error) {
+	var row dnsMonitorRow
+	err := models.NewQuery(qm.From("dns_monitors"), qm.Where("id = ? and deleted_at is null", monitorId)).Bind(ctx, r.db, &row)
+	if err != nil {
+		return &usecase_models.DNSMonitors{}, err
+	}
+	var monitor usecase_models.DNSMonitors
+	err = json.Unmarshal(row.Data.JSON, &monitor)
+	if err != nil {
+		return &usecase_models.DNSMonitors{}, err
+	}
+	monitor.Scheduling.PipelineId = row.ID
+	return &monitor, nil
+}
+
+func (r *dnsMonitorsRepository) GetDNSMonitors(ctx context.Context, projectId int) ([]*usecase_models.DNSMonitors, error) {
+	return r.getDNSMonitors(ctx, qm.Where("project_id = ? and deleted_at is null", projectId))
+}
+
+func (r *dnsMonitorsRepository) GetActiveDNSMonitors(ctx context.Context) ([]*usecase_models.DNSMonitors, error) {
+	return r.getDNSMonitors(ctx, qm.Where("data->'scheduling'->>'is_active' = ? and data->'scheduling'->>'end_at' > ? and deleted_at is null",
+		"true", time.Now().Format("2006-01-02 15:04:05")))
+}
+
+func (r *dnsMonitorsRepository) getDNSMonitors(ctx context.Context, where qm.QueryMod) ([]*usecase_models.DNSMonitors, error) {
+	var rows []dnsMonitorRow
+	err := models.NewQuery(qm.From("dns_monitors"), where, qm.OrderBy("id")).Bind(ctx, r.db, &rows)
+	if err != nil {
+		return []*usecase_models.DNSMonitors{}, err
+	}
+
+	var monitors []*usecase_models.DNSMonitors
+	for _, row := range rows {
+		var monitor usecase_models.DNSMonitors
+		err := json.Unmarshal(row.Data.JSON, &monitor)
+		if err != nil {
+			log.Println(err.Error())
+		}
+		monitor.Scheduling.PipelineId = row.ID
+		monitors = append(monitors, &monitor)
+	}
+	return monitors, nil
+}
+
+// GetLastAnswers returns the answers of the previous session, keyed by datacenter, name and record type
+func (r *dnsMonitorsRepository) GetLastAnswers(ctx context.Context, monitorId int) (map[string][]string, error) {
+	var row dnsMonitorRow
+	err := models.NewQuery(qm.Select("id", "last_answers"), qm.From("dns_monitors"), qm.Where("id = ?", monitorId)).Bind(ctx, r.db, &row)
+	if err != nil {
+		return nil, err
+	}
+	answers := make(map[string][]string)
+	if row.LastAnswers.Valid {
+		err = json.Unmarshal(row.LastAnswers.JSON, &answers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return answers, nil
+}
+
+func (r *dnsMonitorsRepository) SetLastAnswers(ctx context.Context, monitorId int, answers map[string][]string) error {
+	data, err := json.Marshal(answers)
+	if err != nil {
+		return err
+	}
+	_, err = queries.Raw("update dns_monitors set last_answers = $1 where id = $2;", data, monitorId).ExecContext(ctx, r.db)
+	return err
+}
@@ -0,0 +1,126 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+type DNSStatsRepository interface {
+	WriteBulk(ctx context.Context, options []WriteDNSStatsOptions) error
+	GetLastSession(ctx context.Context, monitorId int) ([]usecase_models.DNSStat, error)
+}
+
+type dnsStatsRepository struct {
+	db *sql.DB
+}
+
+func NewDNSStatsRepository(db *sql.DB) DNSStatsRepository {
+	return &dnsStatsRepository{db: db}
+}
+
+type WriteDNSStatsOptions struct {
+	Time           time.Time    `json:"time"`
+	ProjectId      int          `json:"project_id"`
+	SessionId      string       `json:"session_id"`
+	DNSMonitorId   int          `json:"dns_monitor_id"`
+	Name           string       `json:"name"`
+	RecordType     string       `json:"record_type"`
+	Resolver       string       `json:"resolver"`
+	IsHeartBeat    bool         `json:"is_heart_beat"`
+	IsMaintenance  bool         `json:"is_maintenance"`
+	IsAgentError   bool         `json:"is_agent_error"`
+	DatacenterId   int          `json:"datacenter_id"`
+	Success        int          `json:"success"`
+	ResolutionTime null.Float64 `json:"resolution_time"`
+	Answers        []string     `json:"answers"`
+	RootCause      string       `json:"root_cause"`
+}
+
+func (e *dnsStatsRepository) WriteBulk(ctx context.Context, options []WriteDNSStatsOptions) error {
+	txn, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(pq.CopyIn("dns_stats",
+		"time",
+		"session_id",
+		"project_id",
+		"dns_monitor_id",
+		"name",
+		"record_type",
+		"resolver",
+		"datacenter_id",
+		"is_heart_beat",
+		StatsColumnIsMaintenance,
+		StatsColumnIsAgentError,
+		"success",
+		"resolution_time",
+		"answers",
+		"root_cause"))
+	if err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		answers, _ := json.Marshal(option.Answers)
+		_, err = stmt.Exec(
+			option.Time,
+			option.SessionId,
+			option.ProjectId,
+			option.DNSMonitorId,
+			option.Name,
+			option.RecordType,
+			null.NewString(option.Resolver, option.Resolver != ""),
+			option.DatacenterId,
+			option.IsHeartBeat,
+			option.IsMaintenance,
+			option.IsAgentError,
+			option.Success,
+			option.ResolutionTime,
+			null.NewJSON(answers, option.Answers != nil),
+			null.NewString(option.RootCause, option.RootCause != ""),
+		)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
+// GetLastSession returns the answers of every record and datacenter on the last session of the monitor
+func (e *dnsStatsRepository) GetLastSession(ctx context.Context, monitorId int) ([]usecase_models.DNSStat, error) {
+	var stats []usecase_models.DNSStat
+	err := models.NewQuery(
+		qm.From("dns_stats"),
+		qm.Where(`dns_monitor_id = ? and session_id = (select session_id from dns_stats where dns_monitor_id = ? order by time desc limit 1)`,
+			monitorId, monitorId),
+		qm.OrderBy("name, record_type, datacenter_id"),
+	).Bind(ctx, e.db, &stats)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
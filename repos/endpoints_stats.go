@@ -16,12 +16,16 @@ import (
 	"time"
 )
 
+// EndpointStatsColumnAssertionFailures is not generated on the endpoint stat model
+const EndpointStatsColumnAssertionFailures = "assertion_failures"
+
 type EndpointStatsRepository interface {
 	Write(ctx context.Context, options WriteEndpointStatsOptions) error
 	WriteBulk(ctx context.Context, options []WriteEndpointStatsOptions) error
 	Read(ctx context.Context, selectors []string, filters Filters, loads []string, limit int, offset int, total bool) (int64, models.EndpointStatSlice, error)
 	GetLastNSessionsByEndpointId(ctx context.Context, n int, endpointId int) ([]string, error)
 	GetSessionSuccessions(ctx context.Context, filters Filters) ([]EndpointSessionSuccessions, error)
+	GetAssertionFailures(ctx context.Context, points models.EndpointStatSlice) (map[string]string, error)
 	CleanUpResponseBodies(ctx context.Context, time time.Time) error
 	VacuumEndpointStats(ctx context.Context) error
 }
@@ -41,6 +45,8 @@ type WriteEndpointStatsOptions struct {
 	EndpointName     string    `json:"endpoint_name"`
 	EndpointId       int       `json:"endpoint_id"`
 	IsHeartBeat      bool      `json:"is_heart_beat"`
+	IsMaintenance    bool      `json:"is_maintenance"`
+	IsAgentError     bool      `json:"is_agent_error"`
 	Url              string    `json:"url"`
 	DatacenterId     int       `json:"datacenter_id"`
 	Success          int       `json:"success"`
@@ -49,6 +55,8 @@ type WriteEndpointStatsOptions struct {
 	ResponseBodies   string    `json:"response_bodies"`
 	ResponseHeaders  string    `json:"response_headers"`
 	ResponseStatuses string    `json:"response_statuses"`
+	// AssertionFailures is a json map of endpoint name to the failed checks of that step
+	AssertionFailures string `json:"assertion_failures"`
 }
 
 func (e *endpointStatsRepository) Write(ctx context.Context, options WriteEndpointStatsOptions) error {
@@ -88,12 +96,15 @@ func (e *endpointStatsRepository) WriteBulk(ctx context.Context, options []Write
 		models.EndpointStatColumns.URL,
 		models.EndpointStatColumns.DatacenterID,
 		models.EndpointStatColumns.IsHeartBeat,
+		StatsColumnIsMaintenance,
+		StatsColumnIsAgentError,
 		models.EndpointStatColumns.Success,
 		models.EndpointStatColumns.ResponseTime,
 		models.EndpointStatColumns.ResponseTimes,
 		models.EndpointStatColumns.ResponseBodies,
 		models.EndpointStatColumns.ResponseHeaders,
-		models.EndpointStatColumns.ResponseStatuses))
+		models.EndpointStatColumns.ResponseStatuses,
+		EndpointStatsColumnAssertionFailures))
 	if err != nil {
 		return err
 	}
@@ -108,12 +119,15 @@ func (e *endpointStatsRepository) WriteBulk(ctx context.Context, options []Write
 			null.NewString(option.Url, true),
 			option.DatacenterId,
 			option.IsHeartBeat,
+			option.IsMaintenance,
+			option.IsAgentError,
 			option.Success,
 			option.ResponseTime,
 			null.NewString(option.ResponseTimes, true),
 			null.NewBytes([]byte(option.ResponseBodies), true),
 			null.NewString(option.ResponseHeaders, true),
 			null.NewString(option.ResponseStatuses, true),
+			null.NewString(option.AssertionFailures, option.AssertionFailures != ""),
 		)
 		if err != nil {
 			log.Error(err)
@@ -185,6 +199,42 @@ func (e *endpointStatsRepository) Read(ctx context.Context, selectors []string,
 	return tc, res, err
 }
 
+// AssertionFailuresKey identifies a stat row in the result of GetAssertionFailures, a session writes one row per datacenter
+func AssertionFailuresKey(sessionId string, datacenterId int) string {
+	return fmt.Sprintf("%s:%d", sessionId, datacenterId)
+}
+
+// GetAssertionFailures loads the failed checks of the given rows, rows without failures are left out
+func (e *endpointStatsRepository) GetAssertionFailures(ctx context.Context, points models.EndpointStatSlice) (map[string]string, error) {
+	res := make(map[string]string)
+	if len(points) == 0 {
+		return res, nil
+	}
+	var sessionIds []string
+	var datacenterIds []int64
+	for _, point := range points {
+		sessionIds = append(sessionIds, point.SessionID)
+		datacenterIds = append(datacenterIds, int64(point.DatacenterID))
+	}
+
+	var rows []struct {
+		SessionId         string      `boil:"session_id"`
+		DatacenterId      int         `boil:"datacenter_id"`
+		AssertionFailures null.String `boil:"assertion_failures"`
+	}
+	err := queries.Raw(`select s.session_id, s.datacenter_id, s.assertion_failures from endpoint_stats s
+	                    join unnest($1::text[], $2::int[]) as p(session_id, datacenter_id) on s.session_id = p.session_id and s.datacenter_id = p.datacenter_id
+	                    where s.assertion_failures is not null`,
+		pq.Array(sessionIds), pq.Array(datacenterIds)).Bind(ctx, e.db, &rows)
+	if err != nil {
+		return res, err
+	}
+	for _, row := range rows {
+		res[AssertionFailuresKey(row.SessionId, row.DatacenterId)] = row.AssertionFailures.String
+	}
+	return res, nil
+}
+
 func (e *endpointStatsRepository) GetLastNSessionsByEndpointId(ctx context.Context, n int, endpointId int) (res []string, err error) {
 	rows, err := queries.Raw("select session_id from (select session_id, max(time) as tt from endpoint_stats where endpoint_id = $1 group by session_id order by tt desc limit $2) as b", endpointId, n).QueryContext(ctx, e.db)
 	if err != nil {
@@ -203,11 +253,12 @@ func (e *endpointStatsRepository) GetLastNSessionsByEndpointId(ctx context.Conte
 }
 
 type EndpointSessionSuccessions struct {
-	SessionId  string    `json:"session_id"`
-	EndpointId int       `json:"endpoint_id"`
-	MinTime    time.Time `json:"min_time"`
-	MaxTime    time.Time `json:"max_time"`
-	Success    bool      `json:"success"`
+	SessionId     string    `json:"session_id"`
+	EndpointId    int       `json:"endpoint_id"`
+	MinTime       time.Time `json:"min_time"`
+	MaxTime       time.Time `json:"max_time"`
+	Success       bool      `json:"success"`
+	IsMaintenance bool      `json:"is_maintenance"`
 }
 
 func (e *endpointStatsRepository) GetSessionSuccessions(ctx context.Context, filters Filters) ([]EndpointSessionSuccessions, error) {
@@ -235,7 +286,8 @@ func (e *endpointStatsRepository) GetSessionSuccessions(ctx context.Context, fil
 		"endpoint_id",
 		"min(time) as min_time",
 		"max(time) as max_time",
-		"bit_and(success) as success"))
+		"bit_and(success) as success",
+		"bool_or(is_maintenance) as is_maintenance"))
 	qmQuery = append(qmQuery, qm.OrderBy("max_time desc"))
 	qmQuery = append(qmQuery, qm.GroupBy("session_id, endpoint_id"))
 
@@ -0,0 +1,136 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+type IncidentsRepository interface {
+	OpenIncident(ctx context.Context, incident usecase_models.Incident) (int, error)
+	ResolveIncident(ctx context.Context, incidentId int, resolvedAt time.Time, resolvedBy null.Int) (usecase_models.Incident, error)
+	AcknowledgeIncident(ctx context.Context, incidentId int, accountId int) error
+	GetOpenIncident(ctx context.Context, pipelineType string, pipelineId int) (usecase_models.Incident, error)
+	GetIncident(ctx context.Context, incidentId int) (usecase_models.Incident, error)
+	GetIncidents(ctx context.Context, filters Filters, limit int, offset int) ([]usecase_models.Incident, error)
+	SaveIncidentEvent(ctx context.Context, event usecase_models.IncidentEvent) (int, error)
+	GetIncidentEvents(ctx context.Context, incidentId int) ([]usecase_models.IncidentEvent, error)
+}
+
+type incidentsRepository struct {
+	db *sql.DB
+}
+
+func NewIncidentsRepository(db *sql.DB) IncidentsRepository {
+	return &incidentsRepository{db: db}
+}
+
+func (r *incidentsRepository) OpenIncident(ctx context.Context, incident usecase_models.Incident) (int, error) {
+	var id int
+	err := queries.Raw(`insert into incidents (project_id, pipeline_type, pipeline_id, pipeline_name, status, address,
+                       root_cause, datacenters, started_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now()) returning id;`,
+		incident.ProjectId, incident.PipelineType, incident.PipelineId, incident.PipelineName, usecase_models.IncidentStatusOpen,
+		incident.Address, incident.RootCause, incident.Datacenters, incident.StartedAt).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *incidentsRepository) ResolveIncident(ctx context.Context, incidentId int, resolvedAt time.Time, resolvedBy null.Int) (usecase_models.Incident, error) {
+	var incident usecase_models.Incident
+	err := queries.Raw(`update incidents set status = $1, resolved_at = $2, resolved_by = $3,
+                     duration = extract(epoch from ($2 - started_at))::int, updated_at = now()
+                 where id = $4 and status = $5 returning *;`, usecase_models.IncidentStatusResolved, resolvedAt, resolvedBy,
+		incidentId, usecase_models.IncidentStatusOpen).Bind(ctx, r.db, &incident)
+	if err != nil {
+		return usecase_models.Incident{}, err
+	}
+	return incident, nil
+}
+
+func (r *incidentsRepository) AcknowledgeIncident(ctx context.Context, incidentId int, accountId int) error {
+	query := queries.Raw(`update incidents set acknowledged_at = now(), acknowledged_by = $1, updated_at = now()
+                 where id = $2 and status = $3 and acknowledged_at is null;`, accountId, incidentId, usecase_models.IncidentStatusOpen)
+	result, err := query.ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("incident is not open or already acknowledged")
+	}
+	return nil
+}
+
+func (r *incidentsRepository) GetOpenIncident(ctx context.Context, pipelineType string, pipelineId int) (usecase_models.Incident, error) {
+	var incident usecase_models.Incident
+	err := models.NewQuery(
+		qm.From("incidents"),
+		qm.Where("pipeline_type = ? and pipeline_id = ? and status = ?", pipelineType, pipelineId, usecase_models.IncidentStatusOpen),
+		qm.OrderBy("started_at desc"),
+		qm.Limit(1),
+	).Bind(ctx, r.db, &incident)
+	if err != nil {
+		return usecase_models.Incident{}, err
+	}
+	return incident, nil
+}
+
+func (r *incidentsRepository) GetIncident(ctx context.Context, incidentId int) (usecase_models.Incident, error) {
+	var incident usecase_models.Incident
+	err := models.NewQuery(qm.From("incidents"), qm.Where("id = ?", incidentId)).Bind(ctx, r.db, &incident)
+	if err != nil {
+		return usecase_models.Incident{}, err
+	}
+	return incident, nil
+}
+
+func (r *incidentsRepository) GetIncidents(ctx context.Context, filters Filters, limit int, offset int) ([]usecase_models.Incident, error) {
+	qmQuery := append(queryMods(filters), qm.From("incidents"), qm.OrderBy("started_at desc"))
+	if limit > 0 {
+		qmQuery = append(qmQuery, qm.Limit(limit), qm.Offset(offset))
+	}
+	var incidents []usecase_models.Incident
+	err := models.NewQuery(qmQuery...).Bind(ctx, r.db, &incidents)
+	if err != nil {
+		return nil, err
+	}
+	return incidents, nil
+}
+
+func (r *incidentsRepository) SaveIncidentEvent(ctx context.Context, event usecase_models.IncidentEvent) (int, error) {
+	var id int
+	err := queries.Raw(`insert into incident_events (incident_id, event_type, root_cause, datacenters, resolved_datacenters,
+                             failed_datacenters, account_id, note, created_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, now()) returning id;`,
+		event.IncidentId, event.EventType, event.RootCause, event.Datacenters, event.ResolvedDatacenters,
+		event.FailedDatacenters, event.AccountId, event.Note).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *incidentsRepository) GetIncidentEvents(ctx context.Context, incidentId int) ([]usecase_models.IncidentEvent, error) {
+	var events []usecase_models.IncidentEvent
+	err := models.NewQuery(
+		qm.From("incident_events"),
+		qm.Where("incident_id = ?", incidentId),
+		qm.OrderBy("created_at asc"),
+	).Bind(ctx, r.db, &events)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
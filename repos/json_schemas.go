@@ -0,0 +1,87 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+)
+
+type JsonSchemasRepository interface {
+	SaveJsonSchema(ctx context.Context, schema usecase_models.JsonSchema) (int, error)
+	UpdateJsonSchema(ctx context.Context, schema usecase_models.JsonSchema) error
+	DeleteJsonSchema(ctx context.Context, schemaId int) error
+	GetJsonSchema(ctx context.Context, schemaId int) (usecase_models.JsonSchema, error)
+	GetJsonSchemas(ctx context.Context, projectId int) ([]usecase_models.JsonSchema, error)
+}
+
+type jsonSchemasRepository struct {
+	db *sql.DB
+}
+
+func NewJsonSchemasRepository(db *sql.DB) JsonSchemasRepository {
+	return &jsonSchemasRepository{db: db}
+}
+
+func (r *jsonSchemasRepository) SaveJsonSchema(ctx context.Context, schema usecase_models.JsonSchema) (int, error) {
+	var id int
+	err := queries.Raw(`insert into json_schemas (project_id, name, schema, created_at, updated_at)
+		values ($1, $2, $3, now(), now()) returning id;`,
+		schema.ProjectId, schema.Name, schema.Schema).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *jsonSchemasRepository) UpdateJsonSchema(ctx context.Context, schema usecase_models.JsonSchema) error {
+	query := queries.Raw(`update json_schemas set name = $1, schema = $2, updated_at = now()
+                           where id = $3 and deleted_at is null;`,
+		schema.Name, schema.Schema, schema.ID)
+	result, err := query.ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("json schema not found")
+	}
+	return nil
+}
+
+func (r *jsonSchemasRepository) DeleteJsonSchema(ctx context.Context, schemaId int) error {
+	query := queries.Raw("update json_schemas set deleted_at = now() where id = $1 and deleted_at is null;", schemaId)
+	_, err := query.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *jsonSchemasRepository) GetJsonSchema(ctx context.Context, schemaId int) (usecase_models.JsonSchema, error) {
+	var schema usecase_models.JsonSchema
+	err := models.NewQuery(
+		qm.From("json_schemas"),
+		qm.Where("id = ? and deleted_at is null", schemaId),
+	).Bind(ctx, r.db, &schema)
+	if err != nil {
+		return usecase_models.JsonSchema{}, err
+	}
+	return schema, nil
+}
+
+func (r *jsonSchemasRepository) GetJsonSchemas(ctx context.Context, projectId int) ([]usecase_models.JsonSchema, error) {
+	var schemas []usecase_models.JsonSchema
+	err := models.NewQuery(
+		qm.From("json_schemas"),
+		qm.Where("project_id = ? and deleted_at is null", projectId),
+		qm.OrderBy("name"),
+	).Bind(ctx, r.db, &schemas)
+	if err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
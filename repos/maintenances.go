@@ -0,0 +1,116 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+// StatsColumnIsMaintenance is not generated on stats models, stats written inside a maintenance window set it
+const StatsColumnIsMaintenance = "is_maintenance"
+
+type MaintenanceWindowsRepository interface {
+	SaveMaintenanceWindow(ctx context.Context, window usecase_models.MaintenanceWindow) (int, error)
+	UpdateMaintenanceWindow(ctx context.Context, window usecase_models.MaintenanceWindow) error
+	DeleteMaintenanceWindow(ctx context.Context, windowId int) error
+	GetMaintenanceWindow(ctx context.Context, windowId int) (usecase_models.MaintenanceWindow, error)
+	GetMaintenanceWindows(ctx context.Context, projectId int) ([]usecase_models.MaintenanceWindow, error)
+	IsUnderMaintenance(ctx context.Context, projectId int, pipelineType string, pipelineId int, t time.Time) (bool, error)
+}
+
+type maintenanceWindowsRepository struct {
+	db *sql.DB
+}
+
+func NewMaintenanceWindowsRepository(db *sql.DB) MaintenanceWindowsRepository {
+	return &maintenanceWindowsRepository{db: db}
+}
+
+func (r *maintenanceWindowsRepository) SaveMaintenanceWindow(ctx context.Context, window usecase_models.MaintenanceWindow) (int, error) {
+	var id int
+	err := queries.Raw(`insert into maintenance_windows (project_id, pipeline_type, pipeline_id, title, starts_at, ends_at,
+                                 recurrence, repeat_until, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, now(), now()) returning id;`,
+		window.ProjectId, window.PipelineType, window.PipelineId, window.Title, window.StartsAt, window.EndsAt,
+		window.Recurrence, window.RepeatUntil).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *maintenanceWindowsRepository) UpdateMaintenanceWindow(ctx context.Context, window usecase_models.MaintenanceWindow) error {
+	query := queries.Raw(`update maintenance_windows set pipeline_type = $1, pipeline_id = $2, title = $3, starts_at = $4,
+                               ends_at = $5, recurrence = $6, repeat_until = $7, updated_at = now()
+                           where id = $8 and deleted_at is null;`,
+		window.PipelineType, window.PipelineId, window.Title, window.StartsAt, window.EndsAt, window.Recurrence,
+		window.RepeatUntil, window.ID)
+	result, err := query.ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("maintenance window not found")
+	}
+	return nil
+}
+
+func (r *maintenanceWindowsRepository) DeleteMaintenanceWindow(ctx context.Context, windowId int) error {
+	query := queries.Raw("update maintenance_windows set deleted_at = now() where id = $1 and deleted_at is null;", windowId)
+	_, err := query.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *maintenanceWindowsRepository) GetMaintenanceWindow(ctx context.Context, windowId int) (usecase_models.MaintenanceWindow, error) {
+	var window usecase_models.MaintenanceWindow
+	err := models.NewQuery(
+		qm.From("maintenance_windows"),
+		qm.Where("id = ? and deleted_at is null", windowId),
+	).Bind(ctx, r.db, &window)
+	if err != nil {
+		return usecase_models.MaintenanceWindow{}, err
+	}
+	return window, nil
+}
+
+func (r *maintenanceWindowsRepository) GetMaintenanceWindows(ctx context.Context, projectId int) ([]usecase_models.MaintenanceWindow, error) {
+	var windows []usecase_models.MaintenanceWindow
+	err := models.NewQuery(
+		qm.From("maintenance_windows"),
+		qm.Where("project_id = ? and deleted_at is null", projectId),
+		qm.OrderBy("starts_at desc"),
+	).Bind(ctx, r.db, &windows)
+	if err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// IsUnderMaintenance checks project wide windows and the windows of the given pipeline
+func (r *maintenanceWindowsRepository) IsUnderMaintenance(ctx context.Context, projectId int, pipelineType string, pipelineId int, t time.Time) (bool, error) {
+	var windows []usecase_models.MaintenanceWindow
+	err := models.NewQuery(
+		qm.From("maintenance_windows"),
+		qm.Where("project_id = ? and deleted_at is null and starts_at <= ?", projectId, t),
+		qm.Where("(pipeline_type is null or (pipeline_type = ? and pipeline_id = ?))", pipelineType, pipelineId),
+		qm.Where("(recurrence = '' and ends_at > ?) or (recurrence != '' and (repeat_until is null or repeat_until > ?))", t, t),
+	).Bind(ctx, r.db, &windows)
+	if err != nil {
+		return false, err
+	}
+	for _, window := range windows {
+		if window.ActiveAt(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -1,5 +1,11 @@
 package repos
 
+import (
+	"fmt"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"reflect"
+)
+
 // FilterOp filter operation
 type FilterOp string
 
@@ -29,3 +35,25 @@ type Filter struct {
 
 // Filters filters
 type Filters []Filter
+
+// queryMods converts filters to where clauses of sqlboiler
+func queryMods(filters Filters) []qm.QueryMod {
+	var qmQuery []qm.QueryMod
+	for _, filter := range filters {
+		if filter.Op == FilterOpIn {
+			var out []interface{}
+			rv := reflect.ValueOf(filter.Value)
+			if rv.Kind() == reflect.Slice {
+				for i := 0; i < rv.Len(); i++ {
+					out = append(out, rv.Index(i).Interface())
+				}
+			} else {
+				continue
+			}
+			qmQuery = append(qmQuery, qm.WhereIn(fmt.Sprintf("%s %s ?", filter.Field, filter.Op), out...))
+		} else {
+			qmQuery = append(qmQuery, qm.Where(fmt.Sprintf("%s %s ?", filter.Field, filter.Op), filter.Value))
+		}
+	}
+	return qmQuery
+}
@@ -0,0 +1,83 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+)
+
+type MonitorStatesRepository interface {
+	GetStates(ctx context.Context, pipelineType string, pipelineId int) ([]usecase_models.MonitorState, error)
+	GetProjectStates(ctx context.Context, projectId int) ([]usecase_models.MonitorState, error)
+	SaveStates(ctx context.Context, pipelineType string, pipelineId int, states []usecase_models.MonitorState) error
+}
+
+type monitorStatesRepository struct {
+	db *sql.DB
+}
+
+func NewMonitorStatesRepository(db *sql.DB) MonitorStatesRepository {
+	return &monitorStatesRepository{db: db}
+}
+
+func (r *monitorStatesRepository) GetStates(ctx context.Context, pipelineType string, pipelineId int) ([]usecase_models.MonitorState, error) {
+	var states []usecase_models.MonitorState
+	err := models.NewQuery(
+		qm.From("monitor_states"),
+		qm.Where("pipeline_type = ? and pipeline_id = ?", pipelineType, pipelineId),
+		qm.OrderBy("datacenter_id"),
+	).Bind(ctx, r.db, &states)
+	if err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (r *monitorStatesRepository) GetProjectStates(ctx context.Context, projectId int) ([]usecase_models.MonitorState, error) {
+	var states []usecase_models.MonitorState
+	err := models.NewQuery(
+		qm.From("monitor_states"),
+		qm.Where("project_id = ?", projectId),
+		qm.OrderBy("pipeline_type, pipeline_id, datacenter_id"),
+	).Bind(ctx, r.db, &states)
+	if err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SaveStates upserts the state of the given datacenters of the pipeline. datacenters left out keep their row,
+// since a selection strategy may run only some of them on each cycle. since only moves when the status of a datacenter changes
+func (r *monitorStatesRepository) SaveStates(ctx context.Context, pipelineType string, pipelineId int, states []usecase_models.MonitorState) error {
+	txn, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer txn.Rollback()
+
+	for _, state := range states {
+		_, err = queries.Raw(`insert into monitor_states (pipeline_type, pipeline_id, datacenter_id, project_id, status, since,
+                            last_session_id, consecutive_failures, address, root_cause, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, now())
+		on conflict (pipeline_type, pipeline_id, datacenter_id) do update set
+			project_id = excluded.project_id,
+			status = excluded.status,
+			since = case when monitor_states.status = excluded.status then monitor_states.since else excluded.since end,
+			last_session_id = excluded.last_session_id,
+			consecutive_failures = excluded.consecutive_failures,
+			address = excluded.address,
+			root_cause = excluded.root_cause,
+			updated_at = now();`,
+			pipelineType, pipelineId, state.DatacenterId, state.ProjectId, state.Status, state.Since, state.LastSessionId,
+			state.ConsecutiveFailures, state.Address, state.RootCause).ExecContext(ctx, txn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+const (
+	NetCatSessionCachePrefix = "net_cat:session:net_cat_id:"
+)
+
 type NetCatRepository interface {
 	UpdateNetCat(ctx context.Context, NetCat models.NetCat) error
 	GetNetCats(ctx context.Context, projectId int) (netCatUseCase []*usecase_models.NetCats, err error)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
 	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries"
@@ -15,7 +17,8 @@ import (
 )
 
 type NetCatStatsRepository interface {
-	Write(ctx context.Context, time time.Time, options WriteNetCatStatsOptions) error
+	Write(ctx context.Context, options WriteNetCatStatsOptions) error
+	WriteBulk(ctx context.Context, options []WriteNetCatStatsOptions) error
 	Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.NetCatsStatSlice, error)
 	GetLastNSessionsByNetcatId(ctx context.Context, n int, netcatId int) (res []string, err error)
 	GetSessionSuccessions(ctx context.Context, filters Filters) ([]NetcatSessionSuccessions, error)
@@ -30,17 +33,22 @@ func NewNetCatStatsRepository(db *sql.DB) NetCatStatsRepository {
 }
 
 type WriteNetCatStatsOptions struct {
-	ProjectId    int
-	NetCatId     int
-	IsHeartBeat  bool
-	Url          string
-	DatacenterId int
-	Success      int
+	Time          time.Time `json:"time"`
+	ProjectId     int       `json:"project_id"`
+	SessionId     string    `json:"session_id"`
+	NetCatId      int       `json:"netcat_id"`
+	IsHeartBeat   bool      `json:"is_heart_beat"`
+	IsMaintenance bool      `json:"is_maintenance"`
+	IsAgentError  bool      `json:"is_agent_error"`
+	Url           string    `json:"url"`
+	DatacenterId  int       `json:"datacenter_id"`
+	Success       int       `json:"success"`
 }
 
-func (e *netCatStatsRepository) Write(ctx context.Context, time time.Time, options WriteNetCatStatsOptions) error {
+func (e *netCatStatsRepository) Write(ctx context.Context, options WriteNetCatStatsOptions) error {
 	NetCatStat := models.NetCatsStat{
-		Time:         time,
+		Time:         options.Time,
+		SessionID:    options.SessionId,
 		ProjectID:    options.ProjectId,
 		NetcatID:     options.NetCatId,
 		URL:          null.NewString(options.Url, true),
@@ -51,6 +59,61 @@ func (e *netCatStatsRepository) Write(ctx context.Context, time time.Time, optio
 	return NetCatStat.Insert(ctx, e.db, boil.Infer())
 }
 
+func (e *netCatStatsRepository) WriteBulk(ctx context.Context, options []WriteNetCatStatsOptions) error {
+	txn, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(pq.CopyIn("net_cats_stats",
+		models.NetCatsStatColumns.Time,
+		models.NetCatsStatColumns.SessionID,
+		models.NetCatsStatColumns.ProjectID,
+		models.NetCatsStatColumns.NetcatID,
+		models.NetCatsStatColumns.URL,
+		models.NetCatsStatColumns.DatacenterID,
+		models.NetCatsStatColumns.IsHeartBeat,
+		StatsColumnIsMaintenance,
+		StatsColumnIsAgentError,
+		models.NetCatsStatColumns.Success))
+	if err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		_, err = stmt.Exec(
+			option.Time,
+			option.SessionId,
+			option.ProjectId,
+			option.NetCatId,
+			null.NewString(option.Url, true),
+			option.DatacenterId,
+			option.IsHeartBeat,
+			option.IsMaintenance,
+			option.IsAgentError,
+			option.Success,
+		)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
 func (e *netCatStatsRepository) Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.NetCatsStatSlice, error) {
 	var qmQuery []qm.QueryMod
 	for _, load := range loads {
@@ -89,11 +152,12 @@ func (e *netCatStatsRepository) GetLastNSessionsByNetcatId(ctx context.Context,
 }
 
 type NetcatSessionSuccessions struct {
-	SessionId string    `json:"session_id"`
-	NetCatId  int       `json:"netcat_id"`
-	MinTime   time.Time `json:"min_time"`
-	MaxTime   time.Time `json:"max_time"`
-	Success   bool      `json:"success"`
+	SessionId     string    `json:"session_id"`
+	NetCatId      int       `json:"netcat_id"`
+	MinTime       time.Time `json:"min_time"`
+	MaxTime       time.Time `json:"max_time"`
+	Success       bool      `json:"success"`
+	IsMaintenance bool      `json:"is_maintenance"`
 }
 
 func (e *netCatStatsRepository) GetSessionSuccessions(ctx context.Context, filters Filters) ([]NetcatSessionSuccessions, error) {
@@ -121,7 +185,8 @@ func (e *netCatStatsRepository) GetSessionSuccessions(ctx context.Context, filte
 		"netcat_id",
 		"min(time) as min_time",
 		"max(time) as max_time",
-		"bit_and(success) as success"))
+		"bit_and(success) as success",
+		"bool_or(is_maintenance) as is_maintenance"))
 	qmQuery = append(qmQuery, qm.OrderBy("max_time desc"))
 	qmQuery = append(qmQuery, qm.GroupBy("session_id, netcat_id"))
 
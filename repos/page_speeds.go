@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+const (
+	PageSpeedSessionCachePrefix = "page_speed:session:page_speed_id:"
+)
+
 type PageSpeedRepository interface {
 	UpdatePageSpeed(ctx context.Context, PageSpeed models.PageSpeed) error
 	GetPageSpeeds(ctx context.Context, projectId int) (pageSpeedUseCase []*usecase_models.PageSpeeds, err error)
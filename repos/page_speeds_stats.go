@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
 	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries"
@@ -15,7 +17,8 @@ import (
 )
 
 type PageSpeedStatsRepository interface {
-	Write(ctx context.Context, time time.Time, options WritePageSpeedStatsOptions) error
+	Write(ctx context.Context, options WritePageSpeedStatsOptions) error
+	WriteBulk(ctx context.Context, options []WritePageSpeedStatsOptions) error
 	Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.PageSpeedsStatSlice, error)
 	GetLastNSessionsByPageSpeedId(ctx context.Context, n int, pageSpeedId int) (res []string, err error)
 	GetSessionSuccessions(ctx context.Context, filters Filters) ([]PageSpeedSessionSuccessions, error)
@@ -30,17 +33,22 @@ func NewPageSpeedStatsRepository(db *sql.DB) PageSpeedStatsRepository {
 }
 
 type WritePageSpeedStatsOptions struct {
-	ProjectId    int
-	PageSpeedId  int
-	IsHeartBeat  bool
-	Url          string
-	DatacenterId int
-	Success      int
+	Time          time.Time `json:"time"`
+	ProjectId     int       `json:"project_id"`
+	SessionId     string    `json:"session_id"`
+	PageSpeedId   int       `json:"pagespeed_id"`
+	IsHeartBeat   bool      `json:"is_heart_beat"`
+	IsMaintenance bool      `json:"is_maintenance"`
+	IsAgentError  bool      `json:"is_agent_error"`
+	Url           string    `json:"url"`
+	DatacenterId  int       `json:"datacenter_id"`
+	Success       int       `json:"success"`
 }
 
-func (e *pageSpeedStatsRepository) Write(ctx context.Context, time time.Time, options WritePageSpeedStatsOptions) error {
+func (e *pageSpeedStatsRepository) Write(ctx context.Context, options WritePageSpeedStatsOptions) error {
 	PageSpeedStat := models.PageSpeedsStat{
-		Time:         time,
+		Time:         options.Time,
+		SessionID:    options.SessionId,
 		ProjectID:    options.ProjectId,
 		PagespeedID:  options.PageSpeedId,
 		URL:          null.NewString(options.Url, true),
@@ -51,6 +59,61 @@ func (e *pageSpeedStatsRepository) Write(ctx context.Context, time time.Time, op
 	return PageSpeedStat.Insert(ctx, e.db, boil.Infer())
 }
 
+func (e *pageSpeedStatsRepository) WriteBulk(ctx context.Context, options []WritePageSpeedStatsOptions) error {
+	txn, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(pq.CopyIn("page_speeds_stats",
+		models.PageSpeedsStatColumns.Time,
+		models.PageSpeedsStatColumns.SessionID,
+		models.PageSpeedsStatColumns.ProjectID,
+		models.PageSpeedsStatColumns.PagespeedID,
+		models.PageSpeedsStatColumns.URL,
+		models.PageSpeedsStatColumns.DatacenterID,
+		models.PageSpeedsStatColumns.IsHeartBeat,
+		StatsColumnIsMaintenance,
+		StatsColumnIsAgentError,
+		models.PageSpeedsStatColumns.Success))
+	if err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		_, err = stmt.Exec(
+			option.Time,
+			option.SessionId,
+			option.ProjectId,
+			option.PageSpeedId,
+			null.NewString(option.Url, true),
+			option.DatacenterId,
+			option.IsHeartBeat,
+			option.IsMaintenance,
+			option.IsAgentError,
+			option.Success,
+		)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
 func (e *pageSpeedStatsRepository) Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.PageSpeedsStatSlice, error) {
 	var qmQuery []qm.QueryMod
 	for _, load := range loads {
@@ -89,11 +152,12 @@ func (e *pageSpeedStatsRepository) GetLastNSessionsByPageSpeedId(ctx context.Con
 }
 
 type PageSpeedSessionSuccessions struct {
-	SessionId   string    `json:"session_id"`
-	PageSpeedId int       `json:"pagespeed_id"`
-	MinTime     time.Time `json:"min_time"`
-	MaxTime     time.Time `json:"max_time"`
-	Success     bool      `json:"success"`
+	SessionId     string    `json:"session_id"`
+	PageSpeedId   int       `json:"pagespeed_id"`
+	MinTime       time.Time `json:"min_time"`
+	MaxTime       time.Time `json:"max_time"`
+	Success       bool      `json:"success"`
+	IsMaintenance bool      `json:"is_maintenance"`
 }
 
 func (e *pageSpeedStatsRepository) GetSessionSuccessions(ctx context.Context, filters Filters) ([]PageSpeedSessionSuccessions, error) {
@@ -121,7 +185,8 @@ func (e *pageSpeedStatsRepository) GetSessionSuccessions(ctx context.Context, fi
 		"pagespeed_id",
 		"min(time) as min_time",
 		"max(time) as max_time",
-		"bit_and(success) as success"))
+		"bit_and(success) as success",
+		"bool_or(is_maintenance) as is_maintenance"))
 	qmQuery = append(qmQuery, qm.OrderBy("max_time desc"))
 	qmQuery = append(qmQuery, qm.GroupBy("session_id, pagespeed_id"))
 
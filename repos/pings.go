@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+const (
+	PingSessionCachePrefix = "ping:session:ping_id:"
+)
+
 type PingRepository interface {
 	UpdatePing(ctx context.Context, Ping models.Ping) error
 	GetPings(ctx context.Context, projectId int) (pingUseCase []*usecase_models.Pings, err error)
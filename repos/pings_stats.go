@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
 	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries"
@@ -15,7 +17,8 @@ import (
 )
 
 type PingStatsRepository interface {
-	Write(ctx context.Context, time time.Time, options WritePingStatsOptions) error
+	Write(ctx context.Context, options WritePingStatsOptions) error
+	WriteBulk(ctx context.Context, options []WritePingStatsOptions) error
 	Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.PingsStatSlice, error)
 	GetLastNSessionsByPingId(ctx context.Context, n int, pingId int) (res []string, err error)
 	GetSessionSuccessions(ctx context.Context, filters Filters) ([]PingSessionSuccessions, error)
@@ -30,17 +33,22 @@ func NewPingStatsRepository(db *sql.DB) PingStatsRepository {
 }
 
 type WritePingStatsOptions struct {
-	ProjectId    int
-	PingId       int
-	IsHeartBeat  bool
-	Url          string
-	DatacenterId int
-	Success      int
+	Time          time.Time `json:"time"`
+	ProjectId     int       `json:"project_id"`
+	SessionId     string    `json:"session_id"`
+	PingId        int       `json:"ping_id"`
+	IsHeartBeat   bool      `json:"is_heart_beat"`
+	IsMaintenance bool      `json:"is_maintenance"`
+	IsAgentError  bool      `json:"is_agent_error"`
+	Url           string    `json:"url"`
+	DatacenterId  int       `json:"datacenter_id"`
+	Success       int       `json:"success"`
 }
 
-func (e *pingStatsRepository) Write(ctx context.Context, time time.Time, options WritePingStatsOptions) error {
+func (e *pingStatsRepository) Write(ctx context.Context, options WritePingStatsOptions) error {
 	PingStat := models.PingsStat{
-		Time:         time,
+		Time:         options.Time,
+		SessionID:    options.SessionId,
 		ProjectID:    options.ProjectId,
 		PingID:       options.PingId,
 		URL:          null.NewString(options.Url, true),
@@ -51,6 +59,61 @@ func (e *pingStatsRepository) Write(ctx context.Context, time time.Time, options
 	return PingStat.Insert(ctx, e.db, boil.Infer())
 }
 
+func (e *pingStatsRepository) WriteBulk(ctx context.Context, options []WritePingStatsOptions) error {
+	txn, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(pq.CopyIn("pings_stats",
+		models.PingsStatColumns.Time,
+		models.PingsStatColumns.SessionID,
+		models.PingsStatColumns.ProjectID,
+		models.PingsStatColumns.PingID,
+		models.PingsStatColumns.URL,
+		models.PingsStatColumns.DatacenterID,
+		models.PingsStatColumns.IsHeartBeat,
+		StatsColumnIsMaintenance,
+		StatsColumnIsAgentError,
+		models.PingsStatColumns.Success))
+	if err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		_, err = stmt.Exec(
+			option.Time,
+			option.SessionId,
+			option.ProjectId,
+			option.PingId,
+			null.NewString(option.Url, true),
+			option.DatacenterId,
+			option.IsHeartBeat,
+			option.IsMaintenance,
+			option.IsAgentError,
+			option.Success,
+		)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
 func (e *pingStatsRepository) Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.PingsStatSlice, error) {
 	var qmQuery []qm.QueryMod
 	for _, load := range loads {
@@ -102,11 +165,12 @@ func (e *pingStatsRepository) GetLastNSessionsByPingId(ctx context.Context, n in
 }
 
 type PingSessionSuccessions struct {
-	SessionId string    `json:"session_id"`
-	PingId    int       `json:"ping_id"`
-	MinTime   time.Time `json:"min_time"`
-	MaxTime   time.Time `json:"max_time"`
-	Success   bool      `json:"success"`
+	SessionId     string    `json:"session_id"`
+	PingId        int       `json:"ping_id"`
+	MinTime       time.Time `json:"min_time"`
+	MaxTime       time.Time `json:"max_time"`
+	Success       bool      `json:"success"`
+	IsMaintenance bool      `json:"is_maintenance"`
 }
 
 func (e *pingStatsRepository) GetSessionSuccessions(ctx context.Context, filters Filters) ([]PingSessionSuccessions, error) {
@@ -121,7 +185,8 @@ func (e *pingStatsRepository) GetSessionSuccessions(ctx context.Context, filters
 		"ping_id",
 		"min(time) as min_time",
 		"max(time) as max_time",
-		"bit_and(success) as success"))
+		"bit_and(success) as success",
+		"bool_or(is_maintenance) as is_maintenance"))
 	qmQuery = append(qmQuery, qm.OrderBy("max_time desc"))
 	qmQuery = append(qmQuery, qm.GroupBy("session_id, ping_id"))
 
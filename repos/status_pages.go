@@ -0,0 +1,155 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+)
+
+type StatusPagesRepository interface {
+	SaveStatusPage(ctx context.Context, page usecase_models.StatusPage) (int, error)
+	UpdateStatusPage(ctx context.Context, page usecase_models.StatusPage) error
+	DeleteStatusPage(ctx context.Context, pageId int) error
+	GetStatusPage(ctx context.Context, pageId int) (usecase_models.StatusPage, error)
+	GetStatusPageBySlug(ctx context.Context, slug string) (usecase_models.StatusPage, error)
+	GetStatusPages(ctx context.Context, projectId int) ([]usecase_models.StatusPage, error)
+}
+
+type statusPagesRepository struct {
+	db *sql.DB
+}
+
+func NewStatusPagesRepository(db *sql.DB) StatusPagesRepository {
+	return &statusPagesRepository{db: db}
+}
+
+func (r *statusPagesRepository) SaveStatusPage(ctx context.Context, page usecase_models.StatusPage) (int, error) {
+	txn, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	var id int
+	err = queries.Raw(`insert into status_pages (project_id, slug, title, description, created_at, updated_at)
+		values ($1, $2, $3, $4, now(), now()) returning id;`,
+		page.ProjectId, page.Slug, page.Title, page.Description).QueryRowContext(ctx, txn).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = saveStatusPageComponents(ctx, txn, id, page.Components)
+	if err != nil {
+		return 0, err
+	}
+	return id, txn.Commit()
+}
+
+func (r *statusPagesRepository) UpdateStatusPage(ctx context.Context, page usecase_models.StatusPage) error {
+	txn, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	result, err := queries.Raw(`update status_pages set slug = $1, title = $2, description = $3, updated_at = now()
+                    where id = $4 and deleted_at is null;`,
+		page.Slug, page.Title, page.Description, page.ID).ExecContext(ctx, txn)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("status page not found")
+	}
+
+	_, err = queries.Raw("delete from status_page_components where status_page_id = $1;", page.ID).ExecContext(ctx, txn)
+	if err != nil {
+		return err
+	}
+	err = saveStatusPageComponents(ctx, txn, page.ID, page.Components)
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+func saveStatusPageComponents(ctx context.Context, txn *sql.Tx, pageId int, components []usecase_models.StatusPageComponent) error {
+	for i, component := range components {
+		_, err := queries.Raw(`insert into status_page_components (status_page_id, name, pipeline_type, pipeline_id, position)
+			values ($1, $2, $3, $4, $5);`,
+			pageId, component.Name, component.PipelineType, component.PipelineId, i).ExecContext(ctx, txn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *statusPagesRepository) DeleteStatusPage(ctx context.Context, pageId int) error {
+	query := queries.Raw("update status_pages set deleted_at = now() where id = $1 and deleted_at is null;", pageId)
+	_, err := query.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *statusPagesRepository) GetStatusPage(ctx context.Context, pageId int) (usecase_models.StatusPage, error) {
+	return r.getStatusPage(ctx, qm.Where("id = ? and deleted_at is null", pageId))
+}
+
+func (r *statusPagesRepository) GetStatusPageBySlug(ctx context.Context, slug string) (usecase_models.StatusPage, error) {
+	return r.getStatusPage(ctx, qm.Where("slug = ? and deleted_at is null", slug))
+}
+
+func (r *statusPagesRepository) getStatusPage(ctx context.Context, where qm.QueryMod) (usecase_models.StatusPage, error) {
+	var page usecase_models.StatusPage
+	err := models.NewQuery(qm.From("status_pages"), where).Bind(ctx, r.db, &page)
+	if err != nil {
+		return usecase_models.StatusPage{}, err
+	}
+
+	page.Components, err = r.getStatusPageComponents(ctx, page.ID)
+	if err != nil {
+		return usecase_models.StatusPage{}, err
+	}
+	return page, nil
+}
+
+func (r *statusPagesRepository) GetStatusPages(ctx context.Context, projectId int) ([]usecase_models.StatusPage, error) {
+	var pages []usecase_models.StatusPage
+	err := models.NewQuery(
+		qm.From("status_pages"),
+		qm.Where("project_id = ? and deleted_at is null", projectId),
+		qm.OrderBy("id"),
+	).Bind(ctx, r.db, &pages)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pages {
+		pages[i].Components, err = r.getStatusPageComponents(ctx, pages[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+func (r *statusPagesRepository) getStatusPageComponents(ctx context.Context, pageId int) ([]usecase_models.StatusPageComponent, error) {
+	var components []usecase_models.StatusPageComponent
+	err := models.NewQuery(
+		qm.From("status_page_components"),
+		qm.Where("status_page_id = ?", pageId),
+		qm.OrderBy("position"),
+	).Bind(ctx, r.db, &components)
+	if err != nil {
+		return nil, err
+	}
+	return components, nil
+}
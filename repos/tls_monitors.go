@@ -0,0 +1,153 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"log"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+const (
+	TLSSessionCachePrefix = "tls:session:tls_monitor_id:"
+)
+
+type TLSMonitorsRepository interface {
+	SaveTLSMonitor(ctx context.Context, projectId int, monitor usecase_models.TLSMonitors) (int, error)
+	UpdateTLSMonitor(ctx context.Context, monitorId int, monitor usecase_models.TLSMonitors) error
+	DeleteTLSMonitor(ctx context.Context, monitorId int) error
+	GetTLSMonitor(ctx context.Context, monitorId int) (*usecase_models.TLSMonitors, error)
+	GetTLSMonitors(ctx context.Context, projectId int) ([]*usecase_models.TLSMonitors, error)
+	GetActiveTLSMonitors(ctx context.Context) ([]*usecase_models.TLSMonitors, error)
+	GetAlertedThresholds(ctx context.Context, monitorId int) (map[string]int, error)
+	SetAlertedThresholds(ctx context.Context, monitorId int, thresholds map[string]int) error
+}
+
+type tlsMonitorsRepository struct {
+	db *sql.DB
+}
+
+func NewTLSMonitorsRepository(db *sql.DB) TLSMonitorsRepository {
+	return &tlsMonitorsRepository{db: db}
+}
+
+type tlsMonitorRow struct {
+	ID                int       `boil:"id"`
+	Data              null.JSON `boil:"data"`
+	AlertedThresholds null.JSON `boil:"alerted_thresholds"`
+}
+
+func (r *tlsMonitorsRepository) SaveTLSMonitor(ctx context.Context, projectId int, monitor usecase_models.TLSMonitors) (int, error) {
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = queries.Raw(`insert into tls_monitors (data, project_id, created_at, updated_at) values ($1, $2, now(), now()) returning id;`,
+		data, projectId).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *tlsMonitorsRepository) UpdateTLSMonitor(ctx context.Context, monitorId int, monitor usecase_models.TLSMonitors) error {
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		return err
+	}
+	result, err := queries.Raw(`update tls_monitors set data = $1, updated_at = now() where id = $2 and deleted_at is null;`,
+		data, monitorId).ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("tls monitor not found")
+	}
+	return nil
+}
+
+func (r *tlsMonitorsRepository) DeleteTLSMonitor(ctx context.Context, monitorId int) error {
+	_, err := queries.Raw("update tls_monitors set deleted_at = now() where id = $1 and deleted_at is null;", monitorId).ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *tlsMonitorsRepository) GetTLSMonitor(ctx context.Context, monitorId int) (*usecase_models.TLSMonitors, error) {
+	var row tlsMonitorRow
+	err := models.NewQuery(qm.From("tls_monitors"), qm.Where("id = ? and deleted_at is null", monitorId)).Bind(ctx, r.db, &row)
+	if err != nil {
+		return &usecase_models.TLSMonitors{}, err
+	}
+	var monitor usecase_models.TLSMonitors
+	err = json.Unmarshal(row.Data.JSON, &monitor)
+	if err != nil {
+		return &usecase_models.TLSMonitors{}, err
+	}
+	monitor.Scheduling.PipelineId = row.ID
+	return &monitor, nil
+}
+
+func (r *tlsMonitorsRepository) GetTLSMonitors(ctx context.Context, projectId int) ([]*usecase_models.TLSMonitors, error) {
+	return r.getTLSMonitors(ctx, qm.Where("project_id = ? and deleted_at is null", projectId))
+}
+
+func (r *tlsMonitorsRepository) GetActiveTLSMonitors(ctx context.Context) ([]*usecase_models.TLSMonitors, error) {
+	return r.getTLSMonitors(ctx, qm.Where("data->'scheduling'->>'is_active' = ? and data->'scheduling'->>'end_at' > ? and deleted_at is null",
+		"true", time.Now().Format("2006-01-02 15:04:05")))
+}
+
+func (r *tlsMonitorsRepository) getTLSMonitors(ctx context.Context, where qm.QueryMod) ([]*usecase_models.TLSMonitors, error) {
+	var rows []tlsMonitorRow
+	err := models.NewQuery(qm.From("tls_monitors"), where, qm.OrderBy("id")).Bind(ctx, r.db, &rows)
+	if err != nil {
+		return []*usecase_models.TLSMonitors{}, err
+	}
+
+	var monitors []*usecase_models.TLSMonitors
+	for _, row := range rows {
+		var monitor usecase_models.TLSMonitors
+		err := json.Unmarshal(row.Data.JSON, &monitor)
+		if err != nil {
+			log.Println(err.Error())
+		}
+		monitor.Scheduling.PipelineId = row.ID
+		monitors = append(monitors, &monitor)
+	}
+	return monitors, nil
+}
+
+// GetAlertedThresholds returns the last expiry threshold alerted for each address of the monitor
+func (r *tlsMonitorsRepository) GetAlertedThresholds(ctx context.Context, monitorId int) (map[string]int, error) {
+	var row tlsMonitorRow
+	err := models.NewQuery(qm.Select("id", "alerted_thresholds"), qm.From("tls_monitors"), qm.Where("id = ?", monitorId)).Bind(ctx, r.db, &row)
+	if err != nil {
+		return nil, err
+	}
+	thresholds := make(map[string]int)
+	if row.AlertedThresholds.Valid {
+		err = json.Unmarshal(row.AlertedThresholds.JSON, &thresholds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return thresholds, nil
+}
+
+func (r *tlsMonitorsRepository) SetAlertedThresholds(ctx context.Context, monitorId int, thresholds map[string]int) error {
+	data, err := json.Marshal(thresholds)
+	if err != nil {
+		return err
+	}
+	_, err = queries.Raw("update tls_monitors set alerted_thresholds = $1 where id = $2;", data, monitorId).ExecContext(ctx, r.db)
+	return err
+}
@@ -0,0 +1,133 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+	"time"
+)
+
+type TLSStatsRepository interface {
+	WriteBulk(ctx context.Context, options []WriteTLSStatsOptions) error
+	GetLastSession(ctx context.Context, monitorId int) ([]usecase_models.TLSStat, error)
+}
+
+type tlsStatsRepository struct {
+	db *sql.DB
+}
+
+func NewTLSStatsRepository(db *sql.DB) TLSStatsRepository {
+	return &tlsStatsRepository{db: db}
+}
+
+type WriteTLSStatsOptions struct {
+	Time            time.Time `json:"time"`
+	ProjectId       int       `json:"project_id"`
+	SessionId       string    `json:"session_id"`
+	TLSMonitorId    int       `json:"tls_monitor_id"`
+	Address         string    `json:"address"`
+	IsHeartBeat     bool      `json:"is_heart_beat"`
+	IsMaintenance   bool      `json:"is_maintenance"`
+	IsAgentError    bool      `json:"is_agent_error"`
+	DatacenterId    int       `json:"datacenter_id"`
+	Success         int       `json:"success"`
+	DaysUntilExpiry null.Int  `json:"days_until_expiry"`
+	NotAfter        null.Time `json:"not_after"`
+	Issuer          string    `json:"issuer"`
+	SANs            string    `json:"sans"`
+	Chain           null.JSON `json:"chain"`
+	HostnameValid   null.Bool `json:"hostname_valid"`
+	ChainTrusted    null.Bool `json:"chain_trusted"`
+	RootCause       string    `json:"root_cause"`
+}
+
+func (e *tlsStatsRepository) WriteBulk(ctx context.Context, options []WriteTLSStatsOptions) error {
+	txn, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(pq.CopyIn("tls_stats",
+		"time",
+		"session_id",
+		"project_id",
+		"tls_monitor_id",
+		"address",
+		"datacenter_id",
+		"is_heart_beat",
+		StatsColumnIsMaintenance,
+		StatsColumnIsAgentError,
+		"success",
+		"days_until_expiry",
+		"not_after",
+		"issuer",
+		"sans",
+		"chain",
+		"hostname_valid",
+		"chain_trusted",
+		"root_cause"))
+	if err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		_, err = stmt.Exec(
+			option.Time,
+			option.SessionId,
+			option.ProjectId,
+			option.TLSMonitorId,
+			option.Address,
+			option.DatacenterId,
+			option.IsHeartBeat,
+			option.IsMaintenance,
+			option.IsAgentError,
+			option.Success,
+			option.DaysUntilExpiry,
+			option.NotAfter,
+			null.NewString(option.Issuer, option.Issuer != ""),
+			null.NewString(option.SANs, option.SANs != ""),
+			option.Chain,
+			option.HostnameValid,
+			option.ChainTrusted,
+			null.NewString(option.RootCause, option.RootCause != ""),
+		)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
+// GetLastSession returns the certificates of every address and datacenter seen on the last session of the monitor
+func (e *tlsStatsRepository) GetLastSession(ctx context.Context, monitorId int) ([]usecase_models.TLSStat, error) {
+	var stats []usecase_models.TLSStat
+	err := models.NewQuery(
+		qm.From("tls_stats"),
+		qm.Where(`tls_monitor_id = ? and session_id = (select session_id from tls_stats where tls_monitor_id = ? order by time desc limit 1)`,
+			monitorId, monitorId),
+		qm.OrderBy("address, datacenter_id"),
+	).Bind(ctx, e.db, &stats)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
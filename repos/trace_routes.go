@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+const (
+	TraceRouteSessionCachePrefix = "trace_route:session:trace_route_id:"
+)
+
 type TraceRouteRepository interface {
 	UpdateTraceRoute(ctx context.Context, TraceRoute models.TraceRoute) error
 	GetTraceRoutes(ctx context.Context, projectId int) (traceRouteUseCase []*usecase_models.TraceRoutes, err error)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
 	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries"
@@ -15,7 +17,8 @@ import (
 )
 
 type TraceRouteStatsRepository interface {
-	Write(ctx context.Context, time time.Time, options WriteTraceRouteStatsOptions) error
+	Write(ctx context.Context, options WriteTraceRouteStatsOptions) error
+	WriteBulk(ctx context.Context, options []WriteTraceRouteStatsOptions) error
 	Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.TraceRoutesStatSlice, error)
 	GetLastNSessionsByTraceRouteId(ctx context.Context, n int, traceRouteId int) (res []string, err error)
 	GetSessionSuccessions(ctx context.Context, filters Filters) ([]TraceRouteSessionSuccessions, error)
@@ -30,17 +33,22 @@ func NewTraceRouteStatsRepository(db *sql.DB) TraceRouteStatsRepository {
 }
 
 type WriteTraceRouteStatsOptions struct {
-	ProjectId    int
-	TraceRouteId int
-	IsHeartBeat  bool
-	Url          string
-	DatacenterId int
-	Success      int
+	Time          time.Time `json:"time"`
+	ProjectId     int       `json:"project_id"`
+	SessionId     string    `json:"session_id"`
+	TraceRouteId  int       `json:"traceroute_id"`
+	IsHeartBeat   bool      `json:"is_heart_beat"`
+	IsMaintenance bool      `json:"is_maintenance"`
+	IsAgentError  bool      `json:"is_agent_error"`
+	Url           string    `json:"url"`
+	DatacenterId  int       `json:"datacenter_id"`
+	Success       int       `json:"success"`
 }
 
-func (e *traceRouteStatsRepository) Write(ctx context.Context, time time.Time, options WriteTraceRouteStatsOptions) error {
+func (e *traceRouteStatsRepository) Write(ctx context.Context, options WriteTraceRouteStatsOptions) error {
 	TraceRouteStat := models.TraceRoutesStat{
-		Time:         time,
+		Time:         options.Time,
+		SessionID:    options.SessionId,
 		ProjectID:    options.ProjectId,
 		TracerouteID: options.TraceRouteId,
 		URL:          null.NewString(options.Url, true),
@@ -51,6 +59,61 @@ func (e *traceRouteStatsRepository) Write(ctx context.Context, time time.Time, o
 	return TraceRouteStat.Insert(ctx, e.db, boil.Infer())
 }
 
+func (e *traceRouteStatsRepository) WriteBulk(ctx context.Context, options []WriteTraceRouteStatsOptions) error {
+	txn, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(pq.CopyIn("trace_routes_stats",
+		models.TraceRoutesStatColumns.Time,
+		models.TraceRoutesStatColumns.SessionID,
+		models.TraceRoutesStatColumns.ProjectID,
+		models.TraceRoutesStatColumns.TracerouteID,
+		models.TraceRoutesStatColumns.URL,
+		models.TraceRoutesStatColumns.DatacenterID,
+		models.TraceRoutesStatColumns.IsHeartBeat,
+		StatsColumnIsMaintenance,
+		StatsColumnIsAgentError,
+		models.TraceRoutesStatColumns.Success))
+	if err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		_, err = stmt.Exec(
+			option.Time,
+			option.SessionId,
+			option.ProjectId,
+			option.TraceRouteId,
+			null.NewString(option.Url, true),
+			option.DatacenterId,
+			option.IsHeartBeat,
+			option.IsMaintenance,
+			option.IsAgentError,
+			option.Success,
+		)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
 func (e *traceRouteStatsRepository) Read(ctx context.Context, selectors []string, filters Filters, loads []string) (models.TraceRoutesStatSlice, error) {
 	var qmQuery []qm.QueryMod
 	for _, load := range loads {
@@ -102,11 +165,12 @@ func (e *traceRouteStatsRepository) GetLastNSessionsByTraceRouteId(ctx context.C
 }
 
 type TraceRouteSessionSuccessions struct {
-	SessionId    string    `json:"session_id"`
-	TraceRouteId int       `json:"traceroute_id"`
-	MinTime      time.Time `json:"min_time"`
-	MaxTime      time.Time `json:"max_time"`
-	Success      bool      `json:"success"`
+	SessionId     string    `json:"session_id"`
+	TraceRouteId  int       `json:"traceroute_id"`
+	MinTime       time.Time `json:"min_time"`
+	MaxTime       time.Time `json:"max_time"`
+	Success       bool      `json:"success"`
+	IsMaintenance bool      `json:"is_maintenance"`
 }
 
 func (e *traceRouteStatsRepository) GetSessionSuccessions(ctx context.Context, filters Filters) ([]TraceRouteSessionSuccessions, error) {
@@ -121,7 +185,8 @@ func (e *traceRouteStatsRepository) GetSessionSuccessions(ctx context.Context, f
 		"traceroute_id",
 		"min(time) as min_time",
 		"max(time) as max_time",
-		"bit_and(success) as success"))
+		"bit_and(success) as success",
+		"bool_or(is_maintenance) as is_maintenance"))
 	qmQuery = append(qmQuery, qm.OrderBy("max_time desc"))
 	qmQuery = append(qmQuery, qm.GroupBy("session_id, traceroute_id"))
 
@@ -0,0 +1,116 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"test-manager/usecase_models"
+	"time"
+)
+
+// StatsColumnIsAgentError is not generated on stats models, stats whose agent could not run the check set it.
+// such rows say nothing about the target and are left out of uptime
+const StatsColumnIsAgentError = "is_agent_error"
+
+type statsTable struct {
+	table  string
+	column string
+}
+
+// statsTables maps monitor types to their stats table and pipeline id column
+var statsTables = map[string]statsTable{
+	"endpoint":   {table: "endpoint_stats", column: "endpoint_id"},
+	"netcat":     {table: "net_cats_stats", column: "netcat_id"},
+	"pagespeed":  {table: "page_speeds_stats", column: "pagespeed_id"},
+	"ping":       {table: "pings_stats", column: "ping_id"},
+	"traceroute": {table: "trace_routes_stats", column: "traceroute_id"},
+	"tls":        {table: "tls_stats", column: "tls_monitor_id"},
+	"dns":        {table: "dns_stats", column: "dns_monitor_id"},
+}
+
+// LastSessionState is the result of the latest session of a pipeline over all of its datacenters
+type LastSessionState struct {
+	Success       int       `boil:"success"`
+	Total         int       `boil:"total"`
+	IsMaintenance bool      `boil:"is_maintenance"`
+	Time          null.Time `boil:"time"`
+}
+
+type UptimeRepository interface {
+	GetLastSessionState(ctx context.Context, pipelineType string, pipelineId int) (LastSessionState, error)
+	GetUptime(ctx context.Context, pipelineType string, pipelineId int, since time.Time) (null.Float64, error)
+	GetDailyUptime(ctx context.Context, pipelineType string, pipelineId int, since time.Time) ([]usecase_models.DailyUptime, error)
+}
+
+type uptimeRepository struct {
+	db *sql.DB
+}
+
+func NewUptimeRepository(db *sql.DB) UptimeRepository {
+	return &uptimeRepository{db: db}
+}
+
+func getStatsTable(pipelineType string) (statsTable, error) {
+	table, ok := statsTables[pipelineType]
+	if !ok {
+		return statsTable{}, fmt.Errorf("unknown pipeline type: %s", pipelineType)
+	}
+	return table, nil
+}
+
+func (r *uptimeRepository) GetLastSessionState(ctx context.Context, pipelineType string, pipelineId int) (LastSessionState, error) {
+	table, err := getStatsTable(pipelineType)
+	if err != nil {
+		return LastSessionState{}, err
+	}
+
+	var state LastSessionState
+	err = queries.Raw(fmt.Sprintf(`select coalesce(sum(success) filter (where is_agent_error = false), 0) as success,
+                                    count(*) filter (where is_agent_error = false) as total,
+                                    coalesce(bool_or(is_maintenance), false) as is_maintenance, max(time) as time
+		from %[1]s where %[2]s = $1 and session_id = (select session_id from %[1]s where %[2]s = $1 order by time desc limit 1);`,
+		table.table, table.column), pipelineId).Bind(ctx, r.db, &state)
+	if err != nil {
+		return LastSessionState{}, err
+	}
+	return state, nil
+}
+
+// GetUptime returns the percent of successful checks since the given time, sessions in maintenance and agent errors are not counted
+func (r *uptimeRepository) GetUptime(ctx context.Context, pipelineType string, pipelineId int, since time.Time) (null.Float64, error) {
+	table, err := getStatsTable(pipelineType)
+	if err != nil {
+		return null.Float64{}, err
+	}
+
+	var uptime null.Float64
+	err = queries.Raw(fmt.Sprintf(`select avg(success) * 100 from %s where %s = $1 and time >= $2 and is_maintenance = false and is_agent_error = false;`,
+		table.table, table.column), pipelineId, since).QueryRowContext(ctx, r.db).Scan(&uptime)
+	if err != nil {
+		return null.Float64{}, err
+	}
+	return uptime, nil
+}
+
+// GetDailyUptime returns one entry per day since the given time, days without data have a null uptime
+func (r *uptimeRepository) GetDailyUptime(ctx context.Context, pipelineType string, pipelineId int, since time.Time) ([]usecase_models.DailyUptime, error) {
+	table, err := getStatsTable(pipelineType)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []usecase_models.DailyUptime
+	err = queries.Raw(fmt.Sprintf(`select d.day, s.uptime
+		from generate_series(date_trunc('day', $2::timestamptz), date_trunc('day', now()), interval '1 day') as d(day)
+		left join (select date_trunc('day', time) as day, avg(success) * 100 as uptime
+		           from %s where %s = $1 and time >= date_trunc('day', $2::timestamptz)
+		             and is_maintenance = false and is_agent_error = false
+		           group by 1) as s on s.day = d.day
+		order by d.day;`, table.table, table.column), pipelineId, since).Bind(ctx, r.db, &days)
+	if err != nil {
+		return nil, err
+	}
+	return days, nil
+}
@@ -0,0 +1,70 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"test-manager/usecase_models"
+	models "test-manager/usecase_models/boiler"
+)
+
+type WebhookDeliveriesRepository interface {
+	SaveDelivery(ctx context.Context, delivery usecase_models.WebhookDelivery) (int, error)
+	SaveAttempt(ctx context.Context, deliveryId int, status string, responseStatus null.Int, attemptErr null.String) error
+	GetDelivery(ctx context.Context, deliveryId int) (usecase_models.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, filters Filters, limit int, offset int) ([]usecase_models.WebhookDelivery, error)
+}
+
+type webhookDeliveriesRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookDeliveriesRepository(db *sql.DB) WebhookDeliveriesRepository {
+	return &webhookDeliveriesRepository{db: db}
+}
+
+func (r *webhookDeliveriesRepository) SaveDelivery(ctx context.Context, delivery usecase_models.WebhookDelivery) (int, error) {
+	var id int
+	err := queries.Raw(`insert into webhook_deliveries (project_id, event_id, event_type, url, payload, status, attempts,
+                                created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, 0, now(), now()) returning id;`,
+		delivery.ProjectId, delivery.EventId, delivery.EventType, delivery.Url, delivery.Payload,
+		usecase_models.WebhookDeliveryPending).QueryRowContext(ctx, r.db).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// SaveAttempt counts an attempt of the delivery and keeps the result of the latest one
+func (r *webhookDeliveriesRepository) SaveAttempt(ctx context.Context, deliveryId int, status string, responseStatus null.Int, attemptErr null.String) error {
+	_, err := queries.Raw(`update webhook_deliveries set status = $1, attempts = attempts + 1, response_status = $2, error = $3,
+                              delivered_at = case when $1 = $4 then now() end, updated_at = now()
+                          where id = $5;`,
+		status, responseStatus, attemptErr, usecase_models.WebhookDeliveryDelivered, deliveryId).ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *webhookDeliveriesRepository) GetDelivery(ctx context.Context, deliveryId int) (usecase_models.WebhookDelivery, error) {
+	var delivery usecase_models.WebhookDelivery
+	err := models.NewQuery(qm.From("webhook_deliveries"), qm.Where("id = ?", deliveryId)).Bind(ctx, r.db, &delivery)
+	if err != nil {
+		return usecase_models.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func (r *webhookDeliveriesRepository) GetDeliveries(ctx context.Context, filters Filters, limit int, offset int) ([]usecase_models.WebhookDelivery, error) {
+	qmQuery := append(queryMods(filters), qm.From("webhook_deliveries"), qm.OrderBy("created_at desc"))
+	if limit > 0 {
+		qmQuery = append(qmQuery, qm.Limit(limit), qm.Offset(offset))
+	}
+	var deliveries []usecase_models.WebhookDelivery
+	err := models.NewQuery(qmQuery...).Bind(ctx, r.db, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
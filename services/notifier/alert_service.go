@@ -0,0 +1,36 @@
+package notifier
+
+import (
+	"context"
+	"strconv"
+	"test-manager/services/alert_system"
+	"test-manager/usecase_models"
+)
+
+type alertServiceNotifier struct {
+	alertSystem alert_system.AlertHandler
+}
+
+// NewAlertServiceNotifier delivers through the external alert service, target.Channel is its alert type
+func NewAlertServiceNotifier(alertSystem alert_system.AlertHandler) Notifier {
+	return &alertServiceNotifier{alertSystem: alertSystem}
+}
+
+func (n *alertServiceNotifier) Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error {
+	request := alert_system.AlertRequest{
+		AlertType: target.Channel,
+		UserId:    strconv.Itoa(message.ProjectId),
+		Targets:   []string{target.Target},
+		Subject:   message.Subject,
+		Message:   message.Text,
+	}
+	switch target.Channel {
+	case usecase_models.NotificationChannelEmail:
+		request.IsTemplate = true
+		request.Template = message.Template
+		request.TemplateKeyPairs = message.TemplateKeyPairs
+	case usecase_models.NotificationChannelSlack:
+		request.IsTemplate = true
+	}
+	return n.alertSystem.SendAlert(ctx, request)
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"test-manager/usecase_models"
+	"time"
+)
+
+const (
+	DriverAlertService = "alert_service"
+	DriverSlack        = "slack"
+	DriverTelegram     = "telegram"
+	DriverSmtp         = "smtp"
+	DriverWebhook      = "webhook"
+	DriverPagerDuty    = "pagerduty"
+)
+
+const DefaultTimeout = 10 * time.Second
+
+// Message is what every driver delivers, drivers pick the parts their channel understands
+type Message struct {
+	ProjectId int    `json:"project_id"`
+	State     string `json:"state"`
+	Subject   string `json:"subject"`
+	Text      string `json:"message"`
+	// DedupKey is stable per pipeline so incident tools group the down and up of a pipeline
+	DedupKey  string `json:"dedup_key"`
+	Source    string `json:"source"`
+	Component string `json:"component"`
+	// Template and TemplateKeyPairs are rendered by the alert service for emails
+	Template         string            `json:"template,omitempty"`
+	TemplateKeyPairs map[string]string `json:"data,omitempty"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error
+}
+
+// Notifiers maps driver names to their notifier
+type Notifiers map[string]Notifier
+
+func (n Notifiers) Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error {
+	notifier, ok := n[target.Driver]
+	if !ok {
+		return fmt.Errorf("unknown notification driver: %s", target.Driver)
+	}
+	return notifier.Notify(ctx, target, message)
+}
+
+func postJson(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	reqB, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqB))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"test-manager/usecase_models"
+	"testing"
+)
+
+// capturedRequest is what a local stand-in received
+type capturedRequest struct {
+	path        string
+	contentType string
+	body        map[string]interface{}
+}
+
+// standIn answers every request with status and hands over what it received
+func standIn(t *testing.T, status int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+	requests := make(chan capturedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Errorf("body is not json: %s", body)
+		}
+		requests <- capturedRequest{path: r.URL.Path, contentType: r.Header.Get("Content-Type"), body: decoded}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+var testMessage = Message{
+	ProjectId: 7,
+	State:     "down",
+	Subject:   "api is down",
+	Text:      "status 500 from Frankfurt",
+	DedupKey:  "endpoint-12",
+	Component: "api",
+}
+
+func TestSlackNotifier(t *testing.T) {
+	server, requests := standIn(t, http.StatusOK)
+	n := &slackNotifier{client: server.Client()}
+
+	err := n.Notify(context.Background(), usecase_models.NotificationTarget{Driver: DriverSlack, Target: server.URL + "/services/T0/B0/x"}, testMessage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request := <-requests
+	if request.path != "/services/T0/B0/x" || request.contentType != "application/json" {
+		t.Fatalf("got %s with %s, want the webhook path with json", request.path, request.contentType)
+	}
+	if text := request.body["text"]; text != "*api is down*\nstatus 500 from Frankfurt" {
+		t.Fatalf("text = %q", text)
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	server, requests := standIn(t, http.StatusOK)
+	n := NewTelegramNotifier(server.URL+"/", "123:token")
+
+	err := n.Notify(context.Background(), usecase_models.NotificationTarget{Driver: DriverTelegram, Target: "-100200"}, testMessage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request := <-requests
+	if request.path != "/bot123:token/sendMessage" {
+		t.Fatalf("path = %s, want the sendMessage method of the bot", request.path)
+	}
+	if request.body["chat_id"] != "-100200" || request.body["text"] != "api is down\n\nstatus 500 from Frankfurt" {
+		t.Fatalf("body = %v", request.body)
+	}
+
+	if err = NewTelegramNotifier(server.URL, "").Notify(context.Background(), usecase_models.NotificationTarget{Target: "-100200"}, testMessage); err == nil {
+		t.Fatal("sent without a bot token")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, requests := standIn(t, http.StatusNoContent)
+	n := &webhookNotifier{client: server.Client()}
+
+	err := n.Notify(context.Background(), usecase_models.NotificationTarget{Driver: DriverWebhook, Target: server.URL + "/alerts"}, testMessage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request := <-requests
+	if request.path != "/alerts" {
+		t.Fatalf("path = %s", request.path)
+	}
+	if request.body["project_id"] != float64(7) || request.body["state"] != "down" || request.body["dedup_key"] != "endpoint-12" {
+		t.Fatalf("body = %v", request.body)
+	}
+}
+
+func TestWebhookNotifierRejected(t *testing.T) {
+	server, _ := standIn(t, http.StatusInternalServerError)
+	n := &webhookNotifier{client: server.Client()}
+
+	err := n.Notify(context.Background(), usecase_models.NotificationTarget{Driver: DriverWebhook, Target: server.URL}, testMessage)
+	if err == nil || !strings.Contains(err.Error(), "rejected with status 500") {
+		t.Fatalf("error = %v, want the status in it", err)
+	}
+}
+
+func TestTenantUrlsOnlyReachPublicAddresses(t *testing.T) {
+	server, requests := standIn(t, http.StatusOK)
+	target := usecase_models.NotificationTarget{Target: server.URL}
+
+	for name, n := range map[string]Notifier{DriverSlack: NewSlackNotifier(), DriverWebhook: NewWebhookNotifier()} {
+		if err := n.Notify(context.Background(), target, testMessage); err == nil || !strings.Contains(err.Error(), "is not public") {
+			t.Fatalf("%s: error = %v, want the loopback address refused", name, err)
+		}
+	}
+	select {
+	case <-requests:
+		t.Fatal("a request reached the loopback address")
+	default:
+	}
+}
+
+// smtpStandIn accepts one mail without authentication and hands over the envelope and data
+func smtpStandIn(t *testing.T) (string, string, <-chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		var lines []string
+		_ = text.PrintfLine("220 localhost ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				_ = text.PrintfLine("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM"), strings.HasPrefix(command, "RCPT TO"):
+				lines = append(lines, line)
+				_ = text.PrintfLine("250 ok")
+			case command == "DATA":
+				_ = text.PrintfLine("354 go ahead")
+				data, err := text.ReadDotLines()
+				if err != nil {
+					return
+				}
+				lines = append(lines, data...)
+				_ = text.PrintfLine("250 queued")
+			case command == "QUIT":
+				_ = text.PrintfLine("221 bye")
+				received <- lines
+				return
+			default:
+				_ = text.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port, received
+}
+
+func TestSmtpNotifier(t *testing.T) {
+	host, port, received := smtpStandIn(t)
+	n := NewSmtpNotifier(host, port, "", "", "alerts@example.com")
+
+	err := n.Notify(context.Background(), usecase_models.NotificationTarget{Driver: DriverSmtp, Target: "ops@example.com"}, testMessage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mail := strings.Join(<-received, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<alerts@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"From: alerts@example.com",
+		"To: ops@example.com",
+		"Subject: api is down",
+		"status 500 from Frankfurt",
+	} {
+		if !strings.Contains(mail, want) {
+			t.Fatalf("mail does not contain %q:\n%s", want, mail)
+		}
+	}
+}
+
+func TestSmtpNotifierRejectsHeaderInjection(t *testing.T) {
+	n := NewSmtpNotifier("127.0.0.1", "25", "", "", "alerts@example.com")
+	err := n.Notify(context.Background(), usecase_models.NotificationTarget{Target: "ops@example.com\r\nBcc: all@example.com"}, testMessage)
+	if err == nil || !strings.Contains(err.Error(), "invalid email header") {
+		t.Fatalf("error = %v, want the target refused", err)
+	}
+}
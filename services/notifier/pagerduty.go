@@ -0,0 +1,73 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"test-manager/usecase_models"
+)
+
+const PagerDutyBaseUrl = "https://events.pagerduty.com"
+
+type pagerDutyNotifier struct {
+	baseUrl string
+	client  *http.Client
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// NewPagerDutyNotifier sends Events API v2 events, the target is the integration routing key.
+// down and update transitions trigger and up resolves the alert of the message dedup key,
+// warnings like expiring certificates or changed dns answers trigger with warning severity.
+// baseUrl defaults to pagerduty and can point to any Events v2 compatible endpoint or a local stand-in
+func NewPagerDutyNotifier(baseUrl string) Notifier {
+	if baseUrl == "" {
+		baseUrl = PagerDutyBaseUrl
+	}
+	return &pagerDutyNotifier{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		client:  &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+func (n *pagerDutyNotifier) Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error {
+	event := pagerDutyEvent{
+		RoutingKey:  target.Target,
+		EventAction: "trigger",
+		DedupKey:    message.DedupKey,
+	}
+	if message.State == "up" {
+		event.EventAction = "resolve"
+	} else {
+		source := message.Source
+		if source == "" {
+			source = message.Component
+		}
+		severity := "critical"
+		if usecase_models.IsWarningState(message.State) {
+			severity = "warning"
+		}
+		event.Payload = &pagerDutyPayload{
+			Summary:       message.Subject,
+			Source:        source,
+			Severity:      severity,
+			Component:     message.Component,
+			CustomDetails: message.TemplateKeyPairs,
+		}
+	}
+	return postJson(ctx, n.client, n.baseUrl+"/v2/enqueue", event)
+}
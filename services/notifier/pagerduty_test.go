@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"test-manager/usecase_models"
+	"testing"
+)
+
+func TestPagerDutyNotifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  Message
+		action   string
+		severity string
+		source   string
+	}{
+		{
+			name:     "down triggers a critical alert",
+			message:  Message{State: "down", Subject: "api is down", DedupKey: "endpoint-12", Source: "https://api.example.com", Component: "api"},
+			action:   "trigger",
+			severity: "critical",
+			source:   "https://api.example.com",
+		},
+		{
+			name:     "diff triggers on the same dedup key",
+			message:  Message{State: "diff", Subject: "api changed", DedupKey: "endpoint-12", Component: "api"},
+			action:   "trigger",
+			severity: "critical",
+			source:   "api",
+		},
+		{
+			name:     "expiring certificate is a warning",
+			message:  Message{State: usecase_models.TLSNotificationStateExpiring, Subject: "certificate expires", DedupKey: "tls-3", Component: "example.com"},
+			action:   "trigger",
+			severity: "warning",
+			source:   "example.com",
+		},
+		{
+			name:    "up resolves without a payload",
+			message: Message{State: "up", Subject: "api is up", DedupKey: "endpoint-12"},
+			action:  "resolve",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := standIn(t, http.StatusAccepted)
+			n := NewPagerDutyNotifier(server.URL + "/")
+
+			err := n.Notify(context.Background(), usecase_models.NotificationTarget{Driver: DriverPagerDuty, Target: "routing-key"}, tt.message)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			request := <-requests
+			if request.path != "/v2/enqueue" {
+				t.Fatalf("path = %s, want the events v2 enqueue path", request.path)
+			}
+			event := request.body
+			if event["routing_key"] != "routing-key" || event["event_action"] != tt.action || event["dedup_key"] != tt.message.DedupKey {
+				t.Fatalf("event = %v", event)
+			}
+			payload, ok := event["payload"].(map[string]interface{})
+			if tt.action == "resolve" {
+				if ok {
+					t.Fatalf("resolve carries a payload: %v", payload)
+				}
+				return
+			}
+			if !ok || payload["summary"] != tt.message.Subject || payload["severity"] != tt.severity || payload["source"] != tt.source {
+				t.Fatalf("payload = %v", event["payload"])
+			}
+		})
+	}
+}
+
+func TestPagerDutyNotifierRejected(t *testing.T) {
+	server, _ := standIn(t, http.StatusBadRequest)
+	err := NewPagerDutyNotifier(server.URL).Notify(context.Background(), usecase_models.NotificationTarget{Target: "bad-key"}, Message{State: "down"})
+	if err == nil {
+		t.Fatal("rejected event returned no error")
+	}
+}
@@ -0,0 +1,24 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"test-manager/usecase_models"
+	"test-manager/utils"
+)
+
+type slackNotifier struct {
+	client *http.Client
+}
+
+// NewSlackNotifier posts to slack incoming webhooks, the target is the webhook url.
+// it is given by the tenant, so only public addresses are dialed
+func NewSlackNotifier() Notifier {
+	return &slackNotifier{client: utils.PublicHttpClient(DefaultTimeout)}
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error {
+	return postJson(ctx, n.client, target.Target, map[string]string{
+		"text": "*" + message.Subject + "*\n" + message.Text,
+	})
+}
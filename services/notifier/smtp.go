@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"test-manager/usecase_models"
+)
+
+type smtpNotifier struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSmtpNotifier sends plain text emails, the target is the recipient address.
+// authentication is skipped when username is empty, e.g. for a local stand-in
+func NewSmtpNotifier(host string, port string, username string, password string, from string) Notifier {
+	return &smtpNotifier{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (n *smtpNotifier) Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error {
+	if n.host == "" || n.from == "" {
+		return errors.New("smtp is not configured")
+	}
+	if strings.ContainsAny(target.Target, "\r\n") || strings.ContainsAny(message.Subject, "\r\n") {
+		return errors.New("invalid email header")
+	}
+
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"UTF-8\"\r\n\r\n%s\r\n",
+		n.from, target.Target, message.Subject, strings.ReplaceAll(message.Text, "\n", "\r\n"))
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- smtp.SendMail(n.addr, auth, n.from, []string{target.Target}, []byte(body))
+	}()
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,40 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"test-manager/usecase_models"
+)
+
+const TelegramBaseUrl = "https://api.telegram.org"
+
+type telegramNotifier struct {
+	baseUrl  string
+	botToken string
+	client   *http.Client
+}
+
+// NewTelegramNotifier sends with the bot api, the target is the chat id.
+// baseUrl defaults to the telegram api and can point to a local stand-in
+func NewTelegramNotifier(baseUrl string, botToken string) Notifier {
+	if baseUrl == "" {
+		baseUrl = TelegramBaseUrl
+	}
+	return &telegramNotifier{
+		baseUrl:  strings.TrimSuffix(baseUrl, "/"),
+		botToken: botToken,
+		client:   &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+func (n *telegramNotifier) Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error {
+	if n.botToken == "" {
+		return errors.New("telegram bot token is not configured")
+	}
+	return postJson(ctx, n.client, n.baseUrl+"/bot"+n.botToken+"/sendMessage", map[string]string{
+		"chat_id": target.Target,
+		"text":    message.Subject + "\n\n" + message.Text,
+	})
+}
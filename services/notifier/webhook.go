@@ -0,0 +1,21 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"test-manager/usecase_models"
+	"test-manager/utils"
+)
+
+type webhookNotifier struct {
+	client *http.Client
+}
+
+// NewWebhookNotifier posts the message as json to the target url, only public addresses are dialed
+func NewWebhookNotifier() Notifier {
+	return &webhookNotifier{client: utils.PublicHttpClient(DefaultTimeout)}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, target usecase_models.NotificationTarget, message Message) error {
+	return postJson(ctx, n.client, target.Target, message)
+}
@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"strconv"
+	"test-manager/repos"
+	"test-manager/tasks/push"
+	"test-manager/tasks/task_models"
+	"test-manager/usecase_models"
+	"time"
+)
+
+const (
+	// HeaderSignature is "t=<unix timestamp>,v1=<hex hmac-sha256 of "<timestamp>.<body>" with the subscription secret>"
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Sign returns the signature header value of a body sent at the given time
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+type Dispatcher interface {
+	Dispatch(ctx context.Context, projectId int, eventType string, data interface{}) error
+}
+
+type dispatcher struct {
+	projectRepo    repos.ProjectsRepository
+	deliveriesRepo repos.WebhookDeliveriesRepository
+	taskPusher     push.TaskPusher
+}
+
+// NewDispatcher stores a delivery for every subscribed webhook of the project and queues it, sending happens in the webhook queue
+func NewDispatcher(projectRepo repos.ProjectsRepository, deliveriesRepo repos.WebhookDeliveriesRepository, taskPusher push.TaskPusher) Dispatcher {
+	return &dispatcher{
+		projectRepo:    projectRepo,
+		deliveriesRepo: deliveriesRepo,
+		taskPusher:     taskPusher,
+	}
+}
+
+func (d *dispatcher) Dispatch(ctx context.Context, projectId int, eventType string, data interface{}) error {
+	project, err := d.projectRepo.GetProject(ctx, projectId)
+	if err != nil {
+		return err
+	}
+	var notifications usecase_models.Notifications
+	err = json.Unmarshal(project.Notifications.JSON, &notifications)
+	if err != nil {
+		return err
+	}
+
+	eventId, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(usecase_models.WebhookEvent{
+		Id:        eventId.String(),
+		Version:   usecase_models.WebhookEventVersion,
+		Type:      eventType,
+		ProjectId: projectId,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range notifications.Webhooks {
+		if !subscription.Subscribed(eventType) {
+			continue
+		}
+		deliveryId, err := d.deliveriesRepo.SaveDelivery(ctx, usecase_models.WebhookDelivery{
+			ProjectId: projectId,
+			EventId:   eventId.String(),
+			EventType: eventType,
+			Url:       subscription.Url,
+			Payload:   string(payload),
+		})
+		if err != nil {
+			log.Error("error on saving webhook delivery: ", err)
+			continue
+		}
+		_, err = d.taskPusher.PushWebhookDelivery(ctx, task_models.WebhookDeliveryPayload{DeliveryId: deliveryId})
+		if err != nil {
+			log.Error("error on pushing webhook delivery: ", err)
+		}
+	}
+	return nil
+}
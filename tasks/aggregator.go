@@ -8,6 +8,22 @@ import (
 	"test-manager/tasks/task_models"
 )
 
+// AggregateStats routes each store group to the aggregator of its own stats type
+func AggregateStats(group string, tasks []*asynq.Task) *asynq.Task {
+	switch group {
+	case task_models.GroupAggregateNetCatStore:
+		return AggregateNetCatStats(group, tasks)
+	case task_models.GroupAggregatePageSpeedStore:
+		return AggregatePageSpeedStats(group, tasks)
+	case task_models.GroupAggregatePingStore:
+		return AggregatePingStats(group, tasks)
+	case task_models.GroupAggregateTraceRouteStore:
+		return AggregateTraceRouteStats(group, tasks)
+	default:
+		return AggregateEndpointStats(group, tasks)
+	}
+}
+
 func AggregateEndpointStats(group string, tasks []*asynq.Task) *asynq.Task {
 	log.Printf("Aggregating %d tasks from group %q", len(tasks), group)
 	var b = make([]repos.WriteEndpointStatsOptions, len(tasks))
@@ -17,3 +33,43 @@ func AggregateEndpointStats(group string, tasks []*asynq.Task) *asynq.Task {
 	t, _ := json.Marshal(b)
 	return asynq.NewTask(task_models.TypeEndpointStore, t)
 }
+
+func AggregateNetCatStats(group string, tasks []*asynq.Task) *asynq.Task {
+	log.Printf("Aggregating %d tasks from group %q", len(tasks), group)
+	var b = make([]repos.WriteNetCatStatsOptions, len(tasks))
+	for i, t := range tasks {
+		json.Unmarshal(t.Payload(), &b[i])
+	}
+	t, _ := json.Marshal(b)
+	return asynq.NewTask(task_models.TypeNetCatStore, t)
+}
+
+func AggregatePageSpeedStats(group string, tasks []*asynq.Task) *asynq.Task {
+	log.Printf("Aggregating %d tasks from group %q", len(tasks), group)
+	var b = make([]repos.WritePageSpeedStatsOptions, len(tasks))
+	for i, t := range tasks {
+		json.Unmarshal(t.Payload(), &b[i])
+	}
+	t, _ := json.Marshal(b)
+	return asynq.NewTask(task_models.TypePageSpeedStore, t)
+}
+
+func AggregatePingStats(group string, tasks []*asynq.Task) *asynq.Task {
+	log.Printf("Aggregating %d tasks from group %q", len(tasks), group)
+	var b = make([]repos.WritePingStatsOptions, len(tasks))
+	for i, t := range tasks {
+		json.Unmarshal(t.Payload(), &b[i])
+	}
+	t, _ := json.Marshal(b)
+	return asynq.NewTask(task_models.TypePingStore, t)
+}
+
+func AggregateTraceRouteStats(group string, tasks []*asynq.Task) *asynq.Task {
+	log.Printf("Aggregating %d tasks from group %q", len(tasks), group)
+	var b = make([]repos.WriteTraceRouteStatsOptions, len(tasks))
+	for i, t := range tasks {
+		json.Unmarshal(t.Payload(), &b[i])
+	}
+	t, _ := json.Marshal(b)
+	return asynq.NewTask(task_models.TypeTraceRouteStore, t)
+}
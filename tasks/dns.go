@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/labstack/gommon/log"
+	"go.uber.org/zap"
+	"test-manager/handlers"
+	"test-manager/usecase_models"
+)
+
+type DNSTaskHandler struct {
+	DNSHandler handlers.DNSHandler
+
+	Logger *zap.SugaredLogger
+}
+
+func NewDNSTaskHandler(
+	DNSHandler handlers.DNSHandler,
+	Logger *zap.SugaredLogger,
+) *DNSTaskHandler {
+	return &DNSTaskHandler{
+		DNSHandler: DNSHandler,
+		Logger:     Logger,
+	}
+}
+
+func (c *DNSTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload usecase_models.DNSMonitors
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed on dns task: %v: %w", err, asynq.SkipRetry)
+	}
+
+	err := c.DNSHandler.ExecuteDNSRule(ctx, payload)
+	if err != nil {
+		log.Errorf("executing rule on dns task: %v", err)
+	}
+
+	c.Logger.Info("success on processing dns task")
+	return nil
+}
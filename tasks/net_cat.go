@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	"github.com/labstack/gommon/log"
 	"go.uber.org/zap"
 	"test-manager/handlers"
+	"test-manager/repos"
 	"test-manager/usecase_models"
 )
 
@@ -16,28 +18,57 @@ type NetCatTaskHandler struct {
 	Logger *zap.SugaredLogger
 }
 
+func NewNetCatTaskHandler(
+	NetCatHandler handlers.NetCatHandler,
+	Logger *zap.SugaredLogger,
+) *NetCatTaskHandler {
+	return &NetCatTaskHandler{
+		NetCatHandler: NetCatHandler,
+		Logger:        Logger,
+	}
+}
+
 func (c *NetCatTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 	var payload usecase_models.NetCats
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
-		return fmt.Errorf("json.Unmarshal failed on NetCat task: %v: %w", err, asynq.SkipRetry)
+		return fmt.Errorf("json.Unmarshal failed on net cat task: %v: %w", err, asynq.SkipRetry)
 	}
 
 	err := c.NetCatHandler.ExecuteNetCatRule(ctx, payload)
 	if err != nil {
-		c.Logger.Info(err)
-		return fmt.Errorf("executing rule on NetCat task: %v", err)
+		log.Errorf("executing rule on net cat task: %v", err)
 	}
 
 	c.Logger.Info("success on processing net cat task")
 	return nil
 }
 
-func NewNetCatTaskHandler(
-	NetCatHandler handlers.NetCatHandler,
+type NetCatStoreHandler struct {
+	netCatStatsRepo repos.NetCatStatsRepository
+	Logger          *zap.SugaredLogger
+}
+
+func NewNetCatStoreHandler(
+	netCatStatsRepo repos.NetCatStatsRepository,
 	Logger *zap.SugaredLogger,
-) *NetCatTaskHandler {
-	return &NetCatTaskHandler{
-		NetCatHandler: NetCatHandler,
-		Logger:        Logger,
+) *NetCatStoreHandler {
+	return &NetCatStoreHandler{
+		netCatStatsRepo: netCatStatsRepo,
+		Logger:          Logger,
 	}
 }
+
+func (c *NetCatStoreHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload []repos.WriteNetCatStatsOptions
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed on net cat store: %v: %w", err, asynq.SkipRetry)
+	}
+
+	err := c.netCatStatsRepo.WriteBulk(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("problem on writing bulk net cat stat: %s", err.Error())
+	}
+
+	c.Logger.Info("success on processing net cat store task")
+	return nil
+}
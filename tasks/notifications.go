@@ -3,31 +3,42 @@ package tasks
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/hibiken/asynq"
 	"github.com/labstack/gommon/log"
-	"strconv"
+	"github.com/volatiletech/null/v8"
+	"strings"
 	"test-manager/monitoring"
 	"test-manager/repos"
-	"test-manager/services/alert_system"
+	"test-manager/services/notifier"
+	"test-manager/services/webhooks"
 	"test-manager/tasks/task_models"
 	"test-manager/usecase_models"
 	"text/template"
+	"time"
 )
 
 type NotificationTaskHandler struct {
-	alertSystem alert_system.AlertHandler
-	projectRepo repos.ProjectsRepository
+	notifiers         notifier.Notifiers
+	projectRepo       repos.ProjectsRepository
+	incidentsRepo     repos.IncidentsRepository
+	webhookDispatcher webhooks.Dispatcher
 }
 
 func NewNotificationTaskHandler(
-	alertSystem alert_system.AlertHandler,
+	notifiers notifier.Notifiers,
 	projectRepo repos.ProjectsRepository,
+	incidentsRepo repos.IncidentsRepository,
+	webhookDispatcher webhooks.Dispatcher,
 ) *NotificationTaskHandler {
 	return &NotificationTaskHandler{
-		alertSystem: alertSystem,
-		projectRepo: projectRepo,
+		notifiers:         notifiers,
+		projectRepo:       projectRepo,
+		incidentsRepo:     incidentsRepo,
+		webhookDispatcher: webhookDispatcher,
 	}
 }
 
@@ -49,18 +60,36 @@ func (c *NotificationTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task
 		return fmt.Errorf("json.Unmarshal failed on endpoint task notif json: %v: %w", err, asynq.SkipRetry)
 	}
 
+	incident, incidentEvent, err := c.trackIncident(ctx, payload)
+	if err != nil {
+		log.Error("problem on tracking incident: ", err)
+	}
+	c.dispatchWebhooks(ctx, payload, incident, incidentEvent)
+
+	// someone is already on it, only the recovery is worth sending
+	if incident.AcknowledgedAt.Valid && payload.State != "up" {
+		log.Infof("incident %d is acknowledged, skipping %s notification", incident.ID, payload.State)
+		return nil
+	}
+	incidentStartTime := payload.Time
+	incidentEndTime := payload.Time
+	incidentDuration := ""
+	if incident.ID != 0 {
+		incidentStartTime = incident.StartedAt.String()
+		if incident.ResolvedAt.Valid {
+			incidentEndTime = incident.ResolvedAt.Time.String()
+			incidentDuration = (time.Duration(incident.Duration.Int) * time.Second).String()
+		}
+	}
+
 	subject := payload.PipelineName
-	slackMessage := ""
-	telegramMessage := ""
-	emailMessage := ""
+	message := ""
 	emailKeyPairs := map[string]string{}
 	templateKey := ""
 	switch payload.State {
 	case "up":
 		subject = payload.PipelineName + " is UP"
-		slackMessage = fmt.Sprintf("Hi %s,\n%s (%s) is up again\nresolved address:%s\n\nDatacenters envolved: %s\n\nFixed at %s",
-			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.Datacenters, payload.Time)
-		telegramMessage = fmt.Sprintf("Hi %s,\n%s (%s) is up again\nresolved address:%s\n\nDatacenters envolved: %s\n\nFixed at %s",
+		message = fmt.Sprintf("Hi %s,\n%s (%s) is up again\nresolved address:%s\n\nDatacenters envolved: %s\n\nFixed at %s",
 			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.Datacenters, payload.Time)
 		//emailMessage, _ = ParseTemplate("resolved.html", TemplateKeys{
 		//	Username:     payload.Username,
@@ -75,17 +104,14 @@ func (c *NotificationTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task
 			"address":              payload.Address,
 			"root_cause":           payload.RootCause,
 			"datacenters_resolved": payload.Datacenters,
-			"incident_start_time":  payload.Time,
-			"incident_end_time":    payload.Time,
-			"incident_duration":    payload.Time,
+			"incident_start_time":  incidentStartTime,
+			"incident_end_time":    incidentEndTime,
+			"incident_duration":    incidentDuration,
 		}
 		templateKey = "resolved"
-		emailMessage = telegramMessage
 	case "down":
 		subject = payload.PipelineName + " is DOWN"
-		slackMessage = fmt.Sprintf("Hi %s,\n%s (%s) is DOWN\nchecked address:%s\n\nDatacenters envolved: %s\n\nIncident at %s",
-			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.Datacenters, payload.Time)
-		telegramMessage = fmt.Sprintf("Hi %s,\n%s (%s) is DOWN\nchecked address:%s\n\nDatacenters envolved: %s\n\nIncident at %s",
+		message = fmt.Sprintf("Hi %s,\n%s (%s) is DOWN\nchecked address:%s\n\nDatacenters envolved: %s\n\nIncident at %s",
 			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.Datacenters, payload.Time)
 		//emailMessage, _ = ParseTemplate("detected.html", TemplateKeys{
 		//	Username:     payload.Username,
@@ -100,15 +126,12 @@ func (c *NotificationTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task
 			"address":             payload.Address,
 			"root_cause":          payload.RootCause,
 			"datacenters_failed":  payload.Datacenters,
-			"incident_start_time": payload.Time,
+			"incident_start_time": incidentStartTime,
 		}
 		templateKey = "problem_detected"
-		emailMessage = telegramMessage
 	case "diff":
 		subject = payload.PipelineName + " is DOWN update"
-		slackMessage = fmt.Sprintf("Hi %s,\n%s (%s) is DOWN update\nchecked address:%s\n\nDatacenters fixed: %s\nNew Datacenters failed:%s\n\nUpdated at %s",
-			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.ResolvedDatacenters, payload.FailedDatacenters, payload.Time)
-		telegramMessage = fmt.Sprintf("Hi %s,\n%s (%s) is DOWN update\nchecked address:%s\n\nDatacenters fixed: %s\nNew Datacenters failed:%s\n\nUpdated at %s",
+		message = fmt.Sprintf("Hi %s,\n%s (%s) is DOWN update\nchecked address:%s\n\nDatacenters fixed: %s\nNew Datacenters failed:%s\n\nUpdated at %s",
 			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.ResolvedDatacenters, payload.FailedDatacenters, payload.Time)
 		//emailMessage, _ = ParseTemplate("detected_update.html", TemplateKeys{
 		//	Username:            payload.Username,
@@ -126,61 +149,161 @@ func (c *NotificationTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task
 			"root_cause":           payload.RootCause,
 			"datacenters_resolved": payload.ResolvedDatacenters,
 			"datacenters_failed":   payload.FailedDatacenters,
-			"incident_start_time":  payload.Time,
+			"incident_start_time":  incidentStartTime,
 		}
 		templateKey = "problem_detected_update"
-		emailMessage = telegramMessage
+	case usecase_models.TLSNotificationStateExpiring:
+		subject = payload.PipelineName + " certificate is expiring"
+		message = fmt.Sprintf("Hi %s,\n%s (%s) certificate is expiring\nchecked address:%s\n\n%s\n\nDatacenters envolved: %s\n\nChecked at %s",
+			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.RootCause, payload.Datacenters, payload.Time)
+		emailKeyPairs = map[string]string{
+			"object_name": payload.PipelineName,
+			"address":     payload.Address,
+			"root_cause":  payload.RootCause,
+			"datacenters": payload.Datacenters,
+		}
+		templateKey = "certificate_expiring"
+	case usecase_models.DNSNotificationStateChanged:
+		subject = payload.PipelineName + " dns answers changed"
+		message = fmt.Sprintf("Hi %s,\n%s (%s) dns answers changed\nchecked address:%s\n\n%s\n\nDatacenters envolved: %s\n\nChecked at %s",
+			payload.Username, payload.PipelineName, payload.Type, payload.Address, payload.RootCause, payload.Datacenters, payload.Time)
+		emailKeyPairs = map[string]string{
+			"object_name": payload.PipelineName,
+			"address":     payload.Address,
+			"root_cause":  payload.RootCause,
+			"datacenters": payload.Datacenters,
+		}
+		templateKey = "dns_changed"
 	}
 
 	monitoring.NotificationTaskCounter.WithLabelValues(payload.State).Inc()
 
-	if len(notifications.Slack) != 0 {
-		err = c.alertSystem.SendAlert(ctx, alert_system.AlertRequest{
-			AlertType:        "slack",
-			UserId:           strconv.Itoa(payload.ProjectId),
-			Targets:          notifications.Slack,
-			Subject:          subject,
-			Message:          slackMessage,
-			IsTemplate:       true,
-			Template:         "",
-			TemplateKeyPairs: nil,
-			AdditionalData:   nil,
-		})
-		if err != nil {
-			log.Info("error on sending slack alert in executing rule: ", err)
-		}
+	dedupKey := fmt.Sprintf("test-manager-%s-%d", payload.Type, payload.PipelineId)
+	// a warning is its own alert, it must not be merged into or resolved with an outage
+	if usecase_models.IsWarningState(payload.State) {
+		dedupKey += "-" + payload.State
 	}
-	if len(notifications.Email) != 0 {
-		err = c.alertSystem.SendAlert(ctx, alert_system.AlertRequest{
-			AlertType:        "email",
-			UserId:           strconv.Itoa(payload.ProjectId),
-			Targets:          notifications.Email,
+
+	for _, target := range notifications.AllTargets() {
+		err = c.notifiers.Notify(ctx, target, notifier.Message{
+			ProjectId:        payload.ProjectId,
+			State:            payload.State,
 			Subject:          subject,
-			Message:          emailMessage,
-			IsTemplate:       true,
+			Text:             message,
+			DedupKey:         dedupKey,
+			Source:           payload.Address,
+			Component:        payload.PipelineName,
 			Template:         templateKey,
 			TemplateKeyPairs: emailKeyPairs,
-			AdditionalData:   nil,
 		})
 		if err != nil {
-			log.Info("error on sending email alert in executing rule: ", err)
+			log.Infof("error on sending %s alert with %s in executing rule: %s", target.Channel, target.Driver, err)
 		}
 	}
-	if len(notifications.Telegram) != 0 {
-		err = c.alertSystem.SendAlert(ctx, alert_system.AlertRequest{
-			AlertType: "telegram",
-			Subject:   subject,
-			Message:   telegramMessage,
-			UserId:    strconv.Itoa(payload.ProjectId),
-			Targets:   notifications.Telegram,
-		})
+
+	fmt.Println("success on processing notification task")
+	return nil
+}
+
+// trackIncident opens an incident on down, appends the transition to its timeline and closes it on up
+func (c *NotificationTaskHandler) trackIncident(ctx context.Context, payload task_models.NotificationsPayload) (usecase_models.Incident, usecase_models.IncidentEvent, error) {
+	now := time.Now()
+	incident, err := c.incidentsRepo.GetOpenIncident(ctx, payload.Type, payload.PipelineId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return usecase_models.Incident{}, usecase_models.IncidentEvent{}, err
+	}
+
+	switch payload.State {
+	case usecase_models.IncidentEventDown, usecase_models.IncidentEventDiff:
+		if incident.ID == 0 {
+			incident = usecase_models.Incident{
+				ProjectId:    payload.ProjectId,
+				PipelineType: payload.Type,
+				PipelineId:   payload.PipelineId,
+				PipelineName: null.StringFrom(payload.PipelineName),
+				Status:       usecase_models.IncidentStatusOpen,
+				Address:      null.StringFrom(payload.Address),
+				RootCause:    null.StringFrom(payload.RootCause),
+				Datacenters:  null.StringFrom(payload.Datacenters),
+				StartedAt:    now,
+			}
+			incident.ID, err = c.incidentsRepo.OpenIncident(ctx, incident)
+			if err != nil {
+				return usecase_models.Incident{}, usecase_models.IncidentEvent{}, err
+			}
+		}
+	case usecase_models.IncidentEventUp:
+		if incident.ID == 0 {
+			return incident, usecase_models.IncidentEvent{}, nil
+		}
+		incident, err = c.incidentsRepo.ResolveIncident(ctx, incident.ID, now, null.Int{})
 		if err != nil {
-			log.Info("error on sending telegram alert in executing rule: ", err)
+			return usecase_models.Incident{}, usecase_models.IncidentEvent{}, err
 		}
+	default:
+		return incident, usecase_models.IncidentEvent{}, nil
 	}
 
-	fmt.Println("success on processing notification task")
-	return nil
+	event := usecase_models.IncidentEvent{
+		IncidentId:          incident.ID,
+		EventType:           payload.State,
+		RootCause:           null.StringFrom(payload.RootCause),
+		Datacenters:         null.StringFrom(payload.Datacenters),
+		ResolvedDatacenters: null.StringFrom(payload.ResolvedDatacenters),
+		FailedDatacenters:   null.StringFrom(payload.FailedDatacenters),
+		CreatedAt:           now,
+	}
+	event.ID, err = c.incidentsRepo.SaveIncidentEvent(ctx, event)
+	return incident, event, err
+}
+
+// dispatchWebhooks sends the transition as a monitor event and the incident change as incident.updated
+func (c *NotificationTaskHandler) dispatchWebhooks(ctx context.Context, payload task_models.NotificationsPayload,
+	incident usecase_models.Incident, incidentEvent usecase_models.IncidentEvent) {
+	eventType := ""
+	switch payload.State {
+	case "down":
+		eventType = usecase_models.WebhookEventMonitorDown
+	case "up":
+		eventType = usecase_models.WebhookEventMonitorUp
+	case "diff":
+		eventType = usecase_models.WebhookEventMonitorDegraded
+	default:
+		return
+	}
+
+	err := c.webhookDispatcher.Dispatch(ctx, payload.ProjectId, eventType, usecase_models.MonitorEventData{
+		PipelineType:        payload.Type,
+		PipelineId:          payload.PipelineId,
+		PipelineName:        payload.PipelineName,
+		Address:             payload.Address,
+		RootCause:           payload.RootCause,
+		Datacenters:         splitDatacenters(payload.Datacenters),
+		FailedDatacenters:   splitDatacenters(payload.FailedDatacenters),
+		ResolvedDatacenters: splitDatacenters(payload.ResolvedDatacenters),
+		IncidentId:          incident.ID,
+	})
+	if err != nil {
+		log.Error("problem on dispatching monitor webhook: ", err)
+	}
+
+	if incidentEvent.ID == 0 {
+		return
+	}
+	err = c.webhookDispatcher.Dispatch(ctx, payload.ProjectId, usecase_models.WebhookEventIncidentUpdated, usecase_models.IncidentEventData{
+		Incident: incident,
+		Event:    incidentEvent,
+	})
+	if err != nil {
+		log.Error("problem on dispatching incident webhook: ", err)
+	}
+}
+
+func splitDatacenters(datacenters string) []string {
+	if datacenters == "" {
+		return []string{}
+	}
+	return strings.Split(datacenters, ",")
 }
 
 func ParseTemplate(templateFileName string, data interface{}) (string, error) {
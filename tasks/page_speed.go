@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	"github.com/labstack/gommon/log"
 	"go.uber.org/zap"
 	"test-manager/handlers"
+	"test-manager/repos"
 	"test-manager/usecase_models"
 )
 
@@ -16,28 +18,57 @@ type PageSpeedTaskHandler struct {
 	Logger *zap.SugaredLogger
 }
 
+func NewPageSpeedTaskHandler(
+	PageSpeedHandler handlers.PageSpeedHandler,
+	Logger *zap.SugaredLogger,
+) *PageSpeedTaskHandler {
+	return &PageSpeedTaskHandler{
+		PageSpeedHandler: PageSpeedHandler,
+		Logger:           Logger,
+	}
+}
+
 func (c *PageSpeedTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 	var payload usecase_models.PageSpeeds
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
-		return fmt.Errorf("json.Unmarshal failed on PageSpeeds task: %v: %w", err, asynq.SkipRetry)
+		return fmt.Errorf("json.Unmarshal failed on page speed task: %v: %w", err, asynq.SkipRetry)
 	}
 
 	err := c.PageSpeedHandler.ExecutePageSpeedRule(ctx, payload)
 	if err != nil {
-		c.Logger.Info(err)
-		return fmt.Errorf("executing rule on PageSpeeds task: %v", err)
+		log.Errorf("executing rule on page speed task: %v", err)
 	}
 
 	c.Logger.Info("success on processing page speed task")
 	return nil
 }
 
-func NewPageSpeedTaskHandler(
-	PageSpeedHandler handlers.PageSpeedHandler,
+type PageSpeedStoreHandler struct {
+	pageSpeedStatsRepo repos.PageSpeedStatsRepository
+	Logger             *zap.SugaredLogger
+}
+
+func NewPageSpeedStoreHandler(
+	pageSpeedStatsRepo repos.PageSpeedStatsRepository,
 	Logger *zap.SugaredLogger,
-) *PageSpeedTaskHandler {
-	return &PageSpeedTaskHandler{
-		PageSpeedHandler: PageSpeedHandler,
-		Logger:           Logger,
+) *PageSpeedStoreHandler {
+	return &PageSpeedStoreHandler{
+		pageSpeedStatsRepo: pageSpeedStatsRepo,
+		Logger:             Logger,
 	}
 }
+
+func (c *PageSpeedStoreHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload []repos.WritePageSpeedStatsOptions
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed on page speed store: %v: %w", err, asynq.SkipRetry)
+	}
+
+	err := c.pageSpeedStatsRepo.WriteBulk(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("problem on writing bulk page speed stat: %s", err.Error())
+	}
+
+	c.Logger.Info("success on processing page speed store task")
+	return nil
+}
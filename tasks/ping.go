@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	"github.com/labstack/gommon/log"
 	"go.uber.org/zap"
 	"test-manager/handlers"
+	"test-manager/repos"
 	"test-manager/usecase_models"
 )
 
@@ -16,28 +18,57 @@ type PingTaskHandler struct {
 	Logger *zap.SugaredLogger
 }
 
+func NewPingTaskHandler(
+	PingHandler handlers.PingHandler,
+	Logger *zap.SugaredLogger,
+) *PingTaskHandler {
+	return &PingTaskHandler{
+		PingHandler: PingHandler,
+		Logger:      Logger,
+	}
+}
+
 func (c *PingTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 	var payload usecase_models.Pings
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
-		return fmt.Errorf("json.Unmarshal failed on Ping task: %v: %w", err, asynq.SkipRetry)
+		return fmt.Errorf("json.Unmarshal failed on ping task: %v: %w", err, asynq.SkipRetry)
 	}
 
 	err := c.PingHandler.ExecutePingRule(ctx, payload)
 	if err != nil {
-		c.Logger.Info(err)
-		return fmt.Errorf("executing rule on Ping task: %v", err)
+		log.Errorf("executing rule on ping task: %v", err)
 	}
 
 	c.Logger.Info("success on processing ping task")
 	return nil
 }
 
-func NewPingTaskHandler(
-	PingHandler handlers.PingHandler,
+type PingStoreHandler struct {
+	pingStatsRepo repos.PingStatsRepository
+	Logger        *zap.SugaredLogger
+}
+
+func NewPingStoreHandler(
+	pingStatsRepo repos.PingStatsRepository,
 	Logger *zap.SugaredLogger,
-) *PingTaskHandler {
-	return &PingTaskHandler{
-		PingHandler: PingHandler,
-		Logger:      Logger,
+) *PingStoreHandler {
+	return &PingStoreHandler{
+		pingStatsRepo: pingStatsRepo,
+		Logger:        Logger,
 	}
 }
+
+func (c *PingStoreHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload []repos.WritePingStatsOptions
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed on ping store: %v: %w", err, asynq.SkipRetry)
+	}
+
+	err := c.pingStatsRepo.WriteBulk(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("problem on writing bulk ping stat: %s", err.Error())
+	}
+
+	c.Logger.Info("success on processing ping store task")
+	return nil
+}
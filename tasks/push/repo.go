@@ -16,8 +16,22 @@ type TaskPusher interface {
 	PushNotifications(ctx context.Context, payload task_models.NotificationsPayload) (taskId string, err error)
 	PushEndpoint(ctx context.Context, payload usecase_models.Endpoints) (taskId string, err error)
 	PushEndpointStore(ctx context.Context, payload repos.WriteEndpointStatsOptions) (taskId string, err error)
+	PushNetCat(ctx context.Context, payload usecase_models.NetCats) (taskId string, err error)
+	PushNetCatStore(ctx context.Context, payload repos.WriteNetCatStatsOptions) (taskId string, err error)
+	PushPageSpeed(ctx context.Context, payload usecase_models.PageSpeeds) (taskId string, err error)
+	PushPageSpeedStore(ctx context.Context, payload repos.WritePageSpeedStatsOptions) (taskId string, err error)
+	PushPing(ctx context.Context, payload usecase_models.Pings) (taskId string, err error)
+	PushPingStore(ctx context.Context, payload repos.WritePingStatsOptions) (taskId string, err error)
+	PushTraceRoute(ctx context.Context, payload usecase_models.TraceRoutes) (taskId string, err error)
+	PushTraceRouteStore(ctx context.Context, payload repos.WriteTraceRouteStatsOptions) (taskId string, err error)
+	PushTLS(ctx context.Context, payload usecase_models.TLSMonitors) (taskId string, err error)
+	PushDNS(ctx context.Context, payload usecase_models.DNSMonitors) (taskId string, err error)
+	PushWebhookDelivery(ctx context.Context, payload task_models.WebhookDeliveryPayload) (taskId string, err error)
 }
 
+// WebhookMaxRetry with the exponential delay of webhook tasks gives up after about a day
+const WebhookMaxRetry = 12
+
 type taskPush struct {
 	taskClient *asynq.Client
 }
@@ -211,3 +225,212 @@ func (t *taskPush) PushEndpointStore(ctx context.Context, payload repos.WriteEnd
 	}
 	return ti.ID, nil
 }
+
+func (t *taskPush) PushNetCat(ctx context.Context, payload usecase_models.NetCats) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeNetCats, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueueNetCats),
+		asynq.Retention(60*time.Second),
+	)
+	if err != nil {
+		log.Println("error at enqueue net cat task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushNetCatStore(ctx context.Context, payload repos.WriteNetCatStatsOptions) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeAggregateNetCatStore, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueueNetCatStore),
+		asynq.Group(task_models.GroupAggregateNetCatStore),
+	)
+	if err != nil {
+		log.Println("error at enqueue aggregate net cat store task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushPageSpeed(ctx context.Context, payload usecase_models.PageSpeeds) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypePageSpeeds, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueuePageSpeeds),
+		asynq.Retention(60*time.Second),
+	)
+	if err != nil {
+		log.Println("error at enqueue page speed task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushPageSpeedStore(ctx context.Context, payload repos.WritePageSpeedStatsOptions) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeAggregatePageSpeedStore, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueuePageSpeedStore),
+		asynq.Group(task_models.GroupAggregatePageSpeedStore),
+	)
+	if err != nil {
+		log.Println("error at enqueue aggregate page speed store task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushPing(ctx context.Context, payload usecase_models.Pings) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypePings, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueuePings),
+		asynq.Retention(60*time.Second),
+	)
+	if err != nil {
+		log.Println("error at enqueue ping task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushPingStore(ctx context.Context, payload repos.WritePingStatsOptions) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeAggregatePingStore, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueuePingStore),
+		asynq.Group(task_models.GroupAggregatePingStore),
+	)
+	if err != nil {
+		log.Println("error at enqueue aggregate ping store task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushTLS(ctx context.Context, payload usecase_models.TLSMonitors) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeTLS, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueueTLS),
+		asynq.Retention(60*time.Second),
+	)
+	if err != nil {
+		log.Println("error at enqueue tls task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushDNS(ctx context.Context, payload usecase_models.DNSMonitors) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeDNS, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueueDNS),
+		asynq.Retention(60*time.Second),
+	)
+	if err != nil {
+		log.Println("error at enqueue dns task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushTraceRoute(ctx context.Context, payload usecase_models.TraceRoutes) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeTraceRoutes, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueueTraceRoutes),
+		asynq.Retention(60*time.Second),
+	)
+	if err != nil {
+		log.Println("error at enqueue trace route task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushTraceRouteStore(ctx context.Context, payload repos.WriteTraceRouteStatsOptions) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeAggregateTraceRouteStore, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueueTraceRouteStore),
+		asynq.Group(task_models.GroupAggregateTraceRouteStore),
+	)
+	if err != nil {
+		log.Println("error at enqueue aggregate trace route store task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
+
+func (t *taskPush) PushWebhookDelivery(ctx context.Context, payload task_models.WebhookDeliveryPayload) (taskId string, err error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	task := asynq.NewTask(task_models.TypeWebhookDelivery, payloadBytes)
+	ti, err := t.taskClient.EnqueueContext(ctx,
+		task,
+		asynq.Queue(task_models.QueueWebhook),
+		asynq.MaxRetry(WebhookMaxRetry),
+	)
+	if err != nil {
+		log.Println("error at enqueue webhook delivery task: ", err)
+		return "", err
+	}
+	return ti.ID, nil
+}
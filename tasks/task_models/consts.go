@@ -3,28 +3,48 @@ package task_models
 type Type string
 
 const (
-	TypeEndpoint               = "end:point"
-	TypeNotification           = "notification"
-	TypeEndpointStore          = "endpoint_store"
-	TypeAggregateEndpointStore = "aggregate_endpoint_store"
+	TypeEndpoint                 = "end:point"
+	TypeNetCats                  = "net:cats"
+	TypePageSpeeds               = "page:speeds"
+	TypePings                    = "ping:s"
+	TypeTraceRoutes              = "trace:routes"
+	TypeTLS                      = "tls:monitors"
+	TypeDNS                      = "dns:monitors"
+	TypeNotification             = "notification"
+	TypeEndpointStore            = "endpoint_store"
+	TypeNetCatStore              = "net_cat_store"
+	TypePageSpeedStore           = "page_speed_store"
+	TypePingStore                = "ping_store"
+	TypeTraceRouteStore          = "trace_route_store"
+	TypeAggregateEndpointStore   = "aggregate_endpoint_store"
+	TypeAggregateNetCatStore     = "aggregate_net_cat_store"
+	TypeAggregatePageSpeedStore  = "aggregate_page_speed_store"
+	TypeAggregatePingStore       = "aggregate_ping_store"
+	TypeAggregateTraceRouteStore = "aggregate_trace_route_store"
+	TypeWebhookDelivery          = "webhook_delivery"
 )
 
-//TypeNetCats                = "net:cats"
-//TypePageSpeeds             = "page:speeds"
-//TypePings                  = "ping:s"
-//TypeTraceRoutes            = "trace:routes"
-
 const (
-	QueueEndpoint      = "endpoint"
-	QueueNotification  = "notification"
-	QueueEndpointStore = "endpoint_store"
+	QueueEndpoint        = "endpoint"
+	QueueNetCats         = "net_cats"
+	QueuePageSpeeds      = "page_speeds"
+	QueuePings           = "pings"
+	QueueTraceRoutes     = "trace_routes"
+	QueueTLS             = "tls"
+	QueueDNS             = "dns"
+	QueueNotification    = "notification"
+	QueueEndpointStore   = "endpoint_store"
+	QueueNetCatStore     = "net_cat_store"
+	QueuePageSpeedStore  = "page_speed_store"
+	QueuePingStore       = "ping_store"
+	QueueTraceRouteStore = "trace_route_store"
+	QueueWebhook         = "webhook"
 )
 
-//QueueNetCats                = "net_cats"
-//QueuePageSpeeds             = "page_speeds"
-//QueuePings                  = "pings"
-//QueueTraceRoutes            = "trace_routes"
-
 const (
-	GroupAggregateEndpointStore = "group_aggregate_endpoint_store"
+	GroupAggregateEndpointStore   = "group_aggregate_endpoint_store"
+	GroupAggregateNetCatStore     = "group_aggregate_net_cat_store"
+	GroupAggregatePageSpeedStore  = "group_aggregate_page_speed_store"
+	GroupAggregatePingStore       = "group_aggregate_ping_store"
+	GroupAggregateTraceRouteStore = "group_aggregate_trace_route_store"
 )
@@ -4,6 +4,7 @@ type NotificationsPayload struct {
 	Type                string `json:"type"`
 	State               string `json:"state"`
 	ProjectId           int    `json:"project_id"`
+	PipelineId          int    `json:"pipeline_id"`
 	Username            string `json:"username"`
 	Address             string `json:"address"`
 	PipelineName        string `json:"pipeline_name"`
@@ -13,3 +14,7 @@ type NotificationsPayload struct {
 	ResolvedDatacenters string `json:"resolved_datacenters"`
 	FailedDatacenters   string `json:"failed_datacenters"`
 }
+
+type WebhookDeliveryPayload struct {
+	DeliveryId int `json:"delivery_id"`
+}
@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/labstack/gommon/log"
+	"go.uber.org/zap"
+	"test-manager/handlers"
+	"test-manager/usecase_models"
+)
+
+type TLSTaskHandler struct {
+	TLSHandler handlers.TLSHandler
+
+	Logger *zap.SugaredLogger
+}
+
+func NewTLSTaskHandler(
+	TLSHandler handlers.TLSHandler,
+	Logger *zap.SugaredLogger,
+) *TLSTaskHandler {
+	return &TLSTaskHandler{
+		TLSHandler: TLSHandler,
+		Logger:     Logger,
+	}
+}
+
+func (c *TLSTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload usecase_models.TLSMonitors
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed on tls task: %v: %w", err, asynq.SkipRetry)
+	}
+
+	err := c.TLSHandler.ExecuteTLSRule(ctx, payload)
+	if err != nil {
+		log.Errorf("executing rule on tls task: %v", err)
+	}
+
+	c.Logger.Info("success on processing tls task")
+	return nil
+}
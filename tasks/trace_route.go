@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	"github.com/labstack/gommon/log"
 	"go.uber.org/zap"
 	"test-manager/handlers"
+	"test-manager/repos"
 	"test-manager/usecase_models"
 )
 
@@ -16,28 +18,57 @@ type TraceRouteTaskHandler struct {
 	Logger *zap.SugaredLogger
 }
 
+func NewTraceRouteTaskHandler(
+	TraceRouteHandler handlers.TraceRouteHandler,
+	Logger *zap.SugaredLogger,
+) *TraceRouteTaskHandler {
+	return &TraceRouteTaskHandler{
+		TraceRouteHandler: TraceRouteHandler,
+		Logger:            Logger,
+	}
+}
+
 func (c *TraceRouteTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 	var payload usecase_models.TraceRoutes
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
-		return fmt.Errorf("json.Unmarshal failed on TraceRoute task: %v: %w", err, asynq.SkipRetry)
+		return fmt.Errorf("json.Unmarshal failed on trace route task: %v: %w", err, asynq.SkipRetry)
 	}
 
 	err := c.TraceRouteHandler.ExecuteTraceRouteRule(ctx, payload)
 	if err != nil {
-		c.Logger.Info(err)
-		return fmt.Errorf("executing rule on TraceRoute task: %v", err)
+		log.Errorf("executing rule on trace route task: %v", err)
 	}
 
 	c.Logger.Info("success on processing trace route task")
 	return nil
 }
 
-func NewTraceRouteTaskHandler(
-	TraceRouteHandler handlers.TraceRouteHandler,
+type TraceRouteStoreHandler struct {
+	traceRouteStatsRepo repos.TraceRouteStatsRepository
+	Logger              *zap.SugaredLogger
+}
+
+func NewTraceRouteStoreHandler(
+	traceRouteStatsRepo repos.TraceRouteStatsRepository,
 	Logger *zap.SugaredLogger,
-) *TraceRouteTaskHandler {
-	return &TraceRouteTaskHandler{
-		TraceRouteHandler: TraceRouteHandler,
-		Logger:            Logger,
+) *TraceRouteStoreHandler {
+	return &TraceRouteStoreHandler{
+		traceRouteStatsRepo: traceRouteStatsRepo,
+		Logger:              Logger,
 	}
 }
+
+func (c *TraceRouteStoreHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload []repos.WriteTraceRouteStatsOptions
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed on trace route store: %v: %w", err, asynq.SkipRetry)
+	}
+
+	err := c.traceRouteStatsRepo.WriteBulk(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("problem on writing bulk trace route stat: %s", err.Error())
+	}
+
+	c.Logger.Info("success on processing trace route store task")
+	return nil
+}
@@ -0,0 +1,139 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/volatiletech/null/v8"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"strconv"
+	"test-manager/repos"
+	"test-manager/services/webhooks"
+	"test-manager/tasks/task_models"
+	"test-manager/usecase_models"
+	"test-manager/utils"
+	"time"
+)
+
+const webhookTimeout = 10 * time.Second
+
+type WebhookTaskHandler struct {
+	projectRepo    repos.ProjectsRepository
+	deliveriesRepo repos.WebhookDeliveriesRepository
+	client         *http.Client
+	Logger         *zap.SugaredLogger
+}
+
+func NewWebhookTaskHandler(
+	projectRepo repos.ProjectsRepository,
+	deliveriesRepo repos.WebhookDeliveriesRepository,
+	Logger *zap.SugaredLogger,
+) *WebhookTaskHandler {
+	return &WebhookTaskHandler{
+		projectRepo:    projectRepo,
+		deliveriesRepo: deliveriesRepo,
+		client:         utils.PublicHttpClient(webhookTimeout),
+		Logger:         Logger,
+	}
+}
+
+// WebhookRetryDelay backs off exponentially from 10 seconds up to 2 hours between webhook attempts
+func WebhookRetryDelay(n int, e error, t *asynq.Task) time.Duration {
+	if t.Type() != task_models.TypeWebhookDelivery {
+		return asynq.DefaultRetryDelayFunc(n, e, t)
+	}
+	delay := time.Duration(math.Pow(2, float64(n))) * 10 * time.Second
+	if delay > 2*time.Hour {
+		delay = 2 * time.Hour
+	}
+	return delay
+}
+
+func (c *WebhookTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload task_models.WebhookDeliveryPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed on webhook delivery task: %v: %w", err, asynq.SkipRetry)
+	}
+
+	delivery, err := c.deliveriesRepo.GetDelivery(ctx, payload.DeliveryId)
+	if err != nil {
+		return err
+	}
+
+	// the secret is read on every attempt so a rotated secret or a removed webhook applies to pending retries
+	subscription, ok, err := c.getSubscription(ctx, delivery)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		_ = c.deliveriesRepo.SaveAttempt(ctx, delivery.ID, usecase_models.WebhookDeliveryFailed, null.Int{}, null.StringFrom("webhook is removed"))
+		return fmt.Errorf("webhook of delivery %d is removed: %w", delivery.ID, asynq.SkipRetry)
+	}
+
+	responseStatus, err := c.send(ctx, subscription, delivery)
+	if err == nil {
+		err = c.deliveriesRepo.SaveAttempt(ctx, delivery.ID, usecase_models.WebhookDeliveryDelivered, responseStatus, null.String{})
+		if err != nil {
+			c.Logger.Error("error on saving webhook attempt: ", err)
+		}
+		c.Logger.Info("success on processing webhook delivery task")
+		return nil
+	}
+
+	status := usecase_models.WebhookDeliveryPending
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if retried >= maxRetry || errors.Is(err, asynq.SkipRetry) {
+		status = usecase_models.WebhookDeliveryFailed
+	}
+	if saveErr := c.deliveriesRepo.SaveAttempt(ctx, delivery.ID, status, responseStatus, null.StringFrom(err.Error())); saveErr != nil {
+		c.Logger.Error("error on saving webhook attempt: ", saveErr)
+	}
+	return err
+}
+
+func (c *WebhookTaskHandler) getSubscription(ctx context.Context, delivery usecase_models.WebhookDelivery) (usecase_models.WebhookSubscription, bool, error) {
+	project, err := c.projectRepo.GetProject(ctx, delivery.ProjectId)
+	if err != nil {
+		return usecase_models.WebhookSubscription{}, false, err
+	}
+	var notifications usecase_models.Notifications
+	err = json.Unmarshal(project.Notifications.JSON, &notifications)
+	if err != nil {
+		return usecase_models.WebhookSubscription{}, false, fmt.Errorf("json.Unmarshal failed on project notifications: %v: %w", err, asynq.SkipRetry)
+	}
+	for _, subscription := range notifications.Webhooks {
+		if subscription.Url == delivery.Url {
+			return subscription, true, nil
+		}
+	}
+	return usecase_models.WebhookSubscription{}, false, nil
+}
+
+func (c *WebhookTaskHandler) send(ctx context.Context, subscription usecase_models.WebhookSubscription, delivery usecase_models.WebhookDelivery) (null.Int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", subscription.Url, bytes.NewBuffer(body))
+	if err != nil {
+		return null.Int{}, fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooks.HeaderEvent, delivery.EventType)
+	req.Header.Set(webhooks.HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(webhooks.HeaderSignature, webhooks.Sign(subscription.Secret, time.Now(), body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return null.Int{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return null.IntFrom(resp.StatusCode), errors.New("webhook responded with status " + strconv.Itoa(resp.StatusCode))
+	}
+	return null.IntFrom(resp.StatusCode), nil
+}
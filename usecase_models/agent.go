@@ -1,5 +1,10 @@
 package usecase_models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type AgentCurlRequest struct {
 	Url    string              `json:"url"`
 	Method string              `json:"method"`
@@ -71,3 +76,65 @@ type AgentTraceRouteResponse struct {
 	Hop     []string `json:"hop"`
 	Message string   `json:"message"`
 }
+
+type AgentTLSRequest struct {
+	Address    string `json:"address"`
+	Port       int    `json:"port"`
+	ServerName string `json:"server_name"`
+	TimeOut    int    `json:"time_out"`
+}
+
+// AgentTLSCertificate is one certificate of the chain sent by the server, the leaf comes first
+type AgentTLSCertificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SANs         []string  `json:"sans"`
+	SerialNumber string    `json:"serial_number"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// AgentTLSResponse is the handshake seen by the agent, the chain is reported even when it does not verify
+type AgentTLSResponse struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	Statistics struct {
+		Chain         []AgentTLSCertificate `json:"chain"`
+		HostnameValid bool                  `json:"hostname_valid"`
+		ChainTrusted  bool                  `json:"chain_trusted"`
+		VerifyError   string                `json:"verify_error"`
+	} `json:"statistics"`
+}
+
+type AgentDNSRequest struct {
+	Name       string `json:"name"`
+	RecordType string `json:"record_type"`
+	// Resolver is a host:port of the nameserver to ask, empty uses the resolver of the agent
+	Resolver string `json:"resolver"`
+	TimeOut  int    `json:"time_out"`
+}
+
+type AgentDNSResponse struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	Statistics struct {
+		Answers        []string `json:"answers"`
+		ResolutionTime float64  `json:"resolution_time"`
+		Resolver       string   `json:"resolver"`
+	} `json:"statistics"`
+}
+
+// AgentJob is a check handed to a pull agent, Path is the endpoint a push agent would serve it on
+// and Request the body it would be sent
+type AgentJob struct {
+	JobId    string          `json:"job_id"`
+	Path     string          `json:"path"`
+	Request  json.RawMessage `json:"request"`
+	Deadline time.Time       `json:"deadline"`
+}
+
+// AgentJobResult is what a pull agent posts back, Response is the body a push agent would answer with
+type AgentJobResult struct {
+	Response json.RawMessage `json:"response"`
+	Error    string          `json:"error"`
+}
@@ -6,4 +6,8 @@ type AggregateAllRuleSubWorks struct {
 	NetCats     []*NetCats     `json:"net_cats"`
 	Pings       []*Pings       `json:"pings"`
 	PageSpeed   []*PageSpeeds  `json:"page_speeds"`
+	TLSMonitors []*TLSMonitors `json:"tls_monitors"`
+	DNSMonitors []*DNSMonitors `json:"dns_monitors"`
+	// States is the confirmed state of every pipeline of the project on each datacenter
+	States []MonitorState `json:"states"`
 }
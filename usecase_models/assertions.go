@@ -0,0 +1,37 @@
+package usecase_models
+
+const (
+	// AssertionOpExists is used when no operator is set, rules saved before operators existed only checked the key
+	AssertionOpExists      = "exists"
+	AssertionOpEquals      = "equals"
+	AssertionOpNotEquals   = "not_equals"
+	AssertionOpContains    = "contains"
+	AssertionOpRegex       = "regex"
+	AssertionOpGreaterThan = "greater_than"
+	AssertionOpLessThan    = "less_than"
+	AssertionOpTypeOf      = "type_of"
+	AssertionOpLength      = "length"
+	// AssertionOpSchema marks json schema violations, it can not be used in assertions
+	AssertionOpSchema = "schema"
+)
+
+const (
+	AssertionLogicAnd = "and"
+	AssertionLogicOr  = "or"
+)
+
+// AssertionGroup combines assertions and nested groups with and/or, an empty logic means and
+type AssertionGroup struct {
+	Logic      string           `json:"logic"`
+	Assertions []KeyValueModel  `json:"assertions"`
+	Groups     []AssertionGroup `json:"groups"`
+}
+
+// AssertionFailure is one failed check of a step, stored per endpoint name with the session
+type AssertionFailure struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,30 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+// Badge opts a pipeline in to public SVG badges, the badge urls only work with its token
+type Badge struct {
+	ID           int       `boil:"id" json:"id"`
+	ProjectId    int       `boil:"project_id" json:"project_id"`
+	PipelineType string    `boil:"pipeline_type" json:"pipeline_type"`
+	PipelineId   int       `boil:"pipeline_id" json:"pipeline_id"`
+	Token        string    `boil:"token" json:"token"`
+	CreatedAt    time.Time `boil:"created_at" json:"created_at"`
+	DeletedAt    null.Time `boil:"deleted_at" json:"deleted_at"`
+}
+
+type BadgeRequest struct {
+	ProjectId    int    `json:"project_id"`
+	PipelineType string `json:"pipeline_type"`
+	PipelineId   int    `json:"pipeline_id"`
+}
+
+type EnableBadgeResponse struct {
+	BadgeId   int    `json:"badge_id"`
+	Token     string `json:"token"`
+	UptimeUrl string `json:"uptime_url"`
+	StatusUrl string `json:"status_url"`
+}
@@ -0,0 +1,64 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+// CronMonitorType is the pipeline type of inbound heartbeat monitors in notifications and incidents
+const CronMonitorType = "cron"
+
+const (
+	// CronMonitorStatusNew is kept until the first ping, a monitor that never reported is not alerted on
+	CronMonitorStatusNew  = "new"
+	CronMonitorStatusUp   = "up"
+	CronMonitorStatusDown = "down"
+)
+
+const (
+	CronPingKindPing    = "ping"
+	CronPingKindStart   = "start"
+	CronPingKindSuccess = "success"
+	CronPingKindFail    = "fail"
+)
+
+// CronMonitor is an inbound heartbeat, the watched job calls /cron/:token at least every Period seconds.
+// a ping later than Period plus Grace, a fail ping or a start without a finish within Grace marks it down.
+type CronMonitor struct {
+	ID          int         `boil:"id" json:"id"`
+	ProjectId   int         `boil:"project_id" json:"project_id"`
+	Name        string      `boil:"name" json:"name"`
+	Token       string      `boil:"token" json:"token"`
+	Period      int         `boil:"period" json:"period"`
+	Grace       int         `boil:"grace" json:"grace"`
+	IsActive    bool        `boil:"is_active" json:"is_active"`
+	Status      string      `boil:"status" json:"status"`
+	LastPingAt  null.Time   `boil:"last_ping_at" json:"last_ping_at"`
+	LastStartAt null.Time   `boil:"last_start_at" json:"last_start_at"`
+	LastFailAt  null.Time   `boil:"last_fail_at" json:"last_fail_at"`
+	RootCause   null.String `boil:"root_cause" json:"root_cause"`
+	CreatedAt   time.Time   `boil:"created_at" json:"created_at"`
+	UpdatedAt   time.Time   `boil:"updated_at" json:"updated_at"`
+	DeletedAt   null.Time   `boil:"deleted_at" json:"deleted_at"`
+}
+
+type CronMonitorPing struct {
+	ID            int         `boil:"id" json:"id"`
+	CronMonitorId int         `boil:"cron_monitor_id" json:"cron_monitor_id"`
+	Kind          string      `boil:"kind" json:"kind"`
+	RemoteAddr    null.String `boil:"remote_addr" json:"remote_addr"`
+	CreatedAt     time.Time   `boil:"created_at" json:"created_at"`
+}
+
+type CronMonitorRequest struct {
+	ProjectId int    `json:"project_id"`
+	Name      string `json:"name"`
+	Period    int    `json:"period"`
+	Grace     int    `json:"grace"`
+	IsActive  bool   `json:"is_active"`
+}
+
+type CreateCronMonitorResponse struct {
+	CronMonitorId int    `json:"cron_monitor_id"`
+	Token         string `json:"token"`
+}
@@ -0,0 +1,24 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+const (
+	DatacenterStatusHealthy  = "healthy"
+	DatacenterStatusDegraded = "degraded"
+	// DatacenterStatusOffline datacenters are left out of the datacenter selection of every rule
+	DatacenterStatusOffline = "offline"
+)
+
+// DatacenterHealth is what the scheduler health prober last saw of a datacenter agent
+type DatacenterHealth struct {
+	DatacenterId        int          `boil:"datacenter_id" json:"datacenter_id"`
+	Status              string       `boil:"status" json:"status"`
+	Availability        float64      `boil:"availability" json:"availability"` // moving percent of successful probes
+	Latency             null.Float64 `boil:"latency" json:"latency"`           // seconds of the last successful probe
+	ConsecutiveFailures int          `boil:"consecutive_failures" json:"consecutive_failures"`
+	LastError           null.String  `boil:"last_error" json:"last_error"`
+	CheckedAt           time.Time    `boil:"checked_at" json:"checked_at"`
+}
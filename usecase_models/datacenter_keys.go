@@ -0,0 +1,17 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+// DatacenterKey is a shared secret between the manager and the agent of a datacenter.
+// the manager signs with the newest active key and falls back to older ones the agent does not know yet,
+// so a new key can be added before the agent is given it and the old one revoked after
+type DatacenterKey struct {
+	KeyId        string    `boil:"key_id" json:"key_id"`
+	DatacenterId int       `boil:"datacenter_id" json:"datacenter_id"`
+	Secret       string    `boil:"secret" json:"secret,omitempty"` // only returned when the key is created
+	CreatedAt    time.Time `boil:"created_at" json:"created_at"`
+	RevokedAt    null.Time `boil:"revoked_at" json:"revoked_at"`
+}
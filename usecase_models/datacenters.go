@@ -5,20 +5,45 @@ import (
 	"time"
 )
 
+// Datacenter is a location checks run from, ProjectId is set for the private datacenters of a project
 type Datacenter struct {
-	ID             int          `json:"id"`
-	Baseurl        string       `json:"baseurl"`
-	Title          string       `json:"title"`
-	ConnectionRate null.Int     `json:"connection_rate"`
-	Lat            null.Float64 `json:"lat"`
-	LNG            null.Float64 `json:"lng"`
-	LocationName   null.String  `json:"location_name"`
-	CountryName    null.String  `json:"country_name"`
-	UpdatedAt      time.Time    `json:"updated_at"`
-	CreatedAt      time.Time    `json:"created_at"`
-	DeletedAt      null.Time    `json:"deleted_at"`
+	ID             int               `json:"id"`
+	Baseurl        string            `json:"baseurl"`
+	Title          string            `json:"title"`
+	ConnectionRate null.Int          `json:"connection_rate"`
+	Lat            null.Float64      `json:"lat"`
+	LNG            null.Float64      `json:"lng"`
+	LocationName   null.String       `json:"location_name"`
+	CountryName    null.String       `json:"country_name"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	CreatedAt      time.Time         `json:"created_at"`
+	DeletedAt      null.Time         `json:"deleted_at"`
+	Health         *DatacenterHealth `json:"health,omitempty"`
+	Mode           string            `json:"mode"`
+	ProjectId      int               `json:"project_id,omitempty"`
+}
+
+const (
+	// DatacenterModePush datacenters are called by the manager on their baseurl
+	DatacenterModePush = "push"
+	// DatacenterModePull datacenters have no reachable baseurl, their agent long polls the manager for jobs
+	DatacenterModePull = "pull"
+)
+
+// DatacenterAgent is how the manager reaches the agent of a datacenter, LastSeenAt is the last poll of a pull agent
+type DatacenterAgent struct {
+	DatacenterId int       `boil:"datacenter_id" json:"datacenter_id"`
+	Mode         string    `boil:"mode" json:"mode"`
+	LastSeenAt   null.Time `boil:"last_seen_at" json:"last_seen_at"`
 }
 
 type CreateDatacenterResponse struct {
 	DatacenterId int `json:"datacenter_id"`
 }
+
+// CreateProjectDatacenterResponse carries the first key of a private datacenter, its secret is the token
+// the agent signs with and is only returned here
+type CreateProjectDatacenterResponse struct {
+	DatacenterId int           `json:"datacenter_id"`
+	Key          DatacenterKey `json:"key"`
+}
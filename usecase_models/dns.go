@@ -0,0 +1,52 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+// DNSMonitorType is the pipeline type of dns record monitors in notifications, incidents and maintenance windows
+const DNSMonitorType = "dns"
+
+// DNSNotificationStateChanged is sent when the answers of a record differ from the previous session
+const DNSNotificationStateChanged = "changed"
+
+const (
+	DNSRecordA     = "A"
+	DNSRecordAAAA  = "AAAA"
+	DNSRecordCNAME = "CNAME"
+	DNSRecordMX    = "MX"
+	DNSRecordTXT   = "TXT"
+	DNSRecordNS    = "NS"
+)
+
+const (
+	// DNSMatchExact needs the answers to be the expected values, the order does not matter
+	DNSMatchExact = "exact"
+	// DNSMatchContains needs every expected value to be among the answers
+	DNSMatchContains = "contains"
+)
+
+// DNSRules is one record to resolve, answers are only asserted when Expected is set
+type DNSRules struct {
+	AgentDNSRequest
+	Expected []string `json:"expected"`
+	Match    string   `json:"match"`
+}
+
+// DNSStat is what one datacenter resolved for one record of a session
+type DNSStat struct {
+	Time           time.Time    `boil:"time" json:"time"`
+	SessionId      string       `boil:"session_id" json:"session_id"`
+	DNSMonitorId   int          `boil:"dns_monitor_id" json:"dns_monitor_id"`
+	Name           string       `boil:"name" json:"name"`
+	RecordType     string       `boil:"record_type" json:"record_type"`
+	Resolver       null.String  `boil:"resolver" json:"resolver"`
+	DatacenterId   int          `boil:"datacenter_id" json:"datacenter_id"`
+	Success        int          `boil:"success" json:"success"`
+	IsMaintenance  bool         `boil:"is_maintenance" json:"is_maintenance"`
+	IsAgentError   bool         `boil:"is_agent_error" json:"is_agent_error"`
+	ResolutionTime null.Float64 `boil:"resolution_time" json:"resolution_time"`
+	Answers        null.JSON    `boil:"answers" json:"answers"`
+	RootCause      null.String  `boil:"root_cause" json:"root_cause"`
+}
@@ -1,5 +1,7 @@
 package usecase_models
 
+import "encoding/json"
+
 type EndpointRules struct {
 	EndpointName    string            `json:"endpoint_name"`
 	Url             string            `json:"url"`
@@ -7,16 +9,29 @@ type EndpointRules struct {
 	Body            string            `json:"body"`
 	Header          map[string]string `json:"header"`
 	AcceptanceModel AcceptanceModel   `json:"acceptance_model"` // check keys with their type and status
+	// ResponseSchema is an inline json schema of the response body, ResponseSchemaId refers to a stored one instead
+	ResponseSchema   json.RawMessage `json:"response_schema,omitempty"`
+	ResponseSchemaId int             `json:"response_schema_id"`
 }
 
 type AcceptanceModel struct {
 	Statuses       []string        `json:"statuses"`
 	ResponseBodies []KeyValueModel `json:"response_bodies"`
+	// Logic combines ResponseBodies and BodyGroups with and/or, and by default
+	Logic      string           `json:"logic"`
+	BodyGroups []AssertionGroup `json:"body_groups"`
+	// ResponseHeaders are all required to pass, Key is the header name
+	ResponseHeaders []KeyValueModel `json:"response_headers"`
+	// MaxResponseTime fails the step when the response time reported by the agent is above it, in seconds
+	// like the agent reports it. zero disables the check
+	MaxResponseTime float64 `json:"max_response_time"`
 }
 
+// KeyValueModel asserts on the body value at the gjson path Key, Operator is one of the AssertionOp constants
 type KeyValueModel struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
 }
 
 type EndpointResponses struct {
@@ -0,0 +1,64 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+const (
+	IncidentStatusOpen     = "open"
+	IncidentStatusResolved = "resolved"
+)
+
+const (
+	IncidentEventDown = "down"
+	IncidentEventDiff = "diff"
+	IncidentEventUp   = "up"
+	// manual events recorded from the incident API
+	IncidentEventAcknowledged = "acknowledged"
+	IncidentEventNote         = "note"
+	IncidentEventResolved     = "resolved"
+)
+
+// IsWarningState tells if a notification state is a one off warning, warnings do not open or resolve incidents
+func IsWarningState(state string) bool {
+	return state == TLSNotificationStateExpiring || state == DNSNotificationStateChanged
+}
+
+type Incident struct {
+	ID             int             `boil:"id" json:"id"`
+	ProjectId      int             `boil:"project_id" json:"project_id"`
+	PipelineType   string          `boil:"pipeline_type" json:"pipeline_type"`
+	PipelineId     int             `boil:"pipeline_id" json:"pipeline_id"`
+	PipelineName   null.String     `boil:"pipeline_name" json:"pipeline_name"`
+	Status         string          `boil:"status" json:"status"`
+	Address        null.String     `boil:"address" json:"address"`
+	RootCause      null.String     `boil:"root_cause" json:"root_cause"`
+	Datacenters    null.String     `boil:"datacenters" json:"datacenters"`
+	StartedAt      time.Time       `boil:"started_at" json:"started_at"`
+	ResolvedAt     null.Time       `boil:"resolved_at" json:"resolved_at"`
+	Duration       null.Int        `boil:"duration" json:"duration"` // seconds
+	AcknowledgedAt null.Time       `boil:"acknowledged_at" json:"acknowledged_at"`
+	AcknowledgedBy null.Int        `boil:"acknowledged_by" json:"acknowledged_by"`
+	ResolvedBy     null.Int        `boil:"resolved_by" json:"resolved_by"`
+	CreatedAt      time.Time       `boil:"created_at" json:"created_at"`
+	UpdatedAt      time.Time       `boil:"updated_at" json:"updated_at"`
+	Events         []IncidentEvent `boil:"-" json:"events,omitempty"`
+}
+
+type IncidentEvent struct {
+	ID                  int         `boil:"id" json:"id"`
+	IncidentId          int         `boil:"incident_id" json:"incident_id"`
+	EventType           string      `boil:"event_type" json:"event_type"`
+	RootCause           null.String `boil:"root_cause" json:"root_cause"`
+	Datacenters         null.String `boil:"datacenters" json:"datacenters"`
+	ResolvedDatacenters null.String `boil:"resolved_datacenters" json:"resolved_datacenters"`
+	FailedDatacenters   null.String `boil:"failed_datacenters" json:"failed_datacenters"`
+	AccountId           null.Int    `boil:"account_id" json:"account_id"`
+	Note                null.String `boil:"note" json:"note"`
+	CreatedAt           time.Time   `boil:"created_at" json:"created_at"`
+}
+
+type IncidentNoteRequest struct {
+	Note string `json:"note"`
+}
@@ -0,0 +1,28 @@
+package usecase_models
+
+import (
+	"encoding/json"
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+// JsonSchema is a reusable response contract, endpoint steps reference it by ID
+type JsonSchema struct {
+	ID        int       `boil:"id" json:"id"`
+	ProjectId int       `boil:"project_id" json:"project_id"`
+	Name      string    `boil:"name" json:"name"`
+	Schema    null.JSON `boil:"schema" json:"schema"`
+	CreatedAt time.Time `boil:"created_at" json:"created_at"`
+	UpdatedAt time.Time `boil:"updated_at" json:"updated_at"`
+	DeletedAt null.Time `boil:"deleted_at" json:"deleted_at"`
+}
+
+type JsonSchemaRequest struct {
+	ProjectId int             `json:"project_id"`
+	Name      string          `json:"name"`
+	Schema    json.RawMessage `json:"schema"`
+}
+
+type CreateJsonSchemaResponse struct {
+	JsonSchemaId int `json:"json_schema_id"`
+}
@@ -0,0 +1,71 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+const (
+	MaintenanceRecurrenceNone    = ""
+	MaintenanceRecurrenceDaily   = "daily"
+	MaintenanceRecurrenceWeekly  = "weekly"
+	MaintenanceRecurrenceMonthly = "monthly"
+)
+
+// MaintenanceWindow covers the whole project when PipelineType is null, otherwise only that pipeline.
+// StartsAt and EndsAt are the first occurrence, recurring windows repeat it until RepeatUntil.
+type MaintenanceWindow struct {
+	ID           int         `boil:"id" json:"id"`
+	ProjectId    int         `boil:"project_id" json:"project_id"`
+	PipelineType null.String `boil:"pipeline_type" json:"pipeline_type"`
+	PipelineId   null.Int    `boil:"pipeline_id" json:"pipeline_id"`
+	Title        string      `boil:"title" json:"title"`
+	StartsAt     time.Time   `boil:"starts_at" json:"starts_at"`
+	EndsAt       time.Time   `boil:"ends_at" json:"ends_at"`
+	Recurrence   string      `boil:"recurrence" json:"recurrence"`
+	RepeatUntil  null.Time   `boil:"repeat_until" json:"repeat_until"`
+	CreatedAt    time.Time   `boil:"created_at" json:"created_at"`
+	UpdatedAt    time.Time   `boil:"updated_at" json:"updated_at"`
+	DeletedAt    null.Time   `boil:"deleted_at" json:"deleted_at"`
+}
+
+// ActiveAt reports whether t falls in any occurrence of the window
+func (m MaintenanceWindow) ActiveAt(t time.Time) bool {
+	if t.Before(m.StartsAt) || !m.EndsAt.After(m.StartsAt) {
+		return false
+	}
+	if m.RepeatUntil.Valid && t.After(m.RepeatUntil.Time) {
+		return false
+	}
+
+	start := m.StartsAt
+	switch m.Recurrence {
+	case MaintenanceRecurrenceDaily:
+		start = start.AddDate(0, 0, int(t.Sub(start).Hours()/24))
+	case MaintenanceRecurrenceWeekly:
+		start = start.AddDate(0, 0, int(t.Sub(start).Hours()/(24*7))*7)
+	case MaintenanceRecurrenceMonthly:
+		months := (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+		start = start.AddDate(0, months, 0)
+		if start.After(t) {
+			start = m.StartsAt.AddDate(0, months-1, 0)
+		}
+	}
+	end := start.Add(m.EndsAt.Sub(m.StartsAt))
+	return !t.Before(start) && t.Before(end)
+}
+
+type MaintenanceWindowRequest struct {
+	ProjectId    int    `json:"project_id"`
+	PipelineType string `json:"pipeline_type"`
+	PipelineId   int    `json:"pipeline_id"`
+	Title        string `json:"title"`
+	StartsAt     string `json:"starts_at"`
+	EndsAt       string `json:"ends_at"`
+	Recurrence   string `json:"recurrence"`
+	RepeatUntil  string `json:"repeat_until"`
+}
+
+type CreateMaintenanceWindowResponse struct {
+	MaintenanceWindowId int `json:"maintenance_window_id"`
+}
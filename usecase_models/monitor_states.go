@@ -0,0 +1,29 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+const (
+	MonitorStatusUp   = "up"
+	MonitorStatusDown = "down"
+	// MonitorStatusUnknown is kept while the datacenter agent could not run the checks
+	MonitorStatusUnknown = "unknown"
+)
+
+// MonitorState is the confirmed state of a pipeline on one datacenter, it survives cache flushes
+// so up and down transitions are still evaluated against the last known state
+type MonitorState struct {
+	PipelineType        string      `boil:"pipeline_type" json:"pipeline_type"`
+	PipelineId          int         `boil:"pipeline_id" json:"pipeline_id"`
+	DatacenterId        int         `boil:"datacenter_id" json:"datacenter_id"`
+	ProjectId           int         `boil:"project_id" json:"project_id"`
+	Status              string      `boil:"status" json:"status"`
+	Since               time.Time   `boil:"since" json:"since"`
+	LastSessionId       string      `boil:"last_session_id" json:"last_session_id"`
+	ConsecutiveFailures int         `boil:"consecutive_failures" json:"consecutive_failures"`
+	Address             null.String `boil:"address" json:"address"`
+	RootCause           null.String `boil:"root_cause" json:"root_cause"`
+	UpdatedAt           time.Time   `boil:"updated_at" json:"updated_at"`
+}
@@ -22,8 +22,42 @@ type CreateProjectResponse struct {
 	ProjectId int `json:"project_id"`
 }
 
+const (
+	NotificationChannelSlack    = "slack"
+	NotificationChannelTelegram = "telegram"
+	NotificationChannelEmail    = "email"
+	NotificationChannelWebhook  = "webhook"
+)
+
+// Notifications keeps the old per channel lists which are delivered by the alert service,
+// Targets choose their own driver and Webhooks receive signed machine readable events
 type Notifications struct {
-	Telegram []string `json:"telegram"`
-	Slack    []string `json:"slack"`
-	Email    []string `json:"email"`
+	Telegram []string              `json:"telegram"`
+	Slack    []string              `json:"slack"`
+	Email    []string              `json:"email"`
+	Targets  []NotificationTarget  `json:"targets"`
+	Webhooks []WebhookSubscription `json:"webhooks"`
+}
+
+// NotificationTarget is one destination of notifications.
+// Driver is one of alert_service, slack, telegram, smtp, webhook or pagerduty, Channel is only used by alert_service
+type NotificationTarget struct {
+	Driver  string `json:"driver"`
+	Channel string `json:"channel,omitempty"`
+	Target  string `json:"target"`
+}
+
+// AllTargets returns Targets along with the old lists as alert service targets
+func (n Notifications) AllTargets() []NotificationTarget {
+	var targets []NotificationTarget
+	for _, value := range n.Slack {
+		targets = append(targets, NotificationTarget{Driver: "alert_service", Channel: NotificationChannelSlack, Target: value})
+	}
+	for _, value := range n.Email {
+		targets = append(targets, NotificationTarget{Driver: "alert_service", Channel: NotificationChannelEmail, Target: value})
+	}
+	for _, value := range n.Telegram {
+		targets = append(targets, NotificationTarget{Driver: "alert_service", Channel: NotificationChannelTelegram, Target: value})
+	}
+	return append(targets, n.Targets...)
 }
@@ -6,6 +6,8 @@ type RulesRequest struct {
 	NetCats     NetCats     `json:"net_cats"`
 	Pings       Pings       `json:"pings"`
 	PageSpeed   PageSpeeds  `json:"page_speeds"`
+	TLSMonitors TLSMonitors `json:"tls_monitors"`
+	DNSMonitors DNSMonitors `json:"dns_monitors"`
 }
 
 type Endpoints struct {
@@ -32,3 +34,18 @@ type PageSpeeds struct {
 	PageSpeed  []PageSpeedRules `json:"page_speeds"`
 	Scheduling Scheduling       `json:"scheduling"`
 }
+
+// TLSMonitors checks the certificates of its addresses from the datacenter agents. an expired certificate,
+// a hostname mismatch or an untrusted chain fails the session and every expiry threshold is alerted once
+type TLSMonitors struct {
+	TLSMonitors      []TLSRules `json:"tls_monitors"`
+	ExpiryThresholds []int      `json:"expiry_thresholds"`
+	Scheduling       Scheduling `json:"scheduling"`
+}
+
+// DNSMonitors resolves its records from the datacenter agents. answers that stop matching the expected
+// values fail the session and answers that differ from the previous session are notified as a change
+type DNSMonitors struct {
+	DNSMonitors []DNSRules `json:"dns_monitors"`
+	Scheduling  Scheduling `json:"scheduling"`
+}
@@ -1,13 +1,51 @@
 package usecase_models
 
 type Scheduling struct {
-	PipelineId     int    `json:"pipeline_id"`
-	PipelineName   string `json:"pipeline_name"`
-	IsUp           bool   `json:"is_up"`
-	ProjectId      int    `json:"project_id"`
-	Duration       int    `json:"duration"`
-	EndAt          string `json:"end_at"`
-	IsActive       bool   `json:"is_active"`
-	DataCentersIds []int  `json:"data_centers"` // datacenter id
-	IsHeartBeat    bool   `json:"is_heart_beat"`
+	PipelineId          int                 `json:"pipeline_id"`
+	PipelineName        string              `json:"pipeline_name"`
+	IsUp                bool                `json:"is_up"`
+	ProjectId           int                 `json:"project_id"`
+	Duration            int                 `json:"duration"` // minutes
+	EndAt               string              `json:"end_at"`
+	IsActive            bool                `json:"is_active"`
+	DataCentersIds      []int               `json:"data_centers"` // datacenter id
+	IsHeartBeat         bool                `json:"is_heart_beat"`
+	ConfirmationPolicy  ConfirmationPolicy  `json:"confirmation_policy"`
+	IntervalSeconds     int                 `json:"interval_seconds"` // runs the rule every n seconds, wins over duration
+	Cron                string              `json:"cron"`             // five fields or a descriptor like @hourly, wins over interval seconds
+	Timezone            string              `json:"timezone"`         // IANA name the cron is evaluated in, empty means UTC
+	DataCenterSelection DataCenterSelection `json:"data_center_selection"`
+}
+
+// ConfirmationPolicy decides when failures are trusted enough to notify.
+// zero values keep the old behaviour: any single failed datacenter means down
+type ConfirmationPolicy struct {
+	// MinFailedDatacenters is N in "N of M datacenters must fail"
+	MinFailedDatacenters int `json:"min_failed_datacenters"`
+	// ConsecutiveSessions is how many sessions in a row a datacenter must fail before it counts
+	ConsecutiveSessions int `json:"consecutive_sessions"`
+}
+
+const (
+	// DataCenterStrategyAll runs the rule from every datacenter
+	DataCenterStrategyAll = "all"
+	// DataCenterStrategyRandomN runs the rule from Count random datacenters
+	DataCenterStrategyRandomN = "random_n"
+	// DataCenterStrategyRoundRobin runs the rule from the next Count datacenters, each run moves on
+	DataCenterStrategyRoundRobin = "round_robin"
+	// DataCenterStrategyRegion runs the rule from every datacenter in the country and location
+	DataCenterStrategyRegion = "region"
+	// DataCenterStrategyWeighted runs the rule from Count random datacenters, the higher the connection rate the likelier
+	DataCenterStrategyWeighted = "weighted"
+)
+
+// DataCenterSelection picks the datacenters a run of the rule goes to, among DataCentersIds or among all
+// datacenters of the platform when it is empty. an empty strategy keeps the old behaviour: [0] is one
+// random datacenter and an empty DataCentersIds is all of them
+type DataCenterSelection struct {
+	Strategy string `json:"strategy"`
+	// Count is how many datacenters random_n, round_robin and weighted pick, 0 means one
+	Count        int    `json:"count"`
+	CountryName  string `json:"country_name"`
+	LocationName string `json:"location_name"`
 }
@@ -0,0 +1,84 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+const (
+	ComponentStatusOperational = "operational"
+	ComponentStatusDegraded    = "degraded"
+	ComponentStatusDown        = "down"
+	ComponentStatusMaintenance = "maintenance"
+	ComponentStatusNoData      = "no_data"
+)
+
+// StatusPageDays is the number of daily uptime bars shown on a public status page
+const StatusPageDays = 90
+
+type StatusPage struct {
+	ID          int                   `boil:"id" json:"id"`
+	ProjectId   int                   `boil:"project_id" json:"project_id"`
+	Slug        string                `boil:"slug" json:"slug"`
+	Title       string                `boil:"title" json:"title"`
+	Description null.String           `boil:"description" json:"description"`
+	CreatedAt   time.Time             `boil:"created_at" json:"created_at"`
+	UpdatedAt   time.Time             `boil:"updated_at" json:"updated_at"`
+	DeletedAt   null.Time             `boil:"deleted_at" json:"deleted_at"`
+	Components  []StatusPageComponent `boil:"-" json:"components"`
+}
+
+// StatusPageComponent is a monitor shown on the status page under a public name
+type StatusPageComponent struct {
+	ID           int    `boil:"id" json:"id"`
+	StatusPageId int    `boil:"status_page_id" json:"status_page_id"`
+	Name         string `boil:"name" json:"name"`
+	PipelineType string `boil:"pipeline_type" json:"pipeline_type"`
+	PipelineId   int    `boil:"pipeline_id" json:"pipeline_id"`
+	Position     int    `boil:"position" json:"position"`
+}
+
+type StatusPageRequest struct {
+	ProjectId   int    `json:"project_id"`
+	Slug        string `json:"slug"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Components  []struct {
+		Name         string `json:"name"`
+		PipelineType string `json:"pipeline_type"`
+		PipelineId   int    `json:"pipeline_id"`
+	} `json:"components"`
+}
+
+type CreateStatusPageResponse struct {
+	StatusPageId int `json:"status_page_id"`
+}
+
+// DailyUptime is one bar of the status page, Uptime is null when there is no data for that day
+type DailyUptime struct {
+	Day    time.Time    `boil:"day" json:"day"`
+	Uptime null.Float64 `boil:"uptime" json:"uptime"`
+}
+
+type PublicStatusPage struct {
+	Title       string                  `json:"title"`
+	Description string                  `json:"description"`
+	Status      string                  `json:"status"`
+	Components  []PublicStatusComponent `json:"components"`
+	Incidents   []PublicIncident        `json:"incidents"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+}
+
+type PublicStatusComponent struct {
+	Name   string        `json:"name"`
+	Status string        `json:"status"`
+	Uptime null.Float64  `json:"uptime"`
+	Days   []DailyUptime `json:"days"`
+}
+
+// PublicIncident hides internal fields of an incident, root causes may contain addresses of the monitored hosts
+type PublicIncident struct {
+	Component    string    `json:"component"`
+	StartedAt    time.Time `json:"started_at"`
+	Acknowledged bool      `json:"acknowledged"`
+}
@@ -0,0 +1,42 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+// TLSMonitorType is the pipeline type of certificate monitors in notifications, incidents and maintenance windows
+const TLSMonitorType = "tls"
+
+// TLSNotificationStateExpiring is sent once per crossed expiry threshold, it does not open an incident
+const TLSNotificationStateExpiring = "expiring"
+
+// TLSDefaultPort is used when a rule has no port
+const TLSDefaultPort = 443
+
+// DefaultTLSExpiryThresholds are the days before expiry that are alerted on when a monitor sets none
+var DefaultTLSExpiryThresholds = []int{30, 14, 7, 1}
+
+type TLSRules struct {
+	AgentTLSRequest
+}
+
+// TLSStat is the certificate one datacenter saw for one address of a session
+type TLSStat struct {
+	Time            time.Time   `boil:"time" json:"time"`
+	SessionId       string      `boil:"session_id" json:"session_id"`
+	TLSMonitorId    int         `boil:"tls_monitor_id" json:"tls_monitor_id"`
+	Address         string      `boil:"address" json:"address"`
+	DatacenterId    int         `boil:"datacenter_id" json:"datacenter_id"`
+	Success         int         `boil:"success" json:"success"`
+	IsMaintenance   bool        `boil:"is_maintenance" json:"is_maintenance"`
+	IsAgentError    bool        `boil:"is_agent_error" json:"is_agent_error"`
+	DaysUntilExpiry null.Int    `boil:"days_until_expiry" json:"days_until_expiry"`
+	NotAfter        null.Time   `boil:"not_after" json:"not_after"`
+	Issuer          null.String `boil:"issuer" json:"issuer"`
+	SANs            null.String `boil:"sans" json:"sans"`
+	Chain           null.JSON   `boil:"chain" json:"chain"`
+	HostnameValid   null.Bool   `boil:"hostname_valid" json:"hostname_valid"`
+	ChainTrusted    null.Bool   `boil:"chain_trusted" json:"chain_trusted"`
+	RootCause       null.String `boil:"root_cause" json:"root_cause"`
+}
@@ -0,0 +1,87 @@
+package usecase_models
+
+import (
+	"github.com/volatiletech/null/v8"
+	"time"
+)
+
+// WebhookEventVersion is bumped on breaking changes of the event body
+const WebhookEventVersion = "1"
+
+const (
+	WebhookEventMonitorDown     = "monitor.down"
+	WebhookEventMonitorUp       = "monitor.up"
+	WebhookEventMonitorDegraded = "monitor.degraded"
+	WebhookEventIncidentUpdated = "incident.updated"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription receives signed events, an empty Events list subscribes to all of them
+type WebhookSubscription struct {
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// Subscribed reports whether the subscription wants events of the given type
+func (w WebhookSubscription) Subscribed(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, value := range w.Events {
+		if value == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookEvent struct {
+	Id        string      `json:"id"`
+	Version   string      `json:"version"`
+	Type      string      `json:"type"`
+	ProjectId int         `json:"project_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// MonitorEventData is the data of monitor.* events.
+// monitor.degraded is sent when the failed datacenters of a down monitor change
+type MonitorEventData struct {
+	PipelineType        string   `json:"pipeline_type"`
+	PipelineId          int      `json:"pipeline_id"`
+	PipelineName        string   `json:"pipeline_name"`
+	Address             string   `json:"address"`
+	RootCause           string   `json:"root_cause"`
+	Datacenters         []string `json:"datacenters"`
+	FailedDatacenters   []string `json:"failed_datacenters"`
+	ResolvedDatacenters []string `json:"resolved_datacenters"`
+	IncidentId          int      `json:"incident_id,omitempty"`
+}
+
+// IncidentEventData is the data of incident.updated events, Event is the latest entry of the incident timeline
+type IncidentEventData struct {
+	Incident Incident      `json:"incident"`
+	Event    IncidentEvent `json:"event"`
+}
+
+type WebhookDelivery struct {
+	ID             int         `boil:"id" json:"id"`
+	ProjectId      int         `boil:"project_id" json:"project_id"`
+	EventId        string      `boil:"event_id" json:"event_id"`
+	EventType      string      `boil:"event_type" json:"event_type"`
+	Url            string      `boil:"url" json:"url"`
+	Payload        string      `boil:"payload" json:"payload"`
+	Status         string      `boil:"status" json:"status"`
+	Attempts       int         `boil:"attempts" json:"attempts"`
+	ResponseStatus null.Int    `boil:"response_status" json:"response_status"`
+	Error          null.String `boil:"error" json:"error"`
+	DeliveredAt    null.Time   `boil:"delivered_at" json:"delivered_at"`
+	CreatedAt      time.Time   `boil:"created_at" json:"created_at"`
+	UpdatedAt      time.Time   `boil:"updated_at" json:"updated_at"`
+}
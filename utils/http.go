@@ -4,8 +4,14 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func HttpCall(context context.Context, url string, authToken string, verb string, payload []byte) (body []byte, httpstatus int, err error) {
@@ -30,4 +36,75 @@ func HttpCall(context context.Context, url string, authToken string, verb string
 	}
 
 	return body, resp.StatusCode, errors.New("server returned not success status code")
-}
\ No newline at end of file
+}
+
+// blockedNetworks are ranges IsPublicIp refuses on top of the loopback, private and link-local ones
+var blockedNetworks = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"), // carrier grade nat
+	mustParseCIDR("0.0.0.0/8"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// IsPublicIp tells if ip is reachable on the internet, so not a loopback, private, link-local, multicast or unspecified address
+func IsPublicIp(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPublicUrl tells if rawUrl is an http or https url whose host is not a local name or a non public ip.
+// names are only resolved when dialing, PublicHttpClient checks the address they resolve to
+func IsPublicUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return IsPublicIp(ip)
+	}
+	return true
+}
+
+// PublicHttpClient is for urls given by tenants, it refuses to connect to non public addresses, also when a
+// name resolves to one or a redirect points to one, and ignores proxies of the environment
+func PublicHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIp(ip) {
+				return fmt.Errorf("address %s is not public", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+}